@@ -0,0 +1,350 @@
+package dydx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+const (
+	// The indexer pings every 30 seconds, a connection without any message for longer
+	// than the read timeout is considered dead
+	defaultReadTimeout  = 60 * time.Second
+	defaultWriteTimeout = 10 * time.Second
+
+	defaultInitialBackoff = 1 * time.Second
+	defaultMaxBackoff     = 32 * time.Second
+)
+
+// subscription is a channel subscription that is restored on every connection
+type subscription struct {
+	channel  string
+	id       string
+	dispatch func(id string, contents json.RawMessage, snapshot bool) error
+	// Updates are dropped after a resubscribe until the new snapshot arrives
+	resyncing bool
+}
+
+// Client streams the dYdX v4 indexer websocket channels, subscriptions are restored after
+// a reconnect and resubscribed when a message is missed so handlers always see a fresh
+// snapshot. Handlers are called from the read loop and must not block.
+type Client struct {
+	url    string
+	logger *zap.Logger
+	dialer *websocket.Dialer
+
+	readTimeout    time.Duration
+	writeTimeout   time.Duration
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+
+	mu            sync.Mutex
+	conn          *websocket.Conn
+	subscriptions map[string]*subscription
+	lastMessageID int64
+
+	writeMu sync.Mutex
+}
+
+// NewClient creates a websocket client for the indexer at url, e.g. wss://indexer.dydx.trade/v4/ws
+func NewClient(logger *zap.Logger, url string) *Client {
+	return &Client{
+		url:            url,
+		logger:         logger,
+		dialer:         websocket.DefaultDialer,
+		readTimeout:    defaultReadTimeout,
+		writeTimeout:   defaultWriteTimeout,
+		initialBackoff: defaultInitialBackoff,
+		maxBackoff:     defaultMaxBackoff,
+		subscriptions:  make(map[string]*subscription),
+	}
+}
+
+// SubscribeSubaccount streams the positions, orders and fills of a subaccount
+func (c *Client) SubscribeSubaccount(address string, subaccountNumber uint32, handler func(id string, contents *SubaccountContents, snapshot bool)) error {
+	return subscribe(c, ChannelSubaccounts, fmt.Sprintf("%s/%d", address, subaccountNumber), handler)
+}
+
+// SubscribeOrderbook streams the orderbook of a market, e.g. ATOM-USD
+func (c *Client) SubscribeOrderbook(market string, handler func(id string, contents *OrderbookContents, snapshot bool)) error {
+	return subscribe(c, ChannelOrderbook, market, handler)
+}
+
+// SubscribeTrades streams the trades of a market
+func (c *Client) SubscribeTrades(market string, handler func(id string, contents *TradesContents, snapshot bool)) error {
+	return subscribe(c, ChannelTrades, market, handler)
+}
+
+// SubscribeCandles streams the candles of a market at a resolution, e.g. 1MIN
+func (c *Client) SubscribeCandles(market, resolution string, handler func(id string, contents *CandlesContents, snapshot bool)) error {
+	return subscribe(c, ChannelCandles, fmt.Sprintf("%s/%s", market, resolution), handler)
+}
+
+// subscribe registers a typed handler for a channel and subscribes when connected
+func subscribe[T any](c *Client, channel, id string, handler func(id string, contents *T, snapshot bool)) error {
+	sub := &subscription{
+		channel: channel,
+		id:      id,
+		dispatch: func(id string, raw json.RawMessage, snapshot bool) error {
+			var contents T
+			if err := json.Unmarshal(raw, &contents); err != nil {
+				return fmt.Errorf("failed to decode %s contents: %w", channel, err)
+			}
+			handler(id, &contents, snapshot)
+			return nil
+		},
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := subscriptionKey(channel, id)
+	if _, exists := c.subscriptions[key]; exists {
+		return fmt.Errorf("already subscribed to %s %s", channel, id)
+	}
+	c.subscriptions[key] = sub
+
+	if c.conn != nil {
+		return c.send(c.conn, MessageTypeSubscribe, sub)
+	}
+
+	return nil
+}
+
+// Unsubscribe stops streaming a channel, id is the market, candle or subaccount id
+func (c *Client) Unsubscribe(channel, id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := subscriptionKey(channel, id)
+	sub, exists := c.subscriptions[key]
+	if !exists {
+		return fmt.Errorf("not subscribed to %s %s", channel, id)
+	}
+	delete(c.subscriptions, key)
+
+	if c.conn != nil {
+		return c.send(c.conn, MessageTypeUnsubscribe, sub)
+	}
+
+	return nil
+}
+
+// Run connects and streams until the context is cancelled, reconnecting with exponential
+// backoff whenever the connection drops
+func (c *Client) Run(ctx context.Context) error {
+	expBackoff := backoff.NewExponentialBackOff(
+		backoff.WithInitialInterval(c.initialBackoff),
+		backoff.WithMaxInterval(c.maxBackoff),
+		backoff.WithMaxElapsedTime(0), // Retry until the context is cancelled
+	)
+
+	for {
+		connected, err := c.runConnection(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if connected {
+			expBackoff.Reset()
+		}
+
+		wait := expBackoff.NextBackOff()
+		c.logger.Warn("Indexer websocket disconnected, reconnecting",
+			zap.Error(err),
+			zap.Duration("backoff", wait),
+		)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// runConnection dials, restores the subscriptions and reads until the connection fails,
+// it reports whether the connection was established
+func (c *Client) runConnection(ctx context.Context) (bool, error) {
+	conn, _, err := c.dialer.DialContext(ctx, c.url, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to dial indexer websocket: %w", err)
+	}
+	defer conn.Close()
+
+	// Unblock the read loop when the context is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	conn.SetPingHandler(func(data string) error {
+		if err := conn.SetReadDeadline(time.Now().Add(c.readTimeout)); err != nil {
+			return err
+		}
+		c.writeMu.Lock()
+		defer c.writeMu.Unlock()
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(c.writeTimeout))
+	})
+
+	c.mu.Lock()
+	c.conn = conn
+	c.lastMessageID = -1
+	for _, sub := range c.subscriptions {
+		if err := c.send(conn, MessageTypeSubscribe, sub); err != nil {
+			c.conn = nil
+			c.mu.Unlock()
+			return true, err
+		}
+	}
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.conn = nil
+		c.mu.Unlock()
+	}()
+
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(c.readTimeout)); err != nil {
+			return true, err
+		}
+
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return true, fmt.Errorf("failed to read indexer websocket: %w", err)
+		}
+
+		if err := c.handleMessage(conn, data); err != nil {
+			c.logger.Error("Failed to handle indexer message", zap.Error(err))
+		}
+	}
+}
+
+// handleMessage checks the message sequence and dispatches channel data to its handler
+func (c *Client) handleMessage(conn *websocket.Conn, data []byte) error {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("failed to decode message: %w", err)
+	}
+
+	c.mu.Lock()
+	lastMessageID := c.lastMessageID
+	c.lastMessageID = msg.MessageID
+	c.mu.Unlock()
+
+	// A gap means a message was lost, incremental updates are no longer trustworthy so the
+	// message is dropped and handlers wait for the new snapshot
+	if lastMessageID >= 0 && msg.MessageID != lastMessageID+1 {
+		c.logger.Warn("Indexer message sequence gap, resubscribing",
+			zap.Int64("expected", lastMessageID+1),
+			zap.Int64("received", msg.MessageID),
+		)
+
+		return c.resubscribe(conn)
+	}
+
+	switch msg.Type {
+	case MessageTypeConnected:
+		c.logger.Debug("Connected to indexer websocket", zap.String("connection_id", msg.ConnectionID))
+		return nil
+	case MessageTypeSubscribed:
+		return c.dispatch(&msg, msg.Contents, true)
+	case MessageTypeChannelData:
+		return c.dispatch(&msg, msg.Contents, false)
+	case MessageTypeChannelBatchData:
+		var batch []json.RawMessage
+		if err := json.Unmarshal(msg.Contents, &batch); err != nil {
+			return fmt.Errorf("failed to decode batch contents: %w", err)
+		}
+		for _, contents := range batch {
+			if err := c.dispatch(&msg, contents, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	case MessageTypeUnsubscribed:
+		return nil
+	case MessageTypeError:
+		return fmt.Errorf("indexer error: %s", msg.Message)
+	default:
+		return fmt.Errorf("unknown message type %s", msg.Type)
+	}
+}
+
+// dispatch passes the contents of a message to the subscription handler
+func (c *Client) dispatch(msg *Message, contents json.RawMessage, snapshot bool) error {
+	c.mu.Lock()
+	sub, exists := c.subscriptions[subscriptionKey(msg.Channel, msg.ID)]
+	resyncing := false
+	if exists {
+		if snapshot {
+			sub.resyncing = false
+		}
+		resyncing = sub.resyncing
+	}
+	c.mu.Unlock()
+
+	// Messages can still arrive for a channel that was just unsubscribed or resubscribed
+	if !exists || resyncing {
+		return nil
+	}
+
+	return sub.dispatch(msg.ID, contents, snapshot)
+}
+
+// resubscribe unsubscribes and subscribes every subscription so each receives a new snapshot,
+// updates are dropped until it arrives
+func (c *Client) resubscribe(conn *websocket.Conn) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, sub := range c.subscriptions {
+		sub.resyncing = true
+		if err := c.send(conn, MessageTypeUnsubscribe, sub); err != nil {
+			return err
+		}
+		if err := c.send(conn, MessageTypeSubscribe, sub); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// send writes a subscription request, gorilla connections support a single writer
+func (c *Client) send(conn *websocket.Conn, messageType string, sub *subscription) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
+		return err
+	}
+
+	req := subscriptionRequest{
+		Type:    messageType,
+		Channel: sub.channel,
+		ID:      sub.id,
+	}
+
+	if err := conn.WriteJSON(req); err != nil {
+		return fmt.Errorf("failed to %s %s %s: %w", messageType, sub.channel, sub.id, err)
+	}
+
+	return nil
+}
+
+func subscriptionKey(channel, id string) string {
+	return fmt.Sprintf("%s:%s", channel, id)
+}
@@ -0,0 +1,287 @@
+package dydx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testTimeout = 5 * time.Second
+
+// fakeIndexer is a local websocket server speaking the indexer protocol, each accepted
+// connection is handed to the test which scripts the messages sent on it
+type fakeIndexer struct {
+	server   *httptest.Server
+	conns    chan *fakeConn
+	requests chan subscriptionRequest
+}
+
+type fakeConn struct {
+	conn   *websocket.Conn
+	nextID int64
+}
+
+func newFakeIndexer(t *testing.T) *fakeIndexer {
+	f := &fakeIndexer{
+		conns:    make(chan *fakeConn, 4),
+		requests: make(chan subscriptionRequest, 16),
+	}
+
+	upgrader := websocket.Upgrader{}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		fc := &fakeConn{conn: conn}
+		fc.send(t, Message{Type: MessageTypeConnected, ConnectionID: "test"})
+		f.conns <- fc
+
+		for {
+			var req subscriptionRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			f.requests <- req
+		}
+	}))
+	t.Cleanup(f.server.Close)
+
+	return f
+}
+
+func (f *fakeIndexer) url() string {
+	return "ws" + strings.TrimPrefix(f.server.URL, "http")
+}
+
+func (f *fakeIndexer) nextConn(t *testing.T) *fakeConn {
+	select {
+	case fc := <-f.conns:
+		return fc
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for connection")
+		return nil
+	}
+}
+
+func (f *fakeIndexer) nextRequest(t *testing.T) subscriptionRequest {
+	select {
+	case req := <-f.requests:
+		return req
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for request")
+		return subscriptionRequest{}
+	}
+}
+
+// send writes a message with the next message id of the connection
+func (fc *fakeConn) send(t *testing.T, msg Message) {
+	msg.MessageID = fc.nextID
+	fc.nextID++
+	require.NoError(t, fc.conn.WriteJSON(msg))
+}
+
+type orderbookEvent struct {
+	id       string
+	contents *OrderbookContents
+	snapshot bool
+}
+
+func startClient(t *testing.T, f *fakeIndexer) (*Client, chan orderbookEvent) {
+	client := NewClient(zap.NewNop(), f.url())
+	client.initialBackoff = 10 * time.Millisecond
+	client.maxBackoff = 10 * time.Millisecond
+
+	events := make(chan orderbookEvent, 16)
+	err := client.SubscribeOrderbook("ATOM-USD", func(id string, contents *OrderbookContents, snapshot bool) {
+		events <- orderbookEvent{id: id, contents: contents, snapshot: snapshot}
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- client.Run(ctx) }()
+
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case err := <-done:
+			assert.ErrorIs(t, err, context.Canceled)
+		case <-time.After(testTimeout):
+			t.Error("client did not stop")
+		}
+	})
+
+	return client, events
+}
+
+func nextEvent(t *testing.T, events chan orderbookEvent) orderbookEvent {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for event")
+		return orderbookEvent{}
+	}
+}
+
+func orderbookMessage(t *testing.T, messageType, contents string) Message {
+	require.True(t, json.Valid([]byte(contents)))
+	return Message{
+		Type:     messageType,
+		Channel:  ChannelOrderbook,
+		ID:       "ATOM-USD",
+		Contents: json.RawMessage(contents),
+	}
+}
+
+func TestClientStreamsTypedOrderbook(t *testing.T) {
+	f := newFakeIndexer(t)
+	_, events := startClient(t, f)
+
+	fc := f.nextConn(t)
+	req := f.nextRequest(t)
+	assert.Equal(t, subscriptionRequest{Type: MessageTypeSubscribe, Channel: ChannelOrderbook, ID: "ATOM-USD"}, req)
+
+	fc.send(t, orderbookMessage(t, MessageTypeSubscribed,
+		`{"bids":[{"price":"9.9","size":"10"}],"asks":[{"price":"10.1","size":"5"}]}`))
+	fc.send(t, orderbookMessage(t, MessageTypeChannelData, `{"asks":[["10.1","0"],["10.2","7"]]}`))
+
+	snapshot := nextEvent(t, events)
+	assert.True(t, snapshot.snapshot)
+	assert.Equal(t, "ATOM-USD", snapshot.id)
+	assert.Equal(t, []OrderbookLevel{{Price: "9.9", Size: "10"}}, snapshot.contents.Bids)
+	assert.Equal(t, []OrderbookLevel{{Price: "10.1", Size: "5"}}, snapshot.contents.Asks)
+
+	update := nextEvent(t, events)
+	assert.False(t, update.snapshot)
+	assert.Empty(t, update.contents.Bids)
+	assert.Equal(t, []OrderbookLevel{{Price: "10.1", Size: "0"}, {Price: "10.2", Size: "7"}}, update.contents.Asks)
+}
+
+func TestClientResubscribesOnReconnect(t *testing.T) {
+	f := newFakeIndexer(t)
+	_, events := startClient(t, f)
+
+	first := f.nextConn(t)
+	assert.Equal(t, MessageTypeSubscribe, f.nextRequest(t).Type)
+	first.send(t, orderbookMessage(t, MessageTypeSubscribed, `{"bids":[],"asks":[]}`))
+	assert.True(t, nextEvent(t, events).snapshot)
+
+	// Drop the connection, the client should reconnect and subscribe again
+	require.NoError(t, first.conn.Close())
+
+	second := f.nextConn(t)
+	req := f.nextRequest(t)
+	assert.Equal(t, subscriptionRequest{Type: MessageTypeSubscribe, Channel: ChannelOrderbook, ID: "ATOM-USD"}, req)
+
+	second.send(t, orderbookMessage(t, MessageTypeSubscribed, `{"bids":[{"price":"9.8","size":"1"}],"asks":[]}`))
+	event := nextEvent(t, events)
+	assert.True(t, event.snapshot)
+	assert.Equal(t, []OrderbookLevel{{Price: "9.8", Size: "1"}}, event.contents.Bids)
+}
+
+func TestClientResubscribesOnSequenceGap(t *testing.T) {
+	f := newFakeIndexer(t)
+	_, events := startClient(t, f)
+
+	fc := f.nextConn(t)
+	assert.Equal(t, MessageTypeSubscribe, f.nextRequest(t).Type)
+	fc.send(t, orderbookMessage(t, MessageTypeSubscribed, `{"bids":[],"asks":[]}`))
+	assert.True(t, nextEvent(t, events).snapshot)
+
+	// Skip a message id
+	fc.nextID++
+	fc.send(t, orderbookMessage(t, MessageTypeChannelData, `{"bids":[["9.9","1"]]}`))
+
+	assert.Equal(t, subscriptionRequest{Type: MessageTypeUnsubscribe, Channel: ChannelOrderbook, ID: "ATOM-USD"}, f.nextRequest(t))
+	assert.Equal(t, subscriptionRequest{Type: MessageTypeSubscribe, Channel: ChannelOrderbook, ID: "ATOM-USD"}, f.nextRequest(t))
+
+	// The update that revealed the gap and updates before the new snapshot are dropped
+	fc.send(t, orderbookMessage(t, MessageTypeChannelData, `{"bids":[["9.8","2"]]}`))
+	fc.send(t, Message{Type: MessageTypeUnsubscribed, Channel: ChannelOrderbook, ID: "ATOM-USD"})
+	fc.send(t, orderbookMessage(t, MessageTypeSubscribed, `{"bids":[{"price":"9.9","size":"1"}],"asks":[]}`))
+	fc.send(t, orderbookMessage(t, MessageTypeChannelData, `{"bids":[["9.7","3"]]}`))
+
+	event := nextEvent(t, events)
+	assert.True(t, event.snapshot)
+	assert.Equal(t, []OrderbookLevel{{Price: "9.9", Size: "1"}}, event.contents.Bids)
+
+	update := nextEvent(t, events)
+	assert.False(t, update.snapshot)
+	assert.Equal(t, []OrderbookLevel{{Price: "9.7", Size: "3"}}, update.contents.Bids)
+}
+
+func TestClientSubscribeWhileConnected(t *testing.T) {
+	f := newFakeIndexer(t)
+	client, _ := startClient(t, f)
+
+	fc := f.nextConn(t)
+	assert.Equal(t, ChannelOrderbook, f.nextRequest(t).Channel)
+
+	candles := make(chan *CandlesContents, 1)
+	require.Eventually(t, func() bool {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		return client.conn != nil
+	}, testTimeout, 10*time.Millisecond)
+
+	err := client.SubscribeCandles("ATOM-USD", "1MIN", func(_ string, contents *CandlesContents, _ bool) {
+		candles <- contents
+	})
+	require.NoError(t, err)
+
+	req := f.nextRequest(t)
+	assert.Equal(t, subscriptionRequest{Type: MessageTypeSubscribe, Channel: ChannelCandles, ID: "ATOM-USD/1MIN"}, req)
+
+	fc.send(t, Message{
+		Type:     MessageTypeChannelData,
+		Channel:  ChannelCandles,
+		ID:       "ATOM-USD/1MIN",
+		Contents: json.RawMessage(`{"ticker":"ATOM-USD","resolution":"1MIN","close":"10.05","trades":3}`),
+	})
+
+	select {
+	case contents := <-candles:
+		require.Len(t, contents.Candles, 1)
+		assert.Equal(t, "10.05", contents.Candles[0].Close)
+		assert.Equal(t, int64(3), contents.Candles[0].Trades)
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for candle")
+	}
+
+	err = client.SubscribeCandles("ATOM-USD", "1MIN", func(string, *CandlesContents, bool) {})
+	assert.Error(t, err)
+}
+
+func TestCandlesContentsUnmarshal(t *testing.T) {
+	var snapshot CandlesContents
+	require.NoError(t, json.Unmarshal([]byte(`{"candles":[{"close":"1"},{"close":"2"}]}`), &snapshot))
+	require.Len(t, snapshot.Candles, 2)
+	assert.Equal(t, "2", snapshot.Candles[1].Close)
+
+	var update CandlesContents
+	require.NoError(t, json.Unmarshal([]byte(`{"close":"3"}`), &update))
+	require.Len(t, update.Candles, 1)
+	assert.Equal(t, "3", update.Candles[0].Close)
+}
+
+func TestOrderbookLevelUnmarshal(t *testing.T) {
+	var levels []OrderbookLevel
+	require.NoError(t, json.Unmarshal([]byte(`[{"price":"1","size":"2"},["3","4"]]`), &levels))
+	assert.Equal(t, []OrderbookLevel{{Price: "1", Size: "2"}, {Price: "3", Size: "4"}}, levels)
+
+	err := json.Unmarshal([]byte(`[["1"]]`), &levels)
+	assert.Error(t, err)
+}
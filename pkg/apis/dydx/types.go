@@ -0,0 +1,204 @@
+package dydx
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Indexer websocket channels
+const (
+	ChannelSubaccounts = "v4_subaccounts"
+	ChannelOrderbook   = "v4_orderbook"
+	ChannelTrades      = "v4_trades"
+	ChannelCandles     = "v4_candles"
+)
+
+// Indexer websocket message types
+const (
+	MessageTypeConnected        = "connected"
+	MessageTypeSubscribe        = "subscribe"
+	MessageTypeSubscribed       = "subscribed"
+	MessageTypeUnsubscribe      = "unsubscribe"
+	MessageTypeUnsubscribed     = "unsubscribed"
+	MessageTypeChannelData      = "channel_data"
+	MessageTypeChannelBatchData = "channel_batch_data"
+	MessageTypeError            = "error"
+)
+
+// Message is the envelope of every message sent by the indexer, message ids increase by
+// one for each message on a connection
+type Message struct {
+	Type         string          `json:"type"`
+	ConnectionID string          `json:"connection_id"`
+	MessageID    int64           `json:"message_id"`
+	Channel      string          `json:"channel,omitempty"`
+	ID           string          `json:"id,omitempty"`
+	Version      string          `json:"version,omitempty"`
+	Contents     json.RawMessage `json:"contents,omitempty"`
+	Message      string          `json:"message,omitempty"` // Set on errors
+}
+
+// subscriptionRequest subscribes to or unsubscribes from a channel
+type subscriptionRequest struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel"`
+	ID      string `json:"id"`
+}
+
+// OrderbookLevel is a single price level, a zero size removes the level
+type OrderbookLevel struct {
+	Price string `json:"price"`
+	Size  string `json:"size"`
+}
+
+// UnmarshalJSON accepts both the snapshot object form and the [price, size] update form
+func (l *OrderbookLevel) UnmarshalJSON(data []byte) error {
+	var pair []string
+	if err := json.Unmarshal(data, &pair); err == nil {
+		if len(pair) != 2 {
+			return fmt.Errorf("orderbook level must have 2 elements, got %d", len(pair))
+		}
+		l.Price, l.Size = pair[0], pair[1]
+		return nil
+	}
+
+	type level OrderbookLevel
+	return json.Unmarshal(data, (*level)(l))
+}
+
+// OrderbookContents is a full orderbook on subscription and the changed levels afterwards
+type OrderbookContents struct {
+	Bids []OrderbookLevel `json:"bids"`
+	Asks []OrderbookLevel `json:"asks"`
+}
+
+// Trade is a single trade in a market
+type Trade struct {
+	ID              string `json:"id"`
+	Side            string `json:"side"`
+	Size            string `json:"size"`
+	Price           string `json:"price"`
+	Type            string `json:"type"`
+	CreatedAt       string `json:"createdAt"`
+	CreatedAtHeight string `json:"createdAtHeight"`
+}
+
+// TradesContents is the recent trades on subscription and the new trades afterwards
+type TradesContents struct {
+	Trades []Trade `json:"trades"`
+}
+
+// Candle is a single candle of a market
+type Candle struct {
+	StartedAt            string `json:"startedAt"`
+	Ticker               string `json:"ticker"`
+	Resolution           string `json:"resolution"`
+	Low                  string `json:"low"`
+	High                 string `json:"high"`
+	Open                 string `json:"open"`
+	Close                string `json:"close"`
+	BaseTokenVolume      string `json:"baseTokenVolume"`
+	UsdVolume            string `json:"usdVolume"`
+	Trades               int64  `json:"trades"`
+	StartingOpenInterest string `json:"startingOpenInterest"`
+}
+
+// CandlesContents is the recent candles on subscription and the updated candle afterwards
+type CandlesContents struct {
+	Candles []Candle `json:"candles"`
+}
+
+// UnmarshalJSON accepts both the candle list snapshot and the single candle update
+func (c *CandlesContents) UnmarshalJSON(data []byte) error {
+	var snapshot struct {
+		Candles []Candle `json:"candles"`
+	}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+
+	if snapshot.Candles != nil {
+		c.Candles = snapshot.Candles
+		return nil
+	}
+
+	var candle Candle
+	if err := json.Unmarshal(data, &candle); err != nil {
+		return err
+	}
+	c.Candles = []Candle{candle}
+
+	return nil
+}
+
+// PerpetualPosition is a perpetual position of a subaccount
+type PerpetualPosition struct {
+	Market        string `json:"market"`
+	Status        string `json:"status"`
+	Side          string `json:"side"`
+	Size          string `json:"size"`
+	EntryPrice    string `json:"entryPrice"`
+	ExitPrice     string `json:"exitPrice,omitempty"`
+	RealizedPnl   string `json:"realizedPnl"`
+	UnrealizedPnl string `json:"unrealizedPnl"`
+	NetFunding    string `json:"netFunding"`
+}
+
+// AssetPosition is an asset balance of a subaccount
+type AssetPosition struct {
+	Symbol string `json:"symbol"`
+	Side   string `json:"side"`
+	Size   string `json:"size"`
+}
+
+// Order is an order of a subaccount
+type Order struct {
+	ID           string `json:"id"`
+	ClientID     string `json:"clientId"`
+	ClobPairID   string `json:"clobPairId"`
+	Ticker       string `json:"ticker"`
+	Side         string `json:"side"`
+	Size         string `json:"size"`
+	TotalFilled  string `json:"totalFilled"`
+	Price        string `json:"price"`
+	Type         string `json:"type"`
+	Status       string `json:"status"`
+	OrderFlags   string `json:"orderFlags"`
+	ReduceOnly   bool   `json:"reduceOnly"`
+	TriggerPrice string `json:"triggerPrice,omitempty"`
+}
+
+// Fill is a fill of a subaccount order
+type Fill struct {
+	ID        string `json:"id"`
+	OrderID   string `json:"orderId"`
+	Ticker    string `json:"ticker"`
+	Side      string `json:"side"`
+	Liquidity string `json:"liquidity"`
+	Type      string `json:"type"`
+	Price     string `json:"price"`
+	Size      string `json:"size"`
+	Fee       string `json:"fee"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// Subaccount is the subaccount state sent on subscription
+type Subaccount struct {
+	Address                string                       `json:"address"`
+	SubaccountNumber       uint32                       `json:"subaccountNumber"`
+	Equity                 string                       `json:"equity"`
+	FreeCollateral         string                       `json:"freeCollateral"`
+	OpenPerpetualPositions map[string]PerpetualPosition `json:"openPerpetualPositions"`
+	AssetPositions         map[string]AssetPosition     `json:"assetPositions"`
+}
+
+// SubaccountContents is the subaccount state on subscription and the changed positions,
+// orders and fills afterwards
+type SubaccountContents struct {
+	Subaccount         *Subaccount         `json:"subaccount,omitempty"` // Only set on subscription
+	PerpetualPositions []PerpetualPosition `json:"perpetualPositions,omitempty"`
+	AssetPositions     []AssetPosition     `json:"assetPositions,omitempty"`
+	Orders             []Order             `json:"orders,omitempty"`
+	Fills              []Fill              `json:"fills,omitempty"`
+	BlockHeight        string              `json:"blockHeight,omitempty"`
+}
@@ -0,0 +1,145 @@
+package assets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	transfertypes "github.com/cosmos/ibc-go/v8/modules/apps/transfer/types"
+)
+
+// AssetList is the assetlist.json of a chain in the chain registry
+type AssetList struct {
+	ChainName string           `json:"chain_name"`
+	Assets    []AssetListEntry `json:"assets"`
+}
+
+// AssetListEntry is an asset held on the chain of an asset list
+type AssetListEntry struct {
+	Base       string      `json:"base"`
+	Name       string      `json:"name"`
+	Display    string      `json:"display"`
+	Symbol     string      `json:"symbol"`
+	DenomUnits []DenomUnit `json:"denom_units"`
+	Traces     []Trace     `json:"traces"`
+}
+
+// DenomUnit is a unit of an asset
+type DenomUnit struct {
+	Denom    string `json:"denom"`
+	Exponent int    `json:"exponent"`
+}
+
+// Trace is a step an asset took to reach the chain, traces are ordered from the origin
+type Trace struct {
+	Type         string `json:"type"`
+	Counterparty struct {
+		ChainName string `json:"chain_name"`
+		BaseDenom string `json:"base_denom"`
+		ChannelID string `json:"channel_id"`
+	} `json:"counterparty"`
+	Chain struct {
+		ChannelID string `json:"channel_id"`
+		Path      string `json:"path"`
+	} `json:"chain"`
+}
+
+// ParseAssetList decodes an assetlist.json
+func ParseAssetList(r io.Reader) (*AssetList, error) {
+	var list AssetList
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode asset list: %w", err)
+	}
+	if list.ChainName == "" {
+		return nil, errors.New("asset list has no chain_name")
+	}
+	return &list, nil
+}
+
+// LoadAssetListFile reads an assetlist.json snapshot
+func LoadAssetListFile(path string) (*AssetList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open asset list: %w", err)
+	}
+	defer f.Close()
+
+	return ParseAssetList(f)
+}
+
+// decimals returns the exponent of the display unit of the asset
+func (e *AssetListEntry) decimals() int {
+	for _, unit := range e.DenomUnits {
+		if unit.Denom == e.Display {
+			return unit.Exponent
+		}
+	}
+	return 0
+}
+
+// RegisterAssetList registers the assets of the asset list of a chain. Native assets are
+// registered with the chain name of the list as their origin and IBC assets with the chain
+// name their first IBC trace comes from, so assets are identified by chain registry names.
+// The channel of the last IBC trace of each asset is registered to its counterparty chain.
+// Entries that cannot be registered are skipped and returned as a joined error.
+func (r *Registry) RegisterAssetList(chainID string, list *AssetList) error {
+	var errs []error
+	for i := range list.Assets {
+		if err := r.registerAssetListEntry(chainID, list.ChainName, &list.Assets[i]); err != nil {
+			errs = append(errs, fmt.Errorf("asset %s: %w", list.Assets[i].Base, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Registry) registerAssetListEntry(chainID, chainName string, entry *AssetListEntry) error {
+	asset := &Asset{
+		Origin:   chainName,
+		Base:     entry.Base,
+		Symbol:   entry.Symbol,
+		Name:     entry.Name,
+		Display:  entry.Display,
+		Decimals: entry.decimals(),
+	}
+
+	if !strings.HasPrefix(entry.Base, transfertypes.DenomPrefix+"/") {
+		_, err := r.RegisterDenom(chainID, "", asset)
+		return err
+	}
+
+	var first, last *Trace
+	for i := range entry.Traces {
+		if entry.Traces[i].Type != "ibc" {
+			continue
+		}
+		if first == nil {
+			first = &entry.Traces[i]
+		}
+		last = &entry.Traces[i]
+	}
+	if last == nil {
+		return errors.New("no ibc trace")
+	}
+
+	trace := transfertypes.ParseDenomTrace(last.Chain.Path)
+	if trace.BaseDenom != first.Counterparty.BaseDenom {
+		return fmt.Errorf("trace path %s does not end in %s", last.Chain.Path, first.Counterparty.BaseDenom)
+	}
+	if trace.IBCDenom() != entry.Base {
+		return fmt.Errorf("trace path %s does not hash to the base denom", last.Chain.Path)
+	}
+
+	asset.Origin = first.Counterparty.ChainName
+	asset.Base = trace.BaseDenom
+
+	if _, err := r.RegisterDenom(chainID, trace.Path, asset); err != nil {
+		return err
+	}
+
+	hop, _, _ := firstHop(trace.Path)
+	port, channel, _ := strings.Cut(hop, "/")
+	return r.RegisterChannel(chainID, port, channel, last.Counterparty.ChainName)
+}
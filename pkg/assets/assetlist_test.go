@@ -0,0 +1,106 @@
+package assets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const osmosisAssetList = `{
+  "$schema": "../assetlist.schema.json",
+  "chain_name": "osmosis",
+  "assets": [
+    {
+      "description": "The native token of Osmosis",
+      "denom_units": [{"denom": "uosmo", "exponent": 0}, {"denom": "osmo", "exponent": 6}],
+      "base": "uosmo",
+      "name": "Osmosis",
+      "display": "osmo",
+      "symbol": "OSMO"
+    },
+    {
+      "denom_units": [
+        {"denom": "ibc/498A0751C798A0D9A389AA3691123DADA57DAA4FE165D5C75894505B876BA6E4", "exponent": 0, "aliases": ["uusdc"]},
+        {"denom": "usdc", "exponent": 6}
+      ],
+      "type_asset": "ics20",
+      "base": "ibc/498A0751C798A0D9A389AA3691123DADA57DAA4FE165D5C75894505B876BA6E4",
+      "name": "USDC",
+      "display": "usdc",
+      "symbol": "USDC",
+      "traces": [
+        {
+          "type": "ibc",
+          "counterparty": {"chain_name": "noble", "base_denom": "uusdc", "channel_id": "channel-1"},
+          "chain": {"channel_id": "channel-750", "path": "transfer/channel-750/uusdc"}
+        }
+      ]
+    },
+    {
+      "denom_units": [{"denom": "ibc/0000000000000000000000000000000000000000000000000000000000000000", "exponent": 0}],
+      "base": "ibc/0000000000000000000000000000000000000000000000000000000000000000",
+      "display": "broken",
+      "symbol": "BROKEN",
+      "traces": [
+        {
+          "type": "ibc",
+          "counterparty": {"chain_name": "cosmoshub", "base_denom": "uatom", "channel_id": "channel-141"},
+          "chain": {"channel_id": "channel-0", "path": "transfer/channel-0/uatom"}
+        }
+      ]
+    }
+  ]
+}`
+
+func TestRegisterAssetList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "assetlist.json")
+	require.NoError(t, os.WriteFile(path, []byte(osmosisAssetList), 0o600))
+
+	list, err := LoadAssetListFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "osmosis", list.ChainName)
+	require.Len(t, list.Assets, 3)
+
+	registry := NewRegistry(nil)
+	err = registry.RegisterAssetList("osmosis-1", list)
+	assert.ErrorContains(t, err, "does not hash to the base denom")
+
+	osmo, err := registry.Resolve(context.Background(), "osmosis-1", "uosmo")
+	require.NoError(t, err)
+	assert.Equal(t, &Asset{Origin: "osmosis", Base: "uosmo", Symbol: "OSMO", Name: "Osmosis", Display: "osmo", Decimals: 6}, osmo.Asset)
+	assert.False(t, osmo.IsIBC())
+
+	usdc, err := registry.Resolve(context.Background(), "osmosis-1", osmosisUSDC)
+	require.NoError(t, err)
+	assert.Equal(t, "noble", usdc.Asset.Origin)
+	assert.Equal(t, "uusdc", usdc.Asset.Base)
+	assert.Equal(t, 6, usdc.Asset.Decimals)
+	assert.Equal(t, "transfer/channel-750", usdc.Trace.Path)
+
+	// The channel of the trace is registered to the origin of the asset
+	assert.ErrorContains(t, registry.RegisterChannel("osmosis-1", "transfer", "channel-750", "axelar"), "registered to noble")
+
+	asset, ok := registry.Asset("noble", "uusdc")
+	require.True(t, ok)
+	assert.Same(t, usdc.Asset, asset)
+
+	// Loading the list again only reports the broken entry
+	require.Error(t, registry.RegisterAssetList("osmosis-1", list))
+	assert.Len(t, registry.Assets(), 2)
+}
+
+func TestParseAssetListInvalid(t *testing.T) {
+	_, err := ParseAssetList(strings.NewReader(`{"assets": []}`))
+	assert.ErrorContains(t, err, "no chain_name")
+
+	_, err = ParseAssetList(strings.NewReader(`{`))
+	assert.ErrorContains(t, err, "failed to decode asset list")
+
+	_, err = LoadAssetListFile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorContains(t, err, "failed to open asset list")
+}
@@ -0,0 +1,272 @@
+package assets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	locustmath "github.com/margined-protocol/locust-core/pkg/math"
+
+	sdkmath "cosmossdk.io/math"
+
+	transfertypes "github.com/cosmos/ibc-go/v8/modules/apps/transfer/types"
+)
+
+// ErrUnknownAsset is returned when a denom does not map to a registered asset
+var ErrUnknownAsset = errors.New("unknown asset")
+
+// Asset is a token identified by the chain it is native to and its base denom there
+type Asset struct {
+	Origin   string // Chain the asset is native to, e.g. "noble"
+	Base     string // Base denom on the origin chain, e.g. "uusdc"
+	Symbol   string // e.g. "USDC"
+	Name     string
+	Display  string // Display denom, e.g. "usdc"
+	Decimals int    // Exponent of the display denom
+}
+
+func (a *Asset) key() string {
+	return a.Origin + "/" + a.Base
+}
+
+func (a *Asset) validate() error {
+	if a.Origin == "" || a.Base == "" {
+		return errors.New("asset origin and base denom must be set")
+	}
+	if strings.HasPrefix(a.Base, transfertypes.DenomPrefix+"/") {
+		return fmt.Errorf("asset base denom %s must be native to its origin", a.Base)
+	}
+	return nil
+}
+
+// Denom is a denom held on a chain and the asset it represents
+type Denom struct {
+	ChainID string
+	Denom   string
+	// Trace is the path the asset took to the chain, the path is empty for native denoms
+	Trace transfertypes.DenomTrace
+	Asset *Asset
+}
+
+// IsIBC reports whether the denom is an IBC voucher
+func (d *Denom) IsIBC() bool {
+	return d.Trace.Path != ""
+}
+
+// Registry maps the denoms held on each chain to canonical assets
+type Registry struct {
+	mu sync.RWMutex
+	// Map of origin chain/base denom -> asset
+	assets map[string]*Asset
+	// Map of chain ID -> denom -> denom
+	denoms map[string]map[string]*Denom
+	// Map of chain ID -> port/channel -> chain name of the counterparty
+	channels map[string]map[string]string
+	query    TraceQuerier
+}
+
+// NewRegistry creates a registry resolving unregistered IBC denoms with the querier, which may
+// be nil to only resolve registered denoms
+func NewRegistry(query TraceQuerier) *Registry {
+	return &Registry{
+		assets:   make(map[string]*Asset),
+		denoms:   make(map[string]map[string]*Denom),
+		channels: make(map[string]map[string]string),
+		query:    query,
+	}
+}
+
+// RegisterAsset adds a canonical asset, returning the registered asset when it already exists
+func (r *Registry) RegisterAsset(asset *Asset) (*Asset, error) {
+	if err := asset.validate(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.registerAsset(asset), nil
+}
+
+func (r *Registry) registerAsset(asset *Asset) *Asset {
+	if existing, ok := r.assets[asset.key()]; ok {
+		return existing
+	}
+	r.assets[asset.key()] = asset
+	return asset
+}
+
+// RegisterDenom maps a denom held on a chain to an asset, the denom is native when the path
+// is empty and the ibc/<hash> denom of the path and base denom otherwise
+func (r *Registry) RegisterDenom(chainID, path string, asset *Asset) (*Denom, error) {
+	if chainID == "" {
+		return nil, errors.New("chain ID must be set")
+	}
+
+	if err := asset.validate(); err != nil {
+		return nil, err
+	}
+
+	trace := transfertypes.DenomTrace{Path: path, BaseDenom: asset.Base}
+	if err := trace.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid denom trace %s: %w", trace.GetFullDenomPath(), err)
+	}
+	ibcDenom := trace.IBCDenom()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.denoms[chainID][ibcDenom]; ok {
+		if existing.Asset.key() != asset.key() {
+			return nil, fmt.Errorf("denom %s on %s already registered as %s", ibcDenom, chainID, existing.Asset.Symbol)
+		}
+		return existing, nil
+	}
+
+	denom := &Denom{ChainID: chainID, Denom: ibcDenom, Trace: trace, Asset: r.registerAsset(asset)}
+	r.addDenom(denom)
+
+	return denom, nil
+}
+
+func (r *Registry) addDenom(denom *Denom) {
+	if r.denoms[denom.ChainID] == nil {
+		r.denoms[denom.ChainID] = make(map[string]*Denom)
+	}
+	r.denoms[denom.ChainID][denom.Denom] = denom
+}
+
+// RegisterChannel records the chain registry name of the chain a channel on a chain leads to,
+// so denoms received directly from that chain can be resolved to the assets native to it
+func (r *Registry) RegisterChannel(chainID, portID, channelID, counterparty string) error {
+	if chainID == "" || portID == "" || channelID == "" || counterparty == "" {
+		return errors.New("chain ID, port, channel and counterparty must be set")
+	}
+
+	hop := portID + "/" + channelID
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.channels[chainID][hop]; ok {
+		if existing != counterparty {
+			return fmt.Errorf("channel %s on %s already registered to %s", hop, chainID, existing)
+		}
+		return nil
+	}
+
+	if r.channels[chainID] == nil {
+		r.channels[chainID] = make(map[string]string)
+	}
+	r.channels[chainID][hop] = counterparty
+
+	return nil
+}
+
+// Asset returns the registered asset native to the chain with the base denom
+func (r *Registry) Asset(origin, base string) (*Asset, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	asset, ok := r.assets[origin+"/"+base]
+	return asset, ok
+}
+
+// Assets returns the registered assets sorted by origin and base denom
+func (r *Registry) Assets() []*Asset {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	assets := make([]*Asset, 0, len(r.assets))
+	for _, asset := range r.assets {
+		assets = append(assets, asset)
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		return assets[i].key() < assets[j].key()
+	})
+	return assets
+}
+
+// Denom returns the denom of the asset held on the chain when it is registered
+func (r *Registry) Denom(chainID string, asset *Asset) (*Denom, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, denom := range r.denoms[chainID] {
+		if denom.Asset.key() == asset.key() {
+			return denom, true
+		}
+	}
+	return nil, false
+}
+
+// Resolve returns the asset a denom held on a chain represents. IBC denoms that are not
+// registered are resolved with a DenomTrace query on the chain and mapped to the asset with
+// the base denom of the trace only when the path is a single hop over a channel registered to
+// the origin of the asset. Multi-hop denoms must be registered with their full path.
+func (r *Registry) Resolve(ctx context.Context, chainID, denom string) (*Denom, error) {
+	r.mu.RLock()
+	resolved, ok := r.denoms[chainID][denom]
+	r.mu.RUnlock()
+	if ok {
+		return resolved, nil
+	}
+
+	if !strings.HasPrefix(denom, transfertypes.DenomPrefix+"/") {
+		return nil, fmt.Errorf("%w: %s on %s", ErrUnknownAsset, denom, chainID)
+	}
+	if r.query == nil {
+		return nil, fmt.Errorf("%w: %s on %s is not registered", ErrUnknownAsset, denom, chainID)
+	}
+
+	trace, err := r.query(ctx, chainID, denom)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query denom trace of %s on %s: %w", denom, chainID, err)
+	}
+	if trace.IBCDenom() != denom {
+		return nil, fmt.Errorf("denom trace %s does not hash to %s", trace.GetFullDenomPath(), denom)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Further hops may lead anywhere, only the chain a single hop leads to is known
+	hop, rest, ok := firstHop(trace.Path)
+	origin, known := r.channels[chainID][hop]
+	asset, registered := r.assets[origin+"/"+trace.BaseDenom]
+	if !ok || rest != "" || !known || !registered {
+		return nil, fmt.Errorf("%w: %s on %s is %s", ErrUnknownAsset, denom, chainID, trace.GetFullDenomPath())
+	}
+
+	resolved = &Denom{ChainID: chainID, Denom: denom, Trace: trace, Asset: asset}
+	r.addDenom(resolved)
+
+	return resolved, nil
+}
+
+// firstHop splits the port/channel the denom was last received over from the rest of a path
+func firstHop(path string) (hop, rest string, ok bool) {
+	elements := strings.SplitN(path, "/", 3)
+	if len(elements) < 2 {
+		return "", "", false
+	}
+	hop = elements[0] + "/" + elements[1]
+	if len(elements) == 3 {
+		rest = elements[2]
+	}
+	return hop, rest, true
+}
+
+// ConvertDecimals converts an amount of one asset to the decimals of another, truncating when
+// the other asset has fewer decimals
+func ConvertDecimals(amount sdkmath.Int, from, to *Asset) sdkmath.Int {
+	return locustmath.ConvertDecimalsSDK(amount, from.Decimals, to.Decimals)
+}
+
+// ToDisplay returns an amount of base denom in the display denom of the asset
+func (a *Asset) ToDisplay(amount sdkmath.Int) sdkmath.LegacyDec {
+	return sdkmath.LegacyNewDecFromIntWithPrec(amount, int64(a.Decimals))
+}
@@ -0,0 +1,177 @@
+package assets
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdkmath "cosmossdk.io/math"
+
+	transfertypes "github.com/cosmos/ibc-go/v8/modules/apps/transfer/types"
+)
+
+const (
+	osmosisUSDC = "ibc/498A0751C798A0D9A389AA3691123DADA57DAA4FE165D5C75894505B876BA6E4"
+	neutronUSDC = "ibc/B559A80D62249C8AA07A380E2A2BEA6E5CA9A6F079C912C3A9E9B494105E4F81"
+)
+
+func usdc() *Asset {
+	return &Asset{Origin: "noble", Base: "uusdc", Symbol: "USDC", Display: "usdc", Decimals: 6}
+}
+
+func TestIBCDenom(t *testing.T) {
+	tests := []struct {
+		path     string
+		base     string
+		expected string
+		wantErr  bool
+	}{
+		{path: "transfer/channel-750", base: "uusdc", expected: osmosisUSDC},
+		{path: "transfer/channel-30", base: "uusdc", expected: neutronUSDC},
+		{path: "", base: "uosmo", expected: "uosmo"},
+		{path: "transfer", base: "uusdc", wantErr: true},
+		{path: "transfer/channel-30", base: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+"/"+tt.base, func(t *testing.T) {
+			denom, err := IBCDenom(tt.path, tt.base)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, denom)
+		})
+	}
+}
+
+func TestRegistryRegisterDenom(t *testing.T) {
+	registry := NewRegistry(nil)
+
+	native, err := registry.RegisterDenom("noble-1", "", usdc())
+	require.NoError(t, err)
+	assert.Equal(t, "uusdc", native.Denom)
+	assert.False(t, native.IsIBC())
+
+	// The asset registered first is the canonical one
+	voucher, err := registry.RegisterDenom("neutron-1", "transfer/channel-30", usdc())
+	require.NoError(t, err)
+	assert.Equal(t, neutronUSDC, voucher.Denom)
+	assert.True(t, voucher.IsIBC())
+	assert.Same(t, native.Asset, voucher.Asset)
+
+	denom, ok := registry.Denom("neutron-1", usdc())
+	require.True(t, ok)
+	assert.Equal(t, neutronUSDC, denom.Denom)
+
+	_, ok = registry.Denom("osmosis-1", usdc())
+	assert.False(t, ok)
+
+	_, err = registry.RegisterDenom("neutron-1", "transfer/channel-30", &Asset{Origin: "osmosis", Base: "uusdc"})
+	assert.ErrorContains(t, err, "already registered as USDC")
+
+	_, err = registry.RegisterAsset(&Asset{Origin: "neutron", Base: neutronUSDC})
+	assert.ErrorContains(t, err, "must be native")
+
+	assert.Len(t, registry.Assets(), 1)
+}
+
+func TestRegistryResolve(t *testing.T) {
+	queries := 0
+	multiHop := transfertypes.DenomTrace{Path: "transfer/channel-750/transfer/channel-4", BaseDenom: "uusdc"}
+	otherHop := transfertypes.DenomTrace{Path: "transfer/channel-750/transfer/channel-9", BaseDenom: "uusdc"}
+	traces := map[string]transfertypes.DenomTrace{
+		osmosisUSDC: {Path: "transfer/channel-750", BaseDenom: "uusdc"},
+		neutronUSDC: {Path: "transfer/channel-30", BaseDenom: "uusdc"},
+		"ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2": {Path: "transfer/channel-0", BaseDenom: "uatom"},
+		"ibc/00000000000000000000000000000000000000000000000000000000000000AA": {Path: "transfer/channel-1", BaseDenom: "uusdc"},
+		multiHop.IBCDenom(): multiHop,
+		otherHop.IBCDenom(): otherHop,
+	}
+	query := func(_ context.Context, chainID, denom string) (transfertypes.DenomTrace, error) {
+		queries++
+		if chainID != "osmosis-1" {
+			return transfertypes.DenomTrace{}, errors.New("unknown chain")
+		}
+		return traces[denom], nil
+	}
+
+	registry := NewRegistry(query)
+	_, err := registry.RegisterDenom("neutron-1", "transfer/channel-30", usdc())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	denom, err := registry.Resolve(ctx, "neutron-1", neutronUSDC)
+	require.NoError(t, err)
+	assert.Equal(t, "USDC", denom.Asset.Symbol)
+	assert.Zero(t, queries)
+
+	// A matching base denom alone does not prove where the path leads
+	_, err = registry.Resolve(ctx, "osmosis-1", osmosisUSDC)
+	assert.ErrorIs(t, err, ErrUnknownAsset)
+
+	require.NoError(t, registry.RegisterChannel("osmosis-1", "transfer", "channel-750", "noble"))
+	require.NoError(t, registry.RegisterChannel("osmosis-1", "transfer", "channel-0", "cosmoshub"))
+	assert.ErrorContains(t, registry.RegisterChannel("osmosis-1", "transfer", "channel-750", "axelar"), "already registered")
+
+	queries = 0
+	denom, err = registry.Resolve(ctx, "osmosis-1", osmosisUSDC)
+	require.NoError(t, err)
+	assert.Equal(t, "USDC", denom.Asset.Symbol)
+	assert.Equal(t, "transfer/channel-750", denom.Trace.Path)
+	assert.Equal(t, 1, queries)
+
+	// Resolved denoms are cached
+	_, err = registry.Resolve(ctx, "osmosis-1", osmosisUSDC)
+	require.NoError(t, err)
+	assert.Equal(t, 1, queries)
+
+	// A path continuing past the registered channel may lead anywhere, even when it shares its
+	// first hop with a resolved denom
+	_, err = registry.Resolve(ctx, "osmosis-1", multiHop.IBCDenom())
+	assert.ErrorIs(t, err, ErrUnknownAsset)
+
+	// The channel leads to another chain than the origin of the asset
+	_, err = registry.Resolve(ctx, "osmosis-1", "ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2")
+	assert.ErrorIs(t, err, ErrUnknownAsset)
+
+	_, err = registry.Resolve(ctx, "osmosis-1", neutronUSDC)
+	assert.ErrorIs(t, err, ErrUnknownAsset)
+
+	_, err = registry.Resolve(ctx, "osmosis-1", "ibc/00000000000000000000000000000000000000000000000000000000000000AA")
+	assert.ErrorContains(t, err, "does not hash to")
+
+	_, err = registry.Resolve(ctx, "dydx-mainnet-1", osmosisUSDC)
+	assert.ErrorContains(t, err, "unknown chain")
+
+	_, err = registry.Resolve(ctx, "osmosis-1", "uosmo")
+	assert.ErrorIs(t, err, ErrUnknownAsset)
+
+	// Multi-hop denoms resolve once registered with their full path, sharing a first hop with
+	// another registered denom does not make them resolve as it
+	_, err = registry.RegisterDenom("osmosis-1", multiHop.Path, &Asset{Origin: "ethereum", Base: "uusdc", Symbol: "USDC.axl"})
+	require.NoError(t, err)
+
+	denom, err = registry.Resolve(ctx, "osmosis-1", multiHop.IBCDenom())
+	require.NoError(t, err)
+	assert.Equal(t, "USDC.axl", denom.Asset.Symbol)
+
+	_, err = registry.Resolve(ctx, "osmosis-1", otherHop.IBCDenom())
+	assert.ErrorIs(t, err, ErrUnknownAsset)
+
+	_, err = NewRegistry(nil).Resolve(ctx, "osmosis-1", osmosisUSDC)
+	assert.ErrorIs(t, err, ErrUnknownAsset)
+}
+
+func TestConvertDecimals(t *testing.T) {
+	wei := &Asset{Origin: "ethereum", Base: "wei", Decimals: 18}
+
+	assert.Equal(t, "1500000000000000000", ConvertDecimals(sdkmath.NewInt(1_500_000), usdc(), wei).String())
+	assert.Equal(t, "1", ConvertDecimals(sdkmath.NewInt(1_999_999_999_999), wei, usdc()).String())
+	assert.Equal(t, "1.500000000000000000", usdc().ToDisplay(sdkmath.NewInt(1_500_000)).String())
+}
@@ -0,0 +1,46 @@
+package assets
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/margined-protocol/locust-core/pkg/connection"
+
+	transfertypes "github.com/cosmos/ibc-go/v8/modules/apps/transfer/types"
+)
+
+// TraceQuerier returns the denom trace of an ibc/<hash> denom on a chain
+type TraceQuerier func(ctx context.Context, chainID, denom string) (transfertypes.DenomTrace, error)
+
+// IBCDenom returns the ibc/<hash> denom of a base denom that travelled along a path of port and
+// channel pairs, e.g. "transfer/channel-30", the base denom itself when the path is empty
+func IBCDenom(path, baseDenom string) (string, error) {
+	trace := transfertypes.DenomTrace{Path: path, BaseDenom: baseDenom}
+	if err := trace.Validate(); err != nil {
+		return "", fmt.Errorf("invalid denom trace %s: %w", trace.GetFullDenomPath(), err)
+	}
+	return trace.IBCDenom(), nil
+}
+
+// GRPCTraceQuerier queries denom traces from the transfer module over the gRPC endpoints of
+// the chains in the client registry
+func GRPCTraceQuerier(clientRegistry *connection.ClientRegistry) TraceQuerier {
+	return func(ctx context.Context, chainID, denom string) (transfertypes.DenomTrace, error) {
+		grpcClient, err := clientRegistry.GetGRPCClient(chainID)
+		if err != nil {
+			return transfertypes.DenomTrace{}, err
+		}
+
+		res, err := transfertypes.NewQueryClient(grpcClient.GetClient()).DenomTrace(ctx, &transfertypes.QueryDenomTraceRequest{
+			Hash: denom,
+		})
+		if err != nil {
+			return transfertypes.DenomTrace{}, err
+		}
+		if res.DenomTrace == nil {
+			return transfertypes.DenomTrace{}, fmt.Errorf("no denom trace for %s", denom)
+		}
+
+		return *res.DenomTrace, nil
+	}
+}
@@ -0,0 +1,293 @@
+package chains
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/margined-protocol/locust-core/pkg/assets"
+)
+
+// ErrChainNotFound is returned when a chain is not in the registry
+var ErrChainNotFound = errors.New("chain not found in the chain registry")
+
+// IBCChannel is a channel of a chain to a counterparty chain, from the chain's end
+type IBCChannel struct {
+	ChainName             string
+	ChannelID             string
+	PortID                string
+	ConnectionID          string
+	CounterpartyChainName string
+	CounterpartyChannelID string
+	CounterpartyPortID    string
+	Ordering              string
+	Version               string
+	// Status is the status tag of the channel, e.g. "live" or "killed", empty when untagged
+	Status    string
+	Preferred bool
+}
+
+// ibcFile is a file of the _IBC directory of the chain registry
+type ibcFile struct {
+	Chain1   ibcChain `json:"chain_1"`
+	Chain2   ibcChain `json:"chain_2"`
+	Channels []struct {
+		Chain1   ibcChannelEnd `json:"chain_1"`
+		Chain2   ibcChannelEnd `json:"chain_2"`
+		Ordering string        `json:"ordering"`
+		Version  string        `json:"version"`
+		Tags     struct {
+			Status    string `json:"status"`
+			Preferred bool   `json:"preferred"`
+		} `json:"tags"`
+	} `json:"channels"`
+}
+
+type ibcChain struct {
+	ChainName    string `json:"chain_name"`
+	ConnectionID string `json:"connection_id"`
+}
+
+type ibcChannelEnd struct {
+	ChannelID string `json:"channel_id"`
+	PortID    string `json:"port_id"`
+}
+
+// LocalRegistry is a chain registry read from local cosmos chain-registry files
+type LocalRegistry struct {
+	// Map of chain name -> chain
+	chains map[string]*ChainMetadata
+	// Map of chain ID -> chain name
+	names map[string]string
+	// Map of chain name -> asset list
+	assetLists map[string]*assets.AssetList
+	// Map of chain name -> channels
+	channels map[string][]IBCChannel
+}
+
+// LoadLocalRegistryDir reads a checkout of the chain registry, e.g. a vendored copy
+func LoadLocalRegistryDir(dir string) (*LocalRegistry, error) {
+	return LoadLocalRegistry(os.DirFS(dir))
+}
+
+// LoadLocalRegistry reads the chain registry from the root of a file system, which may be an
+// embedded copy. Each chain is read from <chain_name>/chain.json and its optional assetlist.json,
+// and channels from the _IBC directory. Testnets and chains without a chain.json are ignored.
+func LoadLocalRegistry(fsys fs.FS) (*LocalRegistry, error) {
+	r := &LocalRegistry{
+		chains:     make(map[string]*ChainMetadata),
+		names:      make(map[string]string),
+		assetLists: make(map[string]*assets.AssetList),
+		channels:   make(map[string][]IBCChannel),
+	}
+
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chain registry: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), "_") || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if err := r.loadChain(fsys, entry.Name()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.loadChannels(fsys); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *LocalRegistry) loadChain(fsys fs.FS, dir string) error {
+	data, err := fs.ReadFile(fsys, path.Join(dir, "chain.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s/chain.json: %w", dir, err)
+	}
+
+	var chain ChainMetadata
+	if err := json.Unmarshal(data, &chain); err != nil {
+		return fmt.Errorf("failed to unmarshal %s/chain.json: %w", dir, err)
+	}
+	if chain.Name == "" || chain.ChainID == "" {
+		return fmt.Errorf("%s/chain.json has no chain_name or chain_id", dir)
+	}
+	if existing, ok := r.names[chain.ChainID]; ok {
+		return fmt.Errorf("chain ID %s of %s is also used by %s", chain.ChainID, chain.Name, existing)
+	}
+
+	r.chains[chain.Name] = &chain
+	r.names[chain.ChainID] = chain.Name
+
+	f, err := fsys.Open(path.Join(dir, "assetlist.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s/assetlist.json: %w", dir, err)
+	}
+	defer f.Close()
+
+	list, err := assets.ParseAssetList(f)
+	if err != nil {
+		return fmt.Errorf("failed to read %s/assetlist.json: %w", dir, err)
+	}
+	r.assetLists[chain.Name] = list
+
+	return nil
+}
+
+func (r *LocalRegistry) loadChannels(fsys fs.FS) error {
+	files, err := fs.Glob(fsys, "_IBC/*.json")
+	if err != nil {
+		return fmt.Errorf("failed to list IBC files: %w", err)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		var ibc ibcFile
+		if err := json.Unmarshal(data, &ibc); err != nil {
+			return fmt.Errorf("failed to unmarshal %s: %w", file, err)
+		}
+
+		for _, channel := range ibc.Channels {
+			r.channels[ibc.Chain1.ChainName] = append(r.channels[ibc.Chain1.ChainName], IBCChannel{
+				ChainName:             ibc.Chain1.ChainName,
+				ChannelID:             channel.Chain1.ChannelID,
+				PortID:                channel.Chain1.PortID,
+				ConnectionID:          ibc.Chain1.ConnectionID,
+				CounterpartyChainName: ibc.Chain2.ChainName,
+				CounterpartyChannelID: channel.Chain2.ChannelID,
+				CounterpartyPortID:    channel.Chain2.PortID,
+				Ordering:              channel.Ordering,
+				Version:               channel.Version,
+				Status:                channel.Tags.Status,
+				Preferred:             channel.Tags.Preferred,
+			})
+			r.channels[ibc.Chain2.ChainName] = append(r.channels[ibc.Chain2.ChainName], IBCChannel{
+				ChainName:             ibc.Chain2.ChainName,
+				ChannelID:             channel.Chain2.ChannelID,
+				PortID:                channel.Chain2.PortID,
+				ConnectionID:          ibc.Chain2.ConnectionID,
+				CounterpartyChainName: ibc.Chain1.ChainName,
+				CounterpartyChannelID: channel.Chain1.ChannelID,
+				CounterpartyPortID:    channel.Chain1.PortID,
+				Ordering:              channel.Ordering,
+				Version:               channel.Version,
+				Status:                channel.Tags.Status,
+				Preferred:             channel.Tags.Preferred,
+			})
+		}
+	}
+
+	return nil
+}
+
+// Chain returns the metadata of the chain with the chain ID
+func (r *LocalRegistry) Chain(chainID string) (*ChainMetadata, error) {
+	name, ok := r.names[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrChainNotFound, chainID)
+	}
+	return r.chains[name], nil
+}
+
+// ChainByName returns the metadata of the chain with the chain registry name
+func (r *LocalRegistry) ChainByName(name string) (*ChainMetadata, error) {
+	chain, ok := r.chains[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrChainNotFound, name)
+	}
+	return chain, nil
+}
+
+// Chains returns the metadata of every chain sorted by chain name
+func (r *LocalRegistry) Chains() []*ChainMetadata {
+	chains := make([]*ChainMetadata, 0, len(r.chains))
+	for _, chain := range r.chains {
+		chains = append(chains, chain)
+	}
+	sort.Slice(chains, func(i, j int) bool {
+		return chains[i].Name < chains[j].Name
+	})
+	return chains
+}
+
+// AssetList returns the asset list of the chain with the chain ID
+func (r *LocalRegistry) AssetList(chainID string) (*assets.AssetList, bool) {
+	list, ok := r.assetLists[r.names[chainID]]
+	return list, ok
+}
+
+// RegisterAssets registers the asset lists of every chain in an asset registry, entries that
+// cannot be registered are returned as a joined error
+func (r *LocalRegistry) RegisterAssets(registry *assets.Registry) error {
+	var errs []error
+	for _, chain := range r.Chains() {
+		list, ok := r.assetLists[chain.Name]
+		if !ok {
+			continue
+		}
+		if err := registry.RegisterAssetList(chain.ChainID, list); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", chain.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Channels returns the IBC channels of the chain with the chain ID
+func (r *LocalRegistry) Channels(chainID string) ([]IBCChannel, error) {
+	name, ok := r.names[chainID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrChainNotFound, chainID)
+	}
+	return r.channels[name], nil
+}
+
+// TransferChannel returns the channel transfers from one chain to another are sent on, the
+// preferred live channel between the transfer ports
+func (r *LocalRegistry) TransferChannel(sourceChainID, destChainID string) (*IBCChannel, error) {
+	channels, err := r.Channels(sourceChainID)
+	if err != nil {
+		return nil, err
+	}
+	dest, err := r.Chain(destChainID)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *IBCChannel
+	for i := range channels {
+		channel := &channels[i]
+		if channel.CounterpartyChainName != dest.Name || channel.PortID != "transfer" || channel.CounterpartyPortID != "transfer" {
+			continue
+		}
+		if channel.Status != "" && channel.Status != "live" {
+			continue
+		}
+		if found == nil || (channel.Preferred && !found.Preferred) {
+			found = channel
+		}
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("no live transfer channel from %s to %s", sourceChainID, destChainID)
+	}
+	return found, nil
+}
@@ -0,0 +1,227 @@
+package chains
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/margined-protocol/locust-core/pkg/assets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const neutronChain = `{
+  "$schema": "../chain.schema.json",
+  "chain_name": "neutron",
+  "status": "live",
+  "network_type": "mainnet",
+  "pretty_name": "Neutron",
+  "chain_type": "cosmos",
+  "chain_id": "neutron-1",
+  "bech32_prefix": "neutron",
+  "slip44": 118,
+  "fees": {
+    "fee_tokens": [
+      {"denom": "untrn", "fixed_min_gas_price": 0.0053, "low_gas_price": 0.0053, "average_gas_price": 0.0075, "high_gas_price": 0.01},
+      {"denom": "ibc/B559A80D62249C8AA07A380E2A2BEA6E5CA9A6F079C912C3A9E9B494105E4F81", "fixed_min_gas_price": 0.008, "low_gas_price": 0.008, "average_gas_price": 0.008, "high_gas_price": 0.008}
+    ]
+  },
+  "staking": {"staking_tokens": [{"denom": "untrn"}]},
+  "apis": {
+    "rpc": [{"address": "https://rpc-kralum.neutron-1.neutron.org", "provider": "Neutron"}],
+    "rest": [{"address": "https://rest-kralum.neutron-1.neutron.org", "provider": "Neutron"}],
+    "grpc": [
+      {"address": "grpc-kralum.neutron-1.neutron.org:443", "provider": "Neutron"},
+      {"address": "http://neutron-grpc.polkachu.com:19190", "provider": "Polkachu"}
+    ]
+  }
+}`
+
+const nobleChain = `{
+  "chain_name": "noble",
+  "chain_id": "noble-1",
+  "bech32_prefix": "noble",
+  "fees": {"fee_tokens": [{"denom": "uusdc", "fixed_min_gas_price": 0.1}]}
+}`
+
+const nobleAssetList = `{
+  "chain_name": "noble",
+  "assets": [
+    {
+      "denom_units": [{"denom": "uusdc", "exponent": 0}, {"denom": "usdc", "exponent": 6}],
+      "base": "uusdc",
+      "name": "USDC",
+      "display": "usdc",
+      "symbol": "USDC"
+    }
+  ]
+}`
+
+const neutronNobleIBC = `{
+  "$schema": "../ibc_data.schema.json",
+  "chain_1": {"chain_name": "neutron", "client_id": "07-tendermint-40", "connection_id": "connection-31"},
+  "chain_2": {"chain_name": "noble", "client_id": "07-tendermint-25", "connection_id": "connection-20"},
+  "channels": [
+    {
+      "chain_1": {"channel_id": "channel-31", "port_id": "transfer"},
+      "chain_2": {"channel_id": "channel-19", "port_id": "transfer"},
+      "ordering": "unordered",
+      "version": "ics20-1",
+      "tags": {"status": "killed"}
+    },
+    {
+      "chain_1": {"channel_id": "channel-30", "port_id": "transfer"},
+      "chain_2": {"channel_id": "channel-18", "port_id": "transfer"},
+      "ordering": "unordered",
+      "version": "ics20-1",
+      "tags": {"status": "live", "preferred": true}
+    },
+    {
+      "chain_1": {"channel_id": "channel-40", "port_id": "wasm.neutron1contract"},
+      "chain_2": {"channel_id": "channel-50", "port_id": "transfer"},
+      "ordering": "unordered",
+      "version": "ics20-1"
+    }
+  ]
+}`
+
+func testRegistryFS() fstest.MapFS {
+	return fstest.MapFS{
+		"neutron/chain.json":      {Data: []byte(neutronChain)},
+		"noble/chain.json":        {Data: []byte(nobleChain)},
+		"noble/assetlist.json":    {Data: []byte(nobleAssetList)},
+		"_IBC/neutron-noble.json": {Data: []byte(neutronNobleIBC)},
+		"_non-cosmos/chain.json":  {Data: []byte(`{}`)},
+		"testnets/README.md":      {Data: []byte("testnets")},
+		"README.md":               {Data: []byte("chain registry")},
+	}
+}
+
+func TestLoadLocalRegistry(t *testing.T) {
+	registry, err := LoadLocalRegistry(testRegistryFS())
+	require.NoError(t, err)
+
+	chains := registry.Chains()
+	require.Len(t, chains, 2)
+	assert.Equal(t, "neutron", chains[0].Name)
+	assert.Equal(t, "noble", chains[1].Name)
+
+	neutron, err := registry.Chain("neutron-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Neutron", neutron.PrettyName)
+	assert.Equal(t, "neutron", neutron.Bech32Prefix)
+	assert.Equal(t, uint32(118), neutron.Slip44)
+	assert.Equal(t, "untrn", neutron.StakingDenom())
+	assert.Len(t, neutron.Fees.FeeTokens, 2)
+	assert.Equal(t, "https://rest-kralum.neutron-1.neutron.org", neutron.APIs.REST[0].Address)
+
+	noble, err := registry.ChainByName("noble")
+	require.NoError(t, err)
+	assert.Empty(t, noble.StakingDenom())
+
+	_, err = registry.Chain("osmosis-1")
+	assert.ErrorIs(t, err, ErrChainNotFound)
+
+	_, ok := registry.AssetList("neutron-1")
+	assert.False(t, ok)
+	list, ok := registry.AssetList("noble-1")
+	require.True(t, ok)
+	assert.Equal(t, "USDC", list.Assets[0].Symbol)
+}
+
+func TestLocalRegistryChannels(t *testing.T) {
+	registry, err := LoadLocalRegistry(testRegistryFS())
+	require.NoError(t, err)
+
+	channels, err := registry.Channels("noble-1")
+	require.NoError(t, err)
+	require.Len(t, channels, 3)
+	assert.Equal(t, IBCChannel{
+		ChainName:             "noble",
+		ChannelID:             "channel-18",
+		PortID:                "transfer",
+		ConnectionID:          "connection-20",
+		CounterpartyChainName: "neutron",
+		CounterpartyChannelID: "channel-30",
+		CounterpartyPortID:    "transfer",
+		Ordering:              "unordered",
+		Version:               "ics20-1",
+		Status:                "live",
+		Preferred:             true,
+	}, channels[1])
+
+	channel, err := registry.TransferChannel("neutron-1", "noble-1")
+	require.NoError(t, err)
+	assert.Equal(t, "channel-30", channel.ChannelID)
+	assert.Equal(t, "channel-18", channel.CounterpartyChannelID)
+
+	channel, err = registry.TransferChannel("noble-1", "neutron-1")
+	require.NoError(t, err)
+	assert.Equal(t, "channel-18", channel.ChannelID)
+
+	_, err = registry.TransferChannel("neutron-1", "neutron-1")
+	assert.ErrorContains(t, err, "no live transfer channel")
+
+	_, err = registry.TransferChannel("neutron-1", "osmosis-1")
+	assert.ErrorIs(t, err, ErrChainNotFound)
+}
+
+func TestLocalRegistryRegisterAssets(t *testing.T) {
+	registry, err := LoadLocalRegistry(testRegistryFS())
+	require.NoError(t, err)
+
+	assetRegistry := assets.NewRegistry(nil)
+	require.NoError(t, registry.RegisterAssets(assetRegistry))
+
+	denom, err := assetRegistry.Resolve(context.Background(), "noble-1", "uusdc")
+	require.NoError(t, err)
+	assert.Equal(t, "noble", denom.Asset.Origin)
+	assert.Equal(t, 6, denom.Asset.Decimals)
+}
+
+func TestLoadLocalRegistryInvalid(t *testing.T) {
+	tests := []struct {
+		name          string
+		fsys          fstest.MapFS
+		errorContains string
+	}{
+		{
+			name:          "invalid chain.json",
+			fsys:          fstest.MapFS{"neutron/chain.json": {Data: []byte(`{`)}},
+			errorContains: "failed to unmarshal neutron/chain.json",
+		},
+		{
+			name:          "missing chain ID",
+			fsys:          fstest.MapFS{"neutron/chain.json": {Data: []byte(`{"chain_name": "neutron"}`)}},
+			errorContains: "no chain_name or chain_id",
+		},
+		{
+			name: "duplicate chain ID",
+			fsys: fstest.MapFS{
+				"neutron/chain.json":  {Data: []byte(neutronChain)},
+				"neutron2/chain.json": {Data: []byte(`{"chain_name": "neutron2", "chain_id": "neutron-1"}`)},
+			},
+			errorContains: "also used by neutron",
+		},
+		{
+			name: "invalid asset list",
+			fsys: fstest.MapFS{
+				"noble/chain.json":     {Data: []byte(nobleChain)},
+				"noble/assetlist.json": {Data: []byte(`{"assets": []}`)},
+			},
+			errorContains: "failed to read noble/assetlist.json",
+		},
+		{
+			name:          "invalid IBC file",
+			fsys:          fstest.MapFS{"_IBC/neutron-noble.json": {Data: []byte(`[]`)}},
+			errorContains: "failed to unmarshal _IBC/neutron-noble.json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadLocalRegistry(tt.fsys)
+			assert.ErrorContains(t, err, tt.errorContains)
+		})
+	}
+}
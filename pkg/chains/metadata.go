@@ -0,0 +1,197 @@
+package chains
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/margined-protocol/locust-core/pkg/types"
+)
+
+// ChainMetadata is the chain.json of a chain in the chain registry
+type ChainMetadata struct {
+	Name         string   `json:"chain_name"`
+	PrettyName   string   `json:"pretty_name"`
+	ChainType    string   `json:"chain_type"`
+	ChainID      string   `json:"chain_id"`
+	Bech32Prefix string   `json:"bech32_prefix"`
+	Slip44       uint32   `json:"slip44"`
+	KeyAlgos     []string `json:"key_algos"`
+	Fees         struct {
+		FeeTokens []FeeToken `json:"fee_tokens"`
+	} `json:"fees"`
+	Staking struct {
+		StakingTokens []struct {
+			Denom string `json:"denom"`
+		} `json:"staking_tokens"`
+	} `json:"staking"`
+	APIs APIs `json:"apis"`
+}
+
+// FeeToken is a denom fees can be paid in and its gas prices
+type FeeToken struct {
+	Denom            string  `json:"denom"`
+	FixedMinGasPrice float64 `json:"fixed_min_gas_price"`
+	LowGasPrice      float64 `json:"low_gas_price"`
+	AverageGasPrice  float64 `json:"average_gas_price"`
+	HighGasPrice     float64 `json:"high_gas_price"`
+}
+
+// MinGasPrice returns the lowest gas price the chain accepts for the token
+func (t *FeeToken) MinGasPrice() float64 {
+	if t.FixedMinGasPrice > 0 {
+		return t.FixedMinGasPrice
+	}
+	return t.LowGasPrice
+}
+
+// APIs are the public endpoints of a chain
+type APIs struct {
+	RPC  []Endpoint `json:"rpc"`
+	REST []Endpoint `json:"rest"`
+	GRPC []Endpoint `json:"grpc"`
+}
+
+// Endpoint is a public endpoint and who runs it
+type Endpoint struct {
+	Address  string `json:"address"`
+	Provider string `json:"provider"`
+}
+
+// FeeToken returns the fee token with the denom
+func (c *ChainMetadata) FeeToken(denom string) (*FeeToken, bool) {
+	for i := range c.Fees.FeeTokens {
+		if c.Fees.FeeTokens[i].Denom == denom {
+			return &c.Fees.FeeTokens[i], true
+		}
+	}
+	return nil, false
+}
+
+// StakingDenom returns the denom staked on the chain, empty when the chain has none
+func (c *ChainMetadata) StakingDenom() string {
+	if len(c.Staking.StakingTokens) == 0 {
+		return ""
+	}
+	return c.Staking.StakingTokens[0].Denom
+}
+
+// ChainConfig generates the chain config of a locust bot from the metadata. Fees are paid in
+// the first fee token at its average gas price, with the gas of every tx simulated, and every
+// public RPC and gRPC endpoint is used. Accounts take the coin type and first key type of the
+// chain.
+func (c *ChainMetadata) ChainConfig() (types.Chain, error) {
+	if len(c.Fees.FeeTokens) == 0 {
+		return types.Chain{}, fmt.Errorf("chain %s has no fee tokens", c.ChainID)
+	}
+
+	feeToken := c.Fees.FeeTokens[0]
+	price := feeToken.AverageGasPrice
+	if price == 0 {
+		price = feeToken.MinGasPrice()
+	}
+	gasPrices := strconv.FormatFloat(price, 'f', -1, 64) + feeToken.Denom
+
+	chain := types.Chain{
+		Prefix:        c.Bech32Prefix,
+		ChainID:       c.ChainID,
+		GasPrices:     &gasPrices,
+		GasDenom:      feeToken.Denom,
+		FeeEstimation: &types.FeeEstimation{},
+		CoinType:      c.Slip44,
+	}
+	if len(c.KeyAlgos) > 0 {
+		chain.KeyAlgo = keyAlgo(c.KeyAlgos[0])
+	}
+
+	for _, endpoint := range c.APIs.RPC {
+		chain.RPCEndpoints = append(chain.RPCEndpoints, types.RPCEndpointConfig{Address: endpoint.Address})
+	}
+
+	for _, endpoint := range c.APIs.GRPC {
+		address, useTLS := grpcAddress(endpoint.Address)
+		chain.GRPCEndpoints = append(chain.GRPCEndpoints, types.GRPCEndpointConfig{Address: address, UseTLS: useTLS})
+	}
+
+	return chain, nil
+}
+
+// keyAlgo returns the config key type of a chain registry key algorithm
+func keyAlgo(algo string) string {
+	if algo == "ethsecp256k1" {
+		return "eth_secp256k1"
+	}
+	return algo
+}
+
+// grpcAddress returns the host and port of a gRPC endpoint and whether it uses TLS, registry
+// addresses may carry a scheme and TLS is assumed on port 443
+func grpcAddress(address string) (string, bool) {
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		switch u.Scheme {
+		case "https", "grpcs":
+			if u.Port() == "" {
+				return u.Host + ":443", true
+			}
+			return u.Host, true
+		case "http", "grpc":
+			return u.Host, u.Port() == "443"
+		}
+	}
+	return address, strings.HasSuffix(address, ":443")
+}
+
+var gasPriceRegex = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)([a-zA-Z][a-zA-Z0-9/:._-]*)$`)
+
+// ValidateChainConfig checks a chain config against the metadata: the chain ID, prefix and
+// account keys must match and the gas prices must be in a fee token at or above its minimum
+// gas price
+func (c *ChainMetadata) ValidateChainConfig(chain types.Chain) error {
+	if chain.ChainID != c.ChainID {
+		return fmt.Errorf("chain ID %s does not match %s in the chain registry", chain.ChainID, c.ChainID)
+	}
+	if chain.Prefix != c.Bech32Prefix {
+		return fmt.Errorf("prefix %s does not match %s in the chain registry", chain.Prefix, c.Bech32Prefix)
+	}
+
+	if chain.CoinType != 0 && c.Slip44 != 0 && chain.CoinType != c.Slip44 {
+		return fmt.Errorf("coin type %d does not match %d in the chain registry", chain.CoinType, c.Slip44)
+	}
+	if chain.KeyAlgo != "" && len(c.KeyAlgos) > 0 && chain.KeyAlgo != keyAlgo(c.KeyAlgos[0]) {
+		return fmt.Errorf("key algo %s does not match %s in the chain registry", chain.KeyAlgo, keyAlgo(c.KeyAlgos[0]))
+	}
+
+	if chain.GasDenom != "" {
+		if _, ok := c.FeeToken(chain.GasDenom); !ok {
+			return fmt.Errorf("gas denom %s is not a fee token of %s", chain.GasDenom, c.ChainID)
+		}
+	}
+
+	if chain.GasPrices == nil {
+		return nil
+	}
+
+	for _, gasPrice := range strings.Split(*chain.GasPrices, ",") {
+		matches := gasPriceRegex.FindStringSubmatch(strings.TrimSpace(gasPrice))
+		if matches == nil {
+			return fmt.Errorf("invalid gas price %q", gasPrice)
+		}
+
+		price, err := strconv.ParseFloat(matches[1], 64)
+		if err != nil {
+			return fmt.Errorf("invalid gas price %q: %w", gasPrice, err)
+		}
+
+		feeToken, ok := c.FeeToken(matches[2])
+		if !ok {
+			return fmt.Errorf("gas price denom %s is not a fee token of %s", matches[2], c.ChainID)
+		}
+		if price < feeToken.MinGasPrice() {
+			return fmt.Errorf("gas price %s is below the minimum of %v%s", gasPrice, feeToken.MinGasPrice(), feeToken.Denom)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package chains
+
+import (
+	"testing"
+
+	"github.com/margined-protocol/locust-core/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainConfig(t *testing.T) {
+	registry, err := LoadLocalRegistry(testRegistryFS())
+	require.NoError(t, err)
+
+	neutron, err := registry.Chain("neutron-1")
+	require.NoError(t, err)
+
+	chain, err := neutron.ChainConfig()
+	require.NoError(t, err)
+
+	gasPrices := "0.0075untrn"
+	assert.Equal(t, types.Chain{
+		Prefix:        "neutron",
+		ChainID:       "neutron-1",
+		GasPrices:     &gasPrices,
+		GasDenom:      "untrn",
+		FeeEstimation: &types.FeeEstimation{},
+		CoinType:      118,
+		GRPCEndpoints: []types.GRPCEndpointConfig{
+			{Address: "grpc-kralum.neutron-1.neutron.org:443", UseTLS: true},
+			{Address: "neutron-grpc.polkachu.com:19190"},
+		},
+		RPCEndpoints: []types.RPCEndpointConfig{
+			{Address: "https://rpc-kralum.neutron-1.neutron.org"},
+		},
+	}, chain)
+
+	// Generated configs are valid bot configs
+	require.NoError(t, types.ValidateConfig(types.Config{Chain: chain}))
+	require.NoError(t, neutron.ValidateChainConfig(chain))
+
+	// Without an average gas price the minimum is used
+	noble, err := registry.Chain("noble-1")
+	require.NoError(t, err)
+	chain, err = noble.ChainConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "0.1uusdc", *chain.GasPrices)
+
+	injective := &ChainMetadata{
+		ChainID:      "injective-1",
+		Bech32Prefix: "inj",
+		Slip44:       60,
+		KeyAlgos:     []string{"ethsecp256k1"},
+		Fees:         neutron.Fees,
+	}
+	chain, err = injective.ChainConfig()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(60), chain.CoinType)
+	assert.Equal(t, "eth_secp256k1", chain.KeyAlgo)
+
+	_, err = (&ChainMetadata{ChainID: "empty-1"}).ChainConfig()
+	assert.ErrorContains(t, err, "no fee tokens")
+}
+
+func TestValidateChainConfig(t *testing.T) {
+	registry, err := LoadLocalRegistry(testRegistryFS())
+	require.NoError(t, err)
+
+	neutron, err := registry.Chain("neutron-1")
+	require.NoError(t, err)
+
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name          string
+		chain         types.Chain
+		errorContains string
+	}{
+		{
+			name:  "valid",
+			chain: types.Chain{ChainID: "neutron-1", Prefix: "neutron", GasDenom: "untrn", GasPrices: str("0.0053untrn")},
+		},
+		{
+			name:  "valid in a second fee token",
+			chain: types.Chain{ChainID: "neutron-1", Prefix: "neutron", GasPrices: str("0.01ibc/B559A80D62249C8AA07A380E2A2BEA6E5CA9A6F079C912C3A9E9B494105E4F81")},
+		},
+		{
+			name:          "wrong chain ID",
+			chain:         types.Chain{ChainID: "pion-1", Prefix: "neutron"},
+			errorContains: "chain ID pion-1 does not match neutron-1",
+		},
+		{
+			name:          "wrong prefix",
+			chain:         types.Chain{ChainID: "neutron-1", Prefix: "osmo"},
+			errorContains: "prefix osmo does not match neutron",
+		},
+		{
+			name:          "wrong coin type",
+			chain:         types.Chain{ChainID: "neutron-1", Prefix: "neutron", CoinType: 60},
+			errorContains: "coin type 60 does not match 118",
+		},
+		{
+			name:          "gas denom not a fee token",
+			chain:         types.Chain{ChainID: "neutron-1", Prefix: "neutron", GasDenom: "uatom"},
+			errorContains: "gas denom uatom is not a fee token",
+		},
+		{
+			name:          "gas price below minimum",
+			chain:         types.Chain{ChainID: "neutron-1", Prefix: "neutron", GasPrices: str("0.001untrn")},
+			errorContains: "below the minimum of 0.0053untrn",
+		},
+		{
+			name:          "gas price denom not a fee token",
+			chain:         types.Chain{ChainID: "neutron-1", Prefix: "neutron", GasPrices: str("0.1uosmo")},
+			errorContains: "gas price denom uosmo is not a fee token",
+		},
+		{
+			name:          "invalid gas price",
+			chain:         types.Chain{ChainID: "neutron-1", Prefix: "neutron", GasPrices: str("untrn")},
+			errorContains: "invalid gas price",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := neutron.ValidateChainConfig(tt.chain)
+			if tt.errorContains == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.errorContains)
+		})
+	}
+}
+
+func TestGRPCAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		host    string
+		useTLS  bool
+	}{
+		{"grpc.neutron.org:443", "grpc.neutron.org:443", true},
+		{"neutron-grpc.polkachu.com:19190", "neutron-grpc.polkachu.com:19190", false},
+		{"https://grpc.neutron.org", "grpc.neutron.org:443", true},
+		{"https://grpc.neutron.org:9090", "grpc.neutron.org:9090", true},
+		{"http://grpc.neutron.org:9090", "grpc.neutron.org:9090", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			host, useTLS := grpcAddress(tt.address)
+			assert.Equal(t, tt.host, host)
+			assert.Equal(t, tt.useTLS, useTLS)
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/margined-protocol/locust-core/pkg/types"
+
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+const (
+	// DefaultCoinType is the BIP-44 coin type of cosmos chains
+	DefaultCoinType = 118
+	// KeyAlgoSecp256k1 is the key type of cosmos chains
+	KeyAlgoSecp256k1 = "secp256k1"
+	// KeyAlgoEthSecp256k1 is the key type of EVM chains such as Injective and Evmos, whose
+	// addresses are derived from the Ethereum address of the key
+	KeyAlgoEthSecp256k1 = "eth_secp256k1"
+)
+
+// ErrKeyTypeMismatch is returned when an address is derived for a chain whose accounts use a
+// different key than the signer, the same mnemonic gives a different key and address there
+var ErrKeyTypeMismatch = errors.New("signer key type differs from the chain key type")
+
+// ChainKey is the address prefix and key type of the accounts of a chain
+type ChainKey struct {
+	Prefix   string
+	CoinType uint32 // BIP-44 coin type, DefaultCoinType when zero
+	KeyAlgo  string // KeyAlgoSecp256k1 when empty
+}
+
+func (k ChainKey) coinType() uint32 {
+	if k.CoinType == 0 {
+		return DefaultCoinType
+	}
+	return k.CoinType
+}
+
+func (k ChainKey) keyAlgo() string {
+	if k.KeyAlgo == "" {
+		return KeyAlgoSecp256k1
+	}
+	return k.KeyAlgo
+}
+
+// chainKey returns the key of accounts on a configured chain
+func chainKey(chain *types.Chain) ChainKey {
+	return ChainKey{Prefix: chain.Prefix, CoinType: chain.CoinType, KeyAlgo: chain.KeyAlgo}
+}
+
+// DeriveAddress returns the address of a key held on one chain on another chain. Both chains
+// must derive accounts with the same coin type and key type, otherwise the signer has a
+// different key on the other chain and ErrKeyTypeMismatch is returned.
+func DeriveAddress(pubKey cryptotypes.PubKey, from, to ChainKey) (string, error) {
+	if pubKey.Type() != from.keyAlgo() {
+		return "", fmt.Errorf("%w: signer has a %s key but %s accounts use %s", ErrKeyTypeMismatch, pubKey.Type(), from.Prefix, from.keyAlgo())
+	}
+	if from.keyAlgo() != to.keyAlgo() || from.coinType() != to.coinType() {
+		return "", fmt.Errorf("%w: %s accounts use %s keys with coin type %d but %s accounts use %s keys with coin type %d",
+			ErrKeyTypeMismatch, from.Prefix, from.keyAlgo(), from.coinType(), to.Prefix, to.keyAlgo(), to.coinType())
+	}
+	if to.Prefix == "" {
+		return "", errors.New("chain has no address prefix")
+	}
+
+	address, err := sdk.Bech32ifyAddressBytes(to.Prefix, pubKey.Address())
+	if err != nil {
+		return "", fmt.Errorf("failed to encode address: %w", err)
+	}
+	return address, nil
+}
+
+// AddressBook derives the addresses of the signer on every chain from the key of its account
+// on a home chain, so strategies and IBC memos need no per chain address configuration
+type AddressBook struct {
+	registry    *ClientRegistry
+	homeChainID string
+	pubKey      cryptotypes.PubKey
+
+	mu sync.Mutex
+	// Chains not registered with the client registry, e.g. IBC forwarding chains
+	chains map[string]ChainKey
+}
+
+// NewAddressBook creates an address book for the key of the signer on a chain in the registry,
+// see ClientRegistry.SignerPubKey
+func NewAddressBook(registry *ClientRegistry, homeChainID string, pubKey cryptotypes.PubKey) *AddressBook {
+	return &AddressBook{
+		registry:    registry,
+		homeChainID: homeChainID,
+		pubKey:      pubKey,
+		chains:      make(map[string]ChainKey),
+	}
+}
+
+// RegisterChain adds a chain without a client, e.g. a chain transfers are forwarded through
+func (b *AddressBook) RegisterChain(chainID string, key ChainKey) error {
+	if key.Prefix == "" {
+		return fmt.Errorf("chain %s has no address prefix", chainID)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := b.chains[chainID]; exists || b.registry.HasClient(chainID) {
+		return fmt.Errorf("chain %s already registered", chainID)
+	}
+
+	b.chains[chainID] = key
+	return nil
+}
+
+// chainKey returns the key of accounts on a chain in the client registry or the address book
+func (b *AddressBook) chainKey(chainID string) (ChainKey, error) {
+	b.registry.mu.RLock()
+	entry, exists := b.registry.chains[chainID]
+	b.registry.mu.RUnlock()
+	if exists {
+		return chainKey(entry.Chain), nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key, exists := b.chains[chainID]
+	if !exists {
+		return ChainKey{}, fmt.Errorf("chain %s not registered", chainID)
+	}
+	return key, nil
+}
+
+// Address returns the address of the signer on the chain
+func (b *AddressBook) Address(chainID string) (string, error) {
+	home, err := b.chainKey(b.homeChainID)
+	if err != nil {
+		return "", err
+	}
+
+	to, err := b.chainKey(chainID)
+	if err != nil {
+		return "", err
+	}
+
+	address, err := DeriveAddress(b.pubKey, home, to)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive address on %s: %w", chainID, err)
+	}
+	return address, nil
+}
+
+// Addresses returns the addresses of the signer on the chains in order, e.g. the receivers
+// of a multi-hop IBC transfer
+func (b *AddressBook) Addresses(chainIDs ...string) ([]string, error) {
+	addresses := make([]string, len(chainIDs))
+	for i, chainID := range chainIDs {
+		address, err := b.Address(chainID)
+		if err != nil {
+			return nil, err
+		}
+		addresses[i] = address
+	}
+	return addresses, nil
+}
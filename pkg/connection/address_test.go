@@ -0,0 +1,108 @@
+package connection
+
+import (
+	"context"
+	"testing"
+
+	"github.com/margined-protocol/locust-core/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// ethPubKey stands in for an eth_secp256k1 key, only its type matters to derivation
+type ethPubKey struct {
+	*secp256k1.PubKey
+}
+
+func (ethPubKey) Type() string {
+	return KeyAlgoEthSecp256k1
+}
+
+func newAddressBook(t *testing.T, homeChainID string, pubKey cryptotypes.PubKey) *AddressBook {
+	t.Helper()
+
+	registry := NewClientRegistry(context.Background(), zap.NewNop(), "signer")
+	for _, chain := range []*types.Chain{
+		{ChainID: "neutron-1", Prefix: "neutron"},
+		{ChainID: "osmosis-1", Prefix: "osmo", CoinType: DefaultCoinType, KeyAlgo: KeyAlgoSecp256k1},
+		{ChainID: "secret-4", Prefix: "secret", CoinType: 529},
+		{ChainID: "injective-1", Prefix: "inj", CoinType: 60, KeyAlgo: KeyAlgoEthSecp256k1},
+		{ChainID: "evmos_9001-2", Prefix: "evmos", CoinType: 60, KeyAlgo: KeyAlgoEthSecp256k1},
+	} {
+		registry.chains[chain.ChainID] = &ClientEntry{Chain: chain}
+	}
+
+	return NewAddressBook(registry, homeChainID, pubKey)
+}
+
+func TestAddressBook(t *testing.T) {
+	pubKey := secp256k1.GenPrivKeyFromSecret([]byte("locust")).PubKey()
+	book := newAddressBook(t, "neutron-1", pubKey)
+
+	neutron, err := book.Address("neutron-1")
+	require.NoError(t, err)
+	expected, err := sdk.Bech32ifyAddressBytes("neutron", pubKey.Address())
+	require.NoError(t, err)
+	assert.Equal(t, expected, neutron)
+
+	// Chains with the same coin type share the account bytes
+	osmosis, err := book.Address("osmosis-1")
+	require.NoError(t, err)
+	expected, err = sdk.Bech32ifyAddressBytes("osmo", pubKey.Address())
+	require.NoError(t, err)
+	assert.Equal(t, expected, osmosis)
+
+	_, err = book.Address("secret-4")
+	assert.ErrorIs(t, err, ErrKeyTypeMismatch)
+	assert.ErrorContains(t, err, "coin type 118 but secret accounts use secp256k1 keys with coin type 529")
+
+	_, err = book.Address("injective-1")
+	assert.ErrorIs(t, err, ErrKeyTypeMismatch)
+
+	_, err = book.Address("noble-1")
+	assert.ErrorContains(t, err, "chain noble-1 not registered")
+
+	// Forwarding chains without a client are registered with their key
+	require.NoError(t, book.RegisterChain("noble-1", ChainKey{Prefix: "noble"}))
+	addresses, err := book.Addresses("noble-1", "osmosis-1")
+	require.NoError(t, err)
+	expected, err = sdk.Bech32ifyAddressBytes("noble", pubKey.Address())
+	require.NoError(t, err)
+	assert.Equal(t, []string{expected, osmosis}, addresses)
+
+	assert.ErrorContains(t, book.RegisterChain("noble-1", ChainKey{Prefix: "noble"}), "already registered")
+	assert.ErrorContains(t, book.RegisterChain("osmosis-1", ChainKey{Prefix: "osmo"}), "already registered")
+	assert.ErrorContains(t, book.RegisterChain("stride-1", ChainKey{}), "no address prefix")
+}
+
+func TestAddressBookEthereumKeys(t *testing.T) {
+	pubKey := ethPubKey{secp256k1.GenPrivKeyFromSecret([]byte("locust")).PubKey().(*secp256k1.PubKey)}
+	book := newAddressBook(t, "injective-1", pubKey)
+
+	evmos, err := book.Address("evmos_9001-2")
+	require.NoError(t, err)
+	expected, err := sdk.Bech32ifyAddressBytes("evmos", pubKey.Address())
+	require.NoError(t, err)
+	assert.Equal(t, expected, evmos)
+
+	_, err = book.Address("neutron-1")
+	assert.ErrorIs(t, err, ErrKeyTypeMismatch)
+}
+
+func TestAddressBookKeyErrors(t *testing.T) {
+	// A cosmos key configured as the signer of an EVM chain is refused
+	book := newAddressBook(t, "injective-1", secp256k1.GenPrivKey().PubKey())
+	_, err := book.Address("injective-1")
+	assert.ErrorIs(t, err, ErrKeyTypeMismatch)
+	assert.ErrorContains(t, err, "signer has a secp256k1 key but inj accounts use eth_secp256k1")
+
+	// Without a client the registry cannot load the key of the signer
+	registry := NewClientRegistry(context.Background(), zap.NewNop(), "signer")
+	_, err = registry.SignerPubKey("signer", "neutron-1")
+	assert.Error(t, err)
+}
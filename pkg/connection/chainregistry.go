@@ -13,6 +13,7 @@ import (
 
 	sdkmath "cosmossdk.io/math"
 
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
 	banktypes "github.com/cosmos/cosmos-sdk/x/bank/types"
 )
 
@@ -33,6 +34,23 @@ type ClientInstance struct {
 	RPCClient  *MultiEndpointRPCClient
 }
 
+// clientFactory builds a cosmos client for a chain, isFee selects the fee client
+type clientFactory func(ctx context.Context, l *zap.Logger, chain *types.Chain, key *types.SigningKey, isFee bool) (*cosmosclient.Client, error)
+
+// clientKey identifies a cached cosmos client
+type clientKey struct {
+	chainID string
+	isFee   bool
+}
+
+// cachedClient is a long-lived cosmos client, its lock is held while the client is built
+// so concurrent callers wait for a single build
+type cachedClient struct {
+	mu         sync.Mutex
+	client     *cosmosclient.Client
+	rpcAddress string
+}
+
 // ClientRegistry manages connections to multiple chains
 type ClientRegistry struct {
 	chains        map[string]*ClientEntry
@@ -40,6 +58,11 @@ type ClientRegistry struct {
 	mu            sync.RWMutex
 	signerAccount string
 	ctx           context.Context
+
+	// Cosmos clients are cached per chain and fee setting, rebuilt when the RPC endpoint rotates
+	clients   map[clientKey]*cachedClient
+	clientsMu sync.Mutex
+	newClient clientFactory
 }
 
 // NewClientRegistry creates a new chain registry
@@ -49,9 +72,19 @@ func NewClientRegistry(ctx context.Context, logger *zap.Logger, signerAccount st
 		logger:        logger,
 		signerAccount: signerAccount,
 		ctx:           ctx,
+		clients:       make(map[clientKey]*cachedClient),
+		newClient:     newCosmosClient,
 	}
 }
 
+// newCosmosClient builds a cosmos client, or a fee client when isFee is set
+func newCosmosClient(ctx context.Context, l *zap.Logger, chain *types.Chain, key *types.SigningKey, isFee bool) (*cosmosclient.Client, error) {
+	if isFee {
+		return InitFeeClient(ctx, l, chain, key)
+	}
+	return InitCosmosClient(ctx, l, chain, key)
+}
+
 // RegisterClient adds a new chain client to the registry
 func (r *ClientRegistry) RegisterClient(chain *types.Chain, key *types.SigningKey) error {
 	if _, exists := r.chains[chain.ChainID]; exists {
@@ -63,6 +96,7 @@ func (r *ClientRegistry) RegisterClient(chain *types.Chain, key *types.SigningKe
 		AppName: key.AppName,
 		Backend: key.Backend,
 		RootDir: key.RootDir,
+		Remote:  key.Remote,
 	}
 
 	// Create multi-endpoint gRPC client
@@ -126,20 +160,17 @@ func (r *ClientRegistry) RegisterClient(chain *types.Chain, key *types.SigningKe
 	return nil
 }
 
-// GetClient retrieves a chain client entry by its ID
+// initClient returns the cached cosmos client of a chain, building it on first use and
+// rebuilding it when the active RPC endpoint has rotated since it was built
 func (r *ClientRegistry) initClient(chainID string, isFee bool) (*ClientInstance, error) {
 	r.mu.RLock()
-	defer r.mu.RUnlock()
-
 	entry, exists := r.chains[chainID]
+	r.mu.RUnlock()
+
 	if !exists {
 		return nil, fmt.Errorf("chain %s not registered", chainID)
 	}
 
-	// Initialize Cosmos client
-	var client *cosmosclient.Client
-	var err error
-
 	// Override cosmosclient options with the current active RPC endpoint
 	rpcAddress := ""
 	if entry.RPCClient != nil {
@@ -150,30 +181,34 @@ func (r *ClientRegistry) initClient(chainID string, isFee bool) (*ClientInstance
 		rpcAddress = entry.Chain.RPCEndpoints[0].Address
 	}
 
-	if isFee {
-		// Create a temporary chain with the active RPC endpoint
-		tempChain := *entry.Chain // Create a copy
-		if rpcAddress != "" {
-			// Use the active RPC endpoint
-			tempChain.RPCEndpoints[0].Address = rpcAddress
+	cached := r.cachedClient(clientKey{chainID: chainID, isFee: isFee})
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+
+	if cached.client == nil || cached.rpcAddress != rpcAddress {
+		if cached.client != nil {
+			r.logger.Info("RPC endpoint rotated, rebuilding cosmos client",
+				zap.String("chain_id", chainID),
+				zap.Bool("is_fee", isFee),
+				zap.String("previous_endpoint", cached.rpcAddress),
+				zap.String("endpoint", rpcAddress))
 		}
-		client, err = InitFeeClient(context.Background(), r.logger, &tempChain, entry.Key)
-	} else {
-		// Create a temporary chain with the active RPC endpoint
-		tempChain := *entry.Chain // Create a copy
-		if rpcAddress != "" {
-			// Use the active RPC endpoint
-			tempChain.RPCEndpoints[0].Address = rpcAddress
+
+		client, err := r.newClient(context.Background(), r.logger, chainWithActiveRPC(entry.Chain, rpcAddress), entry.Key, isFee)
+		if err != nil {
+			r.logger.Error("Error initializing cosmos client", zap.Error(err))
+			return nil, err
 		}
-		client, err = InitCosmosClient(context.Background(), r.logger, &tempChain, entry.Key)
-	}
-	if err != nil {
-		r.logger.Error("Error initializing cosmos client", zap.Error(err))
-		return nil, err
+
+		if cached.client != nil {
+			releaseFeeEstimator(cached.client)
+		}
+		cached.client = client
+		cached.rpcAddress = rpcAddress
 	}
 
 	return &ClientInstance{
-		Client:     client,
+		Client:     cached.client,
 		Chain:      entry.Chain,
 		Key:        entry.Key,
 		GRPCClient: entry.GRPCClient,
@@ -181,6 +216,50 @@ func (r *ClientRegistry) initClient(chainID string, isFee bool) (*ClientInstance
 	}, nil
 }
 
+// cachedClient returns the cache slot of a client, creating an empty one if needed
+func (r *ClientRegistry) cachedClient(key clientKey) *cachedClient {
+	r.clientsMu.Lock()
+	defer r.clientsMu.Unlock()
+
+	cached, exists := r.clients[key]
+	if !exists {
+		cached = &cachedClient{}
+		r.clients[key] = cached
+	}
+
+	return cached
+}
+
+// chainWithActiveRPC returns a copy of the chain with the active RPC endpoint first, the
+// registered chain configuration is shared and never modified
+func chainWithActiveRPC(chain *types.Chain, rpcAddress string) *types.Chain {
+	active := *chain
+	active.RPCEndpoints = make([]types.RPCEndpointConfig, 0, len(chain.RPCEndpoints))
+
+	if rpcAddress == "" {
+		active.RPCEndpoints = append(active.RPCEndpoints, chain.RPCEndpoints...)
+		return &active
+	}
+
+	// Keep the API token of the active endpoint when it is configured
+	activeEndpoint := types.RPCEndpointConfig{Address: rpcAddress}
+	for _, endpoint := range chain.RPCEndpoints {
+		if endpoint.Address == rpcAddress {
+			activeEndpoint = endpoint
+			break
+		}
+	}
+
+	active.RPCEndpoints = append(active.RPCEndpoints, activeEndpoint)
+	for _, endpoint := range chain.RPCEndpoints {
+		if endpoint.Address != rpcAddress {
+			active.RPCEndpoints = append(active.RPCEndpoints, endpoint)
+		}
+	}
+
+	return &active
+}
+
 // GetClient retrieves a chain client entry by its ID
 func (r *ClientRegistry) GetClient(chainID string, isFeeClient bool) (*ClientInstance, error) {
 	return r.initClient(chainID, isFeeClient)
@@ -198,12 +277,11 @@ func (r *ClientRegistry) GetHeight(ctx context.Context, chainID string) (*int64,
 
 	// Use the multi-endpoint RPC client if available
 	if entry.RPCClient != nil {
-		rpcClient := entry.RPCClient.GetClient()
-		status, err := rpcClient.Status(ctx)
+		height, err := entry.RPCClient.GetHeight(ctx)
 		if err != nil {
 			return nil, err
 		}
-		return &status.SyncInfo.LatestBlockHeight, nil
+		return &height, nil
 	}
 
 	// Fallback to the standard client
@@ -281,9 +359,6 @@ func (r *ClientRegistry) GetBalance(ctx context.Context, chainID, signerAccount,
 
 // GetSignerAccountAndAddress retrieves the account and address for a specific chain
 func (r *ClientRegistry) GetSignerAccountAndAddress(signerAccount, chainID string) (*cosmosaccount.Account, string, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
 	client, err := r.GetClient(chainID, false)
 	if err != nil {
 		return nil, "", fmt.Errorf("error getting client: %w", err)
@@ -303,6 +378,21 @@ func (r *ClientRegistry) GetSignerAccountAndAddress(signerAccount, chainID strin
 	return account, sender, nil
 }
 
+// SignerPubKey retrieves the public key of the signer account on a specific chain
+func (r *ClientRegistry) SignerPubKey(signerAccount, chainID string) (cryptotypes.PubKey, error) {
+	account, _, err := r.GetSignerAccountAndAddress(signerAccount, chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey, err := account.Record.GetPubKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key of %s on %s: %w", signerAccount, chainID, err)
+	}
+
+	return pubKey, nil
+}
+
 // HasClient checks if a client is registered
 func (r *ClientRegistry) HasClient(chainID string) bool {
 	r.mu.RLock()
@@ -363,5 +453,17 @@ func (r *ClientRegistry) Close() {
 
 	// Reset the registry
 	r.chains = make(map[string]*ClientEntry)
+
+	r.clientsMu.Lock()
+	for _, cached := range r.clients {
+		cached.mu.Lock()
+		if cached.client != nil {
+			releaseFeeEstimator(cached.client)
+		}
+		cached.mu.Unlock()
+	}
+	r.clients = make(map[clientKey]*cachedClient)
+	r.clientsMu.Unlock()
+
 	r.logger.Info("Client registry closed")
 }
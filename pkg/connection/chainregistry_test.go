@@ -0,0 +1,226 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
+	"github.com/margined-protocol/locust-core/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testChainID = "test-1"
+
+// fakeClientFactory records the chains cosmos clients are built for
+type fakeClientFactory struct {
+	mu     sync.Mutex
+	builds []*types.Chain
+	fees   []bool
+	err    error
+}
+
+func (f *fakeClientFactory) newClient(_ context.Context, _ *zap.Logger, chain *types.Chain, _ *types.SigningKey, isFee bool) (*cosmosclient.Client, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	f.builds = append(f.builds, chain)
+	f.fees = append(f.fees, isFee)
+	return &cosmosclient.Client{}, nil
+}
+
+func (f *fakeClientFactory) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.builds)
+}
+
+// newTestRegistry returns a registry with a chain whose RPC client is never dialed
+func newTestRegistry(t *testing.T) (*ClientRegistry, *types.Chain, *MultiEndpointRPCClient, *fakeClientFactory) {
+	t.Helper()
+
+	chain := &types.Chain{
+		ChainID: testChainID,
+		Prefix:  "test",
+		RPCEndpoints: []types.RPCEndpointConfig{
+			{Address: "http://a:26657", APIToken: "token-a"},
+			{Address: "http://b:26657", APIToken: "token-b"},
+			{Address: "http://c:26657"},
+		},
+	}
+
+	rpcClient := &MultiEndpointRPCClient{
+		endpoints: []RPCEndpointConfig{
+			{Address: "http://a:26657"},
+			{Address: "http://b:26657"},
+			{Address: "http://c:26657"},
+		},
+	}
+
+	factory := &fakeClientFactory{}
+
+	registry := NewClientRegistry(context.Background(), zap.NewNop(), "signer")
+	registry.newClient = factory.newClient
+	registry.chains[testChainID] = &ClientEntry{
+		Chain:     chain,
+		Key:       &types.SigningKey{AppName: "test"},
+		RPCClient: rpcClient,
+	}
+
+	return registry, chain, rpcClient, factory
+}
+
+func rotateTo(client *MultiEndpointRPCClient, index int) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.currentIndex = index
+}
+
+func TestClientRegistryCachesClients(t *testing.T) {
+	registry, _, _, factory := newTestRegistry(t)
+
+	first, err := registry.GetClient(testChainID, false)
+	require.NoError(t, err)
+
+	second, err := registry.GetClient(testChainID, false)
+	require.NoError(t, err)
+	assert.Same(t, first.Client, second.Client)
+	assert.Equal(t, 1, factory.count())
+
+	fee, err := registry.GetClient(testChainID, true)
+	require.NoError(t, err)
+	assert.NotSame(t, first.Client, fee.Client)
+	assert.Equal(t, []bool{false, true}, factory.fees)
+
+	_, err = registry.GetClient("unknown-1", false)
+	assert.Error(t, err)
+}
+
+func TestClientRegistryConcurrentCallers(t *testing.T) {
+	registry, _, _, factory := newTestRegistry(t)
+
+	var wg sync.WaitGroup
+	clients := make([]*cosmosclient.Client, 50)
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			instance, err := registry.GetClient(testChainID, i%2 == 0)
+			if assert.NoError(t, err) {
+				clients[i] = instance.Client
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// One build per chain and fee setting
+	assert.Equal(t, 2, factory.count())
+	for i := 2; i < len(clients); i++ {
+		assert.Same(t, clients[i%2], clients[i])
+	}
+}
+
+func TestClientRegistryRebuildsOnRotation(t *testing.T) {
+	registry, _, rpcClient, factory := newTestRegistry(t)
+
+	first, err := registry.GetClient(testChainID, false)
+	require.NoError(t, err)
+
+	rotateTo(rpcClient, 1)
+
+	second, err := registry.GetClient(testChainID, false)
+	require.NoError(t, err)
+	assert.NotSame(t, first.Client, second.Client)
+	require.Equal(t, 2, factory.count())
+
+	// The active endpoint is passed first, keeping its own API token
+	built := factory.builds[1]
+	assert.Equal(t, types.RPCEndpointConfig{Address: "http://b:26657", APIToken: "token-b"}, built.RPCEndpoints[0])
+	assert.Len(t, built.RPCEndpoints, 3)
+
+	third, err := registry.GetClient(testChainID, false)
+	require.NoError(t, err)
+	assert.Same(t, second.Client, third.Client)
+	assert.Equal(t, 2, factory.count())
+}
+
+func TestClientRegistryDoesNotMutateConfig(t *testing.T) {
+	registry, chain, rpcClient, _ := newTestRegistry(t)
+
+	original := append([]types.RPCEndpointConfig(nil), chain.RPCEndpoints...)
+
+	for _, index := range []int{0, 2, 1} {
+		rotateTo(rpcClient, index)
+
+		for _, isFee := range []bool{false, true} {
+			instance, err := registry.GetClient(testChainID, isFee)
+			require.NoError(t, err)
+			assert.Same(t, chain, instance.Chain)
+		}
+	}
+
+	assert.Equal(t, original, chain.RPCEndpoints)
+}
+
+func TestClientRegistryDoesNotCacheFailures(t *testing.T) {
+	registry, _, _, factory := newTestRegistry(t)
+
+	factory.err = errors.New("dial failed")
+	_, err := registry.GetClient(testChainID, false)
+	require.Error(t, err)
+
+	factory.err = nil
+	_, err = registry.GetClient(testChainID, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, factory.count())
+}
+
+func TestChainWithActiveRPC(t *testing.T) {
+	chain := &types.Chain{
+		ChainID: testChainID,
+		RPCEndpoints: []types.RPCEndpointConfig{
+			{Address: "a", APIToken: "token-a"},
+			{Address: "b"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		rpcAddress string
+		want       []types.RPCEndpointConfig
+	}{
+		{
+			name: "no active endpoint",
+			want: []types.RPCEndpointConfig{{Address: "a", APIToken: "token-a"}, {Address: "b"}},
+		},
+		{
+			name:       "active endpoint moves first",
+			rpcAddress: "b",
+			want:       []types.RPCEndpointConfig{{Address: "b"}, {Address: "a", APIToken: "token-a"}},
+		},
+		{
+			name:       "unknown endpoint is added first",
+			rpcAddress: "c",
+			want:       []types.RPCEndpointConfig{{Address: "c"}, {Address: "a", APIToken: "token-a"}, {Address: "b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active := chainWithActiveRPC(chain, tt.rpcAddress)
+			assert.Equal(t, tt.want, active.RPCEndpoints)
+			assert.Equal(t, testChainID, active.ChainID)
+
+			// Writing to the copy leaves the original untouched
+			active.RPCEndpoints[0].Address = "changed"
+			assert.Equal(t, "a", chain.RPCEndpoints[0].Address)
+		})
+	}
+}
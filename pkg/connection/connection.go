@@ -9,13 +9,13 @@ import (
 
 	"github.com/ignite/cli/v28/ignite/pkg/cosmosaccount"
 	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
+	"github.com/margined-protocol/locust-core/pkg/fees"
 	"github.com/margined-protocol/locust-core/pkg/messages/authz"
 	"github.com/margined-protocol/locust-core/pkg/types"
 	"go.uber.org/zap"
 
 	cometbft "github.com/cosmos/cosmos-sdk/client"
 	sdk "github.com/cosmos/cosmos-sdk/types"
-	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
 
 	rpchttp "github.com/cometbft/cometbft/rpc/client/http"
 )
@@ -52,56 +52,36 @@ type MessageSender interface {
 	SendAuthzMessagesWithResponse(ctx context.Context, l *zap.Logger, c *cosmosclient.Client, cfg *types.Config, msgs ...sdk.Msg) (*cosmosclient.Response, error)
 	SendMessages(ctx context.Context, l *zap.Logger, c *cosmosclient.Client, cfg *types.Config, msgs ...sdk.Msg) error
 	SendMessagesWithResponse(ctx context.Context, l *zap.Logger, c *cosmosclient.Client, cfg *types.Config, msgs ...sdk.Msg) (*cosmosclient.Response, error)
+	SimulateAuthzMessages(ctx context.Context, l *zap.Logger, c *cosmosclient.Client, cfg *types.Config, msgs ...sdk.Msg) (*Simulation, error)
+	SimulateMessages(ctx context.Context, l *zap.Logger, c *cosmosclient.Client, cfg *types.Config, msgs ...sdk.Msg) (*Simulation, error)
 }
 
 // DefaultMessageSender is a default implementation of the MessageSender interface
 type DefaultMessageSender struct{}
 
+// BroadcastWithRetry broadcasts the messages, retrying failed txs according to the retry
+// policy of their error category
 func BroadcastWithRetry(ctx context.Context, l *zap.Logger, cosmosClient *cosmosclient.Client, account cosmosaccount.Account, cfg *types.Config, msgs ...sdk.Msg) error {
-	for attempt := 0; attempt < cfg.TxRetryCount; attempt++ {
-		l.Debug("Attempting to broadcast transaction",
-			zap.Int("attempt", attempt+1),
-			zap.Int("max_attempts", cfg.TxRetryCount),
-		)
-
-		txResp, err := cosmosClient.BroadcastTx(ctx, account, msgs...)
-		if err != nil {
-			l.Error("Error broadcasting transaction",
-				zap.Int("attempt", attempt+1),
-				zap.String("reason", err.Error()),
-			)
-			continue
-		}
-
-		if txResp.Code == 0 {
-			l.Info("Transaction successful",
-				zap.String("transaction hash", txResp.TxHash),
-			)
-			return nil
-		}
-
-		if txResp.Code == sdkerrors.ErrWrongSequence.ABCICode() {
-			l.Debug("Retrying immediately due to sequence number mismatch")
-			continue
-		}
-
-		l.Error("Transaction failed with code",
-			zap.Int("code", int(txResp.Code)),
-			zap.String("log", txResp.RawLog),
-		)
+	_, err := BroadcastWithRetryAndResponse(ctx, l, cosmosClient, account, cfg, msgs...)
+	return err
+}
 
-		if attempt < cfg.TxRetryCount-1 {
-			l.Debug("Retrying after delay",
-				zap.Duration("retry_delay", cfg.TxRetryDelay),
-			)
-			time.Sleep(cfg.TxRetryDelay)
-		}
+// BroadcastWithRetryAndResponse broadcasts the messages and returns the response of the
+// successful tx. Sequence mismatches are retried immediately with the expected sequence, out of
+// gas and insufficient fee errors with a bumped gas limit or gas price and errors that cannot
+// succeed on retry, such as insufficient funds, are returned straight away. The gas price is
+// only bumped with fee estimation, otherwise insufficient fee errors are returned too. Failures are
+// returned as a *TxError matching the error of their category with errors.Is.
+func BroadcastWithRetryAndResponse(ctx context.Context, l *zap.Logger, cosmosClient *cosmosclient.Client, account cosmosaccount.Account, cfg *types.Config, msgs ...sdk.Msg) (*cosmosclient.Response, error) {
+	// Sequences are shared with every sender using this account
+	sequences, err := accountSequences(cosmosClient, account)
+	if err != nil {
+		return nil, err
 	}
 
-	return fmt.Errorf("failed to send transaction after %d attempts", cfg.TxRetryCount)
-}
+	bump := fees.NoBump()
+	var lastErr error
 
-func BroadcastWithRetryAndResponse(ctx context.Context, l *zap.Logger, cosmosClient *cosmosclient.Client, account cosmosaccount.Account, cfg *types.Config, msgs ...sdk.Msg) (*cosmosclient.Response, error) {
 	for attempt := 0; attempt < cfg.TxRetryCount; attempt++ {
 		l.Debug("Attempting to broadcast transaction",
 			zap.Int("attempt", attempt+1),
@@ -111,41 +91,58 @@ func BroadcastWithRetryAndResponse(ctx context.Context, l *zap.Logger, cosmosCli
 		address, _ := account.Record.GetAddress()
 		l.Debug("Broadcasting transaction from address", zap.String("address", address.String()))
 
-		txResp, err := cosmosClient.BroadcastTx(ctx, account, msgs...)
-		if err != nil {
-			l.Error("Error broadcasting transaction",
-				zap.Int("attempt", attempt+1),
-				zap.String("reason", err.Error()),
-			)
-			continue
-		}
-
-		if txResp.Code == 0 {
+		txResp, err := broadcastWithSequence(fees.WithBump(ctx, bump), cosmosClient, account, sequences, msgs...)
+		txErr := broadcastError(txResp, err)
+		if txErr == nil {
 			l.Info("Transaction successful",
 				zap.String("transaction hash", txResp.TxHash),
 			)
 			return &txResp, nil
 		}
+		lastErr = txErr
 
-		if txResp.Code == sdkerrors.ErrWrongSequence.ABCICode() {
-			l.Debug("Retrying immediately due to sequence number mismatch")
-			continue
+		if ctx.Err() != nil {
+			return nil, txErr
 		}
 
-		l.Error("Transaction failed with code",
-			zap.Int("code", int(txResp.Code)),
-			zap.String("log", txResp.RawLog),
+		policy := retryPolicy(cosmosClient, txErr)
+		l.Error("Transaction failed",
+			zap.Int("attempt", attempt+1),
+			zap.Stringer("category", txErr.Category),
+			zap.Stringer("retry_policy", policy),
+			zap.String("codespace", txErr.Codespace),
+			zap.Uint32("code", txErr.Code),
+			zap.String("log", txErr.Log),
 		)
 
+		switch policy {
+		case RetryFailFast:
+			return nil, txErr
+		case RetryResyncSequence, RetryImmediately:
+			continue
+		case RetryBumpGas:
+			bump.Gas = bump.Gas.Mul(gasBumpFactor)
+		case RetryBumpFee:
+			bump.Price = bump.Price.Mul(feeBumpFactor)
+		}
+
 		if attempt < cfg.TxRetryCount-1 {
 			l.Debug("Retrying after delay",
 				zap.Duration("retry_delay", cfg.TxRetryDelay),
 			)
-			time.Sleep(cfg.TxRetryDelay)
+
+			select {
+			case <-ctx.Done():
+				return nil, txErr
+			case <-time.After(cfg.TxRetryDelay):
+			}
 		}
 	}
 
-	return nil, fmt.Errorf("failed to send transaction after %d attempts", cfg.TxRetryCount)
+	if lastErr == nil {
+		return nil, fmt.Errorf("failed to send transaction after %d attempts", cfg.TxRetryCount)
+	}
+	return nil, fmt.Errorf("failed to send transaction after %d attempts: %w", cfg.TxRetryCount, lastErr)
 }
 
 // BroadcastShortTermOrder broadcasts a transaction and checks for immediate errors,
@@ -200,6 +197,28 @@ func InitRPCClient(logger *zap.Logger, serverAddress, websocketPath string, apiT
 		zap.String("serverAddress", serverAddress),
 		zap.String("websocketPath", websocketPath))
 
+	client, err := newRPCHTTPClient(serverAddress, websocketPath, apiToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	logger.Debug("Starting Websocket Client")
+	err = client.Start()
+	if err != nil {
+		// Clean up the client to avoid resource leaks
+		_ = client.Stop()
+
+		return nil, nil, fmt.Errorf("failed to start websocket connection: %w", err)
+	}
+
+	logger.Info("Successfully connected to RPC server with websockets",
+		zap.String("serverAddress", serverAddress))
+
+	return client, client, nil
+}
+
+// newRPCHTTPClient creates an RPC client without starting its websocket connection
+func newRPCHTTPClient(serverAddress, websocketPath, apiToken string) (*rpchttp.HTTP, error) {
 	var client *rpchttp.HTTP
 	var err error
 
@@ -217,22 +236,10 @@ func InitRPCClient(logger *zap.Logger, serverAddress, websocketPath string, apiT
 	}
 
 	if err != nil {
-		return nil, nil, fmt.Errorf("error creating RPC client: %w", err)
+		return nil, fmt.Errorf("error creating RPC client: %w", err)
 	}
 
-	logger.Debug("Starting Websocket Client")
-	err = client.Start()
-	if err != nil {
-		// Clean up the client to avoid resource leaks
-		_ = client.Stop()
-
-		return nil, nil, fmt.Errorf("failed to start websocket connection: %w", err)
-	}
-
-	logger.Info("Successfully connected to RPC server with websockets",
-		zap.String("serverAddress", serverAddress))
-
-	return client, client, nil
+	return client, nil
 }
 
 // headerTransport wraps an http.RoundTripper and adds custom headers
@@ -284,16 +291,23 @@ func InitCosmosClient(ctx context.Context, l *zap.Logger, chain *types.Chain, ke
 		opts = append(opts, cosmosclient.WithNodeAddress(rpcServerAddress))
 	}
 
-	// If gas, gas adjustment, and gas prices are not set, return an error
-	if chain.GasAdjustment == nil && chain.Gas == nil && chain.GasPrices == nil {
-		return nil, errors.New("invalid configuration: either fees must be set, or gas, gas adjustment, and gas prices must all be set")
-	}
+	// With fee estimation the gas and fees are set per tx, so only the fallback gas prices are required
+	if chain.FeeEstimation != nil {
+		if chain.GasPrices == nil {
+			return nil, errors.New("invalid configuration: gas prices must be set as the fee estimation fallback")
+		}
+	} else {
+		// If gas, gas adjustment, and gas prices are not set, return an error
+		if chain.GasAdjustment == nil && chain.Gas == nil && chain.GasPrices == nil {
+			return nil, errors.New("invalid configuration: either fees must be set, or gas, gas adjustment, and gas prices must all be set")
+		}
 
-	opts = append(opts,
-		cosmosclient.WithGas(*chain.Gas),
-		cosmosclient.WithGasAdjustment(*chain.GasAdjustment),
-		cosmosclient.WithGasPrices(*chain.GasPrices),
-	)
+		opts = append(opts,
+			cosmosclient.WithGas(*chain.Gas),
+			cosmosclient.WithGasAdjustment(*chain.GasAdjustment),
+			cosmosclient.WithGasPrices(*chain.GasPrices),
+		)
+	}
 
 	// Initialise a cosmosclient
 	client, err := cosmosclient.New(ctx, opts...)
@@ -304,6 +318,14 @@ func InitCosmosClient(ctx context.Context, l *zap.Logger, chain *types.Chain, ke
 		return nil, err
 	}
 
+	if err := registerFeeEstimator(l, &client, chain); err != nil {
+		return nil, fmt.Errorf("failed to set up fee estimation: %w", err)
+	}
+
+	if err := useRemoteSigner(ctx, &client, key); err != nil {
+		return nil, err
+	}
+
 	return &client, nil
 }
 
@@ -325,11 +347,14 @@ func InitFeeClient(ctx context.Context, l *zap.Logger, chain *types.Chain, key *
 		cosmosclient.WithKeyringServiceName(key.AppName),
 	}
 
-	if chain.Fees == nil {
-		return nil, errors.New("invalid configuration: fees must be set")
-	}
+	// With fee estimation the fees are set per tx
+	if chain.FeeEstimation == nil {
+		if chain.Fees == nil {
+			return nil, errors.New("invalid configuration: fees must be set")
+		}
 
-	opts = append(opts, cosmosclient.WithFees(*chain.Fees))
+		opts = append(opts, cosmosclient.WithFees(*chain.Fees))
+	}
 
 	// Initialise a cosmosclient
 	client, err := cosmosclient.New(ctx, opts...)
@@ -340,6 +365,14 @@ func InitFeeClient(ctx context.Context, l *zap.Logger, chain *types.Chain, key *
 		return nil, err
 	}
 
+	if err := registerFeeEstimator(l, &client, chain); err != nil {
+		return nil, fmt.Errorf("failed to set up fee estimation: %w", err)
+	}
+
+	if err := useRemoteSigner(ctx, &client, key); err != nil {
+		return nil, err
+	}
+
 	return &client, nil
 }
 
@@ -496,6 +529,40 @@ func (*DefaultMessageSender) SendMessagesWithResponse(ctx context.Context, l *za
 	return res, nil
 }
 
+// SimulateAuthzMessages simulates the given messages wrapped in an Authz MsgExec of the
+// grantee without broadcasting them.
+func (*DefaultMessageSender) SimulateAuthzMessages(ctx context.Context, l *zap.Logger, c *cosmosclient.Client, cfg *types.Config, msgs ...sdk.Msg) (*Simulation, error) {
+	// Get the grantee account and address
+	account, granteeAddress, err := GetSignerAccountAndAddress(c, cfg.SignerAccount, cfg.Chain.Prefix)
+	if err != nil {
+		return nil, fmt.Errorf("error getting grantee account: %w", err)
+	}
+
+	l.Debug("Grantee address", zap.String("granteeAddress", granteeAddress))
+
+	// Convert the grantee address to AccAddress
+	granteeAccAddress, err := sdk.AccAddressFromBech32(granteeAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate AccAddress from grantee address: %w", err)
+	}
+
+	// Create the Authz MsgExec message
+	msgExec := authz.CreateAuthzMsg(granteeAccAddress, msgs)
+
+	return SimulateTx(ctx, c, *account, msgExec)
+}
+
+// SimulateMessages simulates the given messages signed by the signer account without
+// broadcasting them.
+func (*DefaultMessageSender) SimulateMessages(ctx context.Context, _ *zap.Logger, c *cosmosclient.Client, cfg *types.Config, msgs ...sdk.Msg) (*Simulation, error) {
+	account, _, err := GetSignerAccountAndAddress(c, cfg.SignerAccount, cfg.Chain.Prefix)
+	if err != nil {
+		return nil, fmt.Errorf("error getting grantee account: %w", err)
+	}
+
+	return SimulateTx(ctx, c, *account, msgs...)
+}
+
 // SendMessages sends the given messages using the cosmos client and grantee information.
 // It returns an error if any step fails.
 func (*DefaultMessageSender) SendShortTermMessage(ctx context.Context, l *zap.Logger, c *cosmosclient.Client, cfg *types.Config, msgs ...sdk.Msg) error {
@@ -0,0 +1,166 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosaccount"
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
+	"github.com/margined-protocol/locust-core/pkg/fees"
+	"github.com/margined-protocol/locust-core/pkg/types"
+	"go.uber.org/zap"
+
+	sdkmath "cosmossdk.io/math"
+
+	"github.com/cosmos/cosmos-sdk/client/tx"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// feeEstimators holds the fee estimator of each client with fee estimation configured, every
+// estimator queries the fee market through its own client
+var (
+	feeEstimators   = make(map[*cosmosclient.Client]*fees.Estimator)
+	feeEstimatorsMu sync.RWMutex
+)
+
+var (
+	// gasBumpFactor scales the gas limit after each out of gas failure
+	gasBumpFactor = sdkmath.LegacyNewDecWithPrec(15, 1)
+	// feeBumpFactor scales the gas price after each insufficient fee failure
+	feeBumpFactor = sdkmath.LegacyNewDecWithPrec(12, 1)
+)
+
+// registerFeeEstimator sets up fee estimation for the client when configured for the chain,
+// querying the fee market through the client
+func registerFeeEstimator(l *zap.Logger, cosmosClient *cosmosclient.Client, chain *types.Chain) error {
+	if chain.FeeEstimation == nil {
+		return nil
+	}
+
+	config, err := fees.ConfigFromChain(chain)
+	if err != nil {
+		return err
+	}
+
+	source, err := fees.NewGasPriceSource(chain.FeeEstimation.FeeMarket, abciQuerier(cosmosClient))
+	if err != nil {
+		return err
+	}
+
+	estimator, err := fees.NewEstimator(l, config, source)
+	if err != nil {
+		return err
+	}
+
+	feeEstimatorsMu.Lock()
+	feeEstimators[cosmosClient] = estimator
+	feeEstimatorsMu.Unlock()
+
+	return nil
+}
+
+// feeEstimator returns the fee estimator of a client, if fee estimation is configured
+func feeEstimator(cosmosClient *cosmosclient.Client) (*fees.Estimator, bool) {
+	feeEstimatorsMu.RLock()
+	defer feeEstimatorsMu.RUnlock()
+
+	estimator, ok := feeEstimators[cosmosClient]
+	return estimator, ok
+}
+
+// releaseFeeEstimator drops the fee estimator of a client that is no longer used
+func releaseFeeEstimator(cosmosClient *cosmosclient.Client) {
+	feeEstimatorsMu.Lock()
+	defer feeEstimatorsMu.Unlock()
+
+	delete(feeEstimators, cosmosClient)
+}
+
+// abciQuerier runs fee market queries against the client's RPC endpoint
+func abciQuerier(cosmosClient *cosmosclient.Client) fees.ABCIQuerier {
+	return func(ctx context.Context, path string, data []byte) ([]byte, error) {
+		res, err := cosmosClient.RPC.ABCIQuery(ctx, path, data)
+		if err != nil {
+			return nil, err
+		}
+		if res.Response.Code != 0 {
+			return nil, fmt.Errorf("query %s failed with code %d: %s", path, res.Response.Code, res.Response.Log)
+		}
+		return res.Response.Value, nil
+	}
+}
+
+// gasSimulator simulates the messages with the client's factory, which must hold the
+// account number and sequence of the signer
+func gasSimulator(cosmosClient *cosmosclient.Client, msgs ...sdk.Msg) fees.Simulator {
+	return func(context.Context) (uint64, error) {
+		res, _, err := tx.CalculateGas(cosmosClient.Context(), cosmosClient.TxFactory, msgs...)
+		if err != nil {
+			return 0, err
+		}
+		return res.GasInfo.GasUsed, nil
+	}
+}
+
+// retryPolicy returns the retry policy of a failed tx sent by the client. Without fee estimation
+// the client pays its configured gas prices or fees whatever the bump, so a tx with insufficient
+// fees would fail again and is not retried.
+func retryPolicy(cosmosClient *cosmosclient.Client, txErr *TxError) RetryPolicy {
+	policy := txErr.RetryPolicy()
+	if policy == RetryBumpFee {
+		if _, estimated := feeEstimator(cosmosClient); !estimated {
+			return RetryFailFast
+		}
+	}
+	return policy
+}
+
+// broadcastWithEstimatedFee broadcasts the messages with the gas limit and fee estimated for
+// them, the fee caps of the strategy are taken from the context
+func broadcastWithEstimatedFee(ctx context.Context, estimator *fees.Estimator, cosmosClient *cosmosclient.Client, account cosmosaccount.Account, msgs ...sdk.Msg) (cosmosclient.Response, error) {
+	fee, err := estimator.Estimate(ctx, gasSimulator(cosmosClient, msgs...))
+	if err != nil {
+		return cosmosclient.Response{}, fmt.Errorf("failed to estimate fee: %w", err)
+	}
+
+	txService, err := cosmosClient.CreateTxWithOptions(ctx, account, cosmosclient.TxOptions{
+		GasLimit: fee.GasLimit,
+		Fees:     fee.String(),
+	}, msgs...)
+	if err != nil {
+		return cosmosclient.Response{}, fmt.Errorf("failed to create tx: %w", err)
+	}
+
+	return txService.Broadcast(ctx)
+}
+
+// broadcastWithBumpedGas broadcasts the messages with the simulated gas scaled by the client's
+// gas adjustment and the gas bump. Without fee estimation the client pays its configured gas
+// prices or fees, so only the gas limit can be bumped.
+func broadcastWithBumpedGas(ctx context.Context, cosmosClient *cosmosclient.Client, account cosmosaccount.Account, bump fees.Bump, msgs ...sdk.Msg) (cosmosclient.Response, error) {
+	gasUsed, err := gasSimulator(cosmosClient, msgs...)(ctx)
+	if err != nil {
+		return cosmosclient.Response{}, fmt.Errorf("failed to simulate tx: %w", err)
+	}
+
+	adjustment := cosmosClient.TxFactory.GasAdjustment()
+	if adjustment <= 0 {
+		adjustment = 1
+	}
+
+	gasBump, err := bump.Gas.Float64()
+	if err != nil {
+		return cosmosclient.Response{}, fmt.Errorf("invalid gas bump: %w", err)
+	}
+
+	txService, err := cosmosClient.CreateTxWithOptions(ctx, account, cosmosclient.TxOptions{
+		GasLimit: uint64(math.Ceil(float64(gasUsed) * adjustment * gasBump)),
+	}, msgs...)
+	if err != nil {
+		return cosmosclient.Response{}, fmt.Errorf("failed to create tx: %w", err)
+	}
+
+	return txService.Broadcast(ctx)
+}
@@ -0,0 +1,64 @@
+package connection
+
+import (
+	"testing"
+
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
+	"github.com/margined-protocol/locust-core/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFeeEstimatorPerClient(t *testing.T) {
+	gasPrices := "0.0053untrn"
+	estimated := &types.Chain{ChainID: "neutron-1", GasPrices: &gasPrices, FeeEstimation: &types.FeeEstimation{}}
+	static := &types.Chain{ChainID: "neutron-1", GasPrices: &gasPrices}
+
+	cosmosClient := &cosmosclient.Client{}
+	feeClient := &cosmosclient.Client{}
+	t.Cleanup(func() {
+		releaseFeeEstimator(cosmosClient)
+		releaseFeeEstimator(feeClient)
+	})
+
+	require.NoError(t, registerFeeEstimator(zap.NewNop(), cosmosClient, estimated))
+
+	// A client of the same chain without fee estimation leaves the other client estimating
+	require.NoError(t, registerFeeEstimator(zap.NewNop(), feeClient, static))
+	_, ok := feeEstimator(cosmosClient)
+	assert.True(t, ok)
+	_, ok = feeEstimator(feeClient)
+	assert.False(t, ok)
+
+	// Clients estimating on the same chain keep their own estimator
+	require.NoError(t, registerFeeEstimator(zap.NewNop(), feeClient, estimated))
+	first, _ := feeEstimator(cosmosClient)
+	second, _ := feeEstimator(feeClient)
+	assert.NotSame(t, first, second)
+
+	releaseFeeEstimator(feeClient)
+	_, ok = feeEstimator(feeClient)
+	assert.False(t, ok)
+	_, ok = feeEstimator(cosmosClient)
+	assert.True(t, ok)
+}
+
+func TestRetryPolicyWithoutFeeEstimation(t *testing.T) {
+	gasPrices := "0.0053untrn"
+	estimated := &types.Chain{ChainID: "neutron-1", GasPrices: &gasPrices, FeeEstimation: &types.FeeEstimation{}}
+
+	cosmosClient := &cosmosclient.Client{}
+	t.Cleanup(func() { releaseFeeEstimator(cosmosClient) })
+
+	insufficientFees := NewTxError("", "sdk", 13, "insufficient fees; got: 100untrn required: 500untrn: insufficient fee")
+	outOfGas := NewTxError("", "sdk", 11, "out of gas in location: WriteFlat: out of gas")
+
+	// The configured gas prices or fees would be paid again
+	assert.Equal(t, RetryFailFast, retryPolicy(cosmosClient, insufficientFees))
+	assert.Equal(t, RetryBumpGas, retryPolicy(cosmosClient, outOfGas))
+
+	require.NoError(t, registerFeeEstimator(zap.NewNop(), cosmosClient, estimated))
+	assert.Equal(t, RetryBumpFee, retryPolicy(cosmosClient, insufficientFees))
+	assert.Equal(t, RetryBumpGas, retryPolicy(cosmosClient, outOfGas))
+}
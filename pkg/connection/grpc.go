@@ -11,11 +11,15 @@ import (
 	proto "github.com/cosmos/gogoproto/proto"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/encoding"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/cosmos/cosmos-sdk/client/grpc/cmtservice"
 )
 
 const (
@@ -60,6 +64,11 @@ func (c customCodec) Name() string {
 
 // SetupGRPCConnection establishes a GRPC connection, optionally using system's TLS certificates
 func SetupGRPCConnection(address string, useTLS bool, apiToken string) (*grpc.ClientConn, error) {
+	return setupGRPCConnection(address, useTLS, apiToken)
+}
+
+// setupGRPCConnection establishes a GRPC connection with additional dial options
+func setupGRPCConnection(address string, useTLS bool, apiToken string, extraOpts ...grpc.DialOption) (*grpc.ClientConn, error) {
 	// Create custom codec for gogoproto compatibility
 	customCodec := &customCodec{parentCodec: encoding.GetCodec("proto")}
 
@@ -92,6 +101,8 @@ func SetupGRPCConnection(address string, useTLS bool, apiToken string) (*grpc.Cl
 		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	}
 
+	opts = append(opts, extraOpts...)
+
 	conn, err := grpc.NewClient(address, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to gRPC server at %s: %w", address, err)
@@ -100,7 +111,8 @@ func SetupGRPCConnection(address string, useTLS bool, apiToken string) (*grpc.Cl
 	return conn, nil
 }
 
-// MultiEndpointGRPCClient manages multiple gRPC endpoints with automatic failover
+// MultiEndpointGRPCClient manages multiple gRPC endpoints with automatic failover, traffic
+// goes to the endpoint with the best height, latency and error rate
 type MultiEndpointGRPCClient struct {
 	ctx               context.Context
 	cancel            context.CancelFunc
@@ -114,6 +126,10 @@ type MultiEndpointGRPCClient struct {
 	currentIndex        int
 	healthCheckInterval time.Duration
 	connectionTimeout   time.Duration
+
+	// Connections are kept per endpoint so every endpoint can be scored
+	conns  []*grpc.ClientConn
+	health *endpointScorer
 }
 
 // NewMultiEndpointGRPCClient creates a new client that manages multiple gRPC endpoints
@@ -136,6 +152,8 @@ func NewMultiEndpointGRPCClient(
 		currentIndex:        0,
 		healthCheckInterval: DefaultGRPCHealthCheckInterval,
 		connectionTimeout:   DefaultGRPCConnectionTimeout,
+		conns:               make([]*grpc.ClientConn, len(endpoints)),
+		health:              newEndpointScorer(len(endpoints), DefaultHealthConfig()),
 	}
 
 	// Try to establish initial connection
@@ -170,22 +188,22 @@ func NewMultiEndpointGRPCClientWithAddresses(
 
 // connectToEndpoint tries to connect to the current endpoint
 func (c *MultiEndpointGRPCClient) connectToEndpoint() error {
-	endpoint := c.endpoints[c.currentIndex]
+	c.mu.RLock()
+	index := c.currentIndex
+	c.mu.RUnlock()
+
+	endpoint := c.endpoints[index]
 	c.logger.Debug("Connecting to gRPC endpoint",
 		zap.String("endpoint", endpoint.Address),
 		zap.Bool("useTLS", endpoint.UseTLS))
 
-	// Use the existing SetupGRPCConnection function for consistency
-	conn, err := SetupGRPCConnection(endpoint.Address, endpoint.UseTLS, endpoint.APIKey) // No API token for now
+	conn, err := c.connection(index)
 	if err != nil {
-		return fmt.Errorf("failed to connect to gRPC endpoint %s: %w", endpoint.Address, err)
+		return err
 	}
 
-	// Update the client reference
+	// Update the client reference, the previous connection stays open as it is still probed
 	c.mu.Lock()
-	if c.currentClient != nil {
-		_ = c.currentClient.Close() // Close the old connection if it exists
-	}
 	c.currentClient = conn
 	c.mu.Unlock()
 
@@ -195,11 +213,67 @@ func (c *MultiEndpointGRPCClient) connectToEndpoint() error {
 	return nil
 }
 
+// connection returns the connection to an endpoint, creating it on first use
+func (c *MultiEndpointGRPCClient) connection(index int) (*grpc.ClientConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conns[index] != nil {
+		return c.conns[index], nil
+	}
+
+	// Use the existing SetupGRPCConnection options for consistency
+	endpoint := c.endpoints[index]
+	conn, err := setupGRPCConnection(endpoint.Address, endpoint.UseTLS, endpoint.APIKey,
+		grpc.WithChainUnaryInterceptor(c.healthInterceptor(index)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to gRPC endpoint %s: %w", endpoint.Address, err)
+	}
+
+	c.conns[index] = conn
+	return conn, nil
+}
+
+// healthInterceptor records the latency and outcome of every query sent to an endpoint,
+// only errors raised by the endpoint itself count against it
+func (c *MultiEndpointGRPCClient) healthInterceptor(index int) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		code := status.Code(err)
+		switch {
+		case code == codes.Canceled:
+			// Cancelled by the caller, says nothing about the endpoint
+		case isEndpointError(code):
+			c.health.recordQuery(index, time.Since(start), err)
+		default:
+			c.health.recordQuery(index, time.Since(start), nil)
+		}
+
+		return err
+	}
+}
+
+// isEndpointError reports whether a status code means the endpoint failed to serve the
+// query, as opposed to the query itself being rejected
+func isEndpointError(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal:
+		return true
+	default:
+		return false
+	}
+}
+
 // startHealthCheck begins periodic health checks of the connection
 func (c *MultiEndpointGRPCClient) startHealthCheck() {
 	c.healthCheckTicker = time.NewTicker(c.healthCheckInterval)
 
 	go func() {
+		// Score the endpoints straight away rather than after the first interval
+		c.checkConnection()
+
 		for {
 			select {
 			case <-c.healthCheckTicker.C:
@@ -214,10 +288,12 @@ func (c *MultiEndpointGRPCClient) startHealthCheck() {
 	}()
 }
 
-// checkConnection verifies that the connection is healthy and rotates to next endpoint if needed
+// checkConnection probes every endpoint and moves traffic to the best one, the current
+// endpoint is abandoned immediately when its connection is down
 func (c *MultiEndpointGRPCClient) checkConnection() {
 	c.mu.RLock()
 	conn := c.currentClient
+	current := c.currentIndex
 	c.mu.RUnlock()
 
 	if conn == nil {
@@ -226,21 +302,90 @@ func (c *MultiEndpointGRPCClient) checkConnection() {
 		return
 	}
 
+	errs := c.probeEndpoints()
+
 	// Check if the connection is in a good state
 	state := conn.GetState()
-	if state != connectivity.Ready && state != connectivity.Idle {
+	down := state != connectivity.Ready && state != connectivity.Idle
+	if down {
 		c.logger.Warn("gRPC connection is not ready, rotating endpoint",
 			zap.String("state", state.String()),
-			zap.String("current_endpoint", c.endpoints[c.currentIndex].Address))
-		c.rotateEndpoint()
+			zap.String("current_endpoint", c.endpoints[current].Address))
+	} else if errs[current] != nil {
+		down = true
+		c.logger.Warn("gRPC health check failed, rotating endpoint",
+			zap.Error(errs[current]),
+			zap.String("current_endpoint", c.endpoints[current].Address))
+	}
+
+	next := c.health.selectEndpoint(c.addresses(), current, down)
+	if next == current {
+		return
+	}
+
+	if !down {
+		c.logger.Warn("gRPC endpoint demoted, switching to healthier endpoint",
+			zap.String("current_endpoint", c.endpoints[current].Address),
+			zap.String("endpoint", c.endpoints[next].Address))
 	}
+
+	c.switchEndpoint(next)
+}
+
+// probeEndpoints queries the latest block of every endpoint in parallel, latency is
+// recorded by the health interceptor and the reported height here
+func (c *MultiEndpointGRPCClient) probeEndpoints() []error {
+	errs := make([]error, len(c.endpoints))
+
+	var wg sync.WaitGroup
+	for i := range c.endpoints {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			errs[index] = c.probeEndpoint(index)
+		}(i)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// probeEndpoint queries the latest block height of an endpoint
+func (c *MultiEndpointGRPCClient) probeEndpoint(index int) error {
+	conn, err := c.connection(index)
+	if err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	timeout := c.connectionTimeout
+	c.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(c.ctx, timeout)
+	defer cancel()
+
+	res, err := cmtservice.NewServiceClient(conn).GetLatestBlock(ctx, &cmtservice.GetLatestBlockRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to query latest block from %s: %w", c.endpoints[index].Address, err)
+	}
+
+	c.health.recordHeight(index, res.GetSdkBlock().GetHeader().Height)
+	return nil
 }
 
 // rotateEndpoint switches to the next endpoint in the list and tries to connect
 func (c *MultiEndpointGRPCClient) rotateEndpoint() {
+	c.mu.RLock()
+	next := (c.currentIndex + 1) % len(c.endpoints)
+	c.mu.RUnlock()
+
+	c.switchEndpoint(next)
+}
+
+// switchEndpoint moves traffic to the endpoint at index and tries to connect
+func (c *MultiEndpointGRPCClient) switchEndpoint(index int) {
 	c.mu.Lock()
-	// Move to the next endpoint
-	c.currentIndex = (c.currentIndex + 1) % len(c.endpoints)
+	c.currentIndex = index
 	c.mu.Unlock()
 
 	// Try to connect with the new endpoint
@@ -248,8 +393,17 @@ func (c *MultiEndpointGRPCClient) rotateEndpoint() {
 	if err != nil {
 		c.logger.Error("Failed to connect to rotated gRPC endpoint",
 			zap.Error(err),
-			zap.String("endpoint", c.endpoints[c.currentIndex].Address))
+			zap.String("endpoint", c.endpoints[index].Address))
+	}
+}
+
+// addresses returns the endpoint addresses in configuration order
+func (c *MultiEndpointGRPCClient) addresses() []string {
+	addresses := make([]string, len(c.endpoints))
+	for i, endpoint := range c.endpoints {
+		addresses[i] = endpoint.Address
 	}
+	return addresses
 }
 
 // GetClient returns the current gRPC client connection
@@ -293,6 +447,20 @@ func (c *MultiEndpointGRPCClient) GetCurrentEndpoint() (endpoint GRPCEndpointCon
 	return c.endpoints[c.currentIndex], c.currentIndex
 }
 
+// SetHealthConfig changes how endpoints are scored and when they are demoted
+func (c *MultiEndpointGRPCClient) SetHealthConfig(config HealthConfig) {
+	c.health.setConfig(config)
+}
+
+// GetEndpointStats returns the health score of every endpoint in configuration order
+func (c *MultiEndpointGRPCClient) GetEndpointStats() []EndpointStats {
+	c.mu.RLock()
+	current := c.currentIndex
+	c.mu.RUnlock()
+
+	return c.health.stats(c.addresses(), current)
+}
+
 // Close shuts down the client and all connections
 func (c *MultiEndpointGRPCClient) Close() {
 	// Cancel the background goroutine
@@ -307,8 +475,11 @@ func (c *MultiEndpointGRPCClient) Close() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.currentClient != nil {
-		_ = c.currentClient.Close()
-		c.currentClient = nil
+	for i, conn := range c.conns {
+		if conn != nil {
+			_ = conn.Close()
+			c.conns[i] = nil
+		}
 	}
+	c.currentClient = nil
 }
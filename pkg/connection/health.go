@@ -0,0 +1,233 @@
+package connection
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// Default number of blocks an endpoint may trail the highest endpoint before it is demoted
+	DefaultMaxHeightLag = 5
+	// Default share of failed queries above which an endpoint is demoted
+	DefaultMaxErrorRate = 0.5
+	// Default number of queries an endpoint needs before its error rate can demote it
+	DefaultMinErrorSamples = 5
+	// Default number of recent queries the latency and error rate are computed over
+	DefaultHealthSampleSize = 100
+	// Default improvement in score required before traffic moves to another endpoint
+	DefaultSwitchMargin = 0.2
+
+	// Latency at which an endpoint's score is halved
+	healthLatencyScale = 500 * time.Millisecond
+)
+
+// HealthConfig controls how endpoints are scored and when they are demoted
+type HealthConfig struct {
+	MaxHeightLag int64
+	MaxErrorRate float64
+	// MinErrorSamples is capped at the sample size
+	MinErrorSamples int
+	SampleSize      int
+	SwitchMargin    float64
+}
+
+// DefaultHealthConfig returns the default endpoint health configuration
+func DefaultHealthConfig() HealthConfig {
+	return HealthConfig{
+		MaxHeightLag:    DefaultMaxHeightLag,
+		MaxErrorRate:    DefaultMaxErrorRate,
+		MinErrorSamples: DefaultMinErrorSamples,
+		SampleSize:      DefaultHealthSampleSize,
+		SwitchMargin:    DefaultSwitchMargin,
+	}
+}
+
+// EndpointStats is a snapshot of the health of an endpoint
+type EndpointStats struct {
+	Address    string
+	Height     int64
+	HeightLag  int64
+	LatencyP50 time.Duration
+	LatencyP99 time.Duration
+	ErrorRate  float64
+	Samples    int
+	// Score is between 0 and 1, traffic goes to the endpoint with the highest score
+	Score   float64
+	Demoted bool
+	Current bool
+}
+
+// querySample is the outcome of a single query against an endpoint
+type querySample struct {
+	latency time.Duration
+	failed  bool
+}
+
+// endpointHealth keeps the most recent query samples and latest height of an endpoint
+type endpointHealth struct {
+	samples []querySample
+	next    int
+	height  int64
+}
+
+// endpointScorer scores a fixed set of endpoints on block height, latency and error rate
+type endpointScorer struct {
+	mu        sync.Mutex
+	config    HealthConfig
+	endpoints []endpointHealth
+}
+
+func newEndpointScorer(count int, config HealthConfig) *endpointScorer {
+	return &endpointScorer{
+		config:    config,
+		endpoints: make([]endpointHealth, count),
+	}
+}
+
+// setConfig replaces the health configuration, samples are reset when the sample size changes
+func (s *endpointScorer) setConfig(config HealthConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if config.SampleSize != s.config.SampleSize {
+		for i := range s.endpoints {
+			s.endpoints[i].samples = nil
+			s.endpoints[i].next = 0
+		}
+	}
+	s.config = config
+}
+
+// recordQuery records the latency and outcome of a query against an endpoint
+func (s *endpointScorer) recordQuery(index int, latency time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	endpoint := &s.endpoints[index]
+	sample := querySample{latency: latency, failed: err != nil}
+
+	if len(endpoint.samples) < s.config.SampleSize {
+		endpoint.samples = append(endpoint.samples, sample)
+		return
+	}
+
+	endpoint.samples[endpoint.next] = sample
+	endpoint.next = (endpoint.next + 1) % len(endpoint.samples)
+}
+
+// recordHeight records the latest block height reported by an endpoint
+func (s *endpointScorer) recordHeight(index int, height int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if height > s.endpoints[index].height {
+		s.endpoints[index].height = height
+	}
+}
+
+// stats returns the health of every endpoint, addresses are in endpoint order
+func (s *endpointScorer) stats(addresses []string, current int) []EndpointStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var maxHeight int64
+	for _, endpoint := range s.endpoints {
+		if endpoint.height > maxHeight {
+			maxHeight = endpoint.height
+		}
+	}
+
+	// A few failed probes of a new endpoint are not enough to demote it
+	minErrorSamples := min(s.config.MinErrorSamples, s.config.SampleSize)
+
+	stats := make([]EndpointStats, len(s.endpoints))
+	for i, endpoint := range s.endpoints {
+		stat := EndpointStats{
+			Address:   addresses[i],
+			Height:    endpoint.height,
+			HeightLag: maxHeight - endpoint.height,
+			Samples:   len(endpoint.samples),
+			Current:   i == current,
+		}
+
+		latencies := make([]time.Duration, 0, len(endpoint.samples))
+		failures := 0
+		for _, sample := range endpoint.samples {
+			if sample.failed {
+				failures++
+				continue
+			}
+			latencies = append(latencies, sample.latency)
+		}
+
+		if len(endpoint.samples) > 0 {
+			stat.ErrorRate = float64(failures) / float64(len(endpoint.samples))
+		}
+
+		sort.Slice(latencies, func(a, b int) bool { return latencies[a] < latencies[b] })
+		stat.LatencyP50 = percentile(latencies, 0.50)
+		stat.LatencyP99 = percentile(latencies, 0.99)
+
+		erroring := stat.Samples >= minErrorSamples && stat.ErrorRate > s.config.MaxErrorRate
+		stat.Demoted = stat.HeightLag > s.config.MaxHeightLag || erroring
+		if !stat.Demoted && len(latencies) > 0 {
+			// Halved at the latency scale, weighted towards the typical rather than the tail latency
+			latency := (3*stat.LatencyP50 + stat.LatencyP99) / 4
+			stat.Score = (1 - stat.ErrorRate) * float64(healthLatencyScale) / float64(healthLatencyScale+latency)
+		}
+
+		stats[i] = stat
+	}
+
+	return stats
+}
+
+// selectEndpoint returns the endpoint traffic should go to, the current endpoint is kept
+// unless it is down, demoted or another endpoint scores better by the switch margin. When
+// the current endpoint is down and nothing scores better the next endpoint is tried.
+func (s *endpointScorer) selectEndpoint(addresses []string, current int, down bool) int {
+	stats := s.stats(addresses, current)
+
+	s.mu.Lock()
+	margin := s.config.SwitchMargin
+	s.mu.Unlock()
+
+	best := -1
+	for i, stat := range stats {
+		if i == current || stat.Demoted || stat.Samples == 0 {
+			continue
+		}
+		if best == -1 || stat.Score > stats[best].Score {
+			best = i
+		}
+	}
+
+	if best == -1 {
+		if down {
+			return (current + 1) % len(stats)
+		}
+		return current
+	}
+
+	if down || stats[current].Demoted || stats[best].Score > stats[current].Score*(1+margin) {
+		return best
+	}
+
+	return current
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+
+	return sorted[rank]
+}
@@ -0,0 +1,211 @@
+package connection
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testAddresses = []string{"a", "b", "c"}
+
+// recordLatencies records successful queries against an endpoint
+func recordLatencies(s *endpointScorer, index int, latencies ...time.Duration) {
+	for _, latency := range latencies {
+		s.recordQuery(index, latency, nil)
+	}
+}
+
+// recordFailures records failed queries against an endpoint
+func recordFailures(s *endpointScorer, index, count int) {
+	for i := 0; i < count; i++ {
+		s.recordQuery(index, time.Second, errors.New("unavailable"))
+	}
+}
+
+func TestEndpointScorerStats(t *testing.T) {
+	s := newEndpointScorer(3, DefaultHealthConfig())
+
+	for i := 1; i <= 100; i++ {
+		s.recordQuery(0, time.Duration(i)*time.Millisecond, nil)
+	}
+	s.recordHeight(0, 100)
+
+	recordLatencies(s, 1, 10*time.Millisecond)
+	s.recordQuery(1, time.Second, errors.New("unavailable"))
+	s.recordHeight(1, 100)
+
+	s.recordHeight(2, 90)
+	// Heights never go backwards
+	s.recordHeight(2, 80)
+
+	stats := s.stats(testAddresses, 0)
+	require.Len(t, stats, 3)
+
+	assert.Equal(t, "a", stats[0].Address)
+	assert.True(t, stats[0].Current)
+	assert.Equal(t, 50*time.Millisecond, stats[0].LatencyP50)
+	assert.Equal(t, 99*time.Millisecond, stats[0].LatencyP99)
+	assert.Zero(t, stats[0].ErrorRate)
+	assert.Equal(t, 100, stats[0].Samples)
+	assert.False(t, stats[0].Demoted)
+	assert.Greater(t, stats[0].Score, 0.0)
+
+	// Failed queries count towards the error rate but not the latency
+	assert.Equal(t, 10*time.Millisecond, stats[1].LatencyP99)
+	assert.InDelta(t, 0.5, stats[1].ErrorRate, 1e-12)
+	assert.False(t, stats[1].Demoted)
+
+	assert.Equal(t, int64(90), stats[2].Height)
+	assert.Equal(t, int64(10), stats[2].HeightLag)
+	assert.True(t, stats[2].Demoted)
+	assert.Zero(t, stats[2].Score)
+}
+
+func TestEndpointScorerSampleWindow(t *testing.T) {
+	config := DefaultHealthConfig()
+	config.SampleSize = 4
+	s := newEndpointScorer(1, config)
+
+	for i := 0; i < 4; i++ {
+		s.recordQuery(0, time.Second, errors.New("timeout"))
+	}
+	assert.True(t, s.stats(testAddresses, 0)[0].Demoted)
+
+	// Old samples roll out of the window
+	recordLatencies(s, 0, time.Millisecond, time.Millisecond, time.Millisecond)
+	stats := s.stats(testAddresses, 0)[0]
+	assert.Equal(t, 4, stats.Samples)
+	assert.InDelta(t, 0.25, stats.ErrorRate, 1e-12)
+	assert.False(t, stats.Demoted)
+}
+
+func TestEndpointScorerMinErrorSamples(t *testing.T) {
+	s := newEndpointScorer(1, DefaultHealthConfig())
+
+	// A single failed probe is not enough to demote an endpoint
+	recordFailures(s, 0, 1)
+	stats := s.stats(testAddresses, 0)[0]
+	assert.Equal(t, 1.0, stats.ErrorRate)
+	assert.False(t, stats.Demoted)
+
+	recordFailures(s, 0, DefaultMinErrorSamples-2)
+	assert.False(t, s.stats(testAddresses, 0)[0].Demoted)
+
+	recordFailures(s, 0, 1)
+	assert.True(t, s.stats(testAddresses, 0)[0].Demoted)
+}
+
+func TestEndpointScorerSelectEndpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(s *endpointScorer)
+		current int
+		down    bool
+		want    int
+	}{
+		{
+			name: "keeps current when comparable",
+			setup: func(s *endpointScorer) {
+				recordLatencies(s, 0, 100*time.Millisecond)
+				recordLatencies(s, 1, 90*time.Millisecond)
+				recordLatencies(s, 2, 110*time.Millisecond)
+			},
+			current: 0,
+			want:    0,
+		},
+		{
+			name: "moves off a slow endpoint",
+			setup: func(s *endpointScorer) {
+				recordLatencies(s, 0, 3*time.Second)
+				recordLatencies(s, 1, 200*time.Millisecond)
+				recordLatencies(s, 2, 50*time.Millisecond)
+			},
+			current: 0,
+			want:    2,
+		},
+		{
+			name: "demotes a lagging endpoint",
+			setup: func(s *endpointScorer) {
+				recordLatencies(s, 0, 10*time.Millisecond)
+				recordLatencies(s, 1, 100*time.Millisecond)
+				recordLatencies(s, 2, 100*time.Millisecond)
+				s.recordHeight(0, 950)
+				s.recordHeight(1, 1000)
+				s.recordHeight(2, 999)
+			},
+			current: 0,
+			want:    1,
+		},
+		{
+			name: "demotes an erroring endpoint",
+			setup: func(s *endpointScorer) {
+				recordLatencies(s, 0, 10*time.Millisecond, 10*time.Millisecond)
+				recordFailures(s, 0, 3)
+				recordLatencies(s, 1, 2*time.Second)
+			},
+			current: 0,
+			want:    1,
+		},
+		{
+			name: "skips demoted alternatives",
+			setup: func(s *endpointScorer) {
+				recordLatencies(s, 0, 2*time.Second)
+				recordLatencies(s, 1, 10*time.Millisecond)
+				recordLatencies(s, 2, 500*time.Millisecond)
+				s.recordHeight(0, 100)
+				s.recordHeight(1, 50)
+				s.recordHeight(2, 100)
+			},
+			current: 0,
+			want:    2,
+		},
+		{
+			name: "keeps current when everything is demoted",
+			setup: func(s *endpointScorer) {
+				recordFailures(s, 0, DefaultMinErrorSamples)
+				recordFailures(s, 1, DefaultMinErrorSamples)
+			},
+			current: 1,
+			want:    1,
+		},
+		{
+			name: "down endpoint moves to best alternative",
+			setup: func(s *endpointScorer) {
+				recordLatencies(s, 0, 10*time.Millisecond)
+				recordLatencies(s, 1, 500*time.Millisecond)
+				recordLatencies(s, 2, 100*time.Millisecond)
+			},
+			current: 0,
+			down:    true,
+			want:    2,
+		},
+		{
+			name:    "down endpoint rotates without scores",
+			setup:   func(*endpointScorer) {},
+			current: 2,
+			down:    true,
+			want:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newEndpointScorer(len(testAddresses), DefaultHealthConfig())
+			tt.setup(s)
+
+			assert.Equal(t, tt.want, s.selectEndpoint(testAddresses, tt.current, tt.down))
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	assert.Zero(t, percentile(nil, 0.5))
+
+	sorted := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	assert.Equal(t, time.Duration(5), percentile(sorted, 0.5))
+	assert.Equal(t, time.Duration(10), percentile(sorted, 0.99))
+	assert.Equal(t, time.Duration(1), percentile(sorted, 0))
+}
@@ -0,0 +1,305 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosaccount"
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
+	"github.com/margined-protocol/locust-core/pkg/fees"
+	"go.uber.org/zap"
+
+	sdkmath "cosmossdk.io/math"
+
+	"github.com/cosmos/cosmos-sdk/client/tx"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+
+	rpcclient "github.com/cometbft/cometbft/rpc/client"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+)
+
+// accountSequences returns the shared sequence manager of an account on the client's chain
+func accountSequences(cosmosClient *cosmosclient.Client, account cosmosaccount.Account) (*SequenceManager, error) {
+	address, err := account.Record.GetAddress()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account address: %w", err)
+	}
+
+	clientCtx := cosmosClient.Context()
+	fetch := func(ctx context.Context) (uint64, uint64, error) {
+		return authtypes.AccountRetriever{}.GetAccountNumberSequence(clientCtx.WithCmdContext(ctx), address)
+	}
+
+	return DefaultSequenceRegistry.Manager(clientCtx.ChainID, address.String(), fetch), nil
+}
+
+// broadcastWithSequence broadcasts the messages signed with the next sequence of the
+// account, returning the sequence to the manager when CheckTx rejected the tx. Inclusion is
+// awaited after the submission order is released, so other sends from the account can be
+// submitted while the tx waits for a block.
+func broadcastWithSequence(ctx context.Context, cosmosClient *cosmosclient.Client, account cosmosaccount.Account, sequences *SequenceManager, msgs ...sdk.Msg) (cosmosclient.Response, error) {
+	txResp, err := submitWithSequence(ctx, sequences, func(accountNumber, sequence uint64) (cosmosclient.Response, error) {
+		// The client builds txs from a copy of its factory, a zero sequence is fetched from chain
+		pinned := *cosmosClient
+		pinned.TxFactory = pinned.TxFactory.WithAccountNumber(accountNumber).WithSequence(sequence)
+
+		// The client waits for inclusion through its RPC once CheckTx passed
+		checkTx := &checkTxRPC{Client: cosmosClient.RPC}
+		pinned.RPC = checkTx
+
+		estimator, estimated := feeEstimator(cosmosClient)
+		bump, bumped := fees.BumpFromContext(ctx)
+
+		var res cosmosclient.Response
+		var err error
+		switch {
+		case estimated:
+			res, err = broadcastWithEstimatedFee(ctx, estimator, &pinned, account, msgs...)
+		case bumped && bump.Gas.GT(sdkmath.LegacyOneDec()):
+			res, err = broadcastWithBumpedGas(ctx, &pinned, account, bump, msgs...)
+		default:
+			res, err = pinned.BroadcastTx(ctx, account, msgs...)
+		}
+
+		if checkTx.txHash != nil {
+			return cosmosclient.Response{TxResponse: &sdk.TxResponse{TxHash: fmt.Sprintf("%X", checkTx.txHash)}}, nil
+		}
+		return res, err
+	})
+	if err != nil || txResp.TxResponse == nil || txResp.Code != 0 {
+		return txResp, err
+	}
+
+	// The tx consumed its sequence, whether it is included in time or not
+	res, err := cosmosClient.WaitForTx(ctx, txResp.TxHash)
+	if err != nil {
+		return cosmosclient.Response{}, fmt.Errorf("failed to wait for tx %s: %w", txResp.TxHash, err)
+	}
+
+	return cosmosclient.Response{
+		Codec:      cosmosClient.Context().Codec,
+		TxResponse: sdk.NewResponseResultTx(res, nil, ""),
+	}, nil
+}
+
+// errTxAccepted stops a client broadcast once CheckTx accepted the tx
+var errTxAccepted = errors.New("tx accepted by CheckTx")
+
+// checkTxRPC records the hash of the tx a client starts waiting for instead of waiting, as the
+// client only waits for txs that passed CheckTx
+type checkTxRPC struct {
+	rpcclient.Client
+	txHash []byte
+}
+
+func (c *checkTxRPC) Tx(_ context.Context, hash []byte, _ bool) (*coretypes.ResultTx, error) {
+	c.txHash = hash
+	return nil, errTxAccepted
+}
+
+// submitWithSequence submits a tx with the next sequence of the account, holding the
+// submission order until CheckTx accepts or rejects the tx so pipelines cannot interleave
+// with it, and returns the sequence to the manager when the tx was rejected or never reached
+// the node
+func submitWithSequence(ctx context.Context, sequences *SequenceManager, submit func(accountNumber, sequence uint64) (cosmosclient.Response, error)) (cosmosclient.Response, error) {
+	sequences.submitMu.Lock()
+	defer sequences.submitMu.Unlock()
+
+	accountNumber, sequence, err := sequences.Next(ctx)
+	if err != nil {
+		return cosmosclient.Response{}, err
+	}
+
+	txResp, err := submit(accountNumber, sequence)
+
+	if rawLog, mismatch := sequenceMismatch(txResp, err); mismatch {
+		sequences.Resync(sequence, rawLog)
+		return txResp, err
+	}
+
+	if err != nil || txResp.TxResponse == nil || txResp.Code != 0 {
+		sequences.Failed(sequence)
+	}
+
+	return txResp, err
+}
+
+// broadcastError classifies a failed broadcast, nil is returned when the tx succeeded
+func broadcastError(txResp cosmosclient.Response, err error) *TxError {
+	if txResp.TxResponse != nil && txResp.Code != 0 {
+		txErr := NewTxError(txResp.TxHash, txResp.Codespace, txResp.Code, txResp.RawLog)
+		txErr.Err = err
+		return txErr
+	}
+
+	if err != nil {
+		return &TxError{
+			Category: ClassifyTxError("", 0, err.Error()),
+			Log:      err.Error(),
+			Err:      err,
+		}
+	}
+
+	return nil
+}
+
+// sequenceMismatch reports whether a broadcast failed on the account sequence, returning
+// the log holding the expected sequence
+func sequenceMismatch(txResp cosmosclient.Response, err error) (string, bool) {
+	if err != nil {
+		if errors.Is(err, sdkerrors.ErrWrongSequence) || strings.Contains(err.Error(), "account sequence mismatch") {
+			return err.Error(), true
+		}
+		return "", false
+	}
+
+	if txResp.TxResponse != nil && txResp.Code == sdkerrors.ErrWrongSequence.ABCICode() {
+		return txResp.RawLog, true
+	}
+
+	return "", false
+}
+
+// BroadcastPipelined signs each group of messages as its own tx with consecutive sequences
+// and submits them to the mempool in order without waiting for inclusion, so several txs
+// can land in the same block. It returns the CheckTx responses of the accepted txs and
+// stops at the first rejected tx, as every later sequence would be rejected too.
+func BroadcastPipelined(ctx context.Context, l *zap.Logger, cosmosClient *cosmosclient.Client, account cosmosaccount.Account, txs ...[]sdk.Msg) ([]*sdk.TxResponse, error) {
+	sequences, err := accountSequences(cosmosClient, account)
+	if err != nil {
+		return nil, err
+	}
+
+	clientCtx := cosmosClient.Context()
+
+	return submitPipelined(ctx, l, sequences, len(txs), func(i int, accountNumber, sequence uint64) (*sdk.TxResponse, error) {
+		txBytes, err := signTx(ctx, cosmosClient, account, accountNumber, sequence, txs[i]...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign tx %d: %w", i, err)
+		}
+
+		res, err := clientCtx.BroadcastTxSync(txBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to broadcast tx %d: %w", i, err)
+		}
+		return res, nil
+	})
+}
+
+// submitPipelined submits count txs with consecutive sequences, holding the submission order
+// so no other sender can interleave its sequences, and stops at the first rejected tx
+func submitPipelined(ctx context.Context, l *zap.Logger, sequences *SequenceManager, count int, submit func(i int, accountNumber, sequence uint64) (*sdk.TxResponse, error)) ([]*sdk.TxResponse, error) {
+	sequences.submitMu.Lock()
+	defer sequences.submitMu.Unlock()
+
+	responses := make([]*sdk.TxResponse, 0, count)
+
+	for i := 0; i < count; i++ {
+		accountNumber, sequence, err := sequences.Next(ctx)
+		if err != nil {
+			return responses, err
+		}
+
+		res, err := submit(i, accountNumber, sequence)
+		if err != nil {
+			sequences.Failed(sequence)
+			return responses, err
+		}
+
+		if res.Code != 0 {
+			if res.Code == sdkerrors.ErrWrongSequence.ABCICode() {
+				sequences.Resync(sequence, res.RawLog)
+			} else {
+				sequences.Failed(sequence)
+			}
+			return responses, fmt.Errorf("tx %d rejected with code %d: %s", i, res.Code, res.RawLog)
+		}
+
+		l.Debug("Pipelined transaction accepted",
+			zap.String("transaction hash", res.TxHash),
+			zap.Uint64("sequence", sequence),
+		)
+
+		responses = append(responses, res)
+	}
+
+	return responses, nil
+}
+
+// BroadcastRecorded signs the messages as a single tx with the next sequence of the account and
+// passes its hash to record before submitting it to the mempool, without waiting for inclusion.
+// Nothing is submitted when record fails, so a caller persisting the hash can always find the
+// tx again, e.g. after a restart. Rejected txs are returned as a *TxError.
+func BroadcastRecorded(ctx context.Context, cosmosClient *cosmosclient.Client, account cosmosaccount.Account, record func(txHash string) error, msgs ...sdk.Msg) (*sdk.TxResponse, error) {
+	sequences, err := accountSequences(cosmosClient, account)
+	if err != nil {
+		return nil, err
+	}
+
+	clientCtx := cosmosClient.Context()
+
+	txResp, err := submitWithSequence(ctx, sequences, func(accountNumber, sequence uint64) (cosmosclient.Response, error) {
+		txBytes, err := signTx(ctx, cosmosClient, account, accountNumber, sequence, msgs...)
+		if err != nil {
+			return cosmosclient.Response{}, err
+		}
+
+		if err := record(fmt.Sprintf("%X", cmttypes.Tx(txBytes).Hash())); err != nil {
+			return cosmosclient.Response{}, fmt.Errorf("failed to record tx: %w", err)
+		}
+
+		res, err := clientCtx.BroadcastTxSync(txBytes)
+		if err != nil {
+			return cosmosclient.Response{}, fmt.Errorf("failed to broadcast tx: %w", err)
+		}
+		return cosmosclient.Response{TxResponse: res}, nil
+	})
+
+	if txErr := broadcastError(txResp, err); txErr != nil {
+		return nil, txErr
+	}
+
+	return txResp.TxResponse, nil
+}
+
+// signTx simulates, signs and encodes a tx with the given account number and sequence
+func signTx(ctx context.Context, cosmosClient *cosmosclient.Client, account cosmosaccount.Account, accountNumber, sequence uint64, msgs ...sdk.Msg) ([]byte, error) {
+	clientCtx := cosmosClient.Context()
+	txf := cosmosClient.TxFactory.
+		WithAccountNumber(accountNumber).
+		WithSequence(sequence)
+
+	// Gas is simulated against the mempool state, which includes the earlier pipelined txs
+	if estimator, ok := feeEstimator(cosmosClient); ok {
+		pinned := *cosmosClient
+		pinned.TxFactory = txf
+
+		fee, err := estimator.Estimate(ctx, gasSimulator(&pinned, msgs...))
+		if err != nil {
+			return nil, fmt.Errorf("failed to estimate fee: %w", err)
+		}
+		txf = txf.WithGas(fee.GasLimit).WithFees(fee.String())
+	} else {
+		_, gas, err := tx.CalculateGas(clientCtx, txf, msgs...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to simulate tx: %w", err)
+		}
+		txf = txf.WithGas(gas)
+	}
+
+	txBuilder, err := txf.BuildUnsignedTx(msgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build tx: %w", err)
+	}
+
+	if err := tx.Sign(ctx, txf, account.Name, txBuilder, true); err != nil {
+		return nil, fmt.Errorf("failed to sign tx: %w", err)
+	}
+
+	return clientCtx.TxConfig.TxEncoder()(txBuilder.GetTx())
+}
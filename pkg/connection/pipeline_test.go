@@ -0,0 +1,158 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+)
+
+// fakeMempool checks the sequence of submitted txs like CheckTx, rejecting every rejectEvery
+// tx that has the right sequence with an insufficient fee
+type fakeMempool struct {
+	mu          sync.Mutex
+	expected    uint64
+	submitted   int
+	rejectEvery int
+	accepted    []uint64
+	mismatches  int
+}
+
+func (p *fakeMempool) checkTx(sequence uint64) *sdk.TxResponse {
+	// Signing and sending leave other senders time to take sequences
+	time.Sleep(10 * time.Microsecond)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if sequence != p.expected {
+		p.mismatches++
+		return &sdk.TxResponse{
+			Code:   sdkerrors.ErrWrongSequence.ABCICode(),
+			RawLog: fmt.Sprintf("account sequence mismatch, expected %d, got %d: incorrect account sequence", p.expected, sequence),
+		}
+	}
+
+	p.submitted++
+	if p.rejectEvery > 0 && p.submitted%p.rejectEvery == 0 {
+		return &sdk.TxResponse{Code: sdkerrors.ErrInsufficientFee.ABCICode(), RawLog: "insufficient fees"}
+	}
+
+	p.expected++
+	p.accepted = append(p.accepted, sequence)
+	return &sdk.TxResponse{TxHash: fmt.Sprintf("%d", sequence)}
+}
+
+func TestSubmitWithSequence(t *testing.T) {
+	mempool := &fakeMempool{expected: 4}
+	chain := &fakeSequenceFetcher{sequence: 4}
+	sequences := NewSequenceManager("test-1", "addr", chain.fetch)
+
+	// Txs accepted by CheckTx keep their sequence before they are included
+	submit := func(_, sequence uint64) (cosmosclient.Response, error) {
+		return cosmosclient.Response{TxResponse: mempool.checkTx(sequence)}, nil
+	}
+
+	res, err := submitWithSequence(context.Background(), sequences, submit)
+	require.NoError(t, err)
+	assert.Equal(t, "4", res.TxHash)
+
+	// A tx rejected before the mempool hands its sequence out again
+	_, err = submitWithSequence(context.Background(), sequences, func(uint64, uint64) (cosmosclient.Response, error) {
+		return cosmosclient.Response{}, errors.New("failed to simulate tx")
+	})
+	require.Error(t, err)
+
+	res, err = submitWithSequence(context.Background(), sequences, submit)
+	require.NoError(t, err)
+	assert.Equal(t, "5", res.TxHash)
+
+	// A tx rejected by CheckTx hands its sequence out again
+	mempool.rejectEvery = 1
+	res, err = submitWithSequence(context.Background(), sequences, submit)
+	require.NoError(t, err)
+	assert.Equal(t, sdkerrors.ErrInsufficientFee.ABCICode(), res.Code)
+	mempool.rejectEvery = 0
+
+	res, err = submitWithSequence(context.Background(), sequences, submit)
+	require.NoError(t, err)
+	assert.Equal(t, "6", res.TxHash)
+
+	// Another sender using the account moves the chain ahead
+	mempool.expected = 9
+	res, err = submitWithSequence(context.Background(), sequences, submit)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(32), res.Code)
+
+	res, err = submitWithSequence(context.Background(), sequences, submit)
+	require.NoError(t, err)
+	assert.Equal(t, "9", res.TxHash)
+	assert.Equal(t, 1, chain.fetches)
+}
+
+func TestCheckTxRPC(t *testing.T) {
+	checkTx := &checkTxRPC{}
+	client := cosmosclient.Client{RPC: checkTx}
+
+	// The client returns instead of waiting for the tx to be included
+	_, err := client.WaitForTx(context.Background(), "ABCD")
+	require.ErrorIs(t, err, errTxAccepted)
+	assert.Equal(t, []byte{0xab, 0xcd}, checkTx.txHash)
+}
+
+func TestSubmitConcurrentRetryAndPipeline(t *testing.T) {
+	mempool := &fakeMempool{expected: 100, rejectEvery: 7}
+	chain := &fakeSequenceFetcher{sequence: 100}
+	sequences := NewSequenceManager("test-1", "addr", chain.fetch)
+
+	retrySubmit := func(_, sequence uint64) (cosmosclient.Response, error) {
+		return cosmosclient.Response{TxResponse: mempool.checkTx(sequence)}, nil
+	}
+
+	pipelineSubmit := func(_ int, _, sequence uint64) (*sdk.TxResponse, error) {
+		return mempool.checkTx(sequence), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				_, _ = submitWithSequence(context.Background(), sequences, retrySubmit)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 3; j++ {
+				_, _ = submitPipelined(context.Background(), zap.NewNop(), sequences, 4, pipelineSubmit)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Rejected sequences are handed out again and no sender ever raced another on a sequence
+	assert.Zero(t, mempool.mismatches)
+	assert.Equal(t, 1, chain.fetches)
+
+	accepted := append([]uint64{}, mempool.accepted...)
+	sort.Slice(accepted, func(i, j int) bool { return accepted[i] < accepted[j] })
+	for i, sequence := range accepted {
+		assert.Equal(t, uint64(100+i), sequence)
+	}
+
+	_, next, err := sequences.Next(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, mempool.expected, next)
+}
@@ -0,0 +1,461 @@
+package connection
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	proto "github.com/cosmos/gogoproto/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+)
+
+// ReadMode selects how a MultiEndpointReader fans a query out across endpoints
+type ReadMode int
+
+const (
+	// ReadModeHedged returns the first successful response
+	ReadModeHedged ReadMode = iota
+	// ReadModeQuorum requires identical responses at the same height from a quorum of endpoints
+	ReadModeQuorum
+)
+
+func (m ReadMode) String() string {
+	switch m {
+	case ReadModeHedged:
+		return "hedged"
+	case ReadModeQuorum:
+		return "quorum"
+	default:
+		return fmt.Sprintf("ReadMode(%d)", int(m))
+	}
+}
+
+// ErrNoQuorum is returned when not enough endpoints agree on a response
+var ErrNoQuorum = errors.New("no quorum")
+
+// ReadMetrics counts the outcome of the queries sent through a MultiEndpointReader
+type ReadMetrics struct {
+	Queries  uint64
+	Failures uint64
+	// HeightMismatches counts quorum queries retried at a pinned height because the
+	// endpoints answered at different heights
+	HeightMismatches uint64
+	// Disagreements counts quorum queries where endpoints returned different responses at
+	// the same height
+	Disagreements uint64
+	// EndpointDisagreements counts, per endpoint address, the responses that differed from
+	// the quorum response
+	EndpointDisagreements map[string]uint64
+}
+
+// readResult is the response of a single endpoint to a query
+type readResult struct {
+	index  int
+	height int64
+	data   []byte
+	err    error
+}
+
+// MultiEndpointReader sends queries to several endpoints of a MultiEndpointGRPCClient at
+// once, guarding price-sensitive reads against a single slow or stale node. Streams are not
+// fanned out and go to the current endpoint.
+type MultiEndpointReader struct {
+	client     *MultiEndpointGRPCClient
+	logger     *zap.Logger
+	mode       ReadMode
+	fanout     int
+	quorum     int
+	hedgeDelay time.Duration
+
+	mu      sync.Mutex
+	metrics ReadMetrics
+}
+
+var _ grpc.ClientConnInterface = (*MultiEndpointReader)(nil)
+
+// NewHedgedReader creates a reader that sends a query to the best endpoint and, every
+// hedge delay without a response, to the next best up to fanout endpoints, returning the
+// first successful response. A zero delay queries all of them at once.
+func NewHedgedReader(client *MultiEndpointGRPCClient, fanout int, hedgeDelay time.Duration) (*MultiEndpointReader, error) {
+	if fanout < 1 || fanout > len(client.endpoints) {
+		return nil, fmt.Errorf("fanout must be between 1 and %d", len(client.endpoints))
+	}
+
+	return newMultiEndpointReader(client, ReadModeHedged, fanout, 1, hedgeDelay), nil
+}
+
+// NewQuorumReader creates a reader that sends a query to the best fanout endpoints and
+// requires quorum of them to return identical responses at the same height
+func NewQuorumReader(client *MultiEndpointGRPCClient, quorum, fanout int) (*MultiEndpointReader, error) {
+	if fanout < 1 || fanout > len(client.endpoints) {
+		return nil, fmt.Errorf("fanout must be between 1 and %d", len(client.endpoints))
+	}
+
+	if quorum < 1 || quorum > fanout {
+		return nil, fmt.Errorf("quorum must be between 1 and %d", fanout)
+	}
+
+	return newMultiEndpointReader(client, ReadModeQuorum, fanout, quorum, 0), nil
+}
+
+func newMultiEndpointReader(client *MultiEndpointGRPCClient, mode ReadMode, fanout, quorum int, hedgeDelay time.Duration) *MultiEndpointReader {
+	return &MultiEndpointReader{
+		client:     client,
+		logger:     client.logger,
+		mode:       mode,
+		fanout:     fanout,
+		quorum:     quorum,
+		hedgeDelay: hedgeDelay,
+		metrics: ReadMetrics{
+			EndpointDisagreements: make(map[string]uint64),
+		},
+	}
+}
+
+// Invoke implements grpc.ClientConnInterface, call options are applied to every endpoint queried
+func (r *MultiEndpointReader) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	if _, ok := reply.(proto.Message); !ok {
+		return fmt.Errorf("failed to assert proto.Message")
+	}
+
+	var err error
+	switch r.mode {
+	case ReadModeQuorum:
+		err = r.invokeQuorum(ctx, method, args, reply, opts)
+	default:
+		err = r.invokeHedged(ctx, method, args, reply, opts)
+	}
+
+	r.mu.Lock()
+	r.metrics.Queries++
+	if err != nil {
+		r.metrics.Failures++
+	}
+	r.mu.Unlock()
+
+	return err
+}
+
+// NewStream implements grpc.ClientConnInterface using the current endpoint
+func (r *MultiEndpointReader) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	conn := r.client.GetClient()
+	if conn == nil {
+		return nil, fmt.Errorf("no gRPC endpoint connected")
+	}
+
+	return conn.NewStream(ctx, desc, method, opts...)
+}
+
+// Metrics returns a snapshot of the reader's metrics
+func (r *MultiEndpointReader) Metrics() ReadMetrics {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	metrics := r.metrics
+	metrics.EndpointDisagreements = make(map[string]uint64, len(r.metrics.EndpointDisagreements))
+	for address, count := range r.metrics.EndpointDisagreements {
+		metrics.EndpointDisagreements[address] = count
+	}
+
+	return metrics
+}
+
+// invokeHedged returns the first successful response, launching the next endpoint each
+// time the hedge delay passes or a query fails
+func (r *MultiEndpointReader) invokeHedged(ctx context.Context, method string, args, reply interface{}, opts []grpc.CallOption) error {
+	endpoints := r.selectEndpoints()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan readResult, len(endpoints))
+	next, pending := 0, 0
+	launch := func() {
+		index := endpoints[next]
+		next++
+		pending++
+		go func() {
+			results <- r.invokeEndpoint(ctx, index, method, args, reply, opts)
+		}()
+	}
+
+	var errs []error
+	for pending > 0 || next < len(endpoints) {
+		if pending == 0 {
+			launch()
+		}
+
+		var hedge <-chan time.Time
+		if next < len(endpoints) {
+			hedge = time.After(r.hedgeDelay)
+		}
+
+		select {
+		case result := <-results:
+			pending--
+			if result.err == nil {
+				return proto.Unmarshal(result.data, reply.(proto.Message))
+			}
+			errs = append(errs, result.err)
+		case <-hedge:
+			launch()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return fmt.Errorf("all %d endpoints failed: %w", len(endpoints), errors.Join(errs...))
+}
+
+// invokeQuorum returns the response a quorum of endpoints agree on. When the endpoints
+// answered at different heights the query is repeated pinned to the lowest of them.
+func (r *MultiEndpointReader) invokeQuorum(ctx context.Context, method string, args, reply interface{}, opts []grpc.CallOption) error {
+	endpoints := r.selectEndpoints()
+
+	winner, results := r.collectQuorum(ctx, endpoints, method, args, reply, opts)
+	if winner == nil && !hasPinnedHeight(ctx) {
+		if height, mismatched := lowestHeight(results); mismatched {
+			r.mu.Lock()
+			r.metrics.HeightMismatches++
+			r.mu.Unlock()
+
+			r.logger.Debug("Endpoints answered at different heights, retrying at a pinned height",
+				zap.String("method", method),
+				zap.Int64("height", height))
+
+			pinnedCtx := metadata.AppendToOutgoingContext(ctx, grpctypes.GRPCBlockHeightHeader, strconv.FormatInt(height, 10))
+			winner, results = r.collectQuorum(pinnedCtx, endpoints, method, args, reply, opts)
+		}
+	}
+
+	r.recordDisagreements(method, winner, results)
+
+	if winner == nil {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var errs []error
+		for _, result := range results {
+			if result.err != nil {
+				errs = append(errs, result.err)
+			}
+		}
+
+		return fmt.Errorf("%w: %d of %d endpoints required to agree on %s: %w", ErrNoQuorum, r.quorum, len(endpoints), method, errors.Join(errs...))
+	}
+
+	return proto.Unmarshal(winner.data, reply.(proto.Message))
+}
+
+// collectQuorum queries the endpoints in parallel until a quorum agrees or can no longer be reached
+func (r *MultiEndpointReader) collectQuorum(ctx context.Context, endpoints []int, method string, args, reply interface{}, opts []grpc.CallOption) (*readResult, []readResult) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ch := make(chan readResult, len(endpoints))
+	for _, index := range endpoints {
+		go func(index int) {
+			ch <- r.invokeEndpoint(ctx, index, method, args, reply, opts)
+		}(index)
+	}
+
+	results := make([]readResult, 0, len(endpoints))
+	for range endpoints {
+		select {
+		case result := <-ch:
+			results = append(results, result)
+		case <-ctx.Done():
+			return nil, results
+		}
+
+		winner, reachable := tallyResults(results, r.quorum, len(endpoints))
+		if winner != nil || !reachable {
+			return winner, results
+		}
+	}
+
+	return nil, results
+}
+
+// invokeEndpoint sends a query to a single endpoint, returning the encoded response and
+// the height it was served at
+func (r *MultiEndpointReader) invokeEndpoint(ctx context.Context, index int, method string, args, reply interface{}, opts []grpc.CallOption) readResult {
+	address := r.client.endpoints[index].Address
+
+	conn, err := r.client.connection(index)
+	if err != nil {
+		return readResult{index: index, err: err}
+	}
+
+	// Each endpoint decodes into its own message so responses can be compared
+	response := reflect.New(reflect.TypeOf(reply).Elem()).Interface()
+
+	var header metadata.MD
+	callOpts := append(append([]grpc.CallOption{}, opts...), grpc.Header(&header))
+	if err := conn.Invoke(ctx, method, args, response, callOpts...); err != nil {
+		return readResult{index: index, err: fmt.Errorf("%s: %w", address, err)}
+	}
+
+	data, err := proto.Marshal(response.(proto.Message))
+	if err != nil {
+		return readResult{index: index, err: fmt.Errorf("%s: failed to encode response: %w", address, err)}
+	}
+
+	return readResult{
+		index:  index,
+		height: responseHeight(header),
+		data:   data,
+	}
+}
+
+// selectEndpoints returns the fanout best endpoints, demoted endpoints are only used when
+// there are not enough healthy ones
+func (r *MultiEndpointReader) selectEndpoints() []int {
+	stats := r.client.GetEndpointStats()
+
+	endpoints := make([]int, len(stats))
+	for i := range stats {
+		endpoints[i] = i
+	}
+
+	sort.SliceStable(endpoints, func(a, b int) bool {
+		left, right := stats[endpoints[a]], stats[endpoints[b]]
+		if left.Demoted != right.Demoted {
+			return !left.Demoted
+		}
+		if left.Score != right.Score {
+			return left.Score > right.Score
+		}
+		// The current endpoint first among equals, e.g. before any scores are known
+		return left.Current && !right.Current
+	})
+
+	return endpoints[:r.fanout]
+}
+
+// recordDisagreements counts successful responses that differ from others at the same height
+func (r *MultiEndpointReader) recordDisagreements(method string, winner *readResult, results []readResult) {
+	disagreed := false
+	var dissenters []int
+
+	for i, result := range results {
+		if result.err != nil {
+			continue
+		}
+
+		if winner != nil {
+			if result.height == winner.height && !bytes.Equal(result.data, winner.data) {
+				disagreed = true
+				dissenters = append(dissenters, result.index)
+			}
+			continue
+		}
+
+		for _, other := range results[i+1:] {
+			if other.err == nil && other.height == result.height && !bytes.Equal(other.data, result.data) {
+				disagreed = true
+			}
+		}
+	}
+
+	if !disagreed {
+		return
+	}
+
+	r.mu.Lock()
+	r.metrics.Disagreements++
+	for _, index := range dissenters {
+		r.metrics.EndpointDisagreements[r.client.endpoints[index].Address]++
+	}
+	r.mu.Unlock()
+
+	fields := []zap.Field{zap.String("method", method)}
+	if winner != nil {
+		fields = append(fields, zap.Int64("height", winner.height))
+	}
+	for _, index := range dissenters {
+		fields = append(fields, zap.String("endpoint", r.client.endpoints[index].Address))
+	}
+	r.logger.Warn("gRPC endpoints disagree on query response", fields...)
+}
+
+// tallyResults returns the response that at least quorum results agree on, and whether a
+// quorum can still be reached once the outstanding results of total arrive
+func tallyResults(results []readResult, quorum, total int) (*readResult, bool) {
+	largest := 0
+	for i, result := range results {
+		if result.err != nil {
+			continue
+		}
+
+		votes := 0
+		for _, other := range results {
+			if other.err == nil && other.height == result.height && bytes.Equal(other.data, result.data) {
+				votes++
+			}
+		}
+
+		if votes >= quorum {
+			return &results[i], true
+		}
+		if votes > largest {
+			largest = votes
+		}
+	}
+
+	return nil, largest+total-len(results) >= quorum
+}
+
+// lowestHeight returns the lowest height successful results were served at, and whether
+// they were served at more than one height
+func lowestHeight(results []readResult) (int64, bool) {
+	var lowest int64
+	mismatched := false
+
+	for _, result := range results {
+		if result.err != nil || result.height == 0 {
+			continue
+		}
+
+		if lowest != 0 && result.height != lowest {
+			mismatched = true
+		}
+		if lowest == 0 || result.height < lowest {
+			lowest = result.height
+		}
+	}
+
+	return lowest, mismatched
+}
+
+// responseHeight returns the block height a response was served at, zero if not reported
+func responseHeight(header metadata.MD) int64 {
+	values := header.Get(grpctypes.GRPCBlockHeightHeader)
+	if len(values) == 0 {
+		return 0
+	}
+
+	height, err := strconv.ParseInt(values[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return height
+}
+
+// hasPinnedHeight reports whether the caller already asked for a specific height
+func hasPinnedHeight(ctx context.Context) bool {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	return ok && len(md.Get(grpctypes.GRPCBlockHeightHeader)) > 0
+}
@@ -0,0 +1,185 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+)
+
+func TestTallyResults(t *testing.T) {
+	ok := func(index int, height int64, data string) readResult {
+		return readResult{index: index, height: height, data: []byte(data)}
+	}
+	failed := readResult{err: errors.New("unavailable")}
+
+	tests := []struct {
+		name          string
+		results       []readResult
+		quorum        int
+		total         int
+		wantIndex     int
+		wantReachable bool
+	}{
+		{
+			name:          "quorum reached",
+			results:       []readResult{ok(0, 10, "a"), ok(1, 10, "a")},
+			quorum:        2,
+			total:         3,
+			wantIndex:     0,
+			wantReachable: true,
+		},
+		{
+			name:          "same bytes at different heights do not agree",
+			results:       []readResult{ok(0, 10, "a"), ok(1, 11, "a")},
+			quorum:        2,
+			total:         3,
+			wantIndex:     -1,
+			wantReachable: true,
+		},
+		{
+			name:          "disagreement makes quorum unreachable",
+			results:       []readResult{ok(0, 10, "a"), ok(1, 10, "b"), ok(2, 10, "c")},
+			quorum:        2,
+			total:         3,
+			wantIndex:     -1,
+			wantReachable: false,
+		},
+		{
+			name:          "failures make quorum unreachable",
+			results:       []readResult{failed, failed},
+			quorum:        2,
+			total:         3,
+			wantIndex:     -1,
+			wantReachable: false,
+		},
+		{
+			name:          "majority wins over minority",
+			results:       []readResult{ok(0, 10, "b"), ok(1, 10, "a"), failed, ok(3, 10, "a")},
+			quorum:        2,
+			total:         4,
+			wantIndex:     1,
+			wantReachable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			winner, reachable := tallyResults(tt.results, tt.quorum, tt.total)
+			assert.Equal(t, tt.wantReachable, reachable)
+
+			if tt.wantIndex < 0 {
+				assert.Nil(t, winner)
+				return
+			}
+
+			require.NotNil(t, winner)
+			assert.Equal(t, tt.wantIndex, winner.index)
+		})
+	}
+}
+
+func TestLowestHeight(t *testing.T) {
+	height, mismatched := lowestHeight([]readResult{{height: 12}, {height: 10}, {err: errors.New("down")}})
+	assert.Equal(t, int64(10), height)
+	assert.True(t, mismatched)
+
+	height, mismatched = lowestHeight([]readResult{{height: 10}, {height: 10}, {height: 0}})
+	assert.Equal(t, int64(10), height)
+	assert.False(t, mismatched)
+}
+
+func TestResponseHeight(t *testing.T) {
+	assert.Equal(t, int64(42), responseHeight(metadata.Pairs(grpctypes.GRPCBlockHeightHeader, "42")))
+	assert.Zero(t, responseHeight(metadata.Pairs(grpctypes.GRPCBlockHeightHeader, "abc")))
+	assert.Zero(t, responseHeight(nil))
+
+	assert.False(t, hasPinnedHeight(context.Background()))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), grpctypes.GRPCBlockHeightHeader, "42")
+	assert.True(t, hasPinnedHeight(ctx))
+}
+
+// newTestGRPCClient returns a client with scored endpoints that is never dialed
+func newTestGRPCClient(addresses ...string) *MultiEndpointGRPCClient {
+	endpoints := make([]GRPCEndpointConfig, len(addresses))
+	for i, address := range addresses {
+		endpoints[i] = GRPCEndpointConfig{Address: address}
+	}
+
+	return &MultiEndpointGRPCClient{
+		logger:    zap.NewNop(),
+		endpoints: endpoints,
+		health:    newEndpointScorer(len(endpoints), DefaultHealthConfig()),
+	}
+}
+
+func TestNewQuorumReader(t *testing.T) {
+	client := newTestGRPCClient("a", "b", "c")
+
+	_, err := NewQuorumReader(client, 2, 3)
+	require.NoError(t, err)
+
+	_, err = NewQuorumReader(client, 3, 2)
+	assert.Error(t, err)
+
+	_, err = NewQuorumReader(client, 2, 4)
+	assert.Error(t, err)
+
+	_, err = NewHedgedReader(client, 0, time.Millisecond)
+	assert.Error(t, err)
+}
+
+func TestReaderSelectEndpoints(t *testing.T) {
+	client := newTestGRPCClient("a", "b", "c", "d")
+	client.currentIndex = 3
+
+	reader, err := NewHedgedReader(client, 3, 0)
+	require.NoError(t, err)
+
+	// Without scores the current endpoint goes first
+	assert.Equal(t, []int{3, 0, 1}, reader.selectEndpoints())
+
+	recordLatencies(client.health, 0, 400*time.Millisecond)
+	recordLatencies(client.health, 1, 10*time.Millisecond)
+	recordLatencies(client.health, 2, 100*time.Millisecond)
+	client.health.recordQuery(3, time.Second, errors.New("unavailable"))
+
+	assert.Equal(t, []int{1, 2, 0}, reader.selectEndpoints())
+}
+
+func TestReaderRecordDisagreements(t *testing.T) {
+	client := newTestGRPCClient("a", "b", "c")
+	reader, err := NewQuorumReader(client, 2, 3)
+	require.NoError(t, err)
+
+	results := []readResult{
+		{index: 0, height: 10, data: []byte("a")},
+		{index: 1, height: 10, data: []byte("b")},
+		{index: 2, height: 10, data: []byte("a")},
+	}
+
+	winner, _ := tallyResults(results, 2, 3)
+	require.NotNil(t, winner)
+	reader.recordDisagreements("/query", winner, results)
+
+	// Without a winner the disagreement is counted but not attributed
+	reader.recordDisagreements("/query", nil, results[:2])
+
+	// Responses at other heights are not disagreements
+	reader.recordDisagreements("/query", nil, []readResult{{height: 10, data: []byte("a")}, {height: 11, data: []byte("b")}})
+
+	metrics := reader.Metrics()
+	assert.Equal(t, uint64(2), metrics.Disagreements)
+	assert.Equal(t, map[string]uint64{"b": 1}, metrics.EndpointDisagreements)
+
+	// The snapshot is a copy
+	metrics.EndpointDisagreements["b"] = 5
+	assert.Equal(t, uint64(1), reader.Metrics().EndpointDisagreements["b"])
+}
@@ -2,6 +2,7 @@ package connection
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -27,7 +28,8 @@ type RPCEndpointConfig struct {
 	APIKey        string
 }
 
-// MultiEndpointRPCClient manages multiple RPC endpoints with automatic failover
+// MultiEndpointRPCClient manages multiple RPC endpoints with automatic failover, traffic
+// goes to the endpoint with the best height, latency and error rate
 type MultiEndpointRPCClient struct {
 	ctx               context.Context
 	cancel            context.CancelFunc
@@ -42,6 +44,10 @@ type MultiEndpointRPCClient struct {
 	currentIndex        int
 	healthCheckInterval time.Duration
 	connectionTimeout   time.Duration
+
+	// Plain HTTP clients used to score every endpoint without holding a websocket open
+	statusClients []*rpchttp.HTTP
+	health        *endpointScorer
 }
 
 // NewMultiEndpointRPCClient creates a new client that manages multiple RPC endpoints
@@ -64,6 +70,8 @@ func NewMultiEndpointRPCClient(
 		currentIndex:        0,
 		healthCheckInterval: DefaultRPCHealthCheckInterval,
 		connectionTimeout:   DefaultRPCConnectionTimeout,
+		statusClients:       make([]*rpchttp.HTTP, len(endpoints)),
+		health:              newEndpointScorer(len(endpoints), DefaultHealthConfig()),
 	}
 
 	// Try to establish initial connection
@@ -98,7 +106,10 @@ func NewMultiEndpointRPCClientWithAddresses(
 
 // connectToEndpoint tries to connect to the current endpoint
 func (c *MultiEndpointRPCClient) connectToEndpoint() error {
+	c.mu.RLock()
 	endpoint := c.endpoints[c.currentIndex]
+	c.mu.RUnlock()
+
 	c.logger.Debug("Connecting to RPC endpoint",
 		zap.String("address", endpoint.Address),
 		zap.String("websocket_path", endpoint.WebsocketPath))
@@ -125,6 +136,9 @@ func (c *MultiEndpointRPCClient) startHealthCheck() {
 	c.healthCheckTicker = time.NewTicker(c.healthCheckInterval)
 
 	go func() {
+		// Score the endpoints straight away rather than after the first interval
+		c.checkConnection()
+
 		for {
 			select {
 			case <-c.healthCheckTicker.C:
@@ -139,10 +153,12 @@ func (c *MultiEndpointRPCClient) startHealthCheck() {
 	}()
 }
 
-// checkConnection verifies that the connection is healthy and rotates to next endpoint if needed
+// checkConnection probes every endpoint and moves traffic to the best one, the current
+// endpoint is abandoned immediately when its health check fails
 func (c *MultiEndpointRPCClient) checkConnection() {
 	c.mu.RLock()
 	client := c.currentClient
+	current := c.currentIndex
 	c.mu.RUnlock()
 
 	if client == nil {
@@ -151,25 +167,116 @@ func (c *MultiEndpointRPCClient) checkConnection() {
 		return
 	}
 
+	errs := c.probeEndpoints()
+
+	down := errs[current] != nil
+	if down {
+		c.logger.Warn("RPC connection health check failed, rotating endpoint",
+			zap.Error(errs[current]),
+			zap.String("current_endpoint", c.endpoints[current].Address))
+	}
+
+	next := c.health.selectEndpoint(c.addresses(), current, down)
+	if next == current {
+		return
+	}
+
+	if !down {
+		c.logger.Warn("RPC endpoint demoted, switching to healthier endpoint",
+			zap.String("current_endpoint", c.endpoints[current].Address),
+			zap.String("endpoint", c.endpoints[next].Address))
+	}
+
+	c.switchEndpoint(next)
+}
+
+// probeEndpoints queries the status of every endpoint in parallel
+func (c *MultiEndpointRPCClient) probeEndpoints() []error {
+	errs := make([]error, len(c.endpoints))
+
+	var wg sync.WaitGroup
+	for i := range c.endpoints {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			errs[index] = c.probeEndpoint(index)
+		}(i)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// probeEndpoint records the latency of a status query and the latest block height of an endpoint
+func (c *MultiEndpointRPCClient) probeEndpoint(index int) error {
+	client, err := c.statusClient(index)
+	if err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	timeout := c.connectionTimeout
+	c.mu.RUnlock()
+
 	// Create context with timeout for health check
-	ctx, cancel := context.WithTimeout(c.ctx, c.connectionTimeout)
+	ctx, cancel := context.WithTimeout(c.ctx, timeout)
 	defer cancel()
 
-	// Check if the connection is still alive by making a Status request
-	_, err := client.Status(ctx)
+	_, err = c.queryHeight(ctx, index, client)
+	return err
+}
+
+// queryHeight queries the latest block height of an endpoint, recording its latency and outcome
+func (c *MultiEndpointRPCClient) queryHeight(ctx context.Context, index int, client *rpchttp.HTTP) (int64, error) {
+	start := time.Now()
+	status, err := client.Status(ctx)
+
+	// Cancelled by the caller, says nothing about the endpoint
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return 0, ctx.Err()
+	}
+
+	c.health.recordQuery(index, time.Since(start), err)
 	if err != nil {
-		c.logger.Warn("RPC connection health check failed, rotating endpoint",
-			zap.Error(err),
-			zap.String("current_endpoint", c.endpoints[c.currentIndex].Address))
-		c.rotateEndpoint()
+		return 0, fmt.Errorf("failed to query status from %s: %w", c.endpoints[index].Address, err)
+	}
+
+	c.health.recordHeight(index, status.SyncInfo.LatestBlockHeight)
+	return status.SyncInfo.LatestBlockHeight, nil
+}
+
+// statusClient returns the client used to probe an endpoint, creating it on first use
+func (c *MultiEndpointRPCClient) statusClient(index int) (*rpchttp.HTTP, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.statusClients[index] != nil {
+		return c.statusClients[index], nil
+	}
+
+	endpoint := c.endpoints[index]
+	client, err := newRPCHTTPClient(endpoint.Address, endpoint.WebsocketPath, endpoint.APIKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create RPC client for %s: %w", endpoint.Address, err)
 	}
+
+	c.statusClients[index] = client
+	return client, nil
 }
 
 // rotateEndpoint switches to the next endpoint in the list and tries to connect
 func (c *MultiEndpointRPCClient) rotateEndpoint() {
+	c.mu.RLock()
+	next := (c.currentIndex + 1) % len(c.endpoints)
+	c.mu.RUnlock()
+
+	c.switchEndpoint(next)
+}
+
+// switchEndpoint moves traffic to the endpoint at index and tries to connect
+func (c *MultiEndpointRPCClient) switchEndpoint(index int) {
 	c.mu.Lock()
-	// Move to the next endpoint
-	c.currentIndex = (c.currentIndex + 1) % len(c.endpoints)
+	c.currentIndex = index
 	c.mu.Unlock()
 
 	// Try to connect with the new endpoint
@@ -177,10 +284,19 @@ func (c *MultiEndpointRPCClient) rotateEndpoint() {
 	if err != nil {
 		c.logger.Error("Failed to connect to rotated RPC endpoint",
 			zap.Error(err),
-			zap.String("endpoint", c.endpoints[c.currentIndex].Address))
+			zap.String("endpoint", c.endpoints[index].Address))
 	}
 }
 
+// addresses returns the endpoint addresses in configuration order
+func (c *MultiEndpointRPCClient) addresses() []string {
+	addresses := make([]string, len(c.endpoints))
+	for i, endpoint := range c.endpoints {
+		addresses[i] = endpoint.Address
+	}
+	return addresses
+}
+
 // GetClient returns the current RPC client
 func (c *MultiEndpointRPCClient) GetClient() *rpchttp.HTTP {
 	c.mu.RLock()
@@ -195,6 +311,21 @@ func (c *MultiEndpointRPCClient) GetCometClient() cometbft.CometRPC {
 	return c.cometClient
 }
 
+// GetHeight returns the latest block height of the current endpoint, the query counts
+// towards the endpoint's health score
+func (c *MultiEndpointRPCClient) GetHeight(ctx context.Context) (int64, error) {
+	c.mu.RLock()
+	client := c.currentClient
+	index := c.currentIndex
+	c.mu.RUnlock()
+
+	if client == nil {
+		return 0, fmt.Errorf("no RPC endpoint connected")
+	}
+
+	return c.queryHeight(ctx, index, client)
+}
+
 // ForceRotate immediately rotates to the next endpoint
 func (c *MultiEndpointRPCClient) ForceRotate() error {
 	c.rotateEndpoint()
@@ -229,6 +360,20 @@ func (c *MultiEndpointRPCClient) GetCurrentEndpoint() (endpoint RPCEndpointConfi
 	return c.endpoints[c.currentIndex], c.currentIndex
 }
 
+// SetHealthConfig changes how endpoints are scored and when they are demoted
+func (c *MultiEndpointRPCClient) SetHealthConfig(config HealthConfig) {
+	c.health.setConfig(config)
+}
+
+// GetEndpointStats returns the health score of every endpoint in configuration order
+func (c *MultiEndpointRPCClient) GetEndpointStats() []EndpointStats {
+	c.mu.RLock()
+	current := c.currentIndex
+	c.mu.RUnlock()
+
+	return c.health.stats(c.addresses(), current)
+}
+
 // Close shuts down the client and all connections
 func (c *MultiEndpointRPCClient) Close() {
 	// Cancel the background goroutine
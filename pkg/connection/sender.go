@@ -5,10 +5,16 @@ import (
 	"fmt"
 
 	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
+	"github.com/margined-protocol/locust-core/pkg/messages/authz"
 	"github.com/margined-protocol/locust-core/pkg/types"
 	"go.uber.org/zap"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
 )
 
+// SendMessages sends the chain message and waits for its tx to be confirmed. In a dry run the
+// messages are simulated instead and nil is returned, a simulation failure is returned as the
+// error the tx would fail with.
 func SendMessages(
 	ctx context.Context, l *zap.Logger,
 	clientRegistry *ClientRegistry,
@@ -17,13 +23,16 @@ func SendMessages(
 	cfg *types.Config,
 	isDryRun, isFeeClient, wrapAuthz bool,
 ) (*cosmosclient.Response, error) {
-	if isDryRun {
-		l.Debug("Dry run enabled", zap.Any("Generated messages", chainMsg.Messages))
+	if len(chainMsg.Messages) == 0 {
+		l.Info("No messages to send")
 		return nil, nil
 	}
 
-	if len(chainMsg.Messages) == 0 {
-		l.Info("No messages to send")
+	if isDryRun {
+		l.Debug("Dry run enabled", zap.Any("Generated messages", chainMsg.Messages))
+		if _, err := SimulateMessages(ctx, l, clientRegistry, chainMsg, messageSender, cfg, isFeeClient, wrapAuthz); err != nil {
+			return nil, err
+		}
 		return nil, nil
 	}
 
@@ -36,13 +45,7 @@ func SendMessages(
 
 	l.Info("Chain", zap.Any("chain", chain.Chain))
 
-	tmpCfg := types.Config{
-		Chain:         *chain.Chain,
-		SignerAccount: cfg.SignerAccount,
-		DryRun:        isDryRun,
-		TxRetryCount:  cfg.TxRetryCount,
-		TxRetryDelay:  cfg.TxRetryDelay,
-	}
+	tmpCfg := chainConfig(chain, cfg, isDryRun)
 
 	msgs := chainMsg.Messages
 
@@ -74,3 +77,98 @@ func SendMessages(
 
 	return resp, nil
 }
+
+// SubmitMessages submits the chain message to the mempool without waiting for its tx to be
+// included, passing the tx hash to record first so the tx can be found again after a restart.
+// The hash of the submitted tx is returned, empty when there are no messages.
+func SubmitMessages(
+	ctx context.Context, l *zap.Logger,
+	clientRegistry *ClientRegistry,
+	chainMsg ChainMessage,
+	cfg *types.Config,
+	record func(txHash string) error,
+) (string, error) {
+	if len(chainMsg.Messages) == 0 {
+		l.Info("No messages to send")
+		return "", nil
+	}
+
+	chain, err := clientRegistry.GetClient(chainMsg.ChainID, chainMsg.IsFeeClient)
+	if err != nil {
+		return "", fmt.Errorf("error getting client: %w", err)
+	}
+
+	account, granteeAddress, err := GetSignerAccountAndAddress(chain.Client, cfg.SignerAccount, chain.Chain.Prefix)
+	if err != nil {
+		return "", fmt.Errorf("error getting grantee account: %w", err)
+	}
+
+	msgs := chainMsg.Messages
+	if chainMsg.WrapAuthz {
+		granteeAccAddress, err := sdk.AccAddressFromBech32(granteeAddress)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate AccAddress from grantee address: %w", err)
+		}
+		msgs = []sdk.Msg{authz.CreateAuthzMsg(granteeAccAddress, msgs)}
+	}
+
+	l.Info("Submitting messages", zap.String("chainID", chainMsg.ChainID), zap.Any("messages", chainMsg.Messages))
+
+	res, err := BroadcastRecorded(ctx, chain.Client, *account, record, msgs...)
+	if err != nil {
+		return "", err
+	}
+
+	return res.TxHash, nil
+}
+
+// SimulateMessages simulates the chain message against its chain without broadcasting it,
+// logging and returning the balance and contract changes the tx would make
+func SimulateMessages(
+	ctx context.Context, l *zap.Logger,
+	clientRegistry *ClientRegistry,
+	chainMsg ChainMessage,
+	messageSender MessageSender,
+	cfg *types.Config,
+	isFeeClient, wrapAuthz bool,
+) (*Simulation, error) {
+	chain, err := clientRegistry.GetClient(chainMsg.ChainID, isFeeClient)
+	if err != nil {
+		return nil, fmt.Errorf("error getting client: %w", err)
+	}
+
+	tmpCfg := chainConfig(chain, cfg, true)
+
+	var simulation *Simulation
+	if wrapAuthz {
+		simulation, err = messageSender.SimulateAuthzMessages(ctx, l, chain.Client, &tmpCfg, chainMsg.Messages...)
+	} else {
+		simulation, err = messageSender.SimulateMessages(ctx, l, chain.Client, &tmpCfg, chainMsg.Messages...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("simulation failed on %s: %w", chainMsg.ChainID, err)
+	}
+
+	l.Info("Simulated transaction",
+		zap.String("chainID", chainMsg.ChainID),
+		zap.Uint64("gas_used", simulation.GasUsed),
+		zap.Uint64("gas_limit", simulation.GasLimit),
+		zap.String("fees", simulation.Fees),
+		zap.Any("transfers", simulation.Transfers),
+		zap.Any("balance_changes", simulation.BalanceChanges),
+		zap.Any("contract_events", simulation.ContractEvents),
+	)
+
+	return simulation, nil
+}
+
+// chainConfig returns the config of the sender for the chain of the client
+func chainConfig(chain *ClientInstance, cfg *types.Config, isDryRun bool) types.Config {
+	return types.Config{
+		Chain:         *chain.Chain,
+		SignerAccount: cfg.SignerAccount,
+		DryRun:        isDryRun,
+		TxRetryCount:  cfg.TxRetryCount,
+		TxRetryDelay:  cfg.TxRetryDelay,
+	}
+}
@@ -0,0 +1,161 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// SequenceFetcher queries the account number and committed sequence of an account from chain
+type SequenceFetcher func(ctx context.Context) (accountNumber, sequence uint64, err error)
+
+// expectedSequencePattern matches the sequence the chain expected in an ErrWrongSequence log,
+// e.g. "account sequence mismatch, expected 12, got 11: incorrect account sequence"
+var expectedSequencePattern = regexp.MustCompile(`account sequence mismatch, expected (\d+), got \d+`)
+
+// SequenceManager hands out the account sequences of a signer on a chain. Sequences are
+// handed out without waiting for inclusion so several txs can be pipelined into a block,
+// and resynced from the chain when a tx is rejected. Senders hold submitMu from taking a
+// sequence until its tx is accepted or rejected, so rejections only ever rewind past
+// sequences nobody else is submitting.
+type SequenceManager struct {
+	chainID string
+	address string
+	fetch   SequenceFetcher
+
+	mu            sync.Mutex
+	synced        bool
+	accountNumber uint64
+	next          uint64
+
+	// submitMu orders the submission of txs, see submitWithSequence and submitPipelined
+	submitMu sync.Mutex
+}
+
+// NewSequenceManager creates a sequence manager for an address, the account number and
+// sequence are fetched on first use
+func NewSequenceManager(chainID, address string, fetch SequenceFetcher) *SequenceManager {
+	return &SequenceManager{
+		chainID: chainID,
+		address: address,
+		fetch:   fetch,
+	}
+}
+
+// Next returns the account number and the next unused sequence, syncing from chain if needed
+func (m *SequenceManager) Next(ctx context.Context) (accountNumber, sequence uint64, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.synced {
+		number, committed, err := m.fetch(ctx)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to fetch sequence of %s on %s: %w", m.address, m.chainID, err)
+		}
+
+		m.accountNumber = number
+		m.next = committed
+		m.synced = true
+	}
+
+	sequence = m.next
+	m.next++
+
+	return m.accountNumber, sequence, nil
+}
+
+// Failed returns a sequence that was not accepted into the mempool, it is handed out again
+// when it is the latest sequence. Later sequences still in flight are rejected on the gap
+// and the latest of them rewinds through Resync.
+func (m *SequenceManager) Failed(sequence uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.synced && sequence+1 == m.next {
+		m.next = sequence
+	}
+}
+
+// Resync handles a sequence mismatch of a tx, the sequence the chain expected is taken from
+// the raw log when present and fetched from chain on the next tx otherwise. Mismatches of
+// sequences older than the latest are ignored, rewinding would hand out sequences of txs
+// still in flight again.
+func (m *SequenceManager) Resync(sequence uint64, rawLog string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.synced || sequence+1 != m.next {
+		return
+	}
+
+	expected, ok := ParseExpectedSequence(rawLog)
+	if !ok {
+		m.synced = false
+		return
+	}
+
+	m.next = expected
+}
+
+// Reset forces the account number and sequence to be fetched from chain on the next tx
+func (m *SequenceManager) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.synced = false
+}
+
+// ParseExpectedSequence returns the sequence the chain expected from a sequence mismatch log
+func ParseExpectedSequence(rawLog string) (uint64, bool) {
+	matches := expectedSequencePattern.FindStringSubmatch(rawLog)
+	if len(matches) != 2 {
+		return 0, false
+	}
+
+	sequence, err := strconv.ParseUint(matches[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return sequence, true
+}
+
+// sequenceKey identifies the sequence manager of an address on a chain
+type sequenceKey struct {
+	chainID string
+	address string
+}
+
+// SequenceRegistry shares sequence managers between everything signing for an address, so
+// strategies using the same signer do not race on sequences
+type SequenceRegistry struct {
+	mu       sync.Mutex
+	managers map[sequenceKey]*SequenceManager
+}
+
+// NewSequenceRegistry creates an empty sequence registry
+func NewSequenceRegistry() *SequenceRegistry {
+	return &SequenceRegistry{
+		managers: make(map[sequenceKey]*SequenceManager),
+	}
+}
+
+// DefaultSequenceRegistry is used by the broadcast helpers of this package
+var DefaultSequenceRegistry = NewSequenceRegistry()
+
+// Manager returns the sequence manager of an address on a chain, creating it with the
+// fetcher when it does not exist yet
+func (r *SequenceRegistry) Manager(chainID, address string, fetch SequenceFetcher) *SequenceManager {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := sequenceKey{chainID: chainID, address: address}
+	manager, exists := r.managers[key]
+	if !exists {
+		manager = NewSequenceManager(chainID, address, fetch)
+		r.managers[key] = manager
+	}
+
+	return manager
+}
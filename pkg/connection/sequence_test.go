@@ -0,0 +1,170 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSequenceFetcher returns the committed sequence of a fake chain
+type fakeSequenceFetcher struct {
+	mu       sync.Mutex
+	sequence uint64
+	fetches  int
+	err      error
+}
+
+func (f *fakeSequenceFetcher) fetch(context.Context) (uint64, uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.fetches++
+	if f.err != nil {
+		return 0, 0, f.err
+	}
+	return 7, f.sequence, nil
+}
+
+func nextSequence(t *testing.T, m *SequenceManager) uint64 {
+	t.Helper()
+
+	accountNumber, sequence, err := m.Next(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), accountNumber)
+	return sequence
+}
+
+func TestSequenceManagerPipelines(t *testing.T) {
+	chain := &fakeSequenceFetcher{sequence: 10}
+	m := NewSequenceManager("test-1", "addr", chain.fetch)
+
+	assert.Equal(t, uint64(10), nextSequence(t, m))
+	assert.Equal(t, uint64(11), nextSequence(t, m))
+	assert.Equal(t, uint64(12), nextSequence(t, m))
+
+	// Sequences are handed out without going back to the chain
+	assert.Equal(t, 1, chain.fetches)
+}
+
+func TestSequenceManagerConcurrentSenders(t *testing.T) {
+	chain := &fakeSequenceFetcher{sequence: 100}
+	m := NewSequenceManager("test-1", "addr", chain.fetch)
+
+	var mu sync.Mutex
+	var sequences []uint64
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, sequence, err := m.Next(context.Background())
+			if assert.NoError(t, err) {
+				mu.Lock()
+				sequences = append(sequences, sequence)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(sequences, func(i, j int) bool { return sequences[i] < sequences[j] })
+	for i, sequence := range sequences {
+		assert.Equal(t, uint64(100+i), sequence)
+	}
+	assert.Equal(t, 1, chain.fetches)
+}
+
+func TestSequenceManagerFailed(t *testing.T) {
+	chain := &fakeSequenceFetcher{sequence: 5}
+	m := NewSequenceManager("test-1", "addr", chain.fetch)
+
+	// The latest sequence is handed out again
+	sequence := nextSequence(t, m)
+	m.Failed(sequence)
+	assert.Equal(t, uint64(5), nextSequence(t, m))
+	assert.Equal(t, 1, chain.fetches)
+
+	// A gap behind later sequences in flight is left for their mismatch to rewind
+	first := nextSequence(t, m)
+	second := nextSequence(t, m)
+	m.Failed(first)
+	m.Resync(second, "account sequence mismatch, expected 5, got 6: incorrect account sequence")
+	assert.Equal(t, uint64(5), nextSequence(t, m))
+	assert.Equal(t, 1, chain.fetches)
+}
+
+func TestSequenceManagerResync(t *testing.T) {
+	chain := &fakeSequenceFetcher{sequence: 1}
+	m := NewSequenceManager("test-1", "addr", chain.fetch)
+
+	sequence := nextSequence(t, m)
+	m.Resync(sequence, "account sequence mismatch, expected 9, got 1: incorrect account sequence")
+	sequence = nextSequence(t, m)
+	assert.Equal(t, uint64(9), sequence)
+	assert.Equal(t, 1, chain.fetches)
+
+	// Mismatches of older sequences do not rewind past the sequences in flight
+	nextSequence(t, m)
+	m.Resync(sequence, "account sequence mismatch, expected 3, got 9: incorrect account sequence")
+	sequence = nextSequence(t, m)
+	assert.Equal(t, uint64(11), sequence)
+
+	// Without the expected sequence in the log it is fetched from chain
+	m.Resync(sequence, "incorrect account sequence")
+	chain.sequence = 12
+	assert.Equal(t, uint64(12), nextSequence(t, m))
+	assert.Equal(t, 2, chain.fetches)
+
+	m.Reset()
+	assert.Equal(t, uint64(12), nextSequence(t, m))
+	assert.Equal(t, 3, chain.fetches)
+}
+
+func TestSequenceManagerFetchError(t *testing.T) {
+	chain := &fakeSequenceFetcher{err: errors.New("account not found")}
+	m := NewSequenceManager("test-1", "addr", chain.fetch)
+
+	_, _, err := m.Next(context.Background())
+	require.Error(t, err)
+
+	chain.err = nil
+	chain.sequence = 3
+	assert.Equal(t, uint64(3), nextSequence(t, m))
+}
+
+func TestParseExpectedSequence(t *testing.T) {
+	tests := []struct {
+		rawLog string
+		want   uint64
+		wantOK bool
+	}{
+		{rawLog: "account sequence mismatch, expected 12, got 11: incorrect account sequence", want: 12, wantOK: true},
+		{rawLog: "error code: '32' msg: 'account sequence mismatch, expected 3, got 5: incorrect account sequence'", want: 3, wantOK: true},
+		{rawLog: "incorrect account sequence"},
+		{rawLog: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rawLog, func(t *testing.T) {
+			sequence, ok := ParseExpectedSequence(tt.rawLog)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, sequence)
+		})
+	}
+}
+
+func TestSequenceRegistry(t *testing.T) {
+	registry := NewSequenceRegistry()
+	chain := &fakeSequenceFetcher{}
+
+	first := registry.Manager("test-1", "addr", chain.fetch)
+	assert.Same(t, first, registry.Manager("test-1", "addr", chain.fetch))
+	assert.NotSame(t, first, registry.Manager("test-2", "addr", chain.fetch))
+	assert.NotSame(t, first, registry.Manager("test-1", "other", chain.fetch))
+}
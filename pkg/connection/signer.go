@@ -0,0 +1,44 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosaccount"
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
+	"github.com/margined-protocol/locust-core/pkg/signer"
+	"github.com/margined-protocol/locust-core/pkg/types"
+)
+
+// UseSigner signs the client's txs for the account named name with the signer instead of the
+// keyring, every broadcast path signs through the client's tx factory. It returns the account
+// to broadcast with, which is also returned by the client for the name.
+func UseSigner(cosmosClient *cosmosclient.Client, name string, s signer.Signer) (cosmosaccount.Account, error) {
+	kr, err := signer.NewKeyring(cosmosClient.AccountRegistry.Keyring, name, s)
+	if err != nil {
+		return cosmosaccount.Account{}, err
+	}
+
+	cosmosClient.AccountRegistry.Keyring = kr
+	cosmosClient.TxFactory = cosmosClient.TxFactory.WithKeybase(kr)
+
+	return cosmosClient.Account(name)
+}
+
+// useRemoteSigner signs with the remote signer of the key when one is configured
+func useRemoteSigner(ctx context.Context, cosmosClient *cosmosclient.Client, key *types.SigningKey) error {
+	if key.Remote == nil {
+		return nil
+	}
+
+	remote, err := signer.NewRemoteSigner(ctx, key.Remote)
+	if err != nil {
+		return fmt.Errorf("failed to connect to remote signer: %w", err)
+	}
+
+	if _, err := UseSigner(cosmosClient, key.Remote.Account, remote); err != nil {
+		return fmt.Errorf("failed to use remote signer: %w", err)
+	}
+
+	return nil
+}
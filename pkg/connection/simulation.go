@@ -0,0 +1,224 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosaccount"
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
+
+	sdkmath "cosmossdk.io/math"
+
+	"github.com/cosmos/cosmos-sdk/client/tx"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+// Simulation is the outcome of a tx simulated against a node, nothing is broadcast
+type Simulation struct {
+	GasUsed uint64
+	// GasLimit is the gas limit the tx would be sent with
+	GasLimit uint64
+	// Fees are the estimated fees of the tx, empty when the chain has no fee estimation and
+	// the configured fees or gas prices apply
+	Fees string
+	// Transfers are the bank transfers of the tx, including the fee payment
+	Transfers []Transfer
+	// BalanceChanges are the net balance changes of every address, sorted by address and denom
+	BalanceChanges []BalanceChange
+	// ContractEvents are the wasm events emitted by contracts in order
+	ContractEvents []ContractEvent
+	Events         []abci.Event
+}
+
+// Transfer is a bank transfer between two addresses
+type Transfer struct {
+	Sender    string    `json:"sender"`
+	Recipient string    `json:"recipient"`
+	Amount    sdk.Coins `json:"amount"`
+}
+
+// BalanceChange is the net change of the balance of an address in a denom, negative when the
+// address spent more than it received
+type BalanceChange struct {
+	Address string      `json:"address"`
+	Denom   string      `json:"denom"`
+	Amount  sdkmath.Int `json:"amount"`
+}
+
+// ContractEvent is a wasm event emitted by a contract
+type ContractEvent struct {
+	Contract   string           `json:"contract"`
+	Type       string           `json:"type"`
+	Attributes []EventAttribute `json:"attributes"`
+}
+
+// EventAttribute is a key value pair of an event
+type EventAttribute struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// BalanceChange returns the net change of the balance of the address in the denom
+func (s *Simulation) BalanceChange(address, denom string) sdkmath.Int {
+	for _, change := range s.BalanceChanges {
+		if change.Address == address && change.Denom == denom {
+			return change.Amount
+		}
+	}
+	return sdkmath.ZeroInt()
+}
+
+// SimulateTx simulates the messages signed by the account against the node and decodes the
+// state changes of the tx, nothing is broadcast. The account sequence is read from the chain
+// so txs still in the mempool are not accounted for.
+func SimulateTx(ctx context.Context, cosmosClient *cosmosclient.Client, account cosmosaccount.Account, msgs ...sdk.Msg) (*Simulation, error) {
+	address, err := account.Record.GetAddress()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account address: %w", err)
+	}
+
+	clientCtx := cosmosClient.Context().WithFromAddress(address).WithFromName(account.Name)
+	accountNumber, sequence, err := authtypes.AccountRetriever{}.GetAccountNumberSequence(clientCtx.WithCmdContext(ctx), address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account sequence: %w", err)
+	}
+
+	txf := cosmosClient.TxFactory.WithAccountNumber(accountNumber).WithSequence(sequence)
+	res, gasLimit, err := tx.CalculateGas(clientCtx, txf, msgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate tx: %w", err)
+	}
+
+	simulation, err := decodeSimulation(res.Result.Events)
+	if err != nil {
+		return nil, err
+	}
+	simulation.GasUsed = res.GasInfo.GasUsed
+	simulation.GasLimit = gasLimit
+
+	// Fee caps apply to the simulation so a tx that would be refused is reported as such
+	if estimator, ok := feeEstimator(cosmosClient); ok {
+		fee, err := estimator.Estimate(ctx, func(context.Context) (uint64, error) { return simulation.GasUsed, nil })
+		if err != nil {
+			return nil, fmt.Errorf("failed to estimate fee: %w", err)
+		}
+		simulation.GasLimit = fee.GasLimit
+		simulation.Fees = fee.String()
+	}
+
+	return simulation, nil
+}
+
+// decodeSimulation decodes the bank and wasm events of a tx
+func decodeSimulation(events []abci.Event) (*Simulation, error) {
+	simulation := &Simulation{Events: events}
+	balances := make(map[string]map[string]sdkmath.Int)
+
+	addBalance := func(address string, coins sdk.Coins, sign int64) {
+		if balances[address] == nil {
+			balances[address] = make(map[string]sdkmath.Int)
+		}
+		for _, coin := range coins {
+			current, ok := balances[address][coin.Denom]
+			if !ok {
+				current = sdkmath.ZeroInt()
+			}
+			balances[address][coin.Denom] = current.Add(coin.Amount.MulRaw(sign))
+		}
+	}
+
+	for _, event := range events {
+		switch {
+		case event.Type == "transfer":
+			attributes := eventAttributes(event)
+			amount, err := parseEventCoins(attributes["amount"])
+			if err != nil {
+				return nil, err
+			}
+			simulation.Transfers = append(simulation.Transfers, Transfer{
+				Sender:    attributes["sender"],
+				Recipient: attributes["recipient"],
+				Amount:    amount,
+			})
+
+		case event.Type == "coin_spent":
+			attributes := eventAttributes(event)
+			amount, err := parseEventCoins(attributes["amount"])
+			if err != nil {
+				return nil, err
+			}
+			addBalance(attributes["spender"], amount, -1)
+
+		case event.Type == "coin_received":
+			attributes := eventAttributes(event)
+			amount, err := parseEventCoins(attributes["amount"])
+			if err != nil {
+				return nil, err
+			}
+			addBalance(attributes["receiver"], amount, 1)
+
+		case event.Type == "wasm" || strings.HasPrefix(event.Type, "wasm-"):
+			contractEvent := ContractEvent{Type: event.Type}
+			for _, attribute := range event.Attributes {
+				if attribute.Key == "_contract_address" {
+					contractEvent.Contract = attribute.Value
+					continue
+				}
+				contractEvent.Attributes = append(contractEvent.Attributes, EventAttribute{
+					Key:   attribute.Key,
+					Value: attribute.Value,
+				})
+			}
+			simulation.ContractEvents = append(simulation.ContractEvents, contractEvent)
+		}
+	}
+
+	for address, denoms := range balances {
+		for denom, amount := range denoms {
+			if amount.IsZero() {
+				continue
+			}
+			simulation.BalanceChanges = append(simulation.BalanceChanges, BalanceChange{
+				Address: address,
+				Denom:   denom,
+				Amount:  amount,
+			})
+		}
+	}
+
+	sort.Slice(simulation.BalanceChanges, func(i, j int) bool {
+		a, b := simulation.BalanceChanges[i], simulation.BalanceChanges[j]
+		if a.Address != b.Address {
+			return a.Address < b.Address
+		}
+		return a.Denom < b.Denom
+	})
+
+	return simulation, nil
+}
+
+// parseEventCoins parses the amount of a bank event, which is empty when no coins moved
+func parseEventCoins(amount string) (sdk.Coins, error) {
+	if amount == "" {
+		return sdk.Coins{}, nil
+	}
+
+	coins, err := sdk.ParseCoinsNormalized(amount)
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount %q in event: %w", amount, err)
+	}
+	return coins, nil
+}
+
+func eventAttributes(event abci.Event) map[string]string {
+	attributes := make(map[string]string, len(event.Attributes))
+	for _, attribute := range event.Attributes {
+		attributes[attribute.Key] = attribute.Value
+	}
+	return attributes
+}
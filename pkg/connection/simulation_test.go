@@ -0,0 +1,89 @@
+package connection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdkmath "cosmossdk.io/math"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+func event(eventType string, attributes ...string) abci.Event {
+	e := abci.Event{Type: eventType}
+	for i := 0; i+1 < len(attributes); i += 2 {
+		e.Attributes = append(e.Attributes, abci.EventAttribute{Key: attributes[i], Value: attributes[i+1]})
+	}
+	return e
+}
+
+func TestDecodeSimulation(t *testing.T) {
+	const (
+		bot      = "neutron1bot"
+		contract = "neutron1vault"
+		feePool  = "neutron1fees"
+	)
+
+	events := []abci.Event{
+		// Fee payment
+		event("coin_spent", "spender", bot, "amount", "2500untrn"),
+		event("coin_received", "receiver", feePool, "amount", "2500untrn"),
+		event("transfer", "recipient", feePool, "sender", bot, "amount", "2500untrn"),
+		// Deposit to the vault, which mints shares
+		event("coin_spent", "spender", bot, "amount", "1000000ibc/usdc,500untrn"),
+		event("coin_received", "receiver", contract, "amount", "1000000ibc/usdc,500untrn"),
+		event("transfer", "recipient", contract, "sender", bot, "amount", "1000000ibc/usdc,500untrn"),
+		event("execute", "_contract_address", contract),
+		event("wasm", "_contract_address", contract, "action", "deposit", "amount", "1000000"),
+		event("wasm-mint", "_contract_address", contract, "shares", "990000"),
+		event("coin_received", "receiver", bot, "amount", "990000factory/vault/shares"),
+		// Bank events without coins
+		event("coin_spent", "spender", bot, "amount", ""),
+	}
+
+	simulation, err := decodeSimulation(events)
+	require.NoError(t, err)
+
+	assert.Equal(t, []Transfer{
+		{Sender: bot, Recipient: feePool, Amount: sdk.NewCoins(sdk.NewInt64Coin("untrn", 2500))},
+		{Sender: bot, Recipient: contract, Amount: sdk.NewCoins(sdk.NewInt64Coin("ibc/usdc", 1000000), sdk.NewInt64Coin("untrn", 500))},
+	}, simulation.Transfers)
+
+	assert.Equal(t, []BalanceChange{
+		{Address: bot, Denom: "factory/vault/shares", Amount: sdkmath.NewInt(990000)},
+		{Address: bot, Denom: "ibc/usdc", Amount: sdkmath.NewInt(-1000000)},
+		{Address: bot, Denom: "untrn", Amount: sdkmath.NewInt(-3000)},
+		{Address: feePool, Denom: "untrn", Amount: sdkmath.NewInt(2500)},
+		{Address: contract, Denom: "ibc/usdc", Amount: sdkmath.NewInt(1000000)},
+		{Address: contract, Denom: "untrn", Amount: sdkmath.NewInt(500)},
+	}, simulation.BalanceChanges)
+
+	assert.Equal(t, []ContractEvent{
+		{Contract: contract, Type: "wasm", Attributes: []EventAttribute{{Key: "action", Value: "deposit"}, {Key: "amount", Value: "1000000"}}},
+		{Contract: contract, Type: "wasm-mint", Attributes: []EventAttribute{{Key: "shares", Value: "990000"}}},
+	}, simulation.ContractEvents)
+
+	assert.Equal(t, sdkmath.NewInt(-3000), simulation.BalanceChange(bot, "untrn"))
+	assert.True(t, simulation.BalanceChange(bot, "uatom").IsZero())
+	assert.Len(t, simulation.Events, len(events))
+}
+
+func TestDecodeSimulationNetsOutRoundTrips(t *testing.T) {
+	simulation, err := decodeSimulation([]abci.Event{
+		event("coin_spent", "spender", "neutron1a", "amount", "100untrn"),
+		event("coin_received", "receiver", "neutron1b", "amount", "100untrn"),
+		event("coin_spent", "spender", "neutron1b", "amount", "100untrn"),
+		event("coin_received", "receiver", "neutron1a", "amount", "100untrn"),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, simulation.BalanceChanges)
+}
+
+func TestDecodeSimulationInvalidAmount(t *testing.T) {
+	_, err := decodeSimulation([]abci.Event{event("coin_spent", "spender", "neutron1a", "amount", "not-coins")})
+	assert.ErrorContains(t, err, "invalid amount")
+}
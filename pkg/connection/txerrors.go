@@ -0,0 +1,221 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TxErrorCategory groups tx failures that are handled the same way
+type TxErrorCategory int
+
+const (
+	TxErrorUnknown TxErrorCategory = iota
+	TxErrorSequenceMismatch
+	TxErrorOutOfGas
+	TxErrorInsufficientFees
+	TxErrorInsufficientFunds
+	TxErrorContractExecution
+	TxErrorAuthzNotFound
+	TxErrorTimeoutHeight
+	TxErrorMempoolFull
+)
+
+// RetryPolicy is how a broadcast is retried after a tx failure
+type RetryPolicy int
+
+const (
+	// RetryAfterDelay retries the same tx after the configured retry delay
+	RetryAfterDelay RetryPolicy = iota
+	// RetryImmediately retries the tx without waiting
+	RetryImmediately
+	// RetryResyncSequence retries immediately with the sequence expected by the chain
+	RetryResyncSequence
+	// RetryBumpGas retries with a higher gas limit
+	RetryBumpGas
+	// RetryBumpFee retries with a higher gas price
+	RetryBumpFee
+	// RetryFailFast does not retry, the tx fails the same way until something else changes
+	RetryFailFast
+)
+
+var (
+	ErrSequenceMismatch   = errors.New("account sequence mismatch")
+	ErrOutOfGas           = errors.New("out of gas")
+	ErrInsufficientFees   = errors.New("insufficient fees")
+	ErrInsufficientFunds  = errors.New("insufficient funds")
+	ErrContractExecution  = errors.New("contract execution failed")
+	ErrAuthzNotFound      = errors.New("authorization not found")
+	ErrTxTimeoutHeight    = errors.New("tx timeout height reached")
+	ErrMempoolFull        = errors.New("mempool is full")
+	txErrorCategoryErrors = map[TxErrorCategory]error{
+		TxErrorSequenceMismatch:  ErrSequenceMismatch,
+		TxErrorOutOfGas:          ErrOutOfGas,
+		TxErrorInsufficientFees:  ErrInsufficientFees,
+		TxErrorInsufficientFunds: ErrInsufficientFunds,
+		TxErrorContractExecution: ErrContractExecution,
+		TxErrorAuthzNotFound:     ErrAuthzNotFound,
+		TxErrorTimeoutHeight:     ErrTxTimeoutHeight,
+		TxErrorMempoolFull:       ErrMempoolFull,
+	}
+)
+
+// abciErrorKey identifies an ABCI error by codespace and code
+type abciErrorKey struct {
+	codespace string
+	code      uint32
+}
+
+// abciErrorCategories maps the ABCI errors of the SDK and modules to categories
+var abciErrorCategories = map[abciErrorKey]TxErrorCategory{
+	{codespace: "sdk", code: 5}:   TxErrorInsufficientFunds, // ErrInsufficientFunds
+	{codespace: "sdk", code: 11}:  TxErrorOutOfGas,          // ErrOutOfGas
+	{codespace: "sdk", code: 13}:  TxErrorInsufficientFees,  // ErrInsufficientFee
+	{codespace: "sdk", code: 20}:  TxErrorMempoolFull,       // ErrMempoolIsFull
+	{codespace: "sdk", code: 30}:  TxErrorTimeoutHeight,     // ErrTxTimeoutHeight
+	{codespace: "sdk", code: 32}:  TxErrorSequenceMismatch,  // ErrWrongSequence
+	{codespace: "wasm", code: 5}:  TxErrorContractExecution, // ErrExecuteFailed
+	{codespace: "authz", code: 2}: TxErrorAuthzNotFound,     // ErrNoAuthorizationFound
+}
+
+// txErrorPatterns classify errors without a codespace, such as those only known from the
+// error message of the client, the first matching pattern wins
+var txErrorPatterns = []struct {
+	pattern  string
+	category TxErrorCategory
+}{
+	{pattern: "account sequence mismatch", category: TxErrorSequenceMismatch},
+	{pattern: "incorrect account sequence", category: TxErrorSequenceMismatch},
+	{pattern: "out of gas", category: TxErrorOutOfGas},
+	{pattern: "insufficient fee", category: TxErrorInsufficientFees},
+	{pattern: "gas price too low", category: TxErrorInsufficientFees},
+	{pattern: "insufficient funds", category: TxErrorInsufficientFunds},
+	{pattern: "make sure that your account has enough balance", category: TxErrorInsufficientFunds},
+	{pattern: "authorization not found", category: TxErrorAuthzNotFound},
+	{pattern: "execute wasm contract failed", category: TxErrorContractExecution},
+	{pattern: "tx timeout height", category: TxErrorTimeoutHeight},
+	{pattern: "mempool is full", category: TxErrorMempoolFull},
+}
+
+// String returns the name of the category
+func (c TxErrorCategory) String() string {
+	switch c {
+	case TxErrorSequenceMismatch:
+		return "sequence_mismatch"
+	case TxErrorOutOfGas:
+		return "out_of_gas"
+	case TxErrorInsufficientFees:
+		return "insufficient_fees"
+	case TxErrorInsufficientFunds:
+		return "insufficient_funds"
+	case TxErrorContractExecution:
+		return "contract_execution"
+	case TxErrorAuthzNotFound:
+		return "authz_not_found"
+	case TxErrorTimeoutHeight:
+		return "timeout_height"
+	case TxErrorMempoolFull:
+		return "mempool_full"
+	default:
+		return "unknown"
+	}
+}
+
+// RetryPolicy returns how txs failing with the category are retried
+func (c TxErrorCategory) RetryPolicy() RetryPolicy {
+	switch c {
+	case TxErrorSequenceMismatch:
+		return RetryResyncSequence
+	case TxErrorOutOfGas:
+		return RetryBumpGas
+	case TxErrorInsufficientFees:
+		return RetryBumpFee
+	case TxErrorInsufficientFunds, TxErrorContractExecution, TxErrorAuthzNotFound:
+		return RetryFailFast
+	case TxErrorTimeoutHeight:
+		return RetryImmediately
+	default:
+		return RetryAfterDelay
+	}
+}
+
+// String returns the name of the policy
+func (p RetryPolicy) String() string {
+	switch p {
+	case RetryImmediately:
+		return "retry_immediately"
+	case RetryResyncSequence:
+		return "resync_sequence"
+	case RetryBumpGas:
+		return "bump_gas"
+	case RetryBumpFee:
+		return "bump_fee"
+	case RetryFailFast:
+		return "fail_fast"
+	default:
+		return "retry_after_delay"
+	}
+}
+
+// ClassifyTxError returns the category of a tx failure from its ABCI codespace and code,
+// falling back to the raw log when the code is unknown
+func ClassifyTxError(codespace string, code uint32, rawLog string) TxErrorCategory {
+	if category, ok := abciErrorCategories[abciErrorKey{codespace: codespace, code: code}]; ok {
+		return category
+	}
+
+	log := strings.ToLower(rawLog)
+	for _, p := range txErrorPatterns {
+		if strings.Contains(log, p.pattern) {
+			return p.category
+		}
+	}
+
+	return TxErrorUnknown
+}
+
+// TxError is a failed broadcast, it matches the error of its category with errors.Is
+type TxError struct {
+	Category  TxErrorCategory
+	Codespace string
+	Code      uint32
+	TxHash    string
+	Log       string
+	// Err is the error returned by the client, if any
+	Err error
+}
+
+// NewTxError classifies a tx failure
+func NewTxError(txHash, codespace string, code uint32, rawLog string) *TxError {
+	return &TxError{
+		Category:  ClassifyTxError(codespace, code, rawLog),
+		Codespace: codespace,
+		Code:      code,
+		TxHash:    txHash,
+		Log:       rawLog,
+	}
+}
+
+func (e *TxError) Error() string {
+	if e.Code == 0 {
+		return fmt.Sprintf("tx failed (%s): %s", e.Category, e.Log)
+	}
+	return fmt.Sprintf("tx failed with code %d in %s (%s): %s", e.Code, e.Codespace, e.Category, e.Log)
+}
+
+// Unwrap returns the error of the category and the error returned by the client
+func (e *TxError) Unwrap() []error {
+	var errs []error
+	if err, ok := txErrorCategoryErrors[e.Category]; ok {
+		errs = append(errs, err)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// RetryPolicy returns how the tx should be retried
+func (e *TxError) RetryPolicy() RetryPolicy {
+	return e.Category.RetryPolicy()
+}
@@ -0,0 +1,167 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func TestClassifyTxError(t *testing.T) {
+	tests := []struct {
+		name      string
+		codespace string
+		code      uint32
+		rawLog    string
+		want      TxErrorCategory
+		policy    RetryPolicy
+	}{
+		{
+			name:      "out of gas",
+			codespace: "sdk",
+			code:      11,
+			rawLog:    "out of gas in location: WriteFlat; gasWanted: 200000, gasUsed: 201234: out of gas",
+			want:      TxErrorOutOfGas,
+			policy:    RetryBumpGas,
+		},
+		{
+			name:      "insufficient fees",
+			codespace: "sdk",
+			code:      13,
+			rawLog:    "insufficient fees; got: 100untrn required: 500untrn: insufficient fee",
+			want:      TxErrorInsufficientFees,
+			policy:    RetryBumpFee,
+		},
+		{
+			name:      "insufficient funds",
+			codespace: "sdk",
+			code:      5,
+			rawLog:    "spendable balance 10uosmo is smaller than 100uosmo: insufficient funds",
+			want:      TxErrorInsufficientFunds,
+			policy:    RetryFailFast,
+		},
+		{
+			name:      "contract execution",
+			codespace: "wasm",
+			code:      5,
+			rawLog:    "failed to execute message; message index: 0: Generic error: slippage exceeded: execute wasm contract failed",
+			want:      TxErrorContractExecution,
+			policy:    RetryFailFast,
+		},
+		{
+			name:      "authz not found",
+			codespace: "authz",
+			code:      2,
+			rawLog:    "failed to execute message; message index: 0: authorization not found",
+			want:      TxErrorAuthzNotFound,
+			policy:    RetryFailFast,
+		},
+		{
+			name:      "timeout height",
+			codespace: "sdk",
+			code:      30,
+			rawLog:    "block height: 101, timeout height: 100: tx timeout height",
+			want:      TxErrorTimeoutHeight,
+			policy:    RetryImmediately,
+		},
+		{
+			name:      "mempool full",
+			codespace: "sdk",
+			code:      20,
+			rawLog:    "mempool is full",
+			want:      TxErrorMempoolFull,
+			policy:    RetryAfterDelay,
+		},
+		{
+			name:      "sequence mismatch",
+			codespace: "sdk",
+			code:      32,
+			rawLog:    "account sequence mismatch, expected 12, got 11: incorrect account sequence",
+			want:      TxErrorSequenceMismatch,
+			policy:    RetryResyncSequence,
+		},
+		{
+			name:   "client error without codespace",
+			rawLog: "error code: '11' msg: 'out of gas in location: ReadFlat; gasWanted: 100, gasUsed: 150: out of gas'",
+			want:   TxErrorOutOfGas,
+			policy: RetryBumpGas,
+		},
+		{
+			name:   "missing account",
+			rawLog: "make sure that your account has enough balance",
+			want:   TxErrorInsufficientFunds,
+			policy: RetryFailFast,
+		},
+		{
+			name:   "feemarket gas price",
+			rawLog: "error code: '13' msg: 'got: 10untrn required: 50untrn, minGasPrice: 0.0053untrn: insufficient fee'",
+			want:   TxErrorInsufficientFees,
+			policy: RetryBumpFee,
+		},
+		{
+			name:      "code from another module",
+			codespace: "dex",
+			code:      11,
+			rawLog:    "pool not found",
+			want:      TxErrorUnknown,
+			policy:    RetryAfterDelay,
+		},
+		{
+			name:   "connection error",
+			rawLog: "post failed: dial tcp: connection refused",
+			want:   TxErrorUnknown,
+			policy: RetryAfterDelay,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			category := ClassifyTxError(tt.codespace, tt.code, tt.rawLog)
+			assert.Equal(t, tt.want, category)
+			assert.Equal(t, tt.policy, category.RetryPolicy())
+		})
+	}
+}
+
+func TestTxErrorIs(t *testing.T) {
+	err := fmt.Errorf("failed to send transaction after 3 attempts: %w",
+		NewTxError("ABC", "sdk", 13, "insufficient fees; got: 1untrn required: 5untrn: insufficient fee"))
+
+	assert.ErrorIs(t, err, ErrInsufficientFees)
+	assert.NotErrorIs(t, err, ErrOutOfGas)
+
+	var txErr *TxError
+	require.ErrorAs(t, err, &txErr)
+	assert.Equal(t, "ABC", txErr.TxHash)
+	assert.Equal(t, RetryBumpFee, txErr.RetryPolicy())
+
+	// The client error is kept alongside the category
+	err = &TxError{Category: TxErrorUnknown, Log: "context canceled", Err: context.Canceled}
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestBroadcastError(t *testing.T) {
+	assert.Nil(t, broadcastError(cosmosclient.Response{TxResponse: &sdk.TxResponse{TxHash: "ABC"}}, nil))
+
+	clientErr := errors.New("error code: '5' msg: 'execute wasm contract failed'")
+	txErr := broadcastError(cosmosclient.Response{TxResponse: &sdk.TxResponse{
+		TxHash:    "ABC",
+		Codespace: "wasm",
+		Code:      5,
+		RawLog:    "execute wasm contract failed",
+	}}, clientErr)
+	require.NotNil(t, txErr)
+	assert.ErrorIs(t, txErr, ErrContractExecution)
+	assert.ErrorIs(t, txErr, clientErr)
+	assert.Equal(t, "ABC", txErr.TxHash)
+
+	txErr = broadcastError(cosmosclient.Response{}, errors.New("mempool is full"))
+	require.NotNil(t, txErr)
+	assert.ErrorIs(t, txErr, ErrMempoolFull)
+}
@@ -0,0 +1,273 @@
+package connection
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+)
+
+const (
+	// Default interval between /tx polls
+	DefaultTxPollInterval = 2 * time.Second
+	// Default time a tx may be missing from both the chain and the mempool before it is
+	// reported as dropped, gossip can briefly hide a tx from the queried node
+	DefaultTxDroppedGrace = 10 * time.Second
+	// Default number of mempool txs scanned when looking for a tx
+	DefaultMempoolScanLimit = 1000
+)
+
+var (
+	// ErrTxDropped is returned when a tx left the mempool without being included in a block
+	ErrTxDropped = errors.New("tx dropped from mempool")
+	// ErrTxTimeout is returned when a tx was not included before the timeout
+	ErrTxTimeout = errors.New("timed out waiting for tx inclusion")
+)
+
+// TxResult is the outcome of a tx included in a block
+type TxResult struct {
+	TxHash    string
+	Height    int64
+	Code      uint32
+	Codespace string
+	Log       string
+	GasWanted int64
+	GasUsed   int64
+	Events    []abci.Event
+}
+
+// Succeeded reports whether the tx executed successfully in DeliverTx
+func (r *TxResult) Succeeded() bool {
+	return r.Code == 0
+}
+
+// txClient is the subset of the RPC client used to wait for txs
+type txClient interface {
+	Tx(ctx context.Context, hash []byte, prove bool) (*coretypes.ResultTx, error)
+	UnconfirmedTxs(ctx context.Context, limit *int) (*coretypes.ResultUnconfirmedTxs, error)
+	Subscribe(ctx context.Context, subscriber, query string, outCapacity ...int) (<-chan coretypes.ResultEvent, error)
+	Unsubscribe(ctx context.Context, subscriber, query string) error
+}
+
+// TxWaiter waits for broadcast txs to be included in a block, listening for the tx on the
+// websocket and polling /tx in case the event is missed or the websocket is unavailable
+type TxWaiter struct {
+	logger           *zap.Logger
+	client           func() txClient
+	pollInterval     time.Duration
+	droppedGrace     time.Duration
+	mempoolScanLimit int
+}
+
+// NewTxWaiter creates a tx waiter using the current endpoint of the client
+func (c *MultiEndpointRPCClient) NewTxWaiter() *TxWaiter {
+	return newTxWaiter(c.logger, func() txClient {
+		if client := c.GetClient(); client != nil {
+			return client
+		}
+		return nil
+	})
+}
+
+func newTxWaiter(logger *zap.Logger, client func() txClient) *TxWaiter {
+	return &TxWaiter{
+		logger:           logger,
+		client:           client,
+		pollInterval:     DefaultTxPollInterval,
+		droppedGrace:     DefaultTxDroppedGrace,
+		mempoolScanLimit: DefaultMempoolScanLimit,
+	}
+}
+
+// SetPollInterval sets the interval between /tx polls
+func (w *TxWaiter) SetPollInterval(interval time.Duration) {
+	w.pollInterval = interval
+}
+
+// SetDroppedGrace sets how long a tx may be missing from the chain and the mempool before
+// it is reported as dropped
+func (w *TxWaiter) SetDroppedGrace(grace time.Duration) {
+	w.droppedGrace = grace
+}
+
+// WaitForTx waits until the tx with the given hex hash is included in a block and returns
+// its result, a tx failing in DeliverTx is returned with its non-zero code. ErrTxDropped is
+// returned when the tx leaves the mempool without being included and ErrTxTimeout when it
+// is not included within the timeout.
+func (w *TxWaiter) WaitForTx(ctx context.Context, txHash string, timeout time.Duration) (*TxResult, error) {
+	hash, err := hex.DecodeString(txHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tx hash %s: %w", txHash, err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	events := w.subscribe(waitCtx, txHash)
+
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	var missingSince time.Time
+	for {
+		// Poll straight away, the tx may have been included before the subscription
+		result, found, err := w.checkTx(waitCtx, hash)
+		if err != nil {
+			w.logger.Debug("Failed to poll tx", zap.String("tx_hash", txHash), zap.Error(err))
+		}
+		if found {
+			return result, nil
+		}
+
+		if err == nil {
+			dropped, err := w.checkDropped(waitCtx, hash, &missingSince)
+			if err != nil {
+				w.logger.Debug("Failed to check mempool", zap.String("tx_hash", txHash), zap.Error(err))
+			}
+			if dropped {
+				return nil, fmt.Errorf("%w: %s", ErrTxDropped, txHash)
+			}
+		}
+
+		select {
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if data, isTx := event.Data.(cmttypes.EventDataTx); isTx {
+				return newTxResult(hash, data.Height, &data.Result), nil
+			}
+		case <-ticker.C:
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("%w: %s after %s", ErrTxTimeout, txHash, timeout)
+		}
+	}
+}
+
+// subscribe listens for the tx on the websocket, a nil channel is returned when the
+// subscription fails so the waiter falls back to polling
+func (w *TxWaiter) subscribe(ctx context.Context, txHash string) <-chan coretypes.ResultEvent {
+	client := w.client()
+	if client == nil {
+		return nil
+	}
+
+	subscriber := "locust-txwaiter-" + uuid.NewString()
+	query := fmt.Sprintf("tm.event='Tx' AND tx.hash='%s'", strings.ToUpper(txHash))
+
+	events, err := client.Subscribe(ctx, subscriber, query)
+	if err != nil {
+		w.logger.Debug("Failed to subscribe to tx, polling instead",
+			zap.String("tx_hash", txHash),
+			zap.Error(err))
+		return nil
+	}
+
+	go func() {
+		<-ctx.Done()
+		if err := client.Unsubscribe(context.Background(), subscriber, query); err != nil {
+			w.logger.Debug("Failed to unsubscribe from tx", zap.String("tx_hash", txHash), zap.Error(err))
+		}
+	}()
+
+	return events
+}
+
+// checkTx queries the tx, found is false while the tx is not in a block
+func (w *TxWaiter) checkTx(ctx context.Context, hash []byte) (*TxResult, bool, error) {
+	client := w.client()
+	if client == nil {
+		return nil, false, fmt.Errorf("no RPC endpoint connected")
+	}
+
+	res, err := client.Tx(ctx, hash, false)
+	if err != nil {
+		if isTxNotFound(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	return newTxResult(hash, res.Height, &res.TxResult), true, nil
+}
+
+// checkDropped reports whether the tx has been missing from the mempool for longer than the
+// grace period, missingSince tracks when it was first found missing
+func (w *TxWaiter) checkDropped(ctx context.Context, hash []byte, missingSince *time.Time) (bool, error) {
+	client := w.client()
+	if client == nil {
+		return false, fmt.Errorf("no RPC endpoint connected")
+	}
+
+	limit := w.mempoolScanLimit
+	res, err := client.UnconfirmedTxs(ctx, &limit)
+	if err != nil {
+		return false, err
+	}
+
+	inMempool, known := mempoolContains(res, hash)
+	if inMempool || !known {
+		*missingSince = time.Time{}
+		return false, nil
+	}
+
+	if missingSince.IsZero() {
+		*missingSince = time.Now()
+	}
+
+	if time.Since(*missingSince) < w.droppedGrace {
+		return false, nil
+	}
+
+	// The tx may have been included between the two queries
+	_, found, err := w.checkTx(ctx, hash)
+	if err != nil || found {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// mempoolContains reports whether the tx is in the scanned mempool txs, known is false when
+// the mempool holds more txs than were scanned and the tx was not among them
+func mempoolContains(res *coretypes.ResultUnconfirmedTxs, hash []byte) (found, known bool) {
+	for _, tx := range res.Txs {
+		if bytes.Equal(tx.Hash(), hash) {
+			return true, true
+		}
+	}
+
+	return false, res.Total <= len(res.Txs)
+}
+
+// isTxNotFound reports whether a /tx error means the tx is not indexed yet
+func isTxNotFound(err error) bool {
+	return strings.Contains(err.Error(), "not found")
+}
+
+func newTxResult(hash []byte, height int64, result *abci.ExecTxResult) *TxResult {
+	return &TxResult{
+		TxHash:    strings.ToUpper(hex.EncodeToString(hash)),
+		Height:    height,
+		Code:      result.Code,
+		Codespace: result.Codespace,
+		Log:       result.Log,
+		GasWanted: result.GasWanted,
+		GasUsed:   result.GasUsed,
+		Events:    result.Events,
+	}
+}
@@ -0,0 +1,188 @@
+package connection
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+)
+
+var testTx = cmttypes.Tx("test tx")
+
+// fakeTxClient is a node holding a single tx either in its mempool or in a block
+type fakeTxClient struct {
+	mu           sync.Mutex
+	included     *coretypes.ResultTx
+	mempool      []cmttypes.Tx
+	events       chan coretypes.ResultEvent
+	subscribeErr error
+	unsubscribed bool
+}
+
+func (f *fakeTxClient) Tx(_ context.Context, _ []byte, _ bool) (*coretypes.ResultTx, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.included == nil {
+		return nil, errors.New("RPC error -32603 - Internal error: tx (ABC) not found")
+	}
+	return f.included, nil
+}
+
+func (f *fakeTxClient) UnconfirmedTxs(_ context.Context, _ *int) (*coretypes.ResultUnconfirmedTxs, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return &coretypes.ResultUnconfirmedTxs{
+		Count: len(f.mempool),
+		Total: len(f.mempool),
+		Txs:   f.mempool,
+	}, nil
+}
+
+func (f *fakeTxClient) Subscribe(_ context.Context, _, _ string, _ ...int) (<-chan coretypes.ResultEvent, error) {
+	if f.subscribeErr != nil {
+		return nil, f.subscribeErr
+	}
+	return f.events, nil
+}
+
+func (f *fakeTxClient) Unsubscribe(_ context.Context, _, _ string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.unsubscribed = true
+	return nil
+}
+
+func (f *fakeTxClient) include(result *coretypes.ResultTx) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.included = result
+	f.mempool = nil
+}
+
+func newTestTxWaiter(client *fakeTxClient) *TxWaiter {
+	w := newTxWaiter(zap.NewNop(), func() txClient { return client })
+	w.SetPollInterval(10 * time.Millisecond)
+	w.SetDroppedGrace(50 * time.Millisecond)
+	return w
+}
+
+func testTxHash() string {
+	return hex.EncodeToString(testTx.Hash())
+}
+
+func TestTxWaiterPollsForInclusion(t *testing.T) {
+	client := &fakeTxClient{
+		mempool:      []cmttypes.Tx{testTx},
+		subscribeErr: errors.New("websocket not started"),
+	}
+	w := newTestTxWaiter(client)
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		client.include(&coretypes.ResultTx{
+			Height: 42,
+			TxResult: abci.ExecTxResult{
+				GasWanted: 200000,
+				GasUsed:   150000,
+				Events:    []abci.Event{{Type: "transfer"}},
+			},
+			Tx: testTx,
+		})
+	}()
+
+	result, err := w.WaitForTx(context.Background(), testTxHash(), time.Second)
+	require.NoError(t, err)
+	assert.True(t, result.Succeeded())
+	assert.Equal(t, int64(42), result.Height)
+	assert.Equal(t, int64(150000), result.GasUsed)
+	assert.Equal(t, int64(200000), result.GasWanted)
+	assert.Equal(t, []abci.Event{{Type: "transfer"}}, result.Events)
+}
+
+func TestTxWaiterReceivesEvent(t *testing.T) {
+	client := &fakeTxClient{
+		mempool: []cmttypes.Tx{testTx},
+		events:  make(chan coretypes.ResultEvent, 1),
+	}
+	w := newTestTxWaiter(client)
+	w.SetPollInterval(time.Hour)
+
+	client.events <- coretypes.ResultEvent{
+		Data: cmttypes.EventDataTx{TxResult: abci.TxResult{
+			Height: 7,
+			Tx:     testTx,
+			Result: abci.ExecTxResult{Code: 5, Codespace: "sdk", Log: "insufficient funds"},
+		}},
+	}
+
+	// DeliverTx failures are results, not errors
+	result, err := w.WaitForTx(context.Background(), testTxHash(), time.Second)
+	require.NoError(t, err)
+	assert.False(t, result.Succeeded())
+	assert.Equal(t, uint32(5), result.Code)
+	assert.Equal(t, "sdk", result.Codespace)
+	assert.Equal(t, int64(7), result.Height)
+
+	require.Eventually(t, func() bool {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		return client.unsubscribed
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTxWaiterDropped(t *testing.T) {
+	client := &fakeTxClient{subscribeErr: errors.New("websocket not started")}
+	w := newTestTxWaiter(client)
+
+	_, err := w.WaitForTx(context.Background(), testTxHash(), time.Second)
+	assert.ErrorIs(t, err, ErrTxDropped)
+}
+
+func TestTxWaiterTimeout(t *testing.T) {
+	client := &fakeTxClient{
+		mempool:      []cmttypes.Tx{testTx},
+		subscribeErr: errors.New("websocket not started"),
+	}
+	w := newTestTxWaiter(client)
+
+	_, err := w.WaitForTx(context.Background(), testTxHash(), 100*time.Millisecond)
+	assert.ErrorIs(t, err, ErrTxTimeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = w.WaitForTx(ctx, testTxHash(), time.Second)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = w.WaitForTx(context.Background(), "not hex", time.Second)
+	assert.Error(t, err)
+}
+
+func TestMempoolContains(t *testing.T) {
+	hash := testTx.Hash()
+	other := cmttypes.Tx("other tx")
+
+	found, known := mempoolContains(&coretypes.ResultUnconfirmedTxs{Total: 2, Txs: []cmttypes.Tx{other, testTx}}, hash)
+	assert.True(t, found)
+	assert.True(t, known)
+
+	found, known = mempoolContains(&coretypes.ResultUnconfirmedTxs{Total: 1, Txs: []cmttypes.Tx{other}}, hash)
+	assert.False(t, found)
+	assert.True(t, known)
+
+	// The tx may be beyond the scanned part of the mempool
+	found, known = mempoolContains(&coretypes.ResultUnconfirmedTxs{Total: 5, Txs: []cmttypes.Tx{other}}, hash)
+	assert.False(t, found)
+	assert.False(t, known)
+}
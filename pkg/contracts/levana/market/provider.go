@@ -86,6 +86,25 @@ func (p *LevanaProvider) RemoveCollateral(
 	return p.msgGenerator.Execute(sender, contractAddress, "update_position_remove_collateral_impact_leverage", payload, funds, cw20Contract)
 }
 
+// AddCollateralImpactSize executes `update_position_add_collateral_impact_size`
+func (p *LevanaProvider) AddCollateralImpactSize(
+	sender, contractAddress string,
+	cw20Contract *string,
+	payload UpdatePositionAddCollateralImpactSizeMsg,
+	funds *sdktypes.Coins,
+) (*wasmdtypes.MsgExecuteContract, error) {
+	return p.msgGenerator.Execute(sender, contractAddress, "update_position_add_collateral_impact_size", payload, funds, cw20Contract)
+}
+
+// RemoveCollateralImpactSize executes `update_position_remove_collateral_impact_size`
+func (p *LevanaProvider) RemoveCollateralImpactSize(
+	sender, contractAddress string,
+	cw20Contract *string,
+	payload UpdatePositionRemoveCollateralImpactSizeMsg,
+) (*wasmdtypes.MsgExecuteContract, error) {
+	return p.msgGenerator.Execute(sender, contractAddress, "update_position_remove_collateral_impact_size", payload, nil, cw20Contract)
+}
+
 // UpdateLeverage executes `update_position_leverage`
 func (p *LevanaProvider) UpdateLeverage(
 	sender, contractAddress string,
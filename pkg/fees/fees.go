@@ -0,0 +1,291 @@
+package fees
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/margined-protocol/locust-core/pkg/types"
+	"go.uber.org/zap"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// DefaultGasAdjustment is applied to the simulated gas when none is configured
+const DefaultGasAdjustment = 1.3
+
+// ErrFeeCapExceeded is returned when the estimated fee is above a configured cap
+var ErrFeeCapExceeded = errors.New("fee cap exceeded")
+
+// decCoinPattern matches a decimal coin such as "0.025untrn"
+var decCoinPattern = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)([a-zA-Z][a-zA-Z0-9/:._-]{2,127})$`)
+
+// Simulator simulates a tx and returns the gas it used
+type Simulator func(ctx context.Context) (uint64, error)
+
+// Fee is the gas limit and fee to sign a tx with
+type Fee struct {
+	GasLimit uint64
+	GasPrice sdkmath.LegacyDec
+	Amount   sdkmath.Int
+	Denom    string
+}
+
+// String returns the fee as a coin, e.g. "5000untrn"
+func (f *Fee) String() string {
+	return f.Amount.String() + f.Denom
+}
+
+// Cap limits the fee paid for a tx, unset limits are ignored
+type Cap struct {
+	MaxGas      uint64
+	MaxGasPrice sdkmath.LegacyDec
+	MaxFee      sdkmath.Int
+}
+
+// merge returns the tighter of the two caps for each limit
+func (c Cap) merge(other Cap) Cap {
+	merged := c
+
+	if other.MaxGas != 0 && (merged.MaxGas == 0 || other.MaxGas < merged.MaxGas) {
+		merged.MaxGas = other.MaxGas
+	}
+
+	if !other.MaxGasPrice.IsNil() && (merged.MaxGasPrice.IsNil() || other.MaxGasPrice.LT(merged.MaxGasPrice)) {
+		merged.MaxGasPrice = other.MaxGasPrice
+	}
+
+	if !other.MaxFee.IsNil() && (merged.MaxFee.IsNil() || other.MaxFee.LT(merged.MaxFee)) {
+		merged.MaxFee = other.MaxFee
+	}
+
+	return merged
+}
+
+type capKey struct{}
+
+// WithCap returns a context capping the fees of txs estimated with it, used by strategies to
+// tighten the caps of the chain
+func WithCap(ctx context.Context, c Cap) context.Context {
+	if existing, ok := ctx.Value(capKey{}).(Cap); ok {
+		c = existing.merge(c)
+	}
+	return context.WithValue(ctx, capKey{}, c)
+}
+
+// Bump scales the gas limit and gas price of a tx, used when retrying a tx that ran out of gas
+// or paid too little
+type Bump struct {
+	Gas   sdkmath.LegacyDec
+	Price sdkmath.LegacyDec
+}
+
+// NoBump leaves the gas limit and gas price unchanged
+func NoBump() Bump {
+	return Bump{Gas: sdkmath.LegacyOneDec(), Price: sdkmath.LegacyOneDec()}
+}
+
+type bumpKey struct{}
+
+// WithBump returns a context bumping the gas and fees of txs estimated with it
+func WithBump(ctx context.Context, b Bump) context.Context {
+	return context.WithValue(ctx, bumpKey{}, b)
+}
+
+// BumpFromContext returns the bump set on the context, if any
+func BumpFromContext(ctx context.Context) (Bump, bool) {
+	b, ok := ctx.Value(bumpKey{}).(Bump)
+	return b, ok
+}
+
+// Config configures the fee estimation of a chain
+type Config struct {
+	Denom           string
+	GasAdjustment   sdkmath.LegacyDec
+	PriceMultiplier sdkmath.LegacyDec
+	FallbackPrice   sdkmath.LegacyDec
+	Cap             Cap
+}
+
+// ConfigFromChain builds the fee estimation config of a chain, the configured gas prices are
+// the fallback price
+func ConfigFromChain(chain *types.Chain) (Config, error) {
+	if chain.FeeEstimation == nil {
+		return Config{}, fmt.Errorf("fee estimation is not configured for chain %s", chain.ChainID)
+	}
+	if chain.GasPrices == nil {
+		return Config{}, fmt.Errorf("gas prices must be set for fee estimation on chain %s", chain.ChainID)
+	}
+
+	estimation := chain.FeeEstimation
+
+	price, denom, err := parseDecCoin(*chain.GasPrices)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid gas prices: %w", err)
+	}
+
+	config := Config{
+		Denom:           denom,
+		PriceMultiplier: sdkmath.LegacyOneDec(),
+		FallbackPrice:   price,
+		Cap:             Cap{MaxGas: estimation.MaxGas},
+	}
+
+	gasAdjustment := DefaultGasAdjustment
+	if estimation.GasAdjustment != nil {
+		gasAdjustment = *estimation.GasAdjustment
+	}
+	config.GasAdjustment, err = decFromFloat(gasAdjustment)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid gas adjustment: %w", err)
+	}
+
+	if estimation.PriceMultiplier != nil {
+		config.PriceMultiplier, err = decFromFloat(*estimation.PriceMultiplier)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid price multiplier: %w", err)
+		}
+	}
+
+	if estimation.MaxGasPrice != nil {
+		maxPrice, maxDenom, err := parseDecCoin(*estimation.MaxGasPrice)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid max gas price: %w", err)
+		}
+		if maxDenom != denom {
+			return Config{}, fmt.Errorf("max gas price denom %s does not match gas price denom %s", maxDenom, denom)
+		}
+		config.Cap.MaxGasPrice = maxPrice
+	}
+
+	if estimation.MaxFee != nil {
+		maxFee, ok := sdkmath.NewIntFromString(*estimation.MaxFee)
+		if !ok || maxFee.IsNegative() {
+			return Config{}, fmt.Errorf("invalid max fee: %s", *estimation.MaxFee)
+		}
+		config.Cap.MaxFee = maxFee
+	}
+
+	return config, nil
+}
+
+// Estimator estimates the gas limit and fee of txs from a simulation and the chain's gas price
+type Estimator struct {
+	logger *zap.Logger
+	config Config
+	source GasPriceSource
+}
+
+// NewEstimator creates a fee estimator, a nil source always uses the fallback price
+func NewEstimator(logger *zap.Logger, config Config, source GasPriceSource) (*Estimator, error) {
+	if config.Denom == "" {
+		return nil, errors.New("fee denom must be set")
+	}
+	if config.GasAdjustment.IsNil() || !config.GasAdjustment.IsPositive() {
+		return nil, errors.New("gas adjustment must be positive")
+	}
+	if config.PriceMultiplier.IsNil() || !config.PriceMultiplier.IsPositive() {
+		return nil, errors.New("price multiplier must be positive")
+	}
+	if config.FallbackPrice.IsNil() || config.FallbackPrice.IsNegative() {
+		return nil, errors.New("fallback gas price must not be negative")
+	}
+
+	return &Estimator{
+		logger: logger,
+		config: config,
+		source: source,
+	}, nil
+}
+
+// Estimate simulates the tx and returns the fee to sign it with. Any bump set on the context
+// with WithBump is applied before the chain caps and the caps set with WithCap.
+func (e *Estimator) Estimate(ctx context.Context, simulate Simulator) (*Fee, error) {
+	gasUsed, err := simulate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate tx: %w", err)
+	}
+
+	limits := e.config.Cap
+	if strategyCap, ok := ctx.Value(capKey{}).(Cap); ok {
+		limits = limits.merge(strategyCap)
+	}
+
+	adjustment, price := e.config.GasAdjustment, e.GasPrice(ctx)
+	if bump, ok := BumpFromContext(ctx); ok {
+		adjustment = adjustment.Mul(bump.Gas)
+		price = price.Mul(bump.Price)
+	}
+
+	return calculateFee(gasUsed, adjustment, price, e.config.Denom, limits)
+}
+
+// GasPrice returns the fee market gas price scaled by the price multiplier, falling back to
+// the configured price when the fee market cannot be queried
+func (e *Estimator) GasPrice(ctx context.Context) sdkmath.LegacyDec {
+	if e.source == nil {
+		return e.config.FallbackPrice
+	}
+
+	price, err := e.source.GasPrice(ctx, e.config.Denom)
+	if err != nil || !price.IsPositive() {
+		e.logger.Warn("Failed to query fee market gas price, using configured gas price",
+			zap.String("denom", e.config.Denom),
+			zap.String("gas_price", e.config.FallbackPrice.String()),
+			zap.Error(err),
+		)
+		return e.config.FallbackPrice
+	}
+
+	return price.Mul(e.config.PriceMultiplier)
+}
+
+// calculateFee returns the adjusted gas limit and the fee it costs at the gas price, erroring
+// rather than underpaying when a cap is exceeded
+func calculateFee(gasUsed uint64, adjustment, price sdkmath.LegacyDec, denom string, limits Cap) (*Fee, error) {
+	gasLimit := sdkmath.LegacyNewDecFromInt(sdkmath.NewIntFromUint64(gasUsed)).Mul(adjustment).Ceil().TruncateInt()
+	if !gasLimit.IsUint64() {
+		return nil, fmt.Errorf("gas limit %s overflows", gasLimit)
+	}
+
+	if limits.MaxGas != 0 && gasLimit.Uint64() > limits.MaxGas {
+		return nil, fmt.Errorf("%w: gas limit %s above %d", ErrFeeCapExceeded, gasLimit, limits.MaxGas)
+	}
+
+	if !limits.MaxGasPrice.IsNil() && price.GT(limits.MaxGasPrice) {
+		return nil, fmt.Errorf("%w: gas price %s%s above %s%s", ErrFeeCapExceeded, price, denom, limits.MaxGasPrice, denom)
+	}
+
+	amount := price.MulInt(gasLimit).Ceil().TruncateInt()
+	if !limits.MaxFee.IsNil() && amount.GT(limits.MaxFee) {
+		return nil, fmt.Errorf("%w: fee %s%s above %s%s", ErrFeeCapExceeded, amount, denom, limits.MaxFee, denom)
+	}
+
+	return &Fee{
+		GasLimit: gasLimit.Uint64(),
+		GasPrice: price,
+		Amount:   amount,
+		Denom:    denom,
+	}, nil
+}
+
+// parseDecCoin parses a single decimal coin such as "0.025untrn"
+func parseDecCoin(coin string) (sdkmath.LegacyDec, string, error) {
+	matches := decCoinPattern.FindStringSubmatch(coin)
+	if len(matches) != 3 {
+		return sdkmath.LegacyDec{}, "", fmt.Errorf("invalid decimal coin: %s", coin)
+	}
+
+	amount, err := sdkmath.LegacyNewDecFromStr(matches[1])
+	if err != nil {
+		return sdkmath.LegacyDec{}, "", fmt.Errorf("invalid decimal coin %s: %w", coin, err)
+	}
+
+	return amount, matches[2], nil
+}
+
+func decFromFloat(value float64) (sdkmath.LegacyDec, error) {
+	return sdkmath.LegacyNewDecFromStr(strconv.FormatFloat(value, 'f', -1, 64))
+}
@@ -0,0 +1,305 @@
+package fees
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/margined-protocol/locust-core/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// fakePriceSource returns a fixed gas price or error
+type fakePriceSource struct {
+	price sdkmath.LegacyDec
+	err   error
+}
+
+func (f *fakePriceSource) GasPrice(context.Context, string) (sdkmath.LegacyDec, error) {
+	return f.price, f.err
+}
+
+// simulated returns a simulator reporting the gas used
+func simulated(gasUsed uint64) Simulator {
+	return func(context.Context) (uint64, error) {
+		return gasUsed, nil
+	}
+}
+
+func testConfig() Config {
+	return Config{
+		Denom:           "untrn",
+		GasAdjustment:   sdkmath.LegacyMustNewDecFromStr("1.5"),
+		PriceMultiplier: sdkmath.LegacyOneDec(),
+		FallbackPrice:   sdkmath.LegacyMustNewDecFromStr("0.025"),
+	}
+}
+
+func newTestEstimator(t *testing.T, config Config, source GasPriceSource) *Estimator {
+	t.Helper()
+
+	estimator, err := NewEstimator(zap.NewNop(), config, source)
+	require.NoError(t, err)
+	return estimator
+}
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name       string
+		source     GasPriceSource
+		multiplier string
+		gasUsed    uint64
+		wantGas    uint64
+		wantFee    string
+	}{
+		{
+			name:    "fallback price without fee market",
+			gasUsed: 100000,
+			wantGas: 150000,
+			wantFee: "3750untrn",
+		},
+		{
+			name:    "fee market price",
+			source:  &fakePriceSource{price: sdkmath.LegacyMustNewDecFromStr("0.0053")},
+			gasUsed: 100000,
+			wantGas: 150000,
+			wantFee: "795untrn",
+		},
+		{
+			name:       "fee market price with multiplier",
+			source:     &fakePriceSource{price: sdkmath.LegacyMustNewDecFromStr("0.0053")},
+			multiplier: "2",
+			gasUsed:    100000,
+			wantGas:    150000,
+			wantFee:    "1590untrn",
+		},
+		{
+			name:    "fallback on fee market error",
+			source:  &fakePriceSource{err: errors.New("unknown query path")},
+			gasUsed: 100000,
+			wantGas: 150000,
+			wantFee: "3750untrn",
+		},
+		{
+			name:    "fallback on zero fee market price",
+			source:  &fakePriceSource{price: sdkmath.LegacyZeroDec()},
+			gasUsed: 100000,
+			wantGas: 150000,
+			wantFee: "3750untrn",
+		},
+		{
+			name:    "gas and fee are rounded up",
+			source:  &fakePriceSource{price: sdkmath.LegacyMustNewDecFromStr("0.0025")},
+			gasUsed: 99999,
+			wantGas: 149999,
+			wantFee: "375untrn",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			if tt.multiplier != "" {
+				config.PriceMultiplier = sdkmath.LegacyMustNewDecFromStr(tt.multiplier)
+			}
+
+			fee, err := newTestEstimator(t, config, tt.source).Estimate(context.Background(), simulated(tt.gasUsed))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantGas, fee.GasLimit)
+			assert.Equal(t, tt.wantFee, fee.String())
+		})
+	}
+}
+
+func TestEstimateCaps(t *testing.T) {
+	tests := []struct {
+		name        string
+		chainCap    Cap
+		strategyCap *Cap
+		wantErr     bool
+	}{
+		{
+			name: "no caps",
+		},
+		{
+			name:     "within chain caps",
+			chainCap: Cap{MaxGas: 150000, MaxGasPrice: sdkmath.LegacyMustNewDecFromStr("0.025"), MaxFee: sdkmath.NewInt(3750)},
+		},
+		{
+			name:     "gas above chain cap",
+			chainCap: Cap{MaxGas: 149999},
+			wantErr:  true,
+		},
+		{
+			name:     "gas price above chain cap",
+			chainCap: Cap{MaxGasPrice: sdkmath.LegacyMustNewDecFromStr("0.02")},
+			wantErr:  true,
+		},
+		{
+			name:     "fee above chain cap",
+			chainCap: Cap{MaxFee: sdkmath.NewInt(3749)},
+			wantErr:  true,
+		},
+		{
+			name:        "strategy cap tighter than chain cap",
+			chainCap:    Cap{MaxFee: sdkmath.NewInt(10000)},
+			strategyCap: &Cap{MaxFee: sdkmath.NewInt(1000)},
+			wantErr:     true,
+		},
+		{
+			name:        "strategy cap looser than chain cap",
+			chainCap:    Cap{MaxFee: sdkmath.NewInt(1000)},
+			strategyCap: &Cap{MaxFee: sdkmath.NewInt(10000)},
+			wantErr:     true,
+		},
+		{
+			name:        "within strategy cap",
+			strategyCap: &Cap{MaxGas: 200000, MaxFee: sdkmath.NewInt(5000)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			config.Cap = tt.chainCap
+
+			ctx := context.Background()
+			if tt.strategyCap != nil {
+				ctx = WithCap(ctx, *tt.strategyCap)
+			}
+
+			fee, err := newTestEstimator(t, config, nil).Estimate(ctx, simulated(100000))
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrFeeCapExceeded)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "3750untrn", fee.String())
+		})
+	}
+}
+
+func TestEstimateBump(t *testing.T) {
+	config := testConfig()
+	config.Cap = Cap{MaxFee: sdkmath.NewInt(6000)}
+	estimator := newTestEstimator(t, config, nil)
+
+	ctx := WithBump(context.Background(), Bump{
+		Gas:   sdkmath.LegacyMustNewDecFromStr("1.2"),
+		Price: sdkmath.LegacyMustNewDecFromStr("1.1"),
+	})
+
+	fee, err := estimator.Estimate(ctx, simulated(100000))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(180000), fee.GasLimit)
+	assert.Equal(t, sdkmath.LegacyMustNewDecFromStr("0.0275"), fee.GasPrice)
+	assert.Equal(t, "4950untrn", fee.String())
+
+	// Bumps stay within the caps
+	ctx = WithBump(context.Background(), Bump{Gas: sdkmath.LegacyNewDec(2), Price: sdkmath.LegacyOneDec()})
+	_, err = estimator.Estimate(ctx, simulated(100000))
+	assert.ErrorIs(t, err, ErrFeeCapExceeded)
+
+	fee, err = estimator.Estimate(WithBump(context.Background(), NoBump()), simulated(100000))
+	require.NoError(t, err)
+	assert.Equal(t, "3750untrn", fee.String())
+}
+
+func TestEstimateSimulationError(t *testing.T) {
+	estimator := newTestEstimator(t, testConfig(), nil)
+
+	_, err := estimator.Estimate(context.Background(), func(context.Context) (uint64, error) {
+		return 0, errors.New("out of gas")
+	})
+	assert.ErrorContains(t, err, "out of gas")
+}
+
+func TestWithCapMerges(t *testing.T) {
+	ctx := WithCap(context.Background(), Cap{MaxGas: 100, MaxFee: sdkmath.NewInt(50)})
+	ctx = WithCap(ctx, Cap{MaxGas: 200, MaxGasPrice: sdkmath.LegacyOneDec()})
+
+	c, ok := ctx.Value(capKey{}).(Cap)
+	require.True(t, ok)
+	assert.Equal(t, uint64(100), c.MaxGas)
+	assert.Equal(t, sdkmath.LegacyOneDec(), c.MaxGasPrice)
+	assert.Equal(t, sdkmath.NewInt(50), c.MaxFee)
+}
+
+func TestConfigFromChain(t *testing.T) {
+	gasPrices := "0.0053untrn"
+	adjustment := 1.5
+	multiplier := 1.2
+	maxGasPrice := "0.1untrn"
+	maxFee := "500000"
+
+	config, err := ConfigFromChain(&types.Chain{
+		ChainID:   "neutron-1",
+		GasPrices: &gasPrices,
+		FeeEstimation: &types.FeeEstimation{
+			FeeMarket:       FeeMarketSkip,
+			GasAdjustment:   &adjustment,
+			PriceMultiplier: &multiplier,
+			MaxGas:          2000000,
+			MaxGasPrice:     &maxGasPrice,
+			MaxFee:          &maxFee,
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "untrn", config.Denom)
+	assert.Equal(t, sdkmath.LegacyMustNewDecFromStr("0.0053"), config.FallbackPrice)
+	assert.Equal(t, sdkmath.LegacyMustNewDecFromStr("1.5"), config.GasAdjustment)
+	assert.Equal(t, sdkmath.LegacyMustNewDecFromStr("1.2"), config.PriceMultiplier)
+	assert.Equal(t, uint64(2000000), config.Cap.MaxGas)
+	assert.Equal(t, sdkmath.LegacyMustNewDecFromStr("0.1"), config.Cap.MaxGasPrice)
+	assert.Equal(t, sdkmath.NewInt(500000), config.Cap.MaxFee)
+
+	// Defaults apply when only the fee market is configured
+	config, err = ConfigFromChain(&types.Chain{GasPrices: &gasPrices, FeeEstimation: &types.FeeEstimation{}})
+	require.NoError(t, err)
+	assert.Equal(t, sdkmath.LegacyMustNewDecFromStr("1.3"), config.GasAdjustment)
+	assert.Equal(t, sdkmath.LegacyOneDec(), config.PriceMultiplier)
+	assert.True(t, config.Cap.MaxFee.IsNil())
+
+	otherDenom := "0.1uosmo"
+	_, err = ConfigFromChain(&types.Chain{
+		GasPrices:     &gasPrices,
+		FeeEstimation: &types.FeeEstimation{MaxGasPrice: &otherDenom},
+	})
+	assert.Error(t, err)
+
+	_, err = ConfigFromChain(&types.Chain{FeeEstimation: &types.FeeEstimation{}})
+	assert.Error(t, err)
+}
+
+func TestParseDecCoin(t *testing.T) {
+	tests := []struct {
+		coin      string
+		wantPrice string
+		wantDenom string
+		wantErr   bool
+	}{
+		{coin: "0.025untrn", wantPrice: "0.025", wantDenom: "untrn"},
+		{coin: "1uosmo", wantPrice: "1", wantDenom: "uosmo"},
+		{coin: "0.1ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2", wantPrice: "0.1", wantDenom: "ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2"},
+		{coin: "untrn", wantErr: true},
+		{coin: "0.025", wantErr: true},
+		{coin: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.coin, func(t *testing.T) {
+			price, denom, err := parseDecCoin(tt.coin)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, sdkmath.LegacyMustNewDecFromStr(tt.wantPrice), price)
+			assert.Equal(t, tt.wantDenom, denom)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package fees
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+const (
+	// FeeMarketSkip is the fee market module of Skip, used by Neutron
+	FeeMarketSkip = "feemarket"
+	// FeeMarketOsmosis is the EIP-1559 base fee of the Osmosis txfees module
+	FeeMarketOsmosis = "osmosis"
+
+	feeMarketGasPricePath  = "/feemarket.feemarket.v1.Query/GasPrice"
+	osmosisEipBaseFeePath  = "/osmosis.txfees.v1beta1.Query/GetEipBaseFee"
+	decCoinDenomFieldNum   = 1
+	decCoinAmountFieldNum  = 2
+	gasPriceFieldNum       = 1
+	gasPriceDenomFieldNum  = 1
+	osmosisBaseFeeFieldNum = 1
+)
+
+// ABCIQuerier runs an ABCI query and returns the response value
+type ABCIQuerier func(ctx context.Context, path string, data []byte) ([]byte, error)
+
+// GasPriceSource queries the current gas price of a denom from chain
+type GasPriceSource interface {
+	GasPrice(ctx context.Context, denom string) (sdkmath.LegacyDec, error)
+}
+
+// NewGasPriceSource returns the gas price source of a fee market, nil when no fee market is
+// configured
+func NewGasPriceSource(feeMarket string, query ABCIQuerier) (GasPriceSource, error) {
+	switch feeMarket {
+	case "":
+		return nil, nil
+	case FeeMarketSkip:
+		return &FeeMarketSource{query: query}, nil
+	case FeeMarketOsmosis:
+		return &OsmosisSource{query: query}, nil
+	default:
+		return nil, fmt.Errorf("unsupported fee market: %s", feeMarket)
+	}
+}
+
+// FeeMarketSource queries the gas price of the Skip feemarket module
+type FeeMarketSource struct {
+	query ABCIQuerier
+}
+
+// GasPrice returns the minimum gas price of the denom
+func (s *FeeMarketSource) GasPrice(ctx context.Context, denom string) (sdkmath.LegacyDec, error) {
+	req := protowire.AppendTag(nil, gasPriceDenomFieldNum, protowire.BytesType)
+	req = protowire.AppendString(req, denom)
+
+	res, err := s.query(ctx, feeMarketGasPricePath, req)
+	if err != nil {
+		return sdkmath.LegacyDec{}, fmt.Errorf("failed to query feemarket gas price: %w", err)
+	}
+
+	coin, err := decodeField(res, gasPriceFieldNum)
+	if err != nil {
+		return sdkmath.LegacyDec{}, fmt.Errorf("failed to decode feemarket gas price: %w", err)
+	}
+
+	priceDenom, err := decodeField(coin, decCoinDenomFieldNum)
+	if err != nil {
+		return sdkmath.LegacyDec{}, fmt.Errorf("failed to decode feemarket gas price denom: %w", err)
+	}
+	if string(priceDenom) != denom {
+		return sdkmath.LegacyDec{}, fmt.Errorf("feemarket returned gas price in %s, expected %s", priceDenom, denom)
+	}
+
+	amount, err := decodeField(coin, decCoinAmountFieldNum)
+	if err != nil {
+		return sdkmath.LegacyDec{}, fmt.Errorf("failed to decode feemarket gas price amount: %w", err)
+	}
+
+	return decodeDec(amount)
+}
+
+// OsmosisSource queries the EIP-1559 base fee of Osmosis, which is priced in the fee token
+type OsmosisSource struct {
+	query ABCIQuerier
+}
+
+// GasPrice returns the base fee, the denom is not part of the query
+func (s *OsmosisSource) GasPrice(ctx context.Context, _ string) (sdkmath.LegacyDec, error) {
+	res, err := s.query(ctx, osmosisEipBaseFeePath, nil)
+	if err != nil {
+		return sdkmath.LegacyDec{}, fmt.Errorf("failed to query osmosis base fee: %w", err)
+	}
+
+	baseFee, err := decodeField(res, osmosisBaseFeeFieldNum)
+	if err != nil {
+		return sdkmath.LegacyDec{}, fmt.Errorf("failed to decode osmosis base fee: %w", err)
+	}
+
+	return decodeDec(baseFee)
+}
+
+// decodeField returns the last occurrence of a length delimited field of a protobuf message
+func decodeField(msg []byte, field protowire.Number) ([]byte, error) {
+	var (
+		value []byte
+		found bool
+	)
+
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		msg = msg[n:]
+
+		if num == field && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(msg)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			value, found = v, true
+			msg = msg[n:]
+			continue
+		}
+
+		n = protowire.ConsumeFieldValue(num, typ, msg)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		msg = msg[n:]
+	}
+
+	if !found {
+		return nil, fmt.Errorf("field %d not found", field)
+	}
+
+	return value, nil
+}
+
+// decodeDec decodes a LegacyDec encoded as its 18 decimal integer representation
+func decodeDec(bz []byte) (sdkmath.LegacyDec, error) {
+	var dec sdkmath.LegacyDec
+	if err := dec.Unmarshal(bz); err != nil {
+		return sdkmath.LegacyDec{}, err
+	}
+	return dec, nil
+}
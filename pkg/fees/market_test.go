@@ -0,0 +1,103 @@
+package fees
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// fakeChain answers ABCI queries with a fixed response for a path
+type fakeChain struct {
+	path     string
+	response []byte
+	request  []byte
+}
+
+func (f *fakeChain) query(_ context.Context, path string, data []byte) ([]byte, error) {
+	if path != f.path {
+		return nil, errors.New("unknown query path")
+	}
+	f.request = data
+	return f.response, nil
+}
+
+func appendString(msg []byte, field protowire.Number, value []byte) []byte {
+	msg = protowire.AppendTag(msg, field, protowire.BytesType)
+	return protowire.AppendBytes(msg, value)
+}
+
+func encodeDec(t *testing.T, value string) []byte {
+	t.Helper()
+
+	bz, err := sdkmath.LegacyMustNewDecFromStr(value).Marshal()
+	require.NoError(t, err)
+	return bz
+}
+
+func TestFeeMarketSource(t *testing.T) {
+	coin := appendString(nil, decCoinDenomFieldNum, []byte("untrn"))
+	coin = appendString(coin, decCoinAmountFieldNum, encodeDec(t, "0.0053"))
+
+	chain := &fakeChain{
+		path:     feeMarketGasPricePath,
+		response: appendString(nil, gasPriceFieldNum, coin),
+	}
+
+	source, err := NewGasPriceSource(FeeMarketSkip, chain.query)
+	require.NoError(t, err)
+
+	price, err := source.GasPrice(context.Background(), "untrn")
+	require.NoError(t, err)
+	assert.Equal(t, sdkmath.LegacyMustNewDecFromStr("0.0053"), price)
+	assert.Equal(t, appendString(nil, gasPriceDenomFieldNum, []byte("untrn")), chain.request)
+
+	// The price must be in the requested denom
+	_, err = source.GasPrice(context.Background(), "uatom")
+	assert.Error(t, err)
+}
+
+func TestOsmosisSource(t *testing.T) {
+	chain := &fakeChain{
+		path:     osmosisEipBaseFeePath,
+		response: appendString(nil, osmosisBaseFeeFieldNum, encodeDec(t, "0.0025")),
+	}
+
+	source, err := NewGasPriceSource(FeeMarketOsmosis, chain.query)
+	require.NoError(t, err)
+
+	price, err := source.GasPrice(context.Background(), "uosmo")
+	require.NoError(t, err)
+	assert.Equal(t, sdkmath.LegacyMustNewDecFromStr("0.0025"), price)
+}
+
+func TestNewGasPriceSource(t *testing.T) {
+	source, err := NewGasPriceSource("", nil)
+	require.NoError(t, err)
+	assert.Nil(t, source)
+
+	_, err = NewGasPriceSource("eip1559", nil)
+	assert.Error(t, err)
+}
+
+func TestDecodeField(t *testing.T) {
+	msg := protowire.AppendTag(nil, 3, protowire.VarintType)
+	msg = protowire.AppendVarint(msg, 42)
+	msg = appendString(msg, 1, []byte("first"))
+	msg = appendString(msg, 1, []byte("last"))
+
+	value, err := decodeField(msg, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("last"), value)
+
+	_, err = decodeField(msg, 2)
+	assert.Error(t, err)
+
+	_, err = decodeField([]byte{0xff}, 1)
+	assert.Error(t, err)
+}
@@ -12,8 +12,8 @@ import (
 // TransferProvider defines the interface for IBC transfers between chains
 type TransferProvider interface {
 	// Transfer initiates an IBC transfer between chains.
-	// If the WaitForCompletion flag is set to true in the request, this method will block
-	// until the transfer is completed or the timeout is reached.
+	// It blocks until the packet is acknowledged or times out, or the completion timeout is
+	// reached. Error acknowledgements, timeouts and incomplete transfers return an error.
 	Transfer(ctx context.Context, request *TransferRequest) (*TransferResult, error)
 
 	// CreateTransferMsg creates an IBC transfer message
@@ -36,14 +36,19 @@ type TransferRequest struct {
 	RecvDenom         string        // Denom of the token to receive
 	Timeout           uint64        // Timeout in blocks
 	Fee               sdk.Coins     // Optional fee for the transfer
-	CompletionTimeout time.Duration // Maximum time to wait for transfer completion
+	CompletionTimeout time.Duration // Maximum time to wait for transfer completion, DefaultCompletionTimeout when zero
 }
 
 // TransferResult contains the result of a transfer operation
 type TransferResult struct {
 	SourceTxHash   string                 // Hash of the source chain transaction
-	DestTxHash     string                 // Hash of the destination chain transaction if available
+	DestTxHash     string                 // Hash of the destination chain transaction if available, empty for forwarded or routed transfers
+	FirstHopTxHash string                 // Hash of the transaction receiving the packet on the first chain it was sent to
 	Error          error                  // Error if transfer failed
 	SourceResponse *cosmosclient.Response // Source chain response
 	DestResponse   *cosmosclient.Response // Destination chain response (if available)
+	Packet         *Packet                // Packet sent by the source chain transaction
+	Status         TransferStatus         // Final status of the packet
+	Ack            string                 // Acknowledgement written on the destination chain
+	Refunded       bool                   // Whether the funds were returned on the source chain
 }
@@ -0,0 +1,332 @@
+package ibc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/margined-protocol/locust-core/pkg/connection"
+	"go.uber.org/zap"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+)
+
+const (
+	// DefaultCompletionTimeout is how long a transfer is tracked when the request sets no
+	// completion timeout
+	DefaultCompletionTimeout = 6 * time.Minute
+	// DefaultPacketPollInterval is the interval between searches for the packet on each chain
+	DefaultPacketPollInterval = 5 * time.Second
+)
+
+var (
+	// ErrNoSendPacket is returned when a transfer tx sent no IBC packet
+	ErrNoSendPacket = errors.New("no send_packet event in tx")
+	// ErrPacketTimedOut is returned when a packet timed out, its funds are refunded on the source chain
+	ErrPacketTimedOut = errors.New("IBC packet timed out")
+	// ErrPacketErrorAck is returned when a packet was acknowledged with an error, its funds are
+	// refunded on the source chain once the acknowledgement is relayed
+	ErrPacketErrorAck = errors.New("IBC packet acknowledged with an error")
+	// ErrCompletionTimeout is returned when a packet was neither acknowledged nor timed out
+	// before the completion timeout, the transfer may still complete
+	ErrCompletionTimeout = errors.New("IBC transfer not completed before the completion timeout")
+)
+
+// TransferStatus is the state of the packet of a transfer
+type TransferStatus string
+
+const (
+	// TransferStatusPending packets have been sent but not received
+	TransferStatusPending TransferStatus = "pending"
+	// TransferStatusReceived packets have been received without an acknowledgement being
+	// written yet, e.g. while a forwarded transfer completes its next hop
+	TransferStatusReceived TransferStatus = "received"
+	// TransferStatusCompleted packets have been received with a successful acknowledgement
+	TransferStatusCompleted TransferStatus = "completed"
+	// TransferStatusFailed packets have been acknowledged with an error
+	TransferStatusFailed TransferStatus = "failed"
+	// TransferStatusTimedOut packets have timed out on the source chain
+	TransferStatusTimedOut TransferStatus = "timed_out"
+)
+
+// Packet identifies the IBC packet sent by a transfer
+type Packet struct {
+	Sequence      uint64
+	SourcePort    string
+	SourceChannel string
+	DestPort      string
+	DestChannel   string
+}
+
+// SentPacket returns the packet in the send_packet event of a tx
+func SentPacket(events []abci.Event) (*Packet, error) {
+	packets, err := SentPackets(events)
+	if err != nil {
+		return nil, err
+	}
+	return packets[0], nil
+}
+
+// SentPackets returns the packets in the send_packet events of a tx, in the order they were sent
+func SentPackets(events []abci.Event) ([]*Packet, error) {
+	var packets []*Packet
+	for _, event := range events {
+		if event.Type != "send_packet" {
+			continue
+		}
+
+		attributes := eventAttributes(event)
+		sequence, err := strconv.ParseUint(attributes["packet_sequence"], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid packet sequence %q: %w", attributes["packet_sequence"], err)
+		}
+
+		packets = append(packets, &Packet{
+			Sequence:      sequence,
+			SourcePort:    attributes["packet_src_port"],
+			SourceChannel: attributes["packet_src_channel"],
+			DestPort:      attributes["packet_dst_port"],
+			DestChannel:   attributes["packet_dst_channel"],
+		})
+	}
+
+	if len(packets) == 0 {
+		return nil, ErrNoSendPacket
+	}
+
+	return packets, nil
+}
+
+func eventAttributes(event abci.Event) map[string]string {
+	attributes := make(map[string]string, len(event.Attributes))
+	for _, attribute := range event.Attributes {
+		attributes[attribute.Key] = attribute.Value
+	}
+	return attributes
+}
+
+// isErrorAck reports whether an acknowledgement is an error acknowledgement
+func isErrorAck(ack string) bool {
+	var decoded struct {
+		Error *string `json:"error"`
+	}
+	return json.Unmarshal([]byte(ack), &decoded) == nil && decoded.Error != nil
+}
+
+// packetState is what has been observed of a packet on both chains
+type packetState struct {
+	// RecvTxHash is the tx that received the packet on the destination chain
+	RecvTxHash string
+	// Ack is the acknowledgement written on the destination chain
+	Ack        string
+	AckWritten bool
+	// AckRelayed is set once the acknowledgement has been relayed back to the source chain
+	AckRelayed bool
+	// TimedOut is set once the packet timed out on the source chain
+	TimedOut bool
+}
+
+func (s *packetState) status() TransferStatus {
+	switch {
+	case s.TimedOut:
+		return TransferStatusTimedOut
+	case s.AckWritten && isErrorAck(s.Ack):
+		return TransferStatusFailed
+	case s.AckWritten:
+		return TransferStatusCompleted
+	case s.RecvTxHash != "":
+		return TransferStatusReceived
+	default:
+		return TransferStatusPending
+	}
+}
+
+// refunded reports whether the funds of the packet have been returned on the source chain
+func (s *packetState) refunded() bool {
+	return s.TimedOut || (s.status() == TransferStatusFailed && s.AckRelayed)
+}
+
+// done reports whether the packet reached a final state, a failed packet is tracked until
+// its refund is observed
+func (s *packetState) done() bool {
+	switch s.status() {
+	case TransferStatusCompleted, TransferStatusTimedOut:
+		return true
+	case TransferStatusFailed:
+		return s.AckRelayed
+	default:
+		return false
+	}
+}
+
+// txSearch returns the txs matching a tx_search query on a chain
+type txSearch func(ctx context.Context, query string) ([]*coretypes.ResultTx, error)
+
+// PacketTracker follows packets across the source and destination chains
+type PacketTracker struct {
+	logger       *zap.Logger
+	source       txSearch
+	dest         txSearch
+	pollInterval time.Duration
+}
+
+// NewPacketTracker creates a tracker for packets sent from the source to the destination chain,
+// searching both chains through the registry's RPC clients
+func NewPacketTracker(logger *zap.Logger, clientRegistry *connection.ClientRegistry, sourceChainID, destChainID string) (*PacketTracker, error) {
+	source, err := registryTxSearch(clientRegistry, sourceChainID)
+	if err != nil {
+		return nil, err
+	}
+
+	dest, err := registryTxSearch(clientRegistry, destChainID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PacketTracker{
+		logger:       logger,
+		source:       source,
+		dest:         dest,
+		pollInterval: DefaultPacketPollInterval,
+	}, nil
+}
+
+// registryTxSearch searches the txs of a chain through its current RPC endpoint
+func registryTxSearch(clientRegistry *connection.ClientRegistry, chainID string) (txSearch, error) {
+	rpcClient, err := clientRegistry.GetRPCClient(chainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get RPC client for %s: %w", chainID, err)
+	}
+
+	return func(ctx context.Context, query string) ([]*coretypes.ResultTx, error) {
+		client := rpcClient.GetClient()
+		if client == nil {
+			return nil, fmt.Errorf("no RPC endpoint available for %s", chainID)
+		}
+
+		res, err := client.TxSearch(ctx, query, false, nil, nil, "asc")
+		if err != nil {
+			return nil, err
+		}
+		return res.Txs, nil
+	}, nil
+}
+
+// WaitForCompletion tracks the packet until it reaches a final state and returns nil once it
+// was received with a successful acknowledgement. A packet that timed out or was acknowledged
+// with an error returns ErrPacketTimedOut or ErrPacketErrorAck, and ErrCompletionTimeout wraps
+// the context error when the context is done first.
+func (t *PacketTracker) WaitForCompletion(ctx context.Context, packet *Packet) error {
+	state, err := t.track(ctx, packet)
+
+	switch status := state.status(); {
+	case status == TransferStatusTimedOut:
+		return fmt.Errorf("%w: sequence %d on %s", ErrPacketTimedOut, packet.Sequence, packet.SourceChannel)
+	case status == TransferStatusFailed:
+		return fmt.Errorf("%w: sequence %d on %s: %s", ErrPacketErrorAck, packet.Sequence, packet.SourceChannel, state.Ack)
+	case err != nil:
+		return fmt.Errorf("%w: sequence %d on %s is %s: %w", ErrCompletionTimeout, packet.Sequence, packet.SourceChannel, status, err)
+	default:
+		return nil
+	}
+}
+
+// track polls both chains until the packet reaches a final state, returning the last state
+// observed with the context error when it is cancelled first
+func (t *PacketTracker) track(ctx context.Context, packet *Packet) (*packetState, error) {
+	state := &packetState{}
+
+	ticker := time.NewTicker(t.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		t.poll(ctx, packet, state)
+		if state.done() {
+			return state, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return state, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll searches for the events of the packet not observed yet
+func (t *PacketTracker) poll(ctx context.Context, packet *Packet, state *packetState) {
+	if state.RecvTxHash == "" {
+		query := fmt.Sprintf("recv_packet.packet_sequence='%d' AND recv_packet.packet_dst_channel='%s' AND recv_packet.packet_dst_port='%s'",
+			packet.Sequence, packet.DestChannel, packet.DestPort)
+		if tx := t.search(ctx, t.dest, query); tx != nil {
+			state.RecvTxHash = fmt.Sprintf("%X", tx.Hash)
+			t.logger.Info("IBC packet received",
+				zap.Uint64("sequence", packet.Sequence),
+				zap.String("tx_hash", state.RecvTxHash),
+			)
+		}
+	}
+
+	// Forwarded and other async packets write their acknowledgement in a later tx
+	if !state.AckWritten {
+		query := fmt.Sprintf("write_acknowledgement.packet_sequence='%d' AND write_acknowledgement.packet_dst_channel='%s'",
+			packet.Sequence, packet.DestChannel)
+		if tx := t.search(ctx, t.dest, query); tx != nil {
+			state.Ack, state.AckWritten = packetAcknowledgement(tx.TxResult.Events, packet)
+		}
+	}
+
+	// A received packet can no longer time out
+	if state.RecvTxHash == "" && !state.AckWritten && !state.TimedOut {
+		query := fmt.Sprintf("timeout_packet.packet_sequence='%d' AND timeout_packet.packet_src_channel='%s'",
+			packet.Sequence, packet.SourceChannel)
+		if tx := t.search(ctx, t.source, query); tx != nil {
+			state.TimedOut = true
+			t.logger.Warn("IBC packet timed out",
+				zap.Uint64("sequence", packet.Sequence),
+				zap.String("tx_hash", fmt.Sprintf("%X", tx.Hash)),
+			)
+		}
+	}
+
+	if state.status() == TransferStatusFailed && !state.AckRelayed {
+		query := fmt.Sprintf("acknowledge_packet.packet_sequence='%d' AND acknowledge_packet.packet_src_channel='%s'",
+			packet.Sequence, packet.SourceChannel)
+		if tx := t.search(ctx, t.source, query); tx != nil {
+			state.AckRelayed = true
+		}
+	}
+}
+
+// search returns the first tx matching the query, failed searches are retried on the next poll
+func (t *PacketTracker) search(ctx context.Context, search txSearch, query string) *coretypes.ResultTx {
+	txs, err := search(ctx, query)
+	if err != nil {
+		t.logger.Debug("Failed to search for IBC packet", zap.String("query", query), zap.Error(err))
+		return nil
+	}
+	if len(txs) == 0 {
+		return nil
+	}
+	return txs[0]
+}
+
+// packetAcknowledgement returns the acknowledgement written for the packet in the events
+func packetAcknowledgement(events []abci.Event, packet *Packet) (string, bool) {
+	sequence := strconv.FormatUint(packet.Sequence, 10)
+	for _, event := range events {
+		if event.Type != "write_acknowledgement" {
+			continue
+		}
+
+		attributes := eventAttributes(event)
+		if attributes["packet_sequence"] == sequence && attributes["packet_dst_channel"] == packet.DestChannel {
+			return attributes["packet_ack"], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,251 @@
+package ibc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+)
+
+func packetEvent(eventType string, attributes ...string) abci.Event {
+	e := abci.Event{Type: eventType}
+	for i := 0; i+1 < len(attributes); i += 2 {
+		e.Attributes = append(e.Attributes, abci.EventAttribute{Key: attributes[i], Value: attributes[i+1]})
+	}
+	return e
+}
+
+func TestSentPacket(t *testing.T) {
+	packet, err := SentPacket([]abci.Event{
+		packetEvent("transfer", "recipient", "neutron1abc"),
+		packetEvent("send_packet",
+			"packet_sequence", "42",
+			"packet_src_port", "transfer",
+			"packet_src_channel", "channel-10",
+			"packet_dst_port", "transfer",
+			"packet_dst_channel", "channel-874",
+		),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &Packet{
+		Sequence:      42,
+		SourcePort:    "transfer",
+		SourceChannel: "channel-10",
+		DestPort:      "transfer",
+		DestChannel:   "channel-874",
+	}, packet)
+
+	_, err = SentPacket([]abci.Event{packetEvent("transfer")})
+	assert.ErrorIs(t, err, ErrNoSendPacket)
+
+	_, err = SentPacket([]abci.Event{packetEvent("send_packet", "packet_sequence", "abc")})
+	assert.ErrorContains(t, err, "invalid packet sequence")
+}
+
+func TestSentPackets(t *testing.T) {
+	packets, err := SentPackets([]abci.Event{
+		packetEvent("send_packet", "packet_sequence", "42", "packet_src_channel", "channel-10", "packet_dst_channel", "channel-874"),
+		packetEvent("transfer", "recipient", "neutron1abc"),
+		packetEvent("send_packet", "packet_sequence", "43", "packet_src_channel", "channel-10", "packet_dst_channel", "channel-874"),
+	})
+	require.NoError(t, err)
+	require.Len(t, packets, 2)
+	assert.Equal(t, uint64(42), packets[0].Sequence)
+	assert.Equal(t, uint64(43), packets[1].Sequence)
+
+	_, err = SentPackets(nil)
+	assert.ErrorIs(t, err, ErrNoSendPacket)
+}
+
+// fakeChain answers tx searches with the txs registered for the event type of the query
+type fakeChain struct {
+	mu  sync.Mutex
+	txs map[string]*coretypes.ResultTx
+	err error
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{txs: make(map[string]*coretypes.ResultTx)}
+}
+
+func (c *fakeChain) add(eventType string, hash string, events ...abci.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.txs[eventType] = &coretypes.ResultTx{Hash: []byte(hash), TxResult: abci.ExecTxResult{Events: events}}
+}
+
+func (c *fakeChain) search(_ context.Context, query string) ([]*coretypes.ResultTx, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	eventType, _, _ := strings.Cut(query, ".")
+	if tx, ok := c.txs[eventType]; ok {
+		return []*coretypes.ResultTx{tx}, nil
+	}
+	return nil, nil
+}
+
+func ackEvent(ack string) abci.Event {
+	return packetEvent("write_acknowledgement",
+		"packet_sequence", "42",
+		"packet_dst_channel", "channel-874",
+		"packet_ack", ack,
+	)
+}
+
+func TestPacketTracker(t *testing.T) {
+	packet := &Packet{Sequence: 42, SourcePort: "transfer", SourceChannel: "channel-10", DestPort: "transfer", DestChannel: "channel-874"}
+
+	tests := []struct {
+		name         string
+		setup        func(source, dest *fakeChain)
+		wantStatus   TransferStatus
+		wantRecv     string
+		wantRefunded bool
+		wantErr      error
+		wantWaitErr  error
+	}{
+		{
+			name: "completed",
+			setup: func(_, dest *fakeChain) {
+				dest.add("recv_packet", "R", ackEvent(`{"result":"AQ=="}`))
+				dest.add("write_acknowledgement", "R", ackEvent(`{"result":"AQ=="}`))
+			},
+			wantStatus: TransferStatusCompleted,
+			wantRecv:   "52",
+		},
+		{
+			name: "error acknowledgement refunded",
+			setup: func(source, dest *fakeChain) {
+				dest.add("recv_packet", "R")
+				dest.add("write_acknowledgement", "A", ackEvent(`{"error":"ABCI code: 1: error handling packet"}`))
+				source.add("acknowledge_packet", "S")
+			},
+			wantStatus:   TransferStatusFailed,
+			wantRecv:     "52",
+			wantRefunded: true,
+			wantWaitErr:  ErrPacketErrorAck,
+		},
+		{
+			name: "error acknowledgement not relayed",
+			setup: func(_, dest *fakeChain) {
+				dest.add("recv_packet", "R")
+				dest.add("write_acknowledgement", "A", ackEvent(`{"error":"ABCI code: 1: error handling packet"}`))
+			},
+			wantStatus:  TransferStatusFailed,
+			wantRecv:    "52",
+			wantErr:     context.DeadlineExceeded,
+			wantWaitErr: ErrPacketErrorAck,
+		},
+		{
+			name: "timed out",
+			setup: func(source, _ *fakeChain) {
+				source.add("timeout_packet", "T")
+			},
+			wantStatus:   TransferStatusTimedOut,
+			wantRefunded: true,
+			wantWaitErr:  ErrPacketTimedOut,
+		},
+		{
+			name: "received awaiting acknowledgement",
+			setup: func(_, dest *fakeChain) {
+				dest.add("recv_packet", "R")
+			},
+			wantStatus:  TransferStatusReceived,
+			wantRecv:    "52",
+			wantErr:     context.DeadlineExceeded,
+			wantWaitErr: ErrCompletionTimeout,
+		},
+		{
+			name: "pending while searches fail",
+			setup: func(source, dest *fakeChain) {
+				source.err = errors.New("connection refused")
+				dest.err = errors.New("connection refused")
+			},
+			wantStatus:  TransferStatusPending,
+			wantErr:     context.DeadlineExceeded,
+			wantWaitErr: ErrCompletionTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, dest := newFakeChain(), newFakeChain()
+			tt.setup(source, dest)
+
+			tracker := &PacketTracker{
+				logger:       zap.NewNop(),
+				source:       source.search,
+				dest:         dest.search,
+				pollInterval: time.Millisecond,
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			state, err := tracker.track(ctx, packet)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantStatus, state.status())
+			assert.Equal(t, tt.wantRecv, state.RecvTxHash)
+			assert.Equal(t, tt.wantRefunded, state.refunded())
+
+			waitCtx, waitCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer waitCancel()
+
+			err = tracker.WaitForCompletion(waitCtx, packet)
+			if tt.wantWaitErr != nil {
+				assert.ErrorIs(t, err, tt.wantWaitErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPacketTrackerWaitsForReceipt(t *testing.T) {
+	packet := &Packet{Sequence: 42, SourceChannel: "channel-10", DestPort: "transfer", DestChannel: "channel-874"}
+	source, dest := newFakeChain(), newFakeChain()
+
+	tracker := &PacketTracker{
+		logger:       zap.NewNop(),
+		source:       source.search,
+		dest:         dest.search,
+		pollInterval: time.Millisecond,
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		dest.add("recv_packet", "R", ackEvent(`{"result":"AQ=="}`))
+		time.Sleep(10 * time.Millisecond)
+		dest.add("write_acknowledgement", "R", ackEvent(`{"result":"AQ=="}`))
+	}()
+
+	state, err := tracker.track(context.Background(), packet)
+	require.NoError(t, err)
+	assert.Equal(t, TransferStatusCompleted, state.status())
+	assert.Equal(t, `{"result":"AQ=="}`, state.Ack)
+}
+
+func TestIsErrorAck(t *testing.T) {
+	assert.False(t, isErrorAck(`{"result":"AQ=="}`))
+	assert.True(t, isErrorAck(`{"error":"ABCI code: 6: error handling packet"}`))
+	assert.False(t, isErrorAck(""))
+}
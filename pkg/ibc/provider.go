@@ -2,19 +2,18 @@ package ibc
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"runtime"
-	"time"
 
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
 	"github.com/margined-protocol/locust-core/pkg/connection"
-	"github.com/margined-protocol/locust-core/pkg/utils"
 	"go.uber.org/zap"
 
 	sdkmath "cosmossdk.io/math"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
-
-	rpchttp "github.com/cometbft/cometbft/rpc/client/http"
 )
 
 // DefaultTransferProvider implements the TransferProvider interface
@@ -53,125 +52,34 @@ func NewTransferProvider(
 	return defaultProvider
 }
 
-// waitForReceivePacket waits for the IBC receive_packet event
-func (p *DefaultTransferProvider) waitForReceivePacket(
-	ctx context.Context,
-	request *TransferRequest,
-) error {
-	// Create a channel for the balance check result
-	balanceCh := make(chan error, 1)
-
-	// Start balance polling in a separate goroutine
-	go func() {
-		// Get initial balance
-		initialBalance, err := p.queryBalance(ctx, request.DestinationChain, request.Receiver, request.RecvDenom)
-		if err != nil {
-			p.logger.Warn("Failed to query initial balance",
-				zap.Error(err),
-				zap.String("chain", request.DestinationChain),
-				zap.String("receiver", request.Receiver))
-			balanceCh <- err
-			return
-		}
-
-		// Ensure we have a valid initialBalance with Amount initialized
-		if initialBalance.Amount.IsNil() {
-			initialBalance = sdk.NewCoin(request.RecvDenom, sdkmath.ZeroInt())
-		}
-
-		// Poll balance every 10 seconds
-		ticker := time.NewTicker(10 * time.Second)
-		defer ticker.Stop()
-
-		expectedAmount := request.Amount.Amount
-		for {
-			select {
-			case <-ctx.Done():
-				balanceCh <- ctx.Err()
-				return
-			case <-ticker.C:
-				currentBalance, err := p.queryBalance(ctx, request.DestinationChain, request.Receiver, request.RecvDenom)
-				if err != nil {
-					continue // Skip this iteration if query fails
-				}
-
-				// Ensure we have a valid currentBalance with Amount initialized
-				if currentBalance.Amount.IsNil() {
-					currentBalance = sdk.NewCoin(request.RecvDenom, sdkmath.ZeroInt())
-				}
-
-				// Calculate balance difference
-				diff := currentBalance.Amount.Sub(initialBalance.Amount)
-
-				// Check if we received at least 95% of expected amount (accounting for fees)
-				minExpectedAmount := expectedAmount.Mul(sdkmath.NewInt(95)).Quo(sdkmath.NewInt(100))
-				if diff.GTE(minExpectedAmount) {
-					p.logger.Info("Detected balance increase",
-						zap.String("initial", initialBalance.String()),
-						zap.String("current", currentBalance.String()),
-						zap.String("difference", diff.String()),
-						zap.String("expected", expectedAmount.String()))
-					balanceCh <- nil
-					return
-				}
-			}
-		}
-	}()
+// sentPacket returns the packet sent by the source tx, looking the tx up when the response
+// carries no events
+func (p *DefaultTransferProvider) sentPacket(ctx context.Context, chainID string, response *cosmosclient.Response) (*Packet, error) {
+	if packet, err := SentPacket(response.Events); !errors.Is(err, ErrNoSendPacket) {
+		return packet, err
+	}
 
-	// Get the destination chain's RPC endpoint
-	destClientInstance, err := p.clientRegistry.GetClient(request.DestinationChain, false)
+	rpcClient, err := p.clientRegistry.GetRPCClient(chainID)
 	if err != nil {
-		return fmt.Errorf("failed to get destination chain client: %w", err)
+		return nil, fmt.Errorf("failed to get RPC client for %s: %w", chainID, err)
 	}
 
-	// Create websocket client
-	var wsClient *rpchttp.HTTP
-
-	entry, err := p.clientRegistry.GetRPCClient(request.DestinationChain)
-	if err == nil && entry != nil {
-		// Use the multi-endpoint RPC client's current endpoint
-		currentEndpoint, _ := entry.GetCurrentEndpoint()
-		wsClient, _, err = connection.InitRPCClient(p.logger, currentEndpoint.Address, currentEndpoint.WebsocketPath, currentEndpoint.APIKey)
-		if err != nil {
-			return fmt.Errorf("failed to create websocket client: %w", err)
-		}
-	} else {
-		// Fallback to the first RPC endpoint
-		if len(destClientInstance.Chain.RPCEndpoints) == 0 {
-			return fmt.Errorf("no RPC endpoints configured for chain %s", request.DestinationChain)
-		}
-		rpcAddress := destClientInstance.Chain.RPCEndpoints[0].Address
-		apiKey := destClientInstance.Chain.RPCEndpoints[0].APIToken
+	client := rpcClient.GetClient()
+	if client == nil {
+		return nil, fmt.Errorf("no RPC endpoint available for %s", chainID)
+	}
 
-		wsClient, _, err = connection.InitRPCClient(p.logger, rpcAddress, "/websocket", apiKey)
-		if err != nil {
-			return fmt.Errorf("failed to create websocket client: %w", err)
-		}
+	hash, err := hex.DecodeString(response.TxHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tx hash %s: %w", response.TxHash, err)
 	}
-	defer func() {
-		if err := wsClient.Stop(); err != nil {
-			p.logger.Error("Failed to stop websocket client", zap.Error(err))
-		}
-	}()
-
-	// Subscribe to receive_packet events
-	query := fmt.Sprintf("transfer.recipient = '%s'", request.Receiver)
-	eventCh := utils.CreateEventChannel(ctx, p.logger, wsClient, query)
-
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case event := <-eventCh:
-		p.logger.Info("Received IBC packet",
-			zap.Any("event", event.Query)) // Note: don't print the whole event, it's too big
-		return nil
-	case err := <-balanceCh:
-		if err != nil {
-			return fmt.Errorf("balance polling failed: %w", err)
-		}
-		p.logger.Info("Transfer confirmed via balance change")
-		return nil
+
+	tx, err := client.Tx(ctx, hash, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tx %s: %w", response.TxHash, err)
 	}
+
+	return SentPacket(tx.TxResult.Events)
 }
 
 // CreateTransferMsg prepares an IBC transfer message for the given request
@@ -187,10 +95,15 @@ func (p *DefaultTransferProvider) CreateTransferMsg(ctx context.Context, request
 		request.Receiver = receiver
 	}
 
-	// Get the IBC connection for this transfer
+	// Get the IBC connection for this transfer, chains without one follow the shortest route
+	// over the registered channels
 	conn, err := p.ibcRegistry.GetConnection(request.SourceChain, request.DestinationChain)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get IBC connection: %w", err)
+		route, routeErr := p.ibcRegistry.ShortestRoute(request.SourceChain, request.DestinationChain)
+		if routeErr != nil {
+			return nil, fmt.Errorf("failed to get IBC connection: %w", errors.Join(err, routeErr))
+		}
+		return p.createRouteTransferMsg(ctx, request, route)
 	}
 
 	chainID := request.SourceChain
@@ -236,6 +149,70 @@ func (p *DefaultTransferProvider) CreateTransferMsg(ctx context.Context, request
 	return transferMsg, nil
 }
 
+// createRouteTransferMsg prepares a transfer along the route, forwarding through the signer's
+// address on every intermediate chain. The receive denom is set from the route when the
+// request has none.
+func (p *DefaultTransferProvider) createRouteTransferMsg(ctx context.Context, request *TransferRequest, route *Route) (sdk.Msg, error) {
+	receivers := make([]string, len(route.Hops))
+	for i, hop := range route.Hops[:len(route.Hops)-1] {
+		_, receiver, err := p.clientRegistry.GetSignerAccountAndAddress(p.signerAccount, hop.DestChainID)
+		if err != nil {
+			p.logger.Error("Failed to get address for chain", zap.String("chain", hop.DestChainID), zap.Error(err))
+			return nil, err
+		}
+		receivers[i] = receiver
+	}
+	receivers[len(receivers)-1] = request.Receiver
+
+	if request.RecvDenom == "" {
+		recvDenom, err := route.ExpectedDenom(request.Amount.Denom)
+		if err != nil {
+			// Vouchers held as ibc/ hashes have no trace path to follow
+			p.logger.Warn("Failed to derive the receive denom", zap.String("denom", request.Amount.Denom), zap.Error(err))
+		} else {
+			request.RecvDenom = recvDenom
+		}
+	}
+
+	// The timeout height is checked against the chain receiving the packet
+	blockHeight, err := p.clientRegistry.GetHeight(ctx, route.Hops[0].DestChainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get first hop chain height: %w", err)
+	}
+
+	timeout := uint64(*blockHeight) + request.Timeout
+
+	p.logger.Info("Creating routed transfer message",
+		zap.Strings("route", route.ChainIDs()),
+		zap.String("recv_denom", request.RecvDenom),
+	)
+
+	transferMsg, err := CreateRouteTransfer(route, request.Amount, timeout, request.Sender, receivers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transfer message: %w", err)
+	}
+
+	p.logger.Info("Transfer message", zap.Any("message", transferMsg))
+	return transferMsg, nil
+}
+
+// packetDestination returns the chain receiving the packet sent on the source chain, the
+// first hop of a forwarded or routed transfer
+func (p *DefaultTransferProvider) packetDestination(sourceChainID, destChainID string) string {
+	if conn, err := p.ibcRegistry.GetConnection(sourceChainID, destChainID); err == nil {
+		if conn.Transfer.Forward != nil {
+			return conn.Transfer.Forward.ChainID
+		}
+		return destChainID
+	}
+
+	if route, err := p.ibcRegistry.ShortestRoute(sourceChainID, destChainID); err == nil {
+		return route.Hops[0].DestChainID
+	}
+
+	return destChainID
+}
+
 // ProcessTransferMsg sends the transfer message and waits for confirmation if needed
 func (p *DefaultTransferProvider) ProcessTransferMsg(ctx context.Context, request *TransferRequest, transferMsg sdk.Msg) (*TransferResult, error) {
 	result := TransferResult{}
@@ -252,22 +229,70 @@ func (p *DefaultTransferProvider) ProcessTransferMsg(ctx context.Context, reques
 		return &TransferResult{Error: err}, err
 	}
 
+	// Nothing was broadcast in a dry run
+	if response == nil || response.TxResponse == nil {
+		return &result, nil
+	}
+
 	// Update transfer data with source transaction result
 	result.SourceTxHash = response.TxHash
 	result.SourceResponse = response
 
-	// If requested, wait for the receive packet event
-	// Create a context with timeout if specified
-	var cancel context.CancelFunc
-	waitCtx, cancel := context.WithTimeout(ctx, 6*time.Minute)
-	defer cancel()
+	packet, err := p.sentPacket(ctx, request.SourceChain, response)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to find the sent packet: %w", err)
+		return &result, result.Error
+	}
+	result.Packet = packet
+
+	// The packet is received by the first hop of a forwarded or routed transfer
+	packetDestination := p.packetDestination(request.SourceChain, request.DestinationChain)
 
-	err = p.waitForReceivePacket(waitCtx, request)
+	tracker, err := NewPacketTracker(p.logger, p.clientRegistry, request.SourceChain, packetDestination)
 	if err != nil {
-		p.logger.Warn("Failed to wait for receive packet", zap.Error(err))
+		result.Error = err
+		return &result, err
 	}
 
-	return &result, nil
+	completionTimeout := request.CompletionTimeout
+	if completionTimeout <= 0 {
+		completionTimeout = DefaultCompletionTimeout
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, completionTimeout)
+	defer cancel()
+
+	// Only the first hop is tracked, its acknowledgement waits for the rest of the route
+	state, err := tracker.track(waitCtx, packet)
+	result.FirstHopTxHash = state.RecvTxHash
+	if packetDestination == request.DestinationChain {
+		result.DestTxHash = state.RecvTxHash
+	}
+	result.Status = state.status()
+	result.Ack = state.Ack
+	result.Refunded = state.refunded()
+
+	switch {
+	case result.Status == TransferStatusTimedOut:
+		result.Error = fmt.Errorf("%w: sequence %d on %s", ErrPacketTimedOut, packet.Sequence, packet.SourceChannel)
+	case result.Status == TransferStatusFailed:
+		result.Error = fmt.Errorf("%w: sequence %d on %s: %s", ErrPacketErrorAck, packet.Sequence, packet.SourceChannel, state.Ack)
+	case err != nil && ctx.Err() != nil:
+		result.Error = ctx.Err()
+	case err != nil:
+		result.Error = fmt.Errorf("%w: sequence %d on %s is %s after %s", ErrCompletionTimeout, packet.Sequence, packet.SourceChannel, result.Status, completionTimeout)
+	}
+
+	p.logger.Info("IBC transfer finished",
+		zap.Uint64("sequence", packet.Sequence),
+		zap.String("channel", packet.SourceChannel),
+		zap.String("status", string(result.Status)),
+		zap.String("dest_tx_hash", result.DestTxHash),
+		zap.String("first_hop_tx_hash", result.FirstHopTxHash),
+		zap.Bool("refunded", result.Refunded),
+	)
+
+	return &result, result.Error
 }
 
 // Transfer initiates an IBC transfer between chains
@@ -289,6 +314,13 @@ func (p *DefaultTransferProvider) Transfer(ctx context.Context, request *Transfe
 	}
 	p.logger.Info("Source balance", zap.Any("balance", sourceBalance))
 
+	// Create the transfer message, which sets the receiver and, for routed transfers, the
+	// receive denom
+	transferMsg, err := p.CreateTransferMsg(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get destination balance before transfer
 	destDenom := request.RecvDenom
 	if destDenom == "" {
@@ -307,12 +339,6 @@ func (p *DefaultTransferProvider) Transfer(ctx context.Context, request *Transfe
 	}
 	p.logger.Info("Destination balance", zap.Any("balance", destBalance))
 
-	// Create the transfer message
-	transferMsg, err := p.CreateTransferMsg(ctx, request)
-	if err != nil {
-		return nil, err
-	}
-
 	// Process the transfer message
 	return p.ProcessTransferMsg(ctx, request, transferMsg)
 }
@@ -8,6 +8,8 @@ import (
 type ConnectionRegistry struct {
 	// Map of source chain ID -> destination chain ID -> connection
 	connections map[string]map[string]*Connection
+	// Transfer channels routes are planned over, with the hops of the connections
+	channels []Channel
 }
 
 // NewConnectionRegistry creates a new registry
@@ -210,6 +212,7 @@ func DefaultConnectionRegistry() *ConnectionRegistry {
 				"neutron-1": umeeToNeutron,
 			},
 		},
+		channels: DefaultChannels(),
 	}
 }
 
@@ -0,0 +1,350 @@
+package ibc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	transfertypes "github.com/cosmos/ibc-go/v8/modules/apps/transfer/types"
+)
+
+// ErrNoRoute is returned when no channels connect two chains
+var ErrNoRoute = errors.New("no IBC route")
+
+// Channel is a transfer channel from one chain to another
+type Channel struct {
+	SourceChainID string // Chain ID of the sending chain
+	DestChainID   string // Chain ID of the receiving chain
+	Port          string // Port ID on the sending chain, "transfer" when empty
+	Channel       string // Channel ID on the sending chain
+	// CounterpartyPort and CounterpartyChannel identify the channel end on the receiving
+	// chain, the port is "transfer" when empty and the channel is inferred from the channel
+	// back when not set
+	CounterpartyPort    string
+	CounterpartyChannel string
+	// Cost is the relative cost of the hop, e.g. relayer fees or latency, 1 when zero
+	Cost uint64
+}
+
+func (c *Channel) port() string {
+	if c.Port == "" {
+		return transfertypes.PortID
+	}
+	return c.Port
+}
+
+func (c *Channel) counterpartyPort() string {
+	if c.CounterpartyPort == "" {
+		return transfertypes.PortID
+	}
+	return c.CounterpartyPort
+}
+
+func (c *Channel) cost() uint64 {
+	if c.Cost == 0 {
+		return 1
+	}
+	return c.Cost
+}
+
+func (c *Channel) key() string {
+	return c.SourceChainID + "/" + c.port() + "/" + c.Channel
+}
+
+// DefaultChannels are the transfer channels between the Noble hub and the chains it serves
+func DefaultChannels() []Channel {
+	hub := func(chainID, chainChannel, nobleChannel string) []Channel {
+		return []Channel{
+			{SourceChainID: chainID, DestChainID: "noble-1", Channel: chainChannel, CounterpartyChannel: nobleChannel},
+			{SourceChainID: "noble-1", DestChainID: chainID, Channel: nobleChannel, CounterpartyChannel: chainChannel},
+		}
+	}
+
+	var channels []Channel
+	channels = append(channels, hub("osmosis-1", "channel-750", "channel-1")...)
+	channels = append(channels, hub("neutron-1", "channel-30", "channel-18")...)
+	channels = append(channels, hub("dydx-mainnet-1", "channel-0", "channel-33")...)
+	channels = append(channels, hub("umee-1", "channel-120", "channel-51")...)
+	return channels
+}
+
+// RegisterChannel adds a transfer channel to the route graph
+func (r *ConnectionRegistry) RegisterChannel(channel Channel) error {
+	if channel.SourceChainID == "" || channel.DestChainID == "" || channel.Channel == "" {
+		return errors.New("channel source chain, destination chain and channel ID must be set")
+	}
+
+	for _, registered := range r.channels {
+		if registered.key() == channel.key() {
+			return fmt.Errorf("channel %s on %s already registered", channel.Channel, channel.SourceChainID)
+		}
+	}
+
+	r.channels = append(r.channels, channel)
+	return nil
+}
+
+// graph returns the channels between chains, the registered channels followed by the hops of
+// the registered connections that are not registered as channels
+func (r *ConnectionRegistry) graph() map[string][]Channel {
+	channels := append([]Channel(nil), r.channels...)
+	seen := make(map[string]bool, len(channels))
+	for _, channel := range channels {
+		seen[channel.key()] = true
+	}
+
+	add := func(channel Channel) {
+		if !seen[channel.key()] {
+			seen[channel.key()] = true
+			channels = append(channels, channel)
+		}
+	}
+
+	// Connections are visited in order so the graph is the same on every call
+	sources := make([]string, 0, len(r.connections))
+	for source := range r.connections {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	for _, source := range sources {
+		dests := make([]string, 0, len(r.connections[source]))
+		for dest := range r.connections[source] {
+			dests = append(dests, dest)
+		}
+		sort.Strings(dests)
+
+		for _, dest := range dests {
+			transfer := r.connections[source][dest].Transfer
+			if transfer.Forward == nil {
+				add(Channel{SourceChainID: transfer.SourceChainID, DestChainID: transfer.DestChainID, Port: transfer.Port, Channel: transfer.Channel})
+				continue
+			}
+
+			add(Channel{SourceChainID: transfer.SourceChainID, DestChainID: transfer.Forward.ChainID, Port: transfer.Port, Channel: transfer.Channel})
+			add(Channel{SourceChainID: transfer.Forward.ChainID, DestChainID: transfer.DestChainID, Port: transfer.Forward.Port, Channel: transfer.Forward.Channel})
+		}
+	}
+
+	graph := make(map[string][]Channel)
+	for _, channel := range channels {
+		graph[channel.SourceChainID] = append(graph[channel.SourceChainID], channel)
+	}
+	return graph
+}
+
+// Route is a path of transfer channels from one chain to another
+type Route struct {
+	Hops []Channel
+}
+
+// SourceChainID returns the chain the route starts from
+func (r *Route) SourceChainID() string {
+	return r.Hops[0].SourceChainID
+}
+
+// DestChainID returns the chain the route ends on
+func (r *Route) DestChainID() string {
+	return r.Hops[len(r.Hops)-1].DestChainID
+}
+
+// ChainIDs returns the chains along the route, including the source and destination
+func (r *Route) ChainIDs() []string {
+	chainIDs := []string{r.SourceChainID()}
+	for _, hop := range r.Hops {
+		chainIDs = append(chainIDs, hop.DestChainID)
+	}
+	return chainIDs
+}
+
+// Cost returns the total cost of the hops
+func (r *Route) Cost() uint64 {
+	var cost uint64
+	for i := range r.Hops {
+		cost += r.Hops[i].cost()
+	}
+	return cost
+}
+
+// ShortestRoute returns the route with the fewest hops between two chains, the cheapest of
+// them when several have as few hops
+func (r *ConnectionRegistry) ShortestRoute(sourceChainID, destChainID string) (*Route, error) {
+	return r.route(sourceChainID, destChainID, func(hops, cost uint64) [2]uint64 { return [2]uint64{hops, cost} })
+}
+
+// CheapestRoute returns the route with the lowest total cost between two chains, the one
+// with the fewest hops when several cost as little
+func (r *ConnectionRegistry) CheapestRoute(sourceChainID, destChainID string) (*Route, error) {
+	return r.route(sourceChainID, destChainID, func(hops, cost uint64) [2]uint64 { return [2]uint64{cost, hops} })
+}
+
+// route finds the route minimising the weight with Dijkstra's algorithm, channels are tried
+// in graph order so ties resolve the same way on every call
+func (r *ConnectionRegistry) route(sourceChainID, destChainID string, weight func(hops, cost uint64) [2]uint64) (*Route, error) {
+	if sourceChainID == destChainID {
+		return nil, fmt.Errorf("source and destination are both %s", sourceChainID)
+	}
+
+	graph := r.graph()
+
+	type node struct {
+		hops, cost uint64
+		via        *Channel
+	}
+	less := func(a, b [2]uint64) bool {
+		return a[0] < b[0] || (a[0] == b[0] && a[1] < b[1])
+	}
+
+	nodes := map[string]*node{sourceChainID: {}}
+	visited := make(map[string]bool)
+
+	for {
+		// Pick the closest unvisited chain, in chain ID order on ties
+		current := ""
+		for chainID, n := range nodes {
+			if visited[chainID] {
+				continue
+			}
+			if current == "" {
+				current = chainID
+				continue
+			}
+			best := nodes[current]
+			w, bw := weight(n.hops, n.cost), weight(best.hops, best.cost)
+			if less(w, bw) || (w == bw && chainID < current) {
+				current = chainID
+			}
+		}
+
+		if current == "" {
+			return nil, fmt.Errorf("%w from %s to %s", ErrNoRoute, sourceChainID, destChainID)
+		}
+		if current == destChainID {
+			break
+		}
+		visited[current] = true
+
+		from := nodes[current]
+		for i := range graph[current] {
+			channel := &graph[current][i]
+			if visited[channel.DestChainID] {
+				continue
+			}
+
+			candidate := &node{hops: from.hops + 1, cost: from.cost + channel.cost(), via: channel}
+			existing, ok := nodes[channel.DestChainID]
+			if !ok || less(weight(candidate.hops, candidate.cost), weight(existing.hops, existing.cost)) {
+				nodes[channel.DestChainID] = candidate
+			}
+		}
+	}
+
+	var hops []Channel
+	for chainID := destChainID; chainID != sourceChainID; {
+		hop := *nodes[chainID].via
+		hops = append([]Channel{hop}, hops...)
+		chainID = hop.SourceChainID
+	}
+
+	route := &Route{Hops: hops}
+	resolveCounterparties(route, graph)
+
+	return route, nil
+}
+
+// resolveCounterparties sets the counterparty of hops that have none from the only channel
+// back on the same port
+func resolveCounterparties(route *Route, graph map[string][]Channel) {
+	for i := range route.Hops {
+		hop := &route.Hops[i]
+		if hop.CounterpartyChannel != "" {
+			continue
+		}
+
+		var back []Channel
+		for _, channel := range graph[hop.DestChainID] {
+			if channel.DestChainID == hop.SourceChainID && channel.port() == hop.counterpartyPort() {
+				back = append(back, channel)
+			}
+		}
+		if len(back) == 1 {
+			hop.CounterpartyChannel = back[0].Channel
+		}
+	}
+}
+
+// ForwardMemo builds the packet forward middleware memo sending the transfer along the route.
+// Receivers are the addresses on the destination chain of each hop, the first being the
+// receiver of the transfer sent on the source chain. The memo is empty for a single hop.
+func (r *Route) ForwardMemo(receivers []string) (string, error) {
+	if len(receivers) != len(r.Hops) {
+		return "", fmt.Errorf("route has %d hops but %d receivers were given", len(r.Hops), len(receivers))
+	}
+	for i, receiver := range receivers {
+		if receiver == "" {
+			return "", fmt.Errorf("receiver on %s is empty", r.Hops[i].DestChainID)
+		}
+	}
+
+	if len(r.Hops) == 1 {
+		return "", nil
+	}
+
+	// Build the memo from the last hop back to the first forward
+	var forward *ForwardInfo
+	for i := len(r.Hops) - 1; i >= 1; i-- {
+		var next *ForwardNextInfo
+		if forward != nil {
+			next = &ForwardNextInfo{Forward: forward}
+		}
+		forward = &ForwardInfo{
+			Channel:  r.Hops[i].Channel,
+			Next:     next,
+			Port:     r.Hops[i].port(),
+			Receiver: receivers[i],
+		}
+	}
+
+	memoBytes, err := ForwardMemo{Forward: *forward}.MarshalJSON()
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal forward memo: %w", err)
+	}
+
+	return string(memoBytes), nil
+}
+
+// ExpectedDenom returns the denom the destination chain credits for a denom sent along the
+// route. The denom is given as held on the source chain, either a native denom or the full
+// trace of a voucher, e.g. "transfer/channel-30/uusdc". Vouchers returning to a chain they
+// came from are unwound to the denom held there.
+func (r *Route) ExpectedDenom(denom string) (string, error) {
+	if strings.HasPrefix(denom, transfertypes.DenomPrefix+"/") {
+		return "", fmt.Errorf("denom %s must be given as its trace path", denom)
+	}
+
+	trace := transfertypes.ParseDenomTrace(denom)
+	for _, hop := range r.Hops {
+		prefix := hop.port() + "/" + hop.Channel
+		switch {
+		case trace.Path == prefix:
+			trace.Path = ""
+		case strings.HasPrefix(trace.Path, prefix+"/"):
+			trace.Path = strings.TrimPrefix(trace.Path, prefix+"/")
+		default:
+			if hop.CounterpartyChannel == "" {
+				return "", fmt.Errorf("unknown counterparty of %s on %s", hop.Channel, hop.SourceChainID)
+			}
+
+			counterparty := hop.counterpartyPort() + "/" + hop.CounterpartyChannel
+
+			if trace.Path == "" {
+				trace.Path = counterparty
+			} else {
+				trace.Path = counterparty + "/" + trace.Path
+			}
+		}
+	}
+
+	return trace.IBCDenom(), nil
+}
@@ -0,0 +1,181 @@
+package ibc
+
+import (
+	"testing"
+
+	neutrontransfertypes "github.com/margined-protocol/locust-core/pkg/proto/neutron/transfer/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	transfertypes "github.com/cosmos/ibc-go/v8/modules/apps/transfer/types"
+)
+
+func TestShortestRouteDefaultRegistry(t *testing.T) {
+	registry := DefaultConnectionRegistry()
+
+	route, err := registry.ShortestRoute("neutron-1", "osmosis-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"neutron-1", "noble-1", "osmosis-1"}, route.ChainIDs())
+	assert.Equal(t, "channel-30", route.Hops[0].Channel)
+	assert.Equal(t, "channel-1", route.Hops[1].Channel)
+
+	// Noble USDC held on neutron unwinds on noble before arriving on osmosis
+	denom, err := route.ExpectedDenom("transfer/channel-30/uusdc")
+	require.NoError(t, err)
+	assert.Equal(t, "ibc/498A0751C798A0D9A389AA3691123DADA57DAA4FE165D5C75894505B876BA6E4", denom)
+
+	_, err = route.ExpectedDenom("ibc/498A0751C798A0D9A389AA3691123DADA57DAA4FE165D5C75894505B876BA6E4")
+	assert.ErrorContains(t, err, "trace path")
+}
+
+func TestExpectedDenomFromNoble(t *testing.T) {
+	registry := DefaultConnectionRegistry()
+
+	tests := []struct {
+		destChainID string
+		expected    string
+	}{
+		{"neutron-1", "ibc/B559A80D62249C8AA07A380E2A2BEA6E5CA9A6F079C912C3A9E9B494105E4F81"},
+		{"dydx-mainnet-1", "ibc/8E27BA2D5493AF5636760E354E46004562C46AB7EC0CC4C1CA14E9E20E2545B5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.destChainID, func(t *testing.T) {
+			route, err := registry.ShortestRoute("noble-1", tt.destChainID)
+			require.NoError(t, err)
+			require.Len(t, route.Hops, 1)
+
+			denom, err := route.ExpectedDenom("uusdc")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, denom)
+		})
+	}
+}
+
+func TestRouteForwardMemo(t *testing.T) {
+	registry := NewConnectionRegistry()
+	for _, channel := range []Channel{
+		{SourceChainID: "a", DestChainID: "b", Channel: "channel-1", CounterpartyChannel: "channel-10"},
+		{SourceChainID: "b", DestChainID: "c", Channel: "channel-2", CounterpartyChannel: "channel-20"},
+		{SourceChainID: "c", DestChainID: "d", Port: "wasm.d1contract", Channel: "channel-3", CounterpartyChannel: "channel-30"},
+	} {
+		require.NoError(t, registry.RegisterChannel(channel))
+	}
+
+	route, err := registry.ShortestRoute("a", "d")
+	require.NoError(t, err)
+	require.Len(t, route.Hops, 3)
+
+	memo, err := route.ForwardMemo([]string{"b1receiver", "c1receiver", "d1receiver"})
+	require.NoError(t, err)
+	assert.Equal(t,
+		`{"forward":{"channel":"channel-2","next":{"forward":{"channel":"channel-3","port":"wasm.d1contract","receiver":"d1receiver"}},"port":"transfer","receiver":"c1receiver"}}`,
+		memo,
+	)
+
+	_, err = route.ForwardMemo([]string{"b1receiver", "c1receiver"})
+	assert.ErrorContains(t, err, "3 hops but 2 receivers")
+
+	_, err = route.ForwardMemo([]string{"b1receiver", "", "d1receiver"})
+	assert.ErrorContains(t, err, "receiver on c is empty")
+
+	denom, err := route.ExpectedDenom("ua")
+	require.NoError(t, err)
+	assert.Equal(t, "ibc/91B5DA7768A1B4530DCD3387278E7C07018BB6F84FA64ACFE40C468670D50A94", denom)
+
+	single, err := registry.ShortestRoute("a", "b")
+	require.NoError(t, err)
+	memo, err = single.ForwardMemo([]string{"b1receiver"})
+	require.NoError(t, err)
+	assert.Empty(t, memo)
+}
+
+func TestCheapestRoute(t *testing.T) {
+	registry := NewConnectionRegistry()
+	for _, channel := range []Channel{
+		{SourceChainID: "a", DestChainID: "c", Channel: "channel-1", Cost: 10},
+		{SourceChainID: "a", DestChainID: "b", Channel: "channel-2", Cost: 2},
+		{SourceChainID: "b", DestChainID: "c", Channel: "channel-3", Cost: 3},
+		{SourceChainID: "c", DestChainID: "a", Channel: "channel-4"},
+	} {
+		require.NoError(t, registry.RegisterChannel(channel))
+	}
+
+	shortest, err := registry.ShortestRoute("a", "c")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "c"}, shortest.ChainIDs())
+	assert.Equal(t, uint64(10), shortest.Cost())
+	// The counterparty is inferred from the only channel back
+	assert.Equal(t, "channel-4", shortest.Hops[0].CounterpartyChannel)
+
+	cheapest, err := registry.CheapestRoute("a", "c")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, cheapest.ChainIDs())
+	assert.Equal(t, uint64(5), cheapest.Cost())
+
+	// Without a channel back the arrival denom is unknown
+	_, err = cheapest.ExpectedDenom("ua")
+	assert.ErrorContains(t, err, "unknown counterparty of channel-2 on a")
+
+	_, err = registry.ShortestRoute("c", "b")
+	require.NoError(t, err)
+
+	_, err = registry.ShortestRoute("b", "a")
+	require.NoError(t, err)
+
+	_, err = registry.ShortestRoute("a", "z")
+	assert.ErrorIs(t, err, ErrNoRoute)
+
+	assert.ErrorContains(t, registry.RegisterChannel(Channel{SourceChainID: "a", DestChainID: "b", Port: "transfer", Channel: "channel-2"}), "already registered")
+}
+
+func TestRouteFromConnections(t *testing.T) {
+	registry := NewConnectionRegistry()
+	require.NoError(t, registry.RegisterConnections([]*Connection{
+		{Transfer: &Transfer{SourceChainID: "a", DestChainID: "c", Channel: "channel-1", Port: "transfer", Forward: &Forward{ChainID: "b", Port: "transfer", Channel: "channel-2"}}},
+		{Transfer: &Transfer{SourceChainID: "b", DestChainID: "a", Channel: "channel-5", Port: "transfer"}},
+	}))
+
+	route, err := registry.ShortestRoute("a", "c")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, route.ChainIDs())
+	assert.Equal(t, "channel-5", route.Hops[0].CounterpartyChannel)
+	assert.Empty(t, route.Hops[1].CounterpartyChannel)
+}
+
+func TestCreateRouteTransfer(t *testing.T) {
+	registry := DefaultConnectionRegistry()
+	coin := sdk.NewInt64Coin("uusdc", 1000)
+
+	route, err := registry.ShortestRoute("dydx-mainnet-1", "osmosis-1")
+	require.NoError(t, err)
+
+	msg, err := CreateRouteTransfer(route, coin, 100, "dydx1sender", []string{"noble1forward", "osmo1receiver"})
+	require.NoError(t, err)
+
+	transfer, ok := msg.(*transfertypes.MsgTransfer)
+	require.True(t, ok)
+	assert.Equal(t, "transfer", transfer.SourcePort)
+	assert.Equal(t, "channel-0", transfer.SourceChannel)
+	assert.Equal(t, "noble1forward", transfer.Receiver)
+	assert.Equal(t, `{"forward":{"channel":"channel-1","port":"transfer","receiver":"osmo1receiver"}}`, transfer.Memo)
+	assert.Equal(t, uint64(110), transfer.TimeoutHeight.RevisionHeight)
+
+	// Neutron pays relayer fees through its own transfer message
+	route, err = registry.ShortestRoute("neutron-1", "noble-1")
+	require.NoError(t, err)
+
+	msg, err = CreateRouteTransfer(route, coin, 100, "neutron1sender", []string{"noble1receiver"})
+	require.NoError(t, err)
+
+	neutronTransfer, ok := msg.(*neutrontransfertypes.MsgTransfer)
+	require.True(t, ok)
+	assert.Equal(t, "channel-30", neutronTransfer.SourceChannel)
+	assert.Equal(t, "noble1receiver", neutronTransfer.Receiver)
+	assert.Empty(t, neutronTransfer.Memo)
+
+	_, err = CreateRouteTransfer(route, coin, 100, "neutron1sender", nil)
+	assert.ErrorContains(t, err, "1 hops but 0 receivers")
+}
@@ -52,7 +52,8 @@ type WasmExecMemo struct {
 }
 
 type ForwardNextInfo struct {
-	Wasm WasmExecInfo `json:"wasm"`
+	Forward *ForwardInfo `json:"forward,omitempty"`
+	Wasm    WasmExecInfo `json:"wasm"`
 }
 
 type ForwardInfo struct {
@@ -127,8 +128,18 @@ func (w WasmExecInfo) MarshalJSON() ([]byte, error) {
 	})
 }
 
-// MarshalJSON for ForwardNextInfo
+// MarshalJSON for ForwardNextInfo, the wasm execution is omitted when the next step is
+// another forward
 func (f ForwardNextInfo) MarshalJSON() ([]byte, error) {
+	if f.Forward != nil {
+		return orderedMarshalJSON([]struct {
+			Key   string
+			Value interface{}
+		}{
+			{"forward", f.Forward},
+		})
+	}
+
 	return orderedMarshalJSON([]struct {
 		Key   string
 		Value interface{}
@@ -183,11 +194,35 @@ func CreateTransferWithMemo(
 		return nil, err
 	}
 
-	var msg sdk.Msg
+	return newTransferMsg(sourceChainID, conn.Port, conn.Channel, memo, sender, receiver, coin, blockHeight), nil
+}
+
+// CreateRouteTransfer creates an IBC transfer message sending the coin along the route, with the
+// packet forward memo of the later hops. Receivers are the addresses on the destination chain
+// of each hop, as taken by Route.ForwardMemo.
+func CreateRouteTransfer(
+	route *Route,
+	coin sdk.Coin,
+	blockHeight uint64,
+	sender string,
+	receivers []string,
+) (sdk.Msg, error) {
+	memo, err := route.ForwardMemo(receivers)
+	if err != nil {
+		return nil, err
+	}
+
+	hop := route.Hops[0]
+	return newTransferMsg(hop.SourceChainID, hop.port(), hop.Channel, memo, sender, receivers[0], coin, blockHeight), nil
+}
+
+// newTransferMsg creates the transfer message of the source chain, neutron pays relayer fees
+// through its own transfer module
+func newTransferMsg(sourceChainID, port, channel, memo, sender, receiver string, coin sdk.Coin, blockHeight uint64) sdk.Msg {
 	if sourceChainID == "neutron-1" {
-		msg = &neutrontransfertypes.MsgTransfer{
-			SourcePort:    conn.Port,
-			SourceChannel: conn.Channel,
+		return &neutrontransfertypes.MsgTransfer{
+			SourcePort:    port,
+			SourceChannel: channel,
 			Token:         coin,
 			Sender:        sender,
 			Receiver:      receiver,
@@ -202,20 +237,18 @@ func CreateTransferWithMemo(
 				},
 			},
 		}
-	} else {
-		// Create the IBC transfer message with the memo
-		msg = CreateTransferMsg(
-			conn.Port,
-			conn.Channel,
-			memo,
-			sender,
-			receiver,
-			coin,
-			blockHeight,
-		)
 	}
 
-	return msg, nil
+	// Create the IBC transfer message with the memo
+	return CreateTransferMsg(
+		port,
+		channel,
+		memo,
+		sender,
+		receiver,
+		coin,
+		blockHeight,
+	)
 }
 
 // createForwardMemo creates a properly formatted memo for IBC transfers based on connection type
@@ -7,14 +7,15 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"sync"
 	"time"
 
-	"github.com/ignite/cli/v28/ignite/pkg/cosmosaccount"
 	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
 	"github.com/margined-protocol/locust-core/pkg/connection"
 	"github.com/margined-protocol/locust-core/pkg/ibc"
 	"github.com/margined-protocol/locust-core/pkg/math"
 	clob "github.com/margined-protocol/locust-core/pkg/proto/dydx/clob/types"
+	perpetuals "github.com/margined-protocol/locust-core/pkg/proto/dydx/perpetuals/types"
 	send "github.com/margined-protocol/locust-core/pkg/proto/dydx/sending/types"
 	subaccounts "github.com/margined-protocol/locust-core/pkg/proto/dydx/subaccounts/types"
 	"go.uber.org/zap"
@@ -29,8 +30,10 @@ import (
 
 const (
 	DydxChainID        = "dydx-mainnet-1"
-	DefaultOrderExpiry = 60   // seconds
-	DefaultSlippage    = 0.01 // 1% - eventually we should use the best bid/ask or make market orders idk
+	DefaultOrderExpiry = 60    // seconds
+	DefaultSlippage    = 0.01  // 1% - used to price limit orders, market orders are priced off the orderbook
+	DefaultPriceBuffer = 0.002 // 0.2% - added to the worst orderbook price of market orders
+	DefaultMaxSlippage = 0.01  // 1% - market orders with a higher expected slippage are refused
 	maxRetries         = 3
 	retryDelay         = 2 * time.Second
 )
@@ -51,9 +54,19 @@ type DydxProvider struct {
 	subticksPerTick           uint64 // MinPriceTickSize
 	minEquity                 sdkmath.Int
 
+	// Tracked markets keyed by ticker, includes the configured market
+	markets   map[string]*DydxMarket
+	marketsMu sync.RWMutex
+
+	// Stateful orders placed by the provider keyed by client id
+	orders   map[uint32]*DydxOrder
+	ordersMu sync.RWMutex
+
 	// Providers && Clients
 	clientRegistry   *connection.ClientRegistry
 	subaccountClient subaccounts.QueryClient
+	clobClient       clob.QueryClient
+	perpetualsClient perpetuals.QueryClient
 	msgHandler       ibc.MessageHandler
 
 	// Dydx Indexer
@@ -66,6 +79,13 @@ type DydxProvider struct {
 	// Defaults (TODO: make these configurable)
 	slippage    float64
 	orderExpiry uint64 // Order expiry in seconds
+
+	// Orderbook pricing of market orders
+	priceBuffer float64
+	maxSlippage float64
+
+	// Reissuing of unfilled short-term orders, disabled by default
+	reissuePolicy ReissuePolicy
 }
 
 type DydxConfig struct {
@@ -83,6 +103,8 @@ type DydxConfig struct {
 	BaseChainID string
 
 	SubaccountClient subaccounts.QueryClient
+	ClobClient       clob.QueryClient
+	PerpetualsClient perpetuals.QueryClient
 	ClientRegistry   *connection.ClientRegistry
 	MsgHandler       ibc.MessageHandler
 	IndexerURL       string
@@ -90,6 +112,11 @@ type DydxConfig struct {
 	QuantumConversionExp int64
 	AtomicResolution     int64
 	Decimals             int64
+
+	PriceBuffer float64
+	MaxSlippage float64
+
+	ReissuePolicy ReissuePolicy
 }
 
 // NewDydxProvider creates a new Dydx provider
@@ -118,6 +145,8 @@ func NewDydxProvider(
 
 	// Clients and connections
 	subaccountClient subaccounts.QueryClient,
+	clobClient clob.QueryClient,
+	perpetualsClient perpetuals.QueryClient,
 	clientRegistry *connection.ClientRegistry,
 	msgHandler ibc.MessageHandler,
 	indexerURL string,
@@ -136,7 +165,7 @@ func NewDydxProvider(
 		},
 	}
 
-	return &DydxProvider{
+	provider := &DydxProvider{
 		// Logger
 		logger: logger,
 
@@ -148,6 +177,8 @@ func NewDydxProvider(
 		quantumConversionExponent: quantumConversionExponent,
 		atomicResolution:          atomicResolution,
 		slippage:                  DefaultSlippage,
+		priceBuffer:               DefaultPriceBuffer,
+		maxSlippage:               DefaultMaxSlippage,
 		minEquity:                 minEquity,
 		decimals:                  decimals,
 
@@ -163,16 +194,41 @@ func NewDydxProvider(
 
 		// Clients and connections
 		subaccountClient: subaccountClient,
+		clobClient:       clobClient,
+		perpetualsClient: perpetualsClient,
 		clientRegistry:   clientRegistry,
 		msgHandler:       msgHandler,
 		indexerURL:       indexerURL,
 		httpClient:       httpClient,
+
+		orders: make(map[uint32]*DydxOrder),
+	}
+
+	// The configured market is always tracked
+	provider.markets = map[string]*DydxMarket{
+		market: provider.defaultMarket(),
 	}
+
+	return provider
 }
 
 // Initialize implements Provider
-func (m *DydxProvider) Initialize(_ context.Context) error {
-	// Dydx clients should already be initialized at construction
+func (m *DydxProvider) Initialize(ctx context.Context) error {
+	// Market parameters are read from chain, configured values must agree with them
+	discovered, err := m.LoadMarket(ctx, m.marketID)
+	if err != nil {
+		return fmt.Errorf("failed to discover market parameters: %w", err)
+	}
+
+	if err := checkMarketConfig(m.defaultMarket(), discovered); err != nil {
+		return err
+	}
+
+	m.atomicResolution = discovered.AtomicResolution
+	m.quantumConversionExponent = discovered.QuantumConversionExponent
+	m.stepBaseQuantums = discovered.StepBaseQuantums
+	m.subticksPerTick = discovered.SubticksPerTick
+
 	return nil
 }
 
@@ -316,8 +372,26 @@ func (m *DydxProvider) GetSubaccountBalance() (sdk.Coins, error) {
 	return coins, nil
 }
 
+// SetOrderbookPricing overrides the buffer added to the worst orderbook price and the
+// maximum expected slippage accepted for market orders
+func (m *DydxProvider) SetOrderbookPricing(priceBuffer, maxSlippage float64) {
+	m.priceBuffer = priceBuffer
+	m.maxSlippage = maxSlippage
+}
+
 // CreateMarketOrder implements Provider
-func (m *DydxProvider) CreateMarketOrder(ctx context.Context, price, _, size sdkmath.Int, isBuy, reduceOnly bool) ([]sdk.Msg, error) {
+func (m *DydxProvider) CreateMarketOrder(ctx context.Context, price, margin, size sdkmath.Int, isBuy, reduceOnly bool) ([]sdk.Msg, error) {
+	return m.CreateMarketOrderForMarket(ctx, m.market, price, margin, size, isBuy, reduceOnly)
+}
+
+// CreateMarketOrderForMarket implements Provider, the order is priced off the orderbook
+// so the price argument is ignored
+func (m *DydxProvider) CreateMarketOrderForMarket(ctx context.Context, market string, _, _, size sdkmath.Int, isBuy, reduceOnly bool) ([]sdk.Msg, error) {
+	params, err := m.getMarket(market)
+	if err != nil {
+		return nil, err
+	}
+
 	_, account, err := m.clientRegistry.GetSignerAccountAndAddress(m.signerAccount, DydxChainID)
 	if err != nil {
 		return nil, err
@@ -330,28 +404,34 @@ func (m *DydxProvider) CreateMarketOrder(ctx context.Context, price, _, size sdk
 
 	// Validate non-zero inputs
 	// NOTE: we do not error here
-	if price.IsZero() {
-		return nil, nil
-	}
 	if size.IsZero() {
 		return nil, nil
 	}
 
+	price, quote, err := m.PriceMarketOrder(ctx, params, size, isBuy)
+	if err != nil {
+		return nil, err
+	}
+
 	m.logger.Info("Creating market order",
+		zap.String("market", market),
 		zap.String("price", price.String()),
+		zap.Float64("averagePrice", quote.AveragePrice),
+		zap.Float64("worstPrice", quote.WorstPrice),
+		zap.Float64("expectedSlippage", quote.Slippage),
 		zap.String("size", size.String()),
 		zap.Bool("isBuy", isBuy),
 		zap.Bool("reduceOnly", reduceOnly),
 	)
 
 	// Validate and round the price
-	validPrice, err := m.validateAndRoundPrice(price)
+	validPrice, err := params.validateAndRoundPrice(price)
 	if err != nil {
 		return nil, fmt.Errorf("invalid price: %w", err)
 	}
 
 	// Validate and round the size
-	validSize, err := m.validateAndRoundAmount(size)
+	validSize, err := params.validateAndRoundAmount(size)
 	if err != nil {
 		return nil, fmt.Errorf("invalid size: %w", err)
 	}
@@ -368,9 +448,11 @@ func (m *DydxProvider) CreateMarketOrder(ctx context.Context, price, _, size sdk
 		Order: clob.Order{
 			OrderId: clob.OrderId{
 				SubaccountId: subaccounts.SubaccountId{
-					Owner: account,
+					Owner:  account,
+					Number: m.subaccountID,
 				},
-				ClobPairId: m.marketID,
+				ClientId:   m.nextClientID(),
+				ClobPairId: params.ClobPairID,
 				OrderFlags: 0, // 0 short-term, 32 conditional, 64 long-term
 			},
 			Side:       clob.Order_Side(side),
@@ -442,8 +524,10 @@ func (m *DydxProvider) CreateLimitOrder(_ context.Context, price, _, size sdkmat
 		Order: clob.Order{
 			OrderId: clob.OrderId{
 				SubaccountId: subaccounts.SubaccountId{
-					Owner: account,
+					Owner:  account,
+					Number: m.subaccountID,
 				},
+				ClientId:   m.nextClientID(),
 				ClobPairId: m.marketID,
 				OrderFlags: 64, // 0 short-term, 32 conditional, 64 long-term
 			},
@@ -744,41 +828,59 @@ func (m *DydxProvider) IncreasePosition(ctx context.Context, price float64, amou
 	return result, nil
 }
 
-func (m *DydxProvider) ReducePosition(ctx context.Context, price float64, amount, margin sdkmath.Int, isLong bool) (*ExecutionResult, error) {
+// ReducePosition implements Provider, isLong is the direction of the position being reduced.
+// The reduce only order is priced off the orderbook so the price argument is ignored.
+func (m *DydxProvider) ReducePosition(ctx context.Context, _ float64, amount, margin sdkmath.Int, isLong bool) (*ExecutionResult, error) {
 	// High level-logic:
-	// 1. Create a reduce position message
-	// 2. Send the message
-	adjustedPrice := math.AdjustSlippageFloat64(price, m.slippage, isLong)
-	quantumPrice := math.FloatToQuantumPrice(adjustedPrice, m.quantumConversionExponent)
-
-	quantumPrice = math.RoundFixedPointInt(quantumPrice, m.subticksPerTick)
-
-	// 1. Create a reduce order message only market orders can be reduce only
-	orderMsgs, err := m.CreateMarketOrder(ctx, quantumPrice, margin, amount, isLong, true)
+	// 1. Check the open position has the expected direction
+	// 2. Create a reduce position message, priced off the orderbook
+	// 3. Send the message
+	account, address, err := m.clientRegistry.GetSignerAccountAndAddress(m.signerAccount, DydxChainID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Use fee client as these messages are short-term
-	client, err := m.clientRegistry.GetClient(DydxChainID, true)
+	// 1. Check the open position has the expected direction
+	subaccount, err := m.QuerySubaccountIndexer(ctx, address, m.subaccountID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching indexer data: %w", err)
+	}
+
+	if perpPosition, exists := subaccount.Subaccount.OpenPerpetualPositions[m.market]; exists && (perpPosition.Side == "LONG") != isLong {
+		return nil, fmt.Errorf("position direction mismatch: position is %s, isLong %t", perpPosition.Side, isLong)
+	}
+
+	// 2. Create a reduce order message only market orders can be reduce only, reducing a long is a sell
+	orderMsgs, err := m.CreateMarketOrder(ctx, sdkmath.ZeroInt(), margin, amount, !isLong, true)
 	if err != nil {
 		return nil, err
 	}
 
-	account, _, err := m.clientRegistry.GetSignerAccountAndAddress(m.signerAccount, DydxChainID)
+	// Use fee client as these messages are short-term
+	client, err := m.clientRegistry.GetClient(DydxChainID, true)
 	if err != nil {
 		return nil, err
 	}
 
 	m.logger.Debug("order msgs", zap.Any("msgs", orderMsgs))
 
-	// 2. Send the messages
+	// 3. Send the messages
+	var report *OrderReport
 	if orderMsgs != nil {
 		m.logger.Info("Sending short term order")
-		err = m.sendShortTermOrder(ctx, client.Client, account, orderMsgs, amount.Neg())
+		report, orderMsgs, err = m.sendShortTermOrder(ctx, client.Client, account, m.market, orderMsgs)
 		if err != nil {
 			return nil, err
 		}
+
+		// Only release margin once the reduction has fully filled
+		if report.Status != OrderStatusFilled {
+			return &ExecutionResult{
+				Messages: orderMsgs,
+				Order:    report,
+				Notes:    fmt.Sprintf("order %s, margin withdrawal skipped", report.Status),
+			}, nil
+		}
 	}
 
 	// 1. Create a withdraw message
@@ -800,6 +902,7 @@ func (m *DydxProvider) ReducePosition(ctx context.Context, price float64, amount
 
 	result := &ExecutionResult{
 		Messages: append(orderMsgs, withdrawMsgs...),
+		Order:    report,
 	}
 
 	if orderTx != nil {
@@ -810,94 +913,175 @@ func (m *DydxProvider) ReducePosition(ctx context.Context, price float64, amount
 	return result, nil
 }
 
-func (m *DydxProvider) ClosePosition(_ context.Context, _ bool) (*ExecutionResult, error) {
-	return nil, fmt.Errorf("not implemented")
-}
+func (m *DydxProvider) ClosePosition(ctx context.Context, isLong bool) (*ExecutionResult, error) {
+	// High level-logic:
+	// 1. Read the open position from the indexer
+	// 2. Send a reduce only IOC order for the full size
+	// 3. Confirm the position is flat
+	account, address, err := m.clientRegistry.GetSignerAccountAndAddress(m.signerAccount, DydxChainID)
+	if err != nil {
+		return nil, err
+	}
 
-func (m *DydxProvider) AdjustMargin(_ context.Context, _ sdkmath.Int, _ bool) (*ExecutionResult, error) {
-	return nil, fmt.Errorf("not implemented")
-}
+	// 1. Read the open position from the indexer
+	subaccount, err := m.QuerySubaccountIndexer(ctx, address, m.subaccountID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching indexer data: %w", err)
+	}
 
-// sendShortTermOrder sends a short-term order and verifies its execution by checking position changes
-func (m *DydxProvider) sendShortTermOrder(
-	ctx context.Context, client *cosmosclient.Client, account *cosmosaccount.Account,
-	orderMsgs []sdk.Msg, expectedSizeChange sdkmath.Int,
-) error {
-	// Get initial position
-	initialPosition, err := m.GetPosition(ctx)
+	perpPosition, exists := subaccount.Subaccount.OpenPerpetualPositions[m.market]
+	if !exists {
+		m.logger.Info("No open position to close", zap.String("market", m.market))
+		return &ExecutionResult{Notes: "no open position"}, nil
+	}
+
+	if (perpPosition.Side == "LONG") != isLong {
+		return nil, fmt.Errorf("position direction mismatch: position is %s, isLong %t", perpPosition.Side, isLong)
+	}
+
+	quantums, err := m.sizeToQuantums(perpPosition.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Send a reduce only IOC order for the full size, closing a long is a sell
+	orderMsgs, err := m.CreateMarketOrder(ctx, sdkmath.ZeroInt(), sdkmath.ZeroInt(), quantums, !isLong, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if orderMsgs == nil {
+		return nil, fmt.Errorf("failed to create close order for %s", m.market)
+	}
+
+	// Use fee client as these messages are short-term
+	client, err := m.clientRegistry.GetClient(DydxChainID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Closing position",
+		zap.String("market", m.market),
+		zap.String("side", perpPosition.Side),
+		zap.String("size", perpPosition.Size),
+		zap.String("quantums", quantums.String()),
+	)
+
+	report, orderMsgs, err := m.sendShortTermOrder(ctx, client.Client, account, m.market, orderMsgs)
 	if err != nil {
-		return fmt.Errorf("failed to get initial position: %w", err)
+		return nil, err
 	}
 
-	// Broadcast the order
-	err = connection.BroadcastShortTermOrder(ctx, m.logger, client, *account, orderMsgs...)
+	// 3. Confirm the position is flat
+	finalPosition, err := m.GetPosition(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to broadcast order: %w", err)
+		return nil, fmt.Errorf("failed to get final position: %w", err)
 	}
 
-	// Wait a short time for the order to be processed
-	time.Sleep(1 * time.Second)
+	if !finalPosition.Amount.IsZero() {
+		return nil, fmt.Errorf("position not closed: order %s, remaining size %s", report.Status, finalPosition.Amount)
+	}
+
+	return &ExecutionResult{
+		Messages: orderMsgs,
+		Position: finalPosition,
+		Executed: true,
+		Order:    report,
+	}, nil
+}
 
-	// Check position multiple times to confirm the change
-	for attempts := 0; attempts < 3; attempts++ {
-		newPosition, err := m.GetPosition(ctx)
+func (m *DydxProvider) AdjustMargin(ctx context.Context, margin sdkmath.Int, isAdd bool) (*ExecutionResult, error) {
+	if !margin.IsPositive() {
+		return nil, fmt.Errorf("margin must be positive")
+	}
+
+	var msgs []sdk.Msg
+
+	if isAdd {
+		// Make sure the main account holds enough USDC to move into the subaccount
+		balances, err := m.GetAccountBalance()
 		if err != nil {
-			m.logger.Warn("Failed to get updated position",
-				zap.Error(err),
-				zap.Int("attempt", attempts+1),
-			)
-			time.Sleep(1 * time.Second)
-			continue
+			return nil, fmt.Errorf("failed to get account balance: %w", err)
 		}
 
-		// Calculate actual size change
-		actualChange := newPosition.Amount.Sub(initialPosition.Amount)
-
-		// Check if the position changed as expected
-		if actualChange.Equal(expectedSizeChange) {
-			m.logger.Info("Order executed successfully",
-				zap.String("initial_size", initialPosition.Amount.String()),
-				zap.String("final_size", newPosition.Amount.String()),
-				zap.String("change", actualChange.String()),
-			)
-			return nil
+		available := balances.AmountOf(m.denom)
+		if available.LT(margin) {
+			return nil, fmt.Errorf("insufficient balance: requested %s, available %s", margin, available)
 		}
 
-		m.logger.Debug("Position not yet updated",
-			zap.String("expected_change", expectedSizeChange.String()),
-			zap.String("actual_change", actualChange.String()),
-			zap.Int("attempt", attempts+1),
-		)
+		msgs, err = m.DepositSubaccount(ctx, margin)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Make sure the withdrawal leaves the subaccount above the minimum equity
+		balances, err := m.GetSubaccountBalance()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get subaccount balance: %w", err)
+		}
+
+		equity := balances.AmountOf(m.denom)
+		if equity.Sub(margin).LT(m.minEquity) {
+			return nil, fmt.Errorf("withdrawal of %s would leave equity %s below minimum %s", margin, equity.Sub(margin), m.minEquity)
+		}
 
-		time.Sleep(1 * time.Second)
+		msgs, err = m.WithdrawSubaccount(ctx, margin)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return fmt.Errorf("order execution not confirmed: expected size change %s not observed", expectedSizeChange)
-}
+	result := &ExecutionResult{
+		Messages: msgs,
+	}
 
-// validateAndRoundPrice ensures the price is a multiple of subticksPerTick
-func (m *DydxProvider) validateAndRoundPrice(price sdkmath.Int) (sdkmath.Int, error) {
-	if price.IsNegative() {
-		return sdkmath.Int{}, fmt.Errorf("price cannot be negative")
+	if msgs == nil {
+		result.Notes = "no margin adjustment required"
+		return result, nil
 	}
 
-	roundedPrice := math.RoundFixedPointInt(price, m.subticksPerTick)
-	return roundedPrice, nil
-}
+	tx, err := m.msgHandler(DydxChainID, msgs, false, false)
+	if err != nil {
+		return nil, err
+	}
 
-// validateAndRoundAmount ensures the amount is >= stepBaseQuantums and is a multiple thereof
-func (m *DydxProvider) validateAndRoundAmount(amount sdkmath.Int) (sdkmath.Int, error) {
-	if amount.IsNegative() {
-		return sdkmath.Int{}, fmt.Errorf("amount cannot be negative")
+	m.logger.Info("Margin adjustment transaction",
+		zap.String("tx", tx.TxHash),
+		zap.Bool("isAdd", isAdd),
+		zap.String("margin", margin.String()),
+	)
+
+	result.TxHash = tx.TxHash
+	result.Events = tx.Events
+	result.Executed = true
+
+	position, err := m.GetPosition(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed to get updated position: %w", err)
 	}
+	result.Position = position
 
-	minAmount := sdkmath.NewInt(int64(m.stepBaseQuantums))
-	if amount.LT(minAmount) {
-		return sdkmath.Int{}, fmt.Errorf("amount %s is less than minimum allowed %s", amount, minAmount)
+	return result, nil
+}
+
+// sizeToQuantums converts an indexer position size into base quantums using the atomic resolution
+func (m *DydxProvider) sizeToQuantums(size string) (sdkmath.Int, error) {
+	sizeDec, err := sdkmath.LegacyNewDecFromStr(size)
+	if err != nil {
+		return sdkmath.Int{}, fmt.Errorf("failed to parse size %s: %w", size, err)
 	}
 
-	roundedAmount := math.RoundFixedPointInt(amount, m.stepBaseQuantums)
-	return roundedAmount, nil
+	return m.defaultMarket().sizeToQuantums(sizeDec), nil
+}
+
+// validateAndRoundPrice ensures the price is a multiple of subticksPerTick
+func (m *DydxProvider) validateAndRoundPrice(price sdkmath.Int) (sdkmath.Int, error) {
+	return m.defaultMarket().validateAndRoundPrice(price)
+}
+
+// validateAndRoundAmount ensures the amount is >= stepBaseQuantums and is a multiple thereof
+func (m *DydxProvider) validateAndRoundAmount(amount sdkmath.Int) (sdkmath.Int, error) {
+	return m.defaultMarket().validateAndRoundAmount(amount)
 }
 
 // Add these new methods
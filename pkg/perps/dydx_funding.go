@@ -0,0 +1,166 @@
+package perps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// historicalFundingPageLimit is the number of funding payments requested per indexer page
+const historicalFundingPageLimit = 100
+
+var _ FundingSource = (*DydxProvider)(nil)
+
+// FundingHistory implements FundingSource, dYdX pays funding hourly
+func (m *DydxProvider) FundingHistory(ctx context.Context, market string, since time.Time) ([]FundingRate, error) {
+	var history []FundingRate
+
+	// Pages are newest first, walk back until the start of the window
+	var before *time.Time
+	for {
+		page, err := m.QueryHistoricalFunding(ctx, market, before, historicalFundingPageLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		rates, err := ProcessHistoricalFunding(page)
+		if err != nil {
+			return nil, err
+		}
+
+		reachedStart := false
+		for _, rate := range rates {
+			if rate.Time.Before(since) {
+				reachedStart = true
+				break
+			}
+			history = append(history, rate)
+		}
+
+		if reachedStart || len(rates) < historicalFundingPageLimit {
+			break
+		}
+
+		oldest := rates[len(rates)-1].Time.Add(-time.Nanosecond)
+		before = &oldest
+	}
+
+	// Oldest first
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+
+	return history, nil
+}
+
+// CurrentFundingRate implements FundingSource, the latest hourly payment is the current rate
+func (m *DydxProvider) CurrentFundingRate(ctx context.Context, market string) (*FundingRate, error) {
+	page, err := m.QueryHistoricalFunding(ctx, market, nil, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	rates, err := ProcessHistoricalFunding(page)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("no funding history for %s", market)
+	}
+
+	return &rates[0], nil
+}
+
+// AccruedFunding implements FundingSource using the net funding of the open indexer position
+func (m *DydxProvider) AccruedFunding(ctx context.Context, market string) (sdkmath.Int, error) {
+	_, address, err := m.clientRegistry.GetSignerAccountAndAddress(m.signerAccount, DydxChainID)
+	if err != nil {
+		return sdkmath.Int{}, err
+	}
+
+	subaccount, err := m.QuerySubaccountIndexer(ctx, address, m.subaccountID)
+	if err != nil {
+		return sdkmath.Int{}, fmt.Errorf("error fetching indexer data: %w", err)
+	}
+
+	position, exists := subaccount.Subaccount.OpenPerpetualPositions[market]
+	if !exists {
+		return sdkmath.ZeroInt(), nil
+	}
+
+	netFunding, err := sdkmath.LegacyNewDecFromStr(position.NetFunding)
+	if err != nil {
+		return sdkmath.Int{}, fmt.Errorf("failed to parse net funding %s: %w", position.NetFunding, err)
+	}
+
+	return netFunding.Mul(sdkmath.LegacyNewDec(10).Power(uint64(m.decimals))).TruncateInt(), nil
+}
+
+// ProcessHistoricalFunding converts the indexer funding payments into annualized rates,
+// keeping the newest first order of the indexer
+func ProcessHistoricalFunding(response *IndexerHistoricalFundingResponse) ([]FundingRate, error) {
+	rates := make([]FundingRate, 0, len(response.HistoricalFunding))
+	for _, funding := range response.HistoricalFunding {
+		rate, err := strconv.ParseFloat(funding.Rate, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid funding rate %s: %w", funding.Rate, err)
+		}
+
+		effectiveAt, err := time.Parse(time.RFC3339, funding.EffectiveAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid funding time %s: %w", funding.EffectiveAt, err)
+		}
+
+		rates = append(rates, FundingRate{
+			Time: effectiveAt,
+			Rate: rate * hoursPerYear,
+		})
+	}
+
+	return rates, nil
+}
+
+// QueryHistoricalFunding fetches a page of hourly funding payments of a market from the
+// indexer, newest first and effective at or before the given time when set
+func (m *DydxProvider) QueryHistoricalFunding(ctx context.Context, market string, before *time.Time, limit int) (*IndexerHistoricalFundingResponse, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	if before != nil {
+		query.Set("effectiveBeforeOrAt", before.UTC().Format(time.RFC3339Nano))
+	}
+
+	path := fmt.Sprintf("/historicalFunding/%s?%s", market, query.Encode())
+
+	requestURL := fmt.Sprintf("%s%s", m.indexerURL, path)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("indexer request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result IndexerHistoricalFundingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &result, nil
+}
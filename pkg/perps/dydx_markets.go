@@ -0,0 +1,268 @@
+package perps
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/margined-protocol/locust-core/pkg/math"
+	clob "github.com/margined-protocol/locust-core/pkg/proto/dydx/clob/types"
+	perpetuals "github.com/margined-protocol/locust-core/pkg/proto/dydx/perpetuals/types"
+	"go.uber.org/zap"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// DydxMarket holds the parameters needed to build orders for a single dYdX market
+type DydxMarket struct {
+	Ticker                    string // e.g. ATOM-USD, used as the market key
+	ClobPairID                uint32
+	PerpetualID               uint32
+	AtomicResolution          int64  // Decimal places for the amount, -6 == 6dp
+	QuantumConversionExponent int64  // Decimal places for the price, -9 == 9dp
+	StepBaseQuantums          uint64 // MinQuantityTickSize
+	SubticksPerTick           uint64 // MinPriceTickSize
+}
+
+// NewDydxMarket builds the market parameters from the on-chain clob pair and perpetual
+func NewDydxMarket(clobPair clob.ClobPair, perpetual perpetuals.Perpetual) (*DydxMarket, error) {
+	metadata := clobPair.GetPerpetualClobMetadata()
+	if metadata == nil {
+		return nil, fmt.Errorf("clob pair %d is not a perpetual market", clobPair.Id)
+	}
+
+	if metadata.PerpetualId != perpetual.Params.Id {
+		return nil, fmt.Errorf("clob pair %d references perpetual %d, got %d", clobPair.Id, metadata.PerpetualId, perpetual.Params.Id)
+	}
+
+	if clobPair.StepBaseQuantums == 0 {
+		return nil, fmt.Errorf("clob pair %d has zero step base quantums", clobPair.Id)
+	}
+
+	if clobPair.SubticksPerTick == 0 {
+		return nil, fmt.Errorf("clob pair %d has zero subticks per tick", clobPair.Id)
+	}
+
+	return &DydxMarket{
+		Ticker:                    perpetual.Params.Ticker,
+		ClobPairID:                clobPair.Id,
+		PerpetualID:               perpetual.Params.Id,
+		AtomicResolution:          int64(perpetual.Params.AtomicResolution),
+		QuantumConversionExponent: int64(clobPair.QuantumConversionExponent),
+		StepBaseQuantums:          clobPair.StepBaseQuantums,
+		SubticksPerTick:           uint64(clobPair.SubticksPerTick),
+	}, nil
+}
+
+// validateAndRoundPrice ensures the price is a multiple of subticksPerTick
+func (d *DydxMarket) validateAndRoundPrice(price sdkmath.Int) (sdkmath.Int, error) {
+	if d.SubticksPerTick == 0 {
+		return sdkmath.Int{}, fmt.Errorf("market %s parameters not loaded", d.Ticker)
+	}
+
+	if price.IsNegative() {
+		return sdkmath.Int{}, fmt.Errorf("price cannot be negative")
+	}
+
+	roundedPrice := math.RoundFixedPointInt(price, d.SubticksPerTick)
+	return roundedPrice, nil
+}
+
+// validateAndRoundAmount ensures the amount is >= stepBaseQuantums and is a multiple thereof
+func (d *DydxMarket) validateAndRoundAmount(amount sdkmath.Int) (sdkmath.Int, error) {
+	if d.StepBaseQuantums == 0 {
+		return sdkmath.Int{}, fmt.Errorf("market %s parameters not loaded", d.Ticker)
+	}
+
+	if amount.IsNegative() {
+		return sdkmath.Int{}, fmt.Errorf("amount cannot be negative")
+	}
+
+	minAmount := sdkmath.NewInt(int64(d.StepBaseQuantums))
+	if amount.LT(minAmount) {
+		return sdkmath.Int{}, fmt.Errorf("amount %s is less than minimum allowed %s", amount, minAmount)
+	}
+
+	roundedAmount := math.RoundFixedPointInt(amount, d.StepBaseQuantums)
+	return roundedAmount, nil
+}
+
+// quantumsToSize converts base quantums into a size in base units using the atomic resolution
+func (d *DydxMarket) quantumsToSize(quantums sdkmath.Int) sdkmath.LegacyDec {
+	size := sdkmath.LegacyNewDecFromInt(quantums)
+
+	if d.AtomicResolution <= 0 {
+		return size.Quo(sdkmath.LegacyNewDec(10).Power(uint64(-d.AtomicResolution)))
+	}
+
+	return size.Mul(sdkmath.LegacyNewDec(10).Power(uint64(d.AtomicResolution)))
+}
+
+// sizeToQuantums converts a size in base units into base quantums using the atomic resolution
+func (d *DydxMarket) sizeToQuantums(size sdkmath.LegacyDec) sdkmath.Int {
+	// Positions are reported as signed sizes, orders are always positive quantums
+	size = size.Abs()
+
+	if d.AtomicResolution <= 0 {
+		return size.Mul(sdkmath.LegacyNewDec(10).Power(uint64(-d.AtomicResolution))).TruncateInt()
+	}
+
+	return size.Quo(sdkmath.LegacyNewDec(10).Power(uint64(d.AtomicResolution))).TruncateInt()
+}
+
+// checkMarketConfig compares configured market parameters against those discovered on
+// chain, zero configured values are treated as unset
+func checkMarketConfig(configured, discovered *DydxMarket) error {
+	var mismatches []string
+
+	if configured.Ticker != "" && configured.Ticker != discovered.Ticker {
+		mismatches = append(mismatches, fmt.Sprintf("market: configured %s, chain %s", configured.Ticker, discovered.Ticker))
+	}
+	if configured.AtomicResolution != 0 && configured.AtomicResolution != discovered.AtomicResolution {
+		mismatches = append(mismatches, fmt.Sprintf("atomic_resolution: configured %d, chain %d", configured.AtomicResolution, discovered.AtomicResolution))
+	}
+	if configured.QuantumConversionExponent != 0 && configured.QuantumConversionExponent != discovered.QuantumConversionExponent {
+		mismatches = append(mismatches, fmt.Sprintf("quantum_conversion_exponent: configured %d, chain %d", configured.QuantumConversionExponent, discovered.QuantumConversionExponent))
+	}
+	if configured.StepBaseQuantums != 0 && configured.StepBaseQuantums != discovered.StepBaseQuantums {
+		mismatches = append(mismatches, fmt.Sprintf("step_base_quantums: configured %d, chain %d", configured.StepBaseQuantums, discovered.StepBaseQuantums))
+	}
+	if configured.SubticksPerTick != 0 && configured.SubticksPerTick != discovered.SubticksPerTick {
+		mismatches = append(mismatches, fmt.Sprintf("subticks_per_tick: configured %d, chain %d", configured.SubticksPerTick, discovered.SubticksPerTick))
+	}
+
+	if len(mismatches) > 0 {
+		return fmt.Errorf("market config for clob pair %d disagrees with chain: %s", discovered.ClobPairID, strings.Join(mismatches, "; "))
+	}
+
+	return nil
+}
+
+// LoadMarket fetches the parameters for a clob pair from chain and tracks the market
+func (m *DydxProvider) LoadMarket(ctx context.Context, clobPairID uint32) (*DydxMarket, error) {
+	if m.clobClient == nil || m.perpetualsClient == nil {
+		return nil, fmt.Errorf("clob and perpetuals clients are required to load markets")
+	}
+
+	clobPair, err := m.clobClient.ClobPair(ctx, &clob.QueryGetClobPairRequest{Id: clobPairID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query clob pair %d: %w", clobPairID, err)
+	}
+
+	metadata := clobPair.ClobPair.GetPerpetualClobMetadata()
+	if metadata == nil {
+		return nil, fmt.Errorf("clob pair %d is not a perpetual market", clobPairID)
+	}
+
+	perpetual, err := m.perpetualsClient.Perpetual(ctx, &perpetuals.QueryPerpetualRequest{Id: metadata.PerpetualId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query perpetual %d: %w", metadata.PerpetualId, err)
+	}
+
+	market, err := NewDydxMarket(clobPair.ClobPair, perpetual.Perpetual)
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Loaded dYdX market",
+		zap.String("market", market.Ticker),
+		zap.Uint32("clob_pair_id", market.ClobPairID),
+		zap.Int64("atomic_resolution", market.AtomicResolution),
+		zap.Int64("quantum_conversion_exponent", market.QuantumConversionExponent),
+		zap.Uint64("step_base_quantums", market.StepBaseQuantums),
+		zap.Uint64("subticks_per_tick", market.SubticksPerTick),
+	)
+
+	m.marketsMu.Lock()
+	m.markets[market.Ticker] = market
+	m.marketsMu.Unlock()
+
+	return market, nil
+}
+
+// AddMarkets loads and tracks each of the given clob pairs
+func (m *DydxProvider) AddMarkets(ctx context.Context, clobPairIDs ...uint32) error {
+	for _, clobPairID := range clobPairIDs {
+		if _, err := m.LoadMarket(ctx, clobPairID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetMarkets implements Provider
+func (m *DydxProvider) GetMarkets() []string {
+	m.marketsMu.RLock()
+	defer m.marketsMu.RUnlock()
+
+	markets := make([]string, 0, len(m.markets))
+	for ticker := range m.markets {
+		markets = append(markets, ticker)
+	}
+	sort.Strings(markets)
+
+	return markets
+}
+
+// GetPositions implements Provider, all tracked markets are read from a single indexer query
+func (m *DydxProvider) GetPositions(ctx context.Context) (map[string]*Position, error) {
+	_, account, err := m.clientRegistry.GetSignerAccountAndAddress(m.signerAccount, DydxChainID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := m.QuerySubaccountIndexer(ctx, account, m.subaccountID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching indexer data: %w", err)
+	}
+
+	positions := make(map[string]*Position)
+	for _, market := range m.GetMarkets() {
+		position, err := ProcessIndexerResponse(market, m.decimals, result)
+		if err != nil {
+			return nil, fmt.Errorf("error processing indexer data for %s: %w", market, err)
+		}
+
+		candles, err := m.QueryCandlePrices(ctx, market)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching candle prices for %s: %w", market, err)
+		}
+
+		currentPrice, err := ProcessCandlesResponse(candles)
+		if err != nil {
+			return nil, fmt.Errorf("error processing candle prices for %s: %w", market, err)
+		}
+
+		position.CurrentPrice = *currentPrice
+		positions[market] = position
+	}
+
+	return positions, nil
+}
+
+// getMarket returns the parameters of a tracked market
+func (m *DydxProvider) getMarket(market string) (*DydxMarket, error) {
+	m.marketsMu.RLock()
+	defer m.marketsMu.RUnlock()
+
+	params, exists := m.markets[market]
+	if !exists {
+		return nil, fmt.Errorf("market %s is not tracked", market)
+	}
+
+	return params, nil
+}
+
+// defaultMarket returns the parameters of the market the provider was configured with
+func (m *DydxProvider) defaultMarket() *DydxMarket {
+	return &DydxMarket{
+		Ticker:                    m.market,
+		ClobPairID:                m.marketID,
+		AtomicResolution:          m.atomicResolution,
+		QuantumConversionExponent: m.quantumConversionExponent,
+		StepBaseQuantums:          m.stepBaseQuantums,
+		SubticksPerTick:           m.subticksPerTick,
+	}
+}
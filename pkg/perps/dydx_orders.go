@@ -0,0 +1,444 @@
+package perps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/margined-protocol/locust-core/pkg/math"
+	clob "github.com/margined-protocol/locust-core/pkg/proto/dydx/clob/types"
+	subaccounts "github.com/margined-protocol/locust-core/pkg/proto/dydx/subaccounts/types"
+	"go.uber.org/zap"
+
+	sdkmath "cosmossdk.io/math"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// Order flags of the order id, 0 short-term, 32 conditional, 64 long-term
+const (
+	OrderFlagShortTerm   uint32 = 0
+	OrderFlagConditional uint32 = 32
+	OrderFlagLongTerm    uint32 = 64
+)
+
+// MaxStatefulOrderDuration is the furthest in the future a stateful order can expire
+const MaxStatefulOrderDuration = 90 * 24 * time.Hour
+
+// DydxOrderType is the kind of stateful order placed on dYdX
+type DydxOrderType string
+
+const (
+	DydxOrderTypeLimit      DydxOrderType = "LIMIT"       // Long-term good til time limit order
+	DydxOrderTypeStopLoss   DydxOrderType = "STOP_LOSS"   // Conditional order triggered against the position
+	DydxOrderTypeTakeProfit DydxOrderType = "TAKE_PROFIT" // Conditional order triggered in favour of the position
+)
+
+// DydxOrder is a stateful order placed by the provider
+type DydxOrder struct {
+	OrderID          clob.OrderId
+	Market           string
+	Type             DydxOrderType
+	IsBuy            bool
+	Quantums         uint64
+	Subticks         uint64
+	TriggerSubticks  uint64 // Zero for limit orders
+	ReduceOnly       bool
+	GoodTilBlockTime uint32
+	TxHash           string // Empty when restored from the indexer
+}
+
+// newStatefulOrder builds the place order message for a long-term or conditional order
+func newStatefulOrder(order *DydxOrder) (*clob.MsgPlaceOrder, error) {
+	side := clob.Order_SIDE_BUY
+	if !order.IsBuy {
+		side = clob.Order_SIDE_SELL
+	}
+
+	msg := &clob.MsgPlaceOrder{
+		Order: clob.Order{
+			OrderId:    order.OrderID,
+			Side:       side,
+			Quantums:   order.Quantums,
+			Subticks:   order.Subticks,
+			ReduceOnly: order.ReduceOnly,
+			GoodTilOneof: &clob.Order_GoodTilBlockTime{
+				GoodTilBlockTime: order.GoodTilBlockTime,
+			},
+		},
+	}
+
+	switch order.Type {
+	case DydxOrderTypeLimit:
+		if order.OrderID.OrderFlags != OrderFlagLongTerm {
+			return nil, fmt.Errorf("limit orders must be long-term, got order flags %d", order.OrderID.OrderFlags)
+		}
+	case DydxOrderTypeStopLoss, DydxOrderTypeTakeProfit:
+		if order.OrderID.OrderFlags != OrderFlagConditional {
+			return nil, fmt.Errorf("%s orders must be conditional, got order flags %d", order.Type, order.OrderID.OrderFlags)
+		}
+		if order.TriggerSubticks == 0 {
+			return nil, fmt.Errorf("%s orders require a trigger price", order.Type)
+		}
+
+		msg.Order.ConditionType = clob.Order_CONDITION_TYPE_STOP_LOSS
+		if order.Type == DydxOrderTypeTakeProfit {
+			msg.Order.ConditionType = clob.Order_CONDITION_TYPE_TAKE_PROFIT
+		}
+		msg.Order.ConditionalOrderTriggerSubticks = order.TriggerSubticks
+
+		// Triggered orders execute immediately at the limit price or better
+		msg.Order.TimeInForce = clob.Order_TIME_IN_FORCE_IOC
+	default:
+		return nil, fmt.Errorf("unknown order type %s", order.Type)
+	}
+
+	return msg, nil
+}
+
+// PlaceGTTOrder places a long-term limit order that rests on the book until filled,
+// cancelled or goodTil has elapsed
+func (m *DydxProvider) PlaceGTTOrder(ctx context.Context, market string, price float64, size sdkmath.Int, isBuy, reduceOnly bool, goodTil time.Duration) (*DydxOrder, error) {
+	return m.placeStatefulOrder(ctx, market, DydxOrderTypeLimit, price, 0, size, isBuy, reduceOnly, goodTil)
+}
+
+// PlaceStopLossOrder places a reduce only conditional order that executes once the oracle
+// price crosses the trigger price against the position, a long is protected by a sell
+func (m *DydxProvider) PlaceStopLossOrder(ctx context.Context, market string, triggerPrice float64, size sdkmath.Int, isBuy bool, goodTil time.Duration) (*DydxOrder, error) {
+	limitPrice := math.AdjustSlippageFloat64(triggerPrice, m.slippage, isBuy)
+	return m.placeStatefulOrder(ctx, market, DydxOrderTypeStopLoss, limitPrice, triggerPrice, size, isBuy, true, goodTil)
+}
+
+// PlaceTakeProfitOrder places a reduce only conditional order that executes once the
+// oracle price crosses the trigger price in favour of the position
+func (m *DydxProvider) PlaceTakeProfitOrder(ctx context.Context, market string, triggerPrice float64, size sdkmath.Int, isBuy bool, goodTil time.Duration) (*DydxOrder, error) {
+	limitPrice := math.AdjustSlippageFloat64(triggerPrice, m.slippage, isBuy)
+	return m.placeStatefulOrder(ctx, market, DydxOrderTypeTakeProfit, limitPrice, triggerPrice, size, isBuy, true, goodTil)
+}
+
+// placeStatefulOrder validates, broadcasts and tracks a long-term or conditional order
+func (m *DydxProvider) placeStatefulOrder(
+	_ context.Context, market string, orderType DydxOrderType,
+	price, triggerPrice float64, size sdkmath.Int, isBuy, reduceOnly bool, goodTil time.Duration,
+) (*DydxOrder, error) {
+	if goodTil <= 0 || goodTil > MaxStatefulOrderDuration {
+		return nil, fmt.Errorf("good til must be between 0 and %s, got %s", MaxStatefulOrderDuration, goodTil)
+	}
+
+	params, err := m.getMarket(market)
+	if err != nil {
+		return nil, err
+	}
+
+	_, account, err := m.clientRegistry.GetSignerAccountAndAddress(m.signerAccount, DydxChainID)
+	if err != nil {
+		return nil, err
+	}
+
+	validPrice, err := params.validateAndRoundPrice(math.FloatToQuantumPrice(price, params.QuantumConversionExponent))
+	if err != nil {
+		return nil, fmt.Errorf("invalid price: %w", err)
+	}
+
+	validSize, err := params.validateAndRoundAmount(size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid size: %w", err)
+	}
+
+	orderFlags := OrderFlagLongTerm
+	var triggerSubticks uint64
+	if orderType != DydxOrderTypeLimit {
+		orderFlags = OrderFlagConditional
+
+		validTrigger, err := params.validateAndRoundPrice(math.FloatToQuantumPrice(triggerPrice, params.QuantumConversionExponent))
+		if err != nil {
+			return nil, fmt.Errorf("invalid trigger price: %w", err)
+		}
+		triggerSubticks = validTrigger.Uint64()
+	}
+
+	order := &DydxOrder{
+		OrderID: clob.OrderId{
+			SubaccountId: subaccounts.SubaccountId{
+				Owner:  account,
+				Number: m.subaccountID,
+			},
+			ClientId:   m.nextClientID(),
+			ClobPairId: params.ClobPairID,
+			OrderFlags: orderFlags,
+		},
+		Market:           market,
+		Type:             orderType,
+		IsBuy:            isBuy,
+		Quantums:         validSize.Uint64(),
+		Subticks:         validPrice.Uint64(),
+		TriggerSubticks:  triggerSubticks,
+		ReduceOnly:       reduceOnly,
+		GoodTilBlockTime: uint32(time.Now().Add(goodTil).Unix()),
+	}
+
+	msg, err := newStatefulOrder(order)
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Placing stateful order",
+		zap.String("market", market),
+		zap.String("type", string(orderType)),
+		zap.Uint32("client_id", order.OrderID.ClientId),
+		zap.Uint64("quantums", order.Quantums),
+		zap.Uint64("subticks", order.Subticks),
+		zap.Uint64("trigger_subticks", order.TriggerSubticks),
+		zap.Bool("isBuy", isBuy),
+		zap.Bool("reduceOnly", reduceOnly),
+	)
+
+	tx, err := m.msgHandler(DydxChainID, []sdk.Msg{msg}, true, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place %s order: %w", orderType, err)
+	}
+	order.TxHash = tx.TxHash
+
+	m.ordersMu.Lock()
+	m.orders[order.OrderID.ClientId] = order
+	m.ordersMu.Unlock()
+
+	return order, nil
+}
+
+// CancelOrder cancels a tracked stateful order by its client id
+func (m *DydxProvider) CancelOrder(_ context.Context, clientID uint32) (*ExecutionResult, error) {
+	m.ordersMu.RLock()
+	order, exists := m.orders[clientID]
+	m.ordersMu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("order %d is not tracked", clientID)
+	}
+
+	// Stateful cancellations must expire within the stateful order time window
+	expiry := time.Now().Add(time.Duration(m.orderExpiry) * time.Second)
+
+	msgs := []sdk.Msg{
+		&clob.MsgCancelOrder{
+			OrderId: order.OrderID,
+			GoodTilOneof: &clob.MsgCancelOrder_GoodTilBlockTime{
+				GoodTilBlockTime: uint32(expiry.Unix()),
+			},
+		},
+	}
+
+	m.logger.Info("Cancelling stateful order",
+		zap.String("market", order.Market),
+		zap.String("type", string(order.Type)),
+		zap.Uint32("client_id", clientID),
+	)
+
+	tx, err := m.msgHandler(DydxChainID, msgs, true, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel order %d: %w", clientID, err)
+	}
+
+	m.ordersMu.Lock()
+	delete(m.orders, clientID)
+	m.ordersMu.Unlock()
+
+	return &ExecutionResult{
+		TxHash:   tx.TxHash,
+		Events:   tx.Events,
+		Executed: true,
+		Messages: msgs,
+	}, nil
+}
+
+// GetOrders returns the tracked stateful orders ordered by client id
+func (m *DydxProvider) GetOrders() []*DydxOrder {
+	m.ordersMu.RLock()
+	defer m.ordersMu.RUnlock()
+
+	orders := make([]*DydxOrder, 0, len(m.orders))
+	for _, order := range m.orders {
+		orders = append(orders, order)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].OrderID.ClientId < orders[j].OrderID.ClientId
+	})
+
+	return orders
+}
+
+// SyncOrders replaces the tracked orders with the open stateful orders reported by the
+// indexer, so protective orders placed before a restart can still be managed
+func (m *DydxProvider) SyncOrders(ctx context.Context) error {
+	_, account, err := m.clientRegistry.GetSignerAccountAndAddress(m.signerAccount, DydxChainID)
+	if err != nil {
+		return err
+	}
+
+	indexerOrders, err := m.QueryOrdersIndexer(ctx, account, m.subaccountID)
+	if err != nil {
+		return fmt.Errorf("error fetching indexer orders: %w", err)
+	}
+
+	orders := make(map[uint32]*DydxOrder)
+	for _, indexerOrder := range indexerOrders {
+		params, err := m.getMarket(indexerOrder.Ticker)
+		if err != nil {
+			// Orders in markets we do not track are left alone
+			continue
+		}
+
+		order, err := ProcessIndexerOrder(account, params, indexerOrder)
+		if err != nil {
+			return fmt.Errorf("error processing indexer order %s: %w", indexerOrder.ID, err)
+		}
+		if order == nil {
+			continue
+		}
+
+		orders[order.OrderID.ClientId] = order
+	}
+
+	m.ordersMu.Lock()
+	m.orders = orders
+	m.ordersMu.Unlock()
+
+	m.logger.Info("Synced stateful orders", zap.Int("orders", len(orders)))
+
+	return nil
+}
+
+// ProcessIndexerOrder converts an open stateful indexer order into a DydxOrder, nil is
+// returned for short-term and closed orders
+func ProcessIndexerOrder(account string, params *DydxMarket, order IndexerOrder) (*DydxOrder, error) {
+	switch order.Status {
+	case "OPEN", "UNTRIGGERED", "BEST_EFFORT_OPENED":
+	default:
+		return nil, nil
+	}
+
+	orderFlags, err := strconv.ParseUint(order.OrderFlags, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid order flags %s: %w", order.OrderFlags, err)
+	}
+
+	var orderType DydxOrderType
+	switch uint32(orderFlags) {
+	case OrderFlagLongTerm:
+		orderType = DydxOrderTypeLimit
+	case OrderFlagConditional:
+		switch order.Type {
+		case "STOP_LIMIT", "STOP_MARKET":
+			orderType = DydxOrderTypeStopLoss
+		case "TAKE_PROFIT", "TAKE_PROFIT_MARKET":
+			orderType = DydxOrderTypeTakeProfit
+		default:
+			return nil, fmt.Errorf("unknown conditional order type %s", order.Type)
+		}
+	default:
+		return nil, nil
+	}
+
+	clientID, err := strconv.ParseUint(order.ClientID, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client id %s: %w", order.ClientID, err)
+	}
+
+	size, err := sdkmath.LegacyNewDecFromStr(order.Size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid size %s: %w", order.Size, err)
+	}
+
+	price, err := strconv.ParseFloat(order.Price, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid price %s: %w", order.Price, err)
+	}
+
+	var triggerSubticks uint64
+	if orderType != DydxOrderTypeLimit {
+		triggerPrice, err := strconv.ParseFloat(order.TriggerPrice, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trigger price %s: %w", order.TriggerPrice, err)
+		}
+		triggerSubticks = math.FloatToQuantumPrice(triggerPrice, params.QuantumConversionExponent).Uint64()
+	}
+
+	var goodTilBlockTime uint32
+	if order.GoodTilBlockTime != "" {
+		expiry, err := time.Parse(time.RFC3339, order.GoodTilBlockTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid good til block time %s: %w", order.GoodTilBlockTime, err)
+		}
+		goodTilBlockTime = uint32(expiry.Unix())
+	}
+
+	return &DydxOrder{
+		OrderID: clob.OrderId{
+			SubaccountId: subaccounts.SubaccountId{
+				Owner:  account,
+				Number: order.SubaccountNumber,
+			},
+			ClientId:   uint32(clientID),
+			ClobPairId: params.ClobPairID,
+			OrderFlags: uint32(orderFlags),
+		},
+		Market:           order.Ticker,
+		Type:             orderType,
+		IsBuy:            order.Side == "BUY",
+		Quantums:         params.sizeToQuantums(size).Uint64(),
+		Subticks:         math.FloatToQuantumPrice(price, params.QuantumConversionExponent).Uint64(),
+		TriggerSubticks:  triggerSubticks,
+		ReduceOnly:       order.ReduceOnly,
+		GoodTilBlockTime: goodTilBlockTime,
+	}, nil
+}
+
+// QueryOrdersIndexer fetches the orders of a subaccount from the indexer
+func (m *DydxProvider) QueryOrdersIndexer(ctx context.Context, address string, subaccountNumber uint32) ([]IndexerOrder, error) {
+	path := fmt.Sprintf("/orders?address=%s&subaccountNumber=%d", address, subaccountNumber)
+
+	url := fmt.Sprintf("%s%s", m.indexerURL, path)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("indexer request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result []IndexerOrder
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return result, nil
+}
+
+// nextClientID returns a random client id that is not already tracked, stateful orders
+// with the same order id replace each other
+func (m *DydxProvider) nextClientID() uint32 {
+	m.ordersMu.RLock()
+	defer m.ordersMu.RUnlock()
+
+	for {
+		clientID := rand.Uint32()
+		if _, exists := m.orders[clientID]; !exists {
+			return clientID
+		}
+	}
+}
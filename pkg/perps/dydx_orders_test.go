@@ -0,0 +1,220 @@
+package perps
+
+import (
+	"testing"
+
+	clob "github.com/margined-protocol/locust-core/pkg/proto/dydx/clob/types"
+	subaccounts "github.com/margined-protocol/locust-core/pkg/proto/dydx/subaccounts/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStatefulOrder(t *testing.T) {
+	orderID := func(flags uint32) clob.OrderId {
+		return clob.OrderId{
+			SubaccountId: subaccounts.SubaccountId{Owner: "dydx1owner", Number: 0},
+			ClientId:     7,
+			ClobPairId:   3,
+			OrderFlags:   flags,
+		}
+	}
+
+	tests := []struct {
+		name          string
+		order         *DydxOrder
+		wantCondition clob.Order_ConditionType
+		wantTIF       clob.Order_TimeInForce
+		wantErr       bool
+	}{
+		{
+			name: "gtt limit",
+			order: &DydxOrder{
+				OrderID:          orderID(OrderFlagLongTerm),
+				Type:             DydxOrderTypeLimit,
+				IsBuy:            true,
+				Quantums:         1_000_000,
+				Subticks:         10_000,
+				GoodTilBlockTime: 1_700_000_000,
+			},
+			wantCondition: clob.Order_CONDITION_TYPE_UNSPECIFIED,
+			wantTIF:       clob.Order_TIME_IN_FORCE_UNSPECIFIED,
+		},
+		{
+			name: "stop loss",
+			order: &DydxOrder{
+				OrderID:          orderID(OrderFlagConditional),
+				Type:             DydxOrderTypeStopLoss,
+				Quantums:         1_000_000,
+				Subticks:         9_000,
+				TriggerSubticks:  9_100,
+				ReduceOnly:       true,
+				GoodTilBlockTime: 1_700_000_000,
+			},
+			wantCondition: clob.Order_CONDITION_TYPE_STOP_LOSS,
+			wantTIF:       clob.Order_TIME_IN_FORCE_IOC,
+		},
+		{
+			name: "take profit",
+			order: &DydxOrder{
+				OrderID:          orderID(OrderFlagConditional),
+				Type:             DydxOrderTypeTakeProfit,
+				Quantums:         1_000_000,
+				Subticks:         11_000,
+				TriggerSubticks:  11_100,
+				ReduceOnly:       true,
+				GoodTilBlockTime: 1_700_000_000,
+			},
+			wantCondition: clob.Order_CONDITION_TYPE_TAKE_PROFIT,
+			wantTIF:       clob.Order_TIME_IN_FORCE_IOC,
+		},
+		{
+			name: "conditional without trigger",
+			order: &DydxOrder{
+				OrderID: orderID(OrderFlagConditional),
+				Type:    DydxOrderTypeStopLoss,
+			},
+			wantErr: true,
+		},
+		{
+			name: "limit with conditional flags",
+			order: &DydxOrder{
+				OrderID: orderID(OrderFlagConditional),
+				Type:    DydxOrderTypeLimit,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown type",
+			order: &DydxOrder{
+				OrderID: orderID(OrderFlagLongTerm),
+				Type:    "MARKET",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := newStatefulOrder(tt.order)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.order.OrderID, msg.Order.OrderId)
+			assert.Equal(t, tt.order.Quantums, msg.Order.Quantums)
+			assert.Equal(t, tt.order.Subticks, msg.Order.Subticks)
+			assert.Equal(t, tt.order.TriggerSubticks, msg.Order.ConditionalOrderTriggerSubticks)
+			assert.Equal(t, tt.order.ReduceOnly, msg.Order.ReduceOnly)
+			assert.Equal(t, tt.wantCondition, msg.Order.ConditionType)
+			assert.Equal(t, tt.wantTIF, msg.Order.TimeInForce)
+			assert.Equal(t, tt.order.GoodTilBlockTime, msg.Order.GetGoodTilBlockTime())
+		})
+	}
+}
+
+func TestProcessIndexerOrder(t *testing.T) {
+	params := &DydxMarket{
+		Ticker:                    "ATOM-USD",
+		ClobPairID:                3,
+		AtomicResolution:          -6,
+		QuantumConversionExponent: -9,
+		StepBaseQuantums:          1_000,
+		SubticksPerTick:           1_000,
+	}
+
+	tests := []struct {
+		name        string
+		order       IndexerOrder
+		wantNil     bool
+		wantType    DydxOrderType
+		wantTrigger uint64
+		wantErr     bool
+	}{
+		{
+			name: "open long-term limit",
+			order: IndexerOrder{
+				ClientID:         "42",
+				Side:             "BUY",
+				Size:             "1.5",
+				Price:            "8.25",
+				Type:             "LIMIT",
+				Status:           "OPEN",
+				OrderFlags:       "64",
+				GoodTilBlockTime: "2024-01-01T00:00:00.000Z",
+				Ticker:           "ATOM-USD",
+			},
+			wantType: DydxOrderTypeLimit,
+		},
+		{
+			name: "untriggered stop",
+			order: IndexerOrder{
+				ClientID:     "43",
+				Side:         "SELL",
+				Size:         "1.5",
+				Price:        "7.9",
+				TriggerPrice: "8",
+				Type:         "STOP_MARKET",
+				Status:       "UNTRIGGERED",
+				ReduceOnly:   true,
+				OrderFlags:   "32",
+				Ticker:       "ATOM-USD",
+			},
+			wantType:    DydxOrderTypeStopLoss,
+			wantTrigger: 8_000_000_000,
+		},
+		{
+			name: "filled order is skipped",
+			order: IndexerOrder{
+				Status:     "FILLED",
+				OrderFlags: "64",
+			},
+			wantNil: true,
+		},
+		{
+			name: "short-term order is skipped",
+			order: IndexerOrder{
+				Status:     "OPEN",
+				OrderFlags: "0",
+			},
+			wantNil: true,
+		},
+		{
+			name: "unknown conditional type",
+			order: IndexerOrder{
+				Status:     "UNTRIGGERED",
+				OrderFlags: "32",
+				Type:       "TRAILING_STOP",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := ProcessIndexerOrder("dydx1owner", params, tt.order)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			if tt.wantNil {
+				assert.Nil(t, order)
+				return
+			}
+
+			require.NotNil(t, order)
+			assert.Equal(t, tt.wantType, order.Type)
+			assert.Equal(t, params.ClobPairID, order.OrderID.ClobPairId)
+			assert.Equal(t, "dydx1owner", order.OrderID.SubaccountId.Owner)
+			assert.Equal(t, uint64(1_500_000), order.Quantums)
+			assert.Equal(t, tt.wantTrigger, order.TriggerSubticks)
+			assert.Equal(t, tt.order.Side == "BUY", order.IsBuy)
+			assert.Equal(t, tt.order.ReduceOnly, order.ReduceOnly)
+		})
+	}
+}
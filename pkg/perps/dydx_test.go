@@ -3,6 +3,8 @@ package perps
 import (
 	"testing"
 
+	clob "github.com/margined-protocol/locust-core/pkg/proto/dydx/clob/types"
+	perpetuals "github.com/margined-protocol/locust-core/pkg/proto/dydx/perpetuals/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -308,3 +310,192 @@ func TestGetLiquidationPriceEdgeCases(t *testing.T) {
 	)
 	assert.False(t, liquidationPrice.IsNil(), "Small values should not result in nil")
 }
+
+func TestSizeToQuantums(t *testing.T) {
+	tests := []struct {
+		name             string
+		atomicResolution int64
+		size             string
+		want             int64
+		wantErr          bool
+	}{
+		{
+			name:             "long position",
+			atomicResolution: -6,
+			size:             "1.5",
+			want:             1_500_000,
+		},
+		{
+			name:             "short position is absolute",
+			atomicResolution: -6,
+			size:             "-0.25",
+			want:             250_000,
+		},
+		{
+			name:             "positive resolution",
+			atomicResolution: 2,
+			size:             "1200",
+			want:             12,
+		},
+		{
+			name:             "invalid size",
+			atomicResolution: -6,
+			size:             "abc",
+			wantErr:          true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &DydxProvider{atomicResolution: tt.atomicResolution}
+
+			got, err := provider.sizeToQuantums(tt.size)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, sdkmath.NewInt(tt.want), got)
+		})
+	}
+}
+
+func TestNewDydxMarket(t *testing.T) {
+	perpetualClobPair := func(id, perpetualID uint32) clob.ClobPair {
+		return clob.ClobPair{
+			Id: id,
+			Metadata: &clob.ClobPair_PerpetualClobMetadata{
+				PerpetualClobMetadata: &clob.PerpetualClobMetadata{PerpetualId: perpetualID},
+			},
+			StepBaseQuantums:          100_000,
+			SubticksPerTick:           1_000,
+			QuantumConversionExponent: -9,
+		}
+	}
+
+	perpetual := perpetuals.Perpetual{
+		Params: perpetuals.PerpetualParams{
+			Id:               3,
+			Ticker:           "ATOM-USD",
+			AtomicResolution: -6,
+		},
+	}
+
+	tests := []struct {
+		name        string
+		clobPair    clob.ClobPair
+		want        *DydxMarket
+		errContains string
+	}{
+		{
+			name:     "perpetual market",
+			clobPair: perpetualClobPair(7, 3),
+			want: &DydxMarket{
+				Ticker:                    "ATOM-USD",
+				ClobPairID:                7,
+				PerpetualID:               3,
+				AtomicResolution:          -6,
+				QuantumConversionExponent: -9,
+				StepBaseQuantums:          100_000,
+				SubticksPerTick:           1_000,
+			},
+		},
+		{
+			name:        "spot market",
+			clobPair:    clob.ClobPair{Id: 7},
+			errContains: "not a perpetual market",
+		},
+		{
+			name:        "perpetual mismatch",
+			clobPair:    perpetualClobPair(7, 4),
+			errContains: "references perpetual 4",
+		},
+		{
+			name: "zero step size",
+			clobPair: func() clob.ClobPair {
+				pair := perpetualClobPair(7, 3)
+				pair.StepBaseQuantums = 0
+				return pair
+			}(),
+			errContains: "zero step base quantums",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewDydxMarket(tt.clobPair, perpetual)
+
+			if tt.errContains != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.errContains)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCheckMarketConfig(t *testing.T) {
+	discovered := &DydxMarket{
+		Ticker:                    "ATOM-USD",
+		ClobPairID:                7,
+		AtomicResolution:          -6,
+		QuantumConversionExponent: -9,
+		StepBaseQuantums:          100_000,
+		SubticksPerTick:           1_000,
+	}
+
+	tests := []struct {
+		name        string
+		configured  *DydxMarket
+		errContains []string
+	}{
+		{
+			name:       "unset values are filled from chain",
+			configured: &DydxMarket{Ticker: "ATOM-USD", ClobPairID: 7},
+		},
+		{
+			name:       "matching values",
+			configured: discovered,
+		},
+		{
+			name: "mismatching values",
+			configured: &DydxMarket{
+				Ticker:                    "ATOM-USD",
+				ClobPairID:                7,
+				AtomicResolution:          -7,
+				QuantumConversionExponent: -9,
+				StepBaseQuantums:          1_000_000,
+				SubticksPerTick:           1_000,
+			},
+			errContains: []string{
+				"atomic_resolution: configured -7, chain -6",
+				"step_base_quantums: configured 1000000, chain 100000",
+			},
+		},
+		{
+			name:        "wrong market",
+			configured:  &DydxMarket{Ticker: "OSMO-USD", ClobPairID: 7},
+			errContains: []string{"market: configured OSMO-USD, chain ATOM-USD"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkMarketConfig(tt.configured, discovered)
+
+			if len(tt.errContains) > 0 {
+				require.Error(t, err)
+				for _, contains := range tt.errContains {
+					require.Contains(t, err.Error(), contains)
+				}
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
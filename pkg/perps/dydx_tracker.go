@@ -0,0 +1,345 @@
+package perps
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosaccount"
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosclient"
+	"github.com/margined-protocol/locust-core/pkg/connection"
+	clob "github.com/margined-protocol/locust-core/pkg/proto/dydx/clob/types"
+	"go.uber.org/zap"
+
+	sdkmath "cosmossdk.io/math"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+const (
+	orderTrackingAttempts = 10
+	orderTrackingInterval = 1 * time.Second
+	// Blocks the indexer is given to report the fills of an order after it expired
+	orderIndexerLagBlocks = 2
+)
+
+// ReissuePolicy decides whether the unfilled remainder of a short-term order is reissued
+type ReissuePolicy struct {
+	MaxReissues    int  // Maximum number of times the remainder is reissued, zero disables reissuing
+	ReissueExpired bool // Also reissue orders that expired without any fill
+}
+
+// ShouldReissue reports whether the remainder of the order should be sent again after
+// the given number of reissues
+func (p ReissuePolicy) ShouldReissue(report *OrderReport, reissues int) bool {
+	if reissues >= p.MaxReissues {
+		return false
+	}
+
+	switch report.Status {
+	case OrderStatusPartiallyFilled:
+		return true
+	case OrderStatusExpired:
+		return p.ReissueExpired
+	default:
+		return false
+	}
+}
+
+// SetReissuePolicy overrides the policy used to reissue unfilled short-term orders
+func (m *DydxProvider) SetReissuePolicy(policy ReissuePolicy) {
+	m.reissuePolicy = policy
+}
+
+// TrackOrder reconciles a placed order against the orders and fills reported by the indexer
+func (m *DydxProvider) TrackOrder(ctx context.Context, market string, order clob.Order) (*OrderReport, error) {
+	params, err := m.getMarket(market)
+	if err != nil {
+		return nil, err
+	}
+
+	subaccount := order.OrderId.SubaccountId
+
+	orders, err := m.QueryOrdersIndexer(ctx, subaccount.Owner, subaccount.Number)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching indexer orders: %w", err)
+	}
+
+	indexerOrder := findIndexerOrder(orders, order.OrderId)
+
+	var fills []IndexerFill
+	if indexerOrder != nil {
+		result, err := m.QueryFillsIndexer(ctx, subaccount.Owner, subaccount.Number)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching indexer fills: %w", err)
+		}
+		fills = result.Fills
+	}
+
+	return ReconcileOrder(params, order, indexerOrder, fills)
+}
+
+// findIndexerOrder returns the indexer order matching the order id, if any
+func findIndexerOrder(orders []IndexerOrder, orderID clob.OrderId) *IndexerOrder {
+	clientID := strconv.FormatUint(uint64(orderID.ClientId), 10)
+	clobPairID := strconv.FormatUint(uint64(orderID.ClobPairId), 10)
+	orderFlags := strconv.FormatUint(uint64(orderID.OrderFlags), 10)
+
+	for i := range orders {
+		if orders[i].ClientID == clientID && orders[i].ClobPairID == clobPairID && orders[i].OrderFlags == orderFlags {
+			return &orders[i]
+		}
+	}
+
+	return nil
+}
+
+// ReconcileOrder builds the report of an order from its indexer order and fills. The filled
+// size is the total filled reported on the order, fills are only used for the average price
+// and fees and may lag behind it. The order is final once the indexer closes it, an order past
+// its good til block stays pending until waitForOrder sees the chain move past it.
+func ReconcileOrder(params *DydxMarket, order clob.Order, indexerOrder *IndexerOrder, fills []IndexerFill) (*OrderReport, error) {
+	report := &OrderReport{
+		OrderIDs:     []string{strconv.FormatUint(uint64(order.OrderId.ClientId), 10)},
+		Requested:    sdkmath.NewIntFromUint64(order.Quantums),
+		Filled:       sdkmath.ZeroInt(),
+		AveragePrice: sdkmath.LegacyZeroDec(),
+		Fees:         sdkmath.LegacyZeroDec(),
+	}
+
+	final := false
+	if indexerOrder != nil {
+		switch indexerOrder.Status {
+		case "FILLED", "CANCELED", "BEST_EFFORT_CANCELED":
+			final = true
+		}
+
+		if indexerOrder.TotalFilled != "" {
+			totalFilled, err := sdkmath.LegacyNewDecFromStr(indexerOrder.TotalFilled)
+			if err != nil {
+				return nil, fmt.Errorf("invalid total filled %s: %w", indexerOrder.TotalFilled, err)
+			}
+			report.Filled = params.sizeToQuantums(totalFilled)
+		}
+
+		size := sdkmath.LegacyZeroDec()
+		notional := sdkmath.LegacyZeroDec()
+		for _, fill := range fills {
+			if fill.OrderID != indexerOrder.ID {
+				continue
+			}
+
+			fillSize, err := sdkmath.LegacyNewDecFromStr(fill.Size)
+			if err != nil {
+				return nil, fmt.Errorf("invalid fill size %s: %w", fill.Size, err)
+			}
+
+			fillPrice, err := sdkmath.LegacyNewDecFromStr(fill.Price)
+			if err != nil {
+				return nil, fmt.Errorf("invalid fill price %s: %w", fill.Price, err)
+			}
+
+			fee, err := sdkmath.LegacyNewDecFromStr(fill.Fee)
+			if err != nil {
+				return nil, fmt.Errorf("invalid fill fee %s: %w", fill.Fee, err)
+			}
+
+			size = size.Add(fillSize)
+			notional = notional.Add(fillSize.Mul(fillPrice))
+			report.Fees = report.Fees.Add(fee)
+		}
+
+		if size.IsPositive() {
+			report.AveragePrice = notional.Quo(size)
+		}
+	}
+
+	report.Status = orderStatus(report.Requested, report.Filled, final)
+
+	return report, nil
+}
+
+// orderStatus derives the status of an order from the requested and filled sizes
+func orderStatus(requested, filled sdkmath.Int, final bool) OrderStatus {
+	switch {
+	case filled.GTE(requested):
+		return OrderStatusFilled
+	case !final:
+		return OrderStatusPending
+	case filled.IsPositive():
+		return OrderStatusPartiallyFilled
+	default:
+		return OrderStatusExpired
+	}
+}
+
+// mergeOrderReports combines the report of a reissued remainder into the original report
+func mergeOrderReports(original, reissued *OrderReport) *OrderReport {
+	filled := original.Filled.Add(reissued.Filled)
+
+	averagePrice := sdkmath.LegacyZeroDec()
+	if filled.IsPositive() {
+		averagePrice = original.AveragePrice.MulInt(original.Filled).
+			Add(reissued.AveragePrice.MulInt(reissued.Filled)).
+			QuoInt(filled)
+	}
+
+	// A pending remainder leaves the whole order pending
+	final := reissued.Status != OrderStatusPending
+
+	return &OrderReport{
+		OrderIDs:     append(append([]string{}, original.OrderIDs...), reissued.OrderIDs...),
+		Status:       orderStatus(original.Requested, filled, final),
+		Requested:    original.Requested,
+		Filled:       filled,
+		AveragePrice: averagePrice,
+		Fees:         original.Fees.Add(reissued.Fees),
+	}
+}
+
+// waitForOrder polls the indexer until the order is final. A short-term order cannot fill once
+// the chain is past its good til block, so the first report taken after the indexer had time to
+// catch up is final. Polling fails once the indexer or the chain height could not be read for
+// orderTrackingAttempts polls in a row.
+func (m *DydxProvider) waitForOrder(ctx context.Context, market string, order clob.Order) (*OrderReport, error) {
+	goodTilBlock := order.GetGoodTilBlock()
+	if goodTilBlock == 0 {
+		return nil, fmt.Errorf("order %d has no good til block", order.OrderId.ClientId)
+	}
+
+	var err error
+	for failures := 0; failures < orderTrackingAttempts; {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(orderTrackingInterval):
+		}
+
+		// The height is read before the order so an expired order's report includes every fill
+		var height *int64
+		height, err = m.clientRegistry.GetHeight(ctx, DydxChainID)
+		if err != nil {
+			failures++
+			m.logger.Warn("Failed to get height while tracking order",
+				zap.Uint32("client_id", order.OrderId.ClientId),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		var report *OrderReport
+		report, err = m.TrackOrder(ctx, market, order)
+		if err != nil {
+			failures++
+			m.logger.Warn("Failed to track order",
+				zap.Uint32("client_id", order.OrderId.ClientId),
+				zap.Int("failures", failures),
+				zap.Error(err),
+			)
+			continue
+		}
+		failures = 0
+
+		report = expireOrder(report, goodTilBlock, *height)
+		if report.Status != OrderStatusPending {
+			return report, nil
+		}
+
+		m.logger.Debug("Order not yet final",
+			zap.Uint32("client_id", order.OrderId.ClientId),
+			zap.String("filled", report.Filled.String()),
+			zap.Int64("height", *height),
+			zap.Uint32("good_til_block", goodTilBlock),
+		)
+	}
+
+	return nil, fmt.Errorf("failed to track order %d: %w", order.OrderId.ClientId, err)
+}
+
+// expireOrder finalises a pending report once the chain height has passed the good til block
+// of the order and the indexer lag, the filled size so far is final even when the indexer has
+// not closed or listed the order
+func expireOrder(report *OrderReport, goodTilBlock uint32, height int64) *OrderReport {
+	if report.Status != OrderStatusPending || height <= int64(goodTilBlock)+orderIndexerLagBlocks {
+		return report
+	}
+
+	expired := *report
+	expired.Status = orderStatus(report.Requested, report.Filled, true)
+	return &expired
+}
+
+// sendShortTermOrder broadcasts a short-term order, reconciles it against the indexer fills
+// and reissues the unfilled remainder as allowed by the reissue policy. All messages sent
+// are returned alongside the report.
+func (m *DydxProvider) sendShortTermOrder(
+	ctx context.Context, client *cosmosclient.Client, account *cosmosaccount.Account,
+	market string, orderMsgs []sdk.Msg,
+) (*OrderReport, []sdk.Msg, error) {
+	var report *OrderReport
+	var sent []sdk.Msg
+
+	for reissues := 0; ; reissues++ {
+		if len(orderMsgs) != 1 {
+			return nil, sent, fmt.Errorf("expected a single place order message, got %d messages", len(orderMsgs))
+		}
+
+		placeOrder, ok := orderMsgs[0].(*clob.MsgPlaceOrder)
+		if !ok {
+			return nil, sent, fmt.Errorf("expected a place order message, got %T", orderMsgs[0])
+		}
+
+		err := connection.BroadcastShortTermOrder(ctx, m.logger, client, *account, orderMsgs...)
+		if err != nil {
+			return nil, sent, fmt.Errorf("failed to broadcast order: %w", err)
+		}
+		sent = append(sent, orderMsgs...)
+
+		orderReport, err := m.waitForOrder(ctx, market, placeOrder.Order)
+		if err != nil {
+			return nil, sent, err
+		}
+
+		if report == nil {
+			report = orderReport
+		} else {
+			report = mergeOrderReports(report, orderReport)
+		}
+
+		m.logger.Info("Order reconciled",
+			zap.Strings("order_ids", report.OrderIDs),
+			zap.String("status", string(report.Status)),
+			zap.String("requested", report.Requested.String()),
+			zap.String("filled", report.Filled.String()),
+			zap.String("average_price", report.AveragePrice.String()),
+			zap.String("fees", report.Fees.String()),
+		)
+
+		if !m.reissuePolicy.ShouldReissue(orderReport, reissues) {
+			return report, sent, nil
+		}
+
+		remaining := report.Requested.Sub(report.Filled)
+		isBuy := placeOrder.Order.Side == clob.Order_SIDE_BUY
+
+		orderMsgs, err = m.CreateMarketOrderForMarket(ctx, market, sdkmath.ZeroInt(), sdkmath.ZeroInt(), remaining, isBuy, placeOrder.Order.ReduceOnly)
+		if err != nil {
+			// The remainder may be below the minimum order size or the book too thin
+			m.logger.Warn("Not reissuing order remainder",
+				zap.String("remaining", remaining.String()),
+				zap.Error(err),
+			)
+			return report, sent, nil
+		}
+
+		if orderMsgs == nil {
+			return report, sent, nil
+		}
+
+		m.logger.Info("Reissuing order remainder",
+			zap.String("remaining", remaining.String()),
+			zap.Int("reissue", reissues+1),
+		)
+	}
+}
@@ -0,0 +1,237 @@
+package perps
+
+import (
+	"testing"
+
+	clob "github.com/margined-protocol/locust-core/pkg/proto/dydx/clob/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+func TestReconcileOrder(t *testing.T) {
+	params := &DydxMarket{
+		Ticker:           "ATOM-USD",
+		ClobPairID:       3,
+		AtomicResolution: -6,
+	}
+
+	order := clob.Order{
+		OrderId:      clob.OrderId{ClientId: 7, ClobPairId: 3},
+		Quantums:     2_000_000,
+		GoodTilOneof: &clob.Order_GoodTilBlock{GoodTilBlock: 100},
+	}
+
+	fills := []IndexerFill{
+		{OrderID: "order-1", Price: "10", Size: "1.5", Fee: "0.0075"},
+		{OrderID: "order-1", Price: "10.2", Size: "0.5", Fee: "0.0025"},
+		{OrderID: "order-2", Price: "11", Size: "3", Fee: "0.1"},
+	}
+
+	tests := []struct {
+		name         string
+		indexerOrder *IndexerOrder
+		fills        []IndexerFill
+		wantStatus   OrderStatus
+		wantFilled   int64
+		wantAverage  string
+		wantFees     string
+		wantErr      bool
+	}{
+		{
+			name:         "filled across fills",
+			indexerOrder: &IndexerOrder{ID: "order-1", Status: "FILLED", TotalFilled: "2"},
+			fills:        fills,
+			wantStatus:   OrderStatusFilled,
+			wantFilled:   2_000_000,
+			wantAverage:  "10.05",
+			wantFees:     "0.01",
+		},
+		{
+			name:         "filled before its fills are indexed",
+			indexerOrder: &IndexerOrder{ID: "order-1", Status: "FILLED", TotalFilled: "2"},
+			wantStatus:   OrderStatusFilled,
+			wantFilled:   2_000_000,
+			wantAverage:  "0",
+			wantFees:     "0",
+		},
+		{
+			name:         "cancelled with some fills not yet indexed",
+			indexerOrder: &IndexerOrder{ID: "order-1", Status: "CANCELED", TotalFilled: "1.5"},
+			fills:        []IndexerFill{{OrderID: "order-1", Price: "10", Size: "1", Fee: "0.005"}},
+			wantStatus:   OrderStatusPartiallyFilled,
+			wantFilled:   1_500_000,
+			wantAverage:  "10",
+			wantFees:     "0.005",
+		},
+		{
+			name:         "partially filled and cancelled",
+			indexerOrder: &IndexerOrder{ID: "order-1", Status: "BEST_EFFORT_CANCELED", TotalFilled: "1.5"},
+			fills:        fills[:1],
+			wantStatus:   OrderStatusPartiallyFilled,
+			wantFilled:   1_500_000,
+			wantAverage:  "10",
+			wantFees:     "0.0075",
+		},
+		{
+			name:         "partially filled and still open",
+			indexerOrder: &IndexerOrder{ID: "order-1", Status: "OPEN", TotalFilled: "1.5"},
+			fills:        fills[:1],
+			wantStatus:   OrderStatusPending,
+			wantFilled:   1_500_000,
+			wantAverage:  "10",
+			wantFees:     "0.0075",
+		},
+		{
+			name:         "cancelled without fills",
+			indexerOrder: &IndexerOrder{ID: "order-1", Status: "BEST_EFFORT_CANCELED", TotalFilled: "0"},
+			wantStatus:   OrderStatusExpired,
+			wantAverage:  "0",
+			wantFees:     "0",
+		},
+		{
+			// Past the good til block an open order is pending until the indexer closes it
+			name:         "open on a lagging indexer",
+			indexerOrder: &IndexerOrder{ID: "order-1", Status: "OPEN", TotalFilled: "0"},
+			wantStatus:   OrderStatusPending,
+			wantAverage:  "0",
+			wantFees:     "0",
+		},
+		{
+			name:        "unknown to the indexer",
+			wantStatus:  OrderStatusPending,
+			wantAverage: "0",
+			wantFees:    "0",
+		},
+		{
+			name:         "invalid total filled",
+			indexerOrder: &IndexerOrder{ID: "order-1", Status: "FILLED", TotalFilled: "abc"},
+			wantErr:      true,
+		},
+		{
+			name:         "invalid fill",
+			indexerOrder: &IndexerOrder{ID: "order-1", Status: "FILLED", TotalFilled: "1"},
+			fills:        []IndexerFill{{OrderID: "order-1", Price: "abc", Size: "1", Fee: "0"}},
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := ReconcileOrder(params, order, tt.indexerOrder, tt.fills)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, []string{"7"}, report.OrderIDs)
+			assert.Equal(t, tt.wantStatus, report.Status)
+			assert.Equal(t, sdkmath.NewInt(2_000_000), report.Requested)
+			assert.Equal(t, sdkmath.NewInt(tt.wantFilled), report.Filled)
+			assert.True(t, sdkmath.LegacyMustNewDecFromStr(tt.wantAverage).Equal(report.AveragePrice),
+				"average price: got %s, want %s", report.AveragePrice, tt.wantAverage)
+			assert.True(t, sdkmath.LegacyMustNewDecFromStr(tt.wantFees).Equal(report.Fees),
+				"fees: got %s, want %s", report.Fees, tt.wantFees)
+		})
+	}
+}
+
+func TestMergeOrderReports(t *testing.T) {
+	original := &OrderReport{
+		OrderIDs:     []string{"1"},
+		Status:       OrderStatusPartiallyFilled,
+		Requested:    sdkmath.NewInt(4_000_000),
+		Filled:       sdkmath.NewInt(1_000_000),
+		AveragePrice: sdkmath.LegacyMustNewDecFromStr("10"),
+		Fees:         sdkmath.LegacyMustNewDecFromStr("0.005"),
+	}
+
+	reissued := &OrderReport{
+		OrderIDs:     []string{"2"},
+		Status:       OrderStatusFilled,
+		Requested:    sdkmath.NewInt(3_000_000),
+		Filled:       sdkmath.NewInt(3_000_000),
+		AveragePrice: sdkmath.LegacyMustNewDecFromStr("11"),
+		Fees:         sdkmath.LegacyMustNewDecFromStr("0.015"),
+	}
+
+	merged := mergeOrderReports(original, reissued)
+
+	assert.Equal(t, []string{"1", "2"}, merged.OrderIDs)
+	assert.Equal(t, OrderStatusFilled, merged.Status)
+	assert.Equal(t, sdkmath.NewInt(4_000_000), merged.Requested)
+	assert.Equal(t, sdkmath.NewInt(4_000_000), merged.Filled)
+	assert.True(t, sdkmath.LegacyMustNewDecFromStr("10.75").Equal(merged.AveragePrice), "got %s", merged.AveragePrice)
+	assert.True(t, sdkmath.LegacyMustNewDecFromStr("0.02").Equal(merged.Fees), "got %s", merged.Fees)
+	assert.Equal(t, []string{"1"}, original.OrderIDs)
+}
+
+func TestExpireOrder(t *testing.T) {
+	pending := func(filled int64) *OrderReport {
+		return &OrderReport{
+			OrderIDs:  []string{"7"},
+			Status:    OrderStatusPending,
+			Requested: sdkmath.NewInt(2_000_000),
+			Filled:    sdkmath.NewInt(filled),
+		}
+	}
+
+	tests := []struct {
+		name       string
+		report     *OrderReport
+		height     int64
+		wantStatus OrderStatus
+	}{
+		{name: "before good til block", report: pending(0), height: 100, wantStatus: OrderStatusPending},
+		{name: "within indexer lag", report: pending(0), height: 100 + orderIndexerLagBlocks, wantStatus: OrderStatusPending},
+		{name: "never indexed", report: pending(0), height: 103, wantStatus: OrderStatusExpired},
+		{name: "filled so far", report: pending(500_000), height: 103, wantStatus: OrderStatusPartiallyFilled},
+		{
+			name:       "final report kept",
+			report:     &OrderReport{Status: OrderStatusFilled, Requested: sdkmath.NewInt(1), Filled: sdkmath.NewInt(1)},
+			height:     103,
+			wantStatus: OrderStatusFilled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := expireOrder(tt.report, 100, tt.height)
+			assert.Equal(t, tt.wantStatus, report.Status)
+			assert.Equal(t, tt.report.Filled, report.Filled)
+		})
+	}
+
+	// The report passed in is not modified
+	report := pending(0)
+	expireOrder(report, 100, 200)
+	assert.Equal(t, OrderStatusPending, report.Status)
+}
+
+func TestReissuePolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   ReissuePolicy
+		status   OrderStatus
+		reissues int
+		want     bool
+	}{
+		{name: "disabled", policy: ReissuePolicy{}, status: OrderStatusPartiallyFilled, want: false},
+		{name: "partial fill", policy: ReissuePolicy{MaxReissues: 2}, status: OrderStatusPartiallyFilled, want: true},
+		{name: "reissues exhausted", policy: ReissuePolicy{MaxReissues: 2}, status: OrderStatusPartiallyFilled, reissues: 2, want: false},
+		{name: "expired not reissued", policy: ReissuePolicy{MaxReissues: 2}, status: OrderStatusExpired, want: false},
+		{name: "expired reissued", policy: ReissuePolicy{MaxReissues: 2, ReissueExpired: true}, status: OrderStatusExpired, want: true},
+		{name: "filled", policy: ReissuePolicy{MaxReissues: 2, ReissueExpired: true}, status: OrderStatusFilled, want: false},
+		{name: "pending", policy: ReissuePolicy{MaxReissues: 2, ReissueExpired: true}, status: OrderStatusPending, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.ShouldReissue(&OrderReport{Status: tt.status}, tt.reissues)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"fmt"
 
 	"github.com/margined-protocol/locust-core/pkg/connection"
+	levanamarket "github.com/margined-protocol/locust-core/pkg/contracts/levana/market"
 	"github.com/margined-protocol/locust-core/pkg/contracts/mars/creditmanager"
 	marsperps "github.com/margined-protocol/locust-core/pkg/contracts/mars/perps"
 	"github.com/margined-protocol/locust-core/pkg/ibc"
+	clob "github.com/margined-protocol/locust-core/pkg/proto/dydx/clob/types"
+	perpetuals "github.com/margined-protocol/locust-core/pkg/proto/dydx/perpetuals/types"
 	subaccounts "github.com/margined-protocol/locust-core/pkg/proto/dydx/subaccounts/types"
 	"github.com/margined-protocol/locust-core/pkg/types"
 	"go.uber.org/zap"
@@ -18,8 +21,9 @@ import (
 type ProviderType string
 
 const (
-	ProviderMars ProviderType = "mars"
-	ProviderDydx ProviderType = "dydx"
+	ProviderMars   ProviderType = "mars"
+	ProviderDydx   ProviderType = "dydx"
+	ProviderLevana ProviderType = "levana"
 	// Add other providers as needed
 )
 
@@ -29,6 +33,8 @@ func GetProvider(provider string) ProviderType {
 		return ProviderMars
 	case "dydx":
 		return ProviderDydx
+	case "levana":
+		return ProviderLevana
 	}
 	return ProviderMars
 }
@@ -46,6 +52,8 @@ func CreateProvider(
 	case ProviderDydx:
 		// Create dYdX provider when implemented
 		return createDydxProvider(logger, config)
+	case ProviderLevana:
+		return createLevanaProvider(logger, config)
 	default:
 		return nil, fmt.Errorf("unsupported provider type: %s", providerType)
 	}
@@ -59,7 +67,10 @@ type MarsConfig struct {
 	MarsConfig      types.MarsConfig
 	CollateralDenom string
 	OutDecimals     int
+	SignerAccount   string
 	Executor        string
+	ClientRegistry  *connection.ClientRegistry
+	MsgHandler      ibc.MessageHandler
 }
 
 // createMarsProvider creates a new MarsProvider from the provided configuration
@@ -69,6 +80,15 @@ func createMarsProvider(logger *zap.Logger, rawConfig map[string]interface{}) (P
 		return nil, fmt.Errorf("failed to parse Mars config: %w", err)
 	}
 
+	// Without a client registry the provider only queries, it has no address to build messages for
+	var senderAddress string
+	if config.ClientRegistry != nil {
+		_, senderAddress, err = config.ClientRegistry.GetSignerAccountAndAddress(config.SignerAccount, config.ChainID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Mars signer address: %w", err)
+		}
+	}
+
 	return NewMarsProvider(
 		logger,
 		config.ChainID,
@@ -77,7 +97,9 @@ func createMarsProvider(logger *zap.Logger, rawConfig map[string]interface{}) (P
 		config.MarsConfig,
 		config.CollateralDenom,
 		config.OutDecimals,
+		senderAddress,
 		config.Executor,
+		config.MsgHandler,
 	), nil
 }
 
@@ -130,6 +152,28 @@ func parseMarsConfig(rawConfig map[string]interface{}) (MarsConfig, error) {
 		return config, fmt.Errorf("executor must be string")
 	}
 
+	// Optional fields, without a message handler messages are built but not sent
+	if raw, exists := rawConfig["signer_account"]; exists {
+		config.SignerAccount, ok = raw.(string)
+		if !ok {
+			return config, fmt.Errorf("signer_account must be string")
+		}
+	}
+
+	if raw, exists := rawConfig["client_registry"]; exists {
+		config.ClientRegistry, ok = raw.(*connection.ClientRegistry)
+		if !ok {
+			return config, fmt.Errorf("client_registry must be *connection.ClientRegistry")
+		}
+	}
+
+	if raw, exists := rawConfig["msg_handler"]; exists {
+		config.MsgHandler, ok = raw.(ibc.MessageHandler)
+		if !ok {
+			return config, fmt.Errorf("msg_handler must be ibc.MessageHandler")
+		}
+	}
+
 	return config, nil
 }
 
@@ -140,7 +184,7 @@ func createDydxProvider(logger *zap.Logger, rawConfig map[string]interface{}) (P
 		return nil, fmt.Errorf("failed to parse dYdX config: %w", err)
 	}
 
-	return NewDydxProvider(
+	provider := NewDydxProvider(
 		logger,
 		config.MarketID,
 		config.Market,
@@ -156,10 +200,16 @@ func createDydxProvider(logger *zap.Logger, rawConfig map[string]interface{}) (P
 		config.Denom,
 		config.BaseChainID,
 		config.SubaccountClient,
+		config.ClobClient,
+		config.PerpetualsClient,
 		config.ClientRegistry,
 		config.MsgHandler,
 		config.IndexerURL,
-	), nil
+	)
+	provider.SetOrderbookPricing(config.PriceBuffer, config.MaxSlippage)
+	provider.SetReissuePolicy(config.ReissuePolicy)
+
+	return provider, nil
 }
 
 // parseDydxConfig converts the raw config map into a strongly-typed DydxConfig
@@ -213,34 +263,164 @@ func parseDydxConfig(rawConfig map[string]interface{}) (DydxConfig, error) {
 		return config, fmt.Errorf("msg_handler must be ibc.MessageHandler")
 	}
 
-	config.QuantumConversionExp, ok = rawConfig["quantum_conversion_exponent"].(int64)
+	config.Decimals, ok = rawConfig["decimals"].(int64)
 	if !ok {
-		return config, fmt.Errorf("quantum_conversion_exponent must be int64")
+		return config, fmt.Errorf("decimals must be int64")
 	}
 
-	config.AtomicResolution, ok = rawConfig["atomic_resolution"].(int64)
+	config.IndexerURL, ok = rawConfig["indexer_url"].(string)
 	if !ok {
-		return config, fmt.Errorf("atomic_resolution must be int64")
+		return config, fmt.Errorf("indexer_url must be string")
 	}
 
-	config.Decimals, ok = rawConfig["decimals"].(int64)
+	config.Executor, ok = rawConfig["executor"].(string)
 	if !ok {
-		return config, fmt.Errorf("decimals must be int64")
+		return config, fmt.Errorf("executor must be string")
 	}
 
-	config.SubticksPerTick, ok = rawConfig["subticks_per_tick"].(uint64)
+	config.MinEquity, ok = rawConfig["min_equity"].(sdkmath.Int)
 	if !ok {
-		return config, fmt.Errorf("subticks_per_tick must be uint64")
+		return config, fmt.Errorf("min_equity must be sdkmath.Int")
 	}
 
-	config.StepBaseQuantums, ok = rawConfig["step_base_quantums"].(uint64)
+	// Market parameters are discovered from chain on Initialize
+	config.ClobClient, ok = rawConfig["clob_client"].(clob.QueryClient)
 	if !ok {
-		return config, fmt.Errorf("step_base_quantums must be uint64")
+		return config, fmt.Errorf("clob_client must be clob.QueryClient")
 	}
 
-	config.IndexerURL, ok = rawConfig["indexer_url"].(string)
+	config.PerpetualsClient, ok = rawConfig["perpetuals_client"].(perpetuals.QueryClient)
 	if !ok {
-		return config, fmt.Errorf("indexer_url must be string")
+		return config, fmt.Errorf("perpetuals_client must be perpetuals.QueryClient")
+	}
+
+	// Optional fields, when set they must match the chain
+	if raw, exists := rawConfig["quantum_conversion_exponent"]; exists {
+		config.QuantumConversionExp, ok = raw.(int64)
+		if !ok {
+			return config, fmt.Errorf("quantum_conversion_exponent must be int64")
+		}
+	}
+
+	if raw, exists := rawConfig["atomic_resolution"]; exists {
+		config.AtomicResolution, ok = raw.(int64)
+		if !ok {
+			return config, fmt.Errorf("atomic_resolution must be int64")
+		}
+	}
+
+	if raw, exists := rawConfig["subticks_per_tick"]; exists {
+		config.SubticksPerTick, ok = raw.(uint64)
+		if !ok {
+			return config, fmt.Errorf("subticks_per_tick must be uint64")
+		}
+	}
+
+	if raw, exists := rawConfig["step_base_quantums"]; exists {
+		config.StepBaseQuantums, ok = raw.(uint64)
+		if !ok {
+			return config, fmt.Errorf("step_base_quantums must be uint64")
+		}
+	}
+
+	// Orderbook pricing of market orders
+	config.PriceBuffer = DefaultPriceBuffer
+	if raw, exists := rawConfig["price_buffer"]; exists {
+		config.PriceBuffer, ok = raw.(float64)
+		if !ok {
+			return config, fmt.Errorf("price_buffer must be float64")
+		}
+	}
+
+	config.MaxSlippage = DefaultMaxSlippage
+	if raw, exists := rawConfig["max_slippage"]; exists {
+		config.MaxSlippage, ok = raw.(float64)
+		if !ok {
+			return config, fmt.Errorf("max_slippage must be float64")
+		}
+	}
+
+	// Reissuing of unfilled short-term orders
+	if raw, exists := rawConfig["max_reissues"]; exists {
+		config.ReissuePolicy.MaxReissues, ok = raw.(int)
+		if !ok {
+			return config, fmt.Errorf("max_reissues must be int")
+		}
+	}
+
+	if raw, exists := rawConfig["reissue_expired"]; exists {
+		config.ReissuePolicy.ReissueExpired, ok = raw.(bool)
+		if !ok {
+			return config, fmt.Errorf("reissue_expired must be bool")
+		}
+	}
+
+	return config, nil
+}
+
+// createLevanaProvider creates a new LevanaPerpsProvider from the provided configuration
+func createLevanaProvider(logger *zap.Logger, rawConfig map[string]interface{}) (Provider, error) {
+	config, err := parseLevanaConfig(rawConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Levana config: %w", err)
+	}
+
+	_, senderAddress, err := config.ClientRegistry.GetSignerAccountAndAddress(config.SignerAccount, config.ChainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Levana signer address: %w", err)
+	}
+
+	return NewLevanaPerpsProvider(
+		logger,
+		config.ChainID,
+		config.MarketAddress,
+		config.MarketClient,
+		config.CollateralDenom,
+		config.CW20Contract,
+		config.Decimals,
+		config.Leverage,
+		config.SignerAccount,
+		senderAddress,
+		config.Executor,
+		config.ClientRegistry,
+		config.MsgHandler,
+	), nil
+}
+
+// parseLevanaConfig converts the raw config map into a strongly-typed LevanaConfig
+func parseLevanaConfig(rawConfig map[string]interface{}) (LevanaConfig, error) {
+	var config LevanaConfig
+	var ok bool
+
+	// Required fields
+	config.ChainID, ok = rawConfig["chain_id"].(string)
+	if !ok {
+		return config, fmt.Errorf("chain_id must be string")
+	}
+
+	config.MarketAddress, ok = rawConfig["market_address"].(string)
+	if !ok {
+		return config, fmt.Errorf("market_address must be string")
+	}
+
+	config.MarketClient, ok = rawConfig["market_client"].(levanamarket.QueryClient)
+	if !ok {
+		return config, fmt.Errorf("market_client must be levanamarket.QueryClient")
+	}
+
+	config.CollateralDenom, ok = rawConfig["collateral_denom"].(string)
+	if !ok {
+		return config, fmt.Errorf("collateral_denom must be string")
+	}
+
+	config.Decimals, ok = rawConfig["decimals"].(int64)
+	if !ok {
+		return config, fmt.Errorf("decimals must be int64")
+	}
+
+	config.SignerAccount, ok = rawConfig["signer_account"].(string)
+	if !ok {
+		return config, fmt.Errorf("signer_account must be string")
 	}
 
 	config.Executor, ok = rawConfig["executor"].(string)
@@ -248,9 +428,30 @@ func parseDydxConfig(rawConfig map[string]interface{}) (DydxConfig, error) {
 		return config, fmt.Errorf("executor must be string")
 	}
 
-	config.MinEquity, ok = rawConfig["min_equity"].(sdkmath.Int)
+	config.ClientRegistry, ok = rawConfig["client_registry"].(*connection.ClientRegistry)
 	if !ok {
-		return config, fmt.Errorf("min_equity must be sdkmath.Int")
+		return config, fmt.Errorf("client_registry must be *connection.ClientRegistry")
+	}
+
+	config.MsgHandler, ok = rawConfig["msg_handler"].(ibc.MessageHandler)
+	if !ok {
+		return config, fmt.Errorf("msg_handler must be ibc.MessageHandler")
+	}
+
+	// Optional fields
+	if raw, exists := rawConfig["cw20_contract"]; exists {
+		cw20Contract, ok := raw.(string)
+		if !ok {
+			return config, fmt.Errorf("cw20_contract must be string")
+		}
+		config.CW20Contract = &cw20Contract
+	}
+
+	if raw, exists := rawConfig["leverage"]; exists {
+		config.Leverage, ok = raw.(float64)
+		if !ok {
+			return config, fmt.Errorf("leverage must be float64")
+		}
 	}
 
 	return config, nil
@@ -0,0 +1,87 @@
+package perps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/margined-protocol/locust-core/pkg/contracts/levana/evaluator"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+const (
+	hoursPerYear = 24 * 365
+	daysPerYear  = 365
+)
+
+// ErrInsufficientFundingHistory is returned when a funding source has no rates covering the
+// requested period
+var ErrInsufficientFundingHistory = errors.New("insufficient funding history")
+
+// FundingRate is a funding rate sample, rates are annualized and positive when longs pay shorts
+// so venues with different funding intervals compare on the same terms
+type FundingRate struct {
+	Time time.Time
+	Rate float64
+}
+
+// FundingSource provides venue-neutral funding data for the markets of a provider
+type FundingSource interface {
+	// FundingHistory returns the funding rates of the market since the given time, oldest first
+	FundingHistory(ctx context.Context, market string, since time.Time) ([]FundingRate, error)
+	// CurrentFundingRate returns the latest funding rate of the market
+	CurrentFundingRate(ctx context.Context, market string) (*FundingRate, error)
+	// AccruedFunding returns the funding accrued by the open position in the market in the
+	// collateral's base units, positive when the position has received funding
+	AccruedFunding(ctx context.Context, market string) (sdkmath.Int, error)
+}
+
+// FundingProjection is the expected funding rate of a market, all rates are annualized
+type FundingProjection struct {
+	Current   float64
+	EMA       float64
+	Projected float64
+}
+
+// ProjectFunding projects the next funding rate from the history using its EMA over the
+// given period, with the same EMA and projection the Levana evaluator uses
+func ProjectFunding(history []FundingRate, period int) (*FundingProjection, error) {
+	if len(history) == 0 {
+		return nil, fmt.Errorf("no funding history")
+	}
+
+	if period <= 0 {
+		return nil, fmt.Errorf("period must be greater than 0")
+	}
+
+	if len(history) < period {
+		return nil, fmt.Errorf("not enough funding history for EMA: got %d, need at least %d", len(history), period)
+	}
+
+	rates := make([]float64, len(history))
+	for i, sample := range history {
+		rates[i] = sample.Rate
+	}
+
+	ema := evaluator.ComputeEMA(rates, period)
+	current := rates[len(rates)-1]
+
+	return &FundingProjection{
+		Current:   current,
+		EMA:       ema,
+		Projected: evaluator.ProjectFundingRate(current, ema),
+	}, nil
+}
+
+// ProjectFundingForMarket fetches the funding history of a market since the given time
+// and projects the next funding rate
+func ProjectFundingForMarket(ctx context.Context, source FundingSource, market string, since time.Time, period int) (*FundingProjection, error) {
+	history, err := source.FundingHistory(ctx, market, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch funding history for %s: %w", market, err)
+	}
+
+	return ProjectFunding(history, period)
+}
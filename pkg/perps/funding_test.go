@@ -0,0 +1,172 @@
+package perps
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	marsperps "github.com/margined-protocol/locust-core/pkg/contracts/mars/perps"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestProjectFunding(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	history := func(rates ...float64) []FundingRate {
+		samples := make([]FundingRate, len(rates))
+		for i, rate := range rates {
+			samples[i] = FundingRate{Time: start.Add(time.Duration(i) * time.Hour), Rate: rate}
+		}
+		return samples
+	}
+
+	tests := []struct {
+		name          string
+		history       []FundingRate
+		period        int
+		wantCurrent   float64
+		wantEMA       float64
+		wantProjected float64
+		wantErr       bool
+	}{
+		{
+			name:          "well above ema cools off",
+			history:       history(0.1, 0.1, 0.1, 0.2),
+			period:        3,
+			wantCurrent:   0.2,
+			wantEMA:       0.15,
+			wantProjected: 0.185,
+		},
+		{
+			name:          "near ema holds",
+			history:       history(0.1, 0.1, 0.1),
+			period:        3,
+			wantCurrent:   0.1,
+			wantEMA:       0.1,
+			wantProjected: 0.1,
+		},
+		{
+			name:          "below ema rebounds",
+			history:       history(0.1, 0.1, 0.1, -0.1),
+			period:        3,
+			wantCurrent:   -0.1,
+			wantEMA:       0,
+			wantProjected: -0.09,
+		},
+		{
+			name:    "not enough history",
+			history: history(0.1),
+			period:  3,
+			wantErr: true,
+		},
+		{
+			name:    "no history",
+			period:  3,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projection, err := ProjectFunding(tt.history, tt.period)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.InDelta(t, tt.wantCurrent, projection.Current, 1e-12)
+			assert.InDelta(t, tt.wantEMA, projection.EMA, 1e-12)
+			assert.InDelta(t, tt.wantProjected, projection.Projected, 1e-12)
+		})
+	}
+}
+
+func TestProcessHistoricalFunding(t *testing.T) {
+	response := &IndexerHistoricalFundingResponse{
+		HistoricalFunding: []IndexerHistoricalFunding{
+			{Ticker: "ATOM-USD", Rate: "0.00001", EffectiveAt: "2024-01-01T01:00:00.000Z"},
+			{Ticker: "ATOM-USD", Rate: "-0.000002", EffectiveAt: "2024-01-01T00:00:00.000Z"},
+		},
+	}
+
+	rates, err := ProcessHistoricalFunding(response)
+	require.NoError(t, err)
+	require.Len(t, rates, 2)
+
+	assert.Equal(t, time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC), rates[0].Time)
+	assert.InDelta(t, 0.0876, rates[0].Rate, 1e-12)
+	assert.InDelta(t, -0.01752, rates[1].Rate, 1e-12)
+
+	response.HistoricalFunding[0].Rate = "abc"
+	_, err = ProcessHistoricalFunding(response)
+	assert.Error(t, err)
+}
+
+// fakeMarsMarketStateClient returns the configured funding rate of the market
+type fakeMarsMarketStateClient struct {
+	marsperps.QueryClient
+	rate        string
+	lastUpdated time.Time
+}
+
+func (c *fakeMarsMarketStateClient) MarketState(_ context.Context, req *marsperps.MarketStateRequest, _ ...grpc.CallOption) (*marsperps.MarketStateResponse, error) {
+	return &marsperps.MarketStateResponse{
+		Denom: req.Denom,
+		MarketState: marsperps.MarketState{
+			Funding:     marsperps.Funding{Rate: c.rate},
+			LastUpdated: uint64(c.lastUpdated.Unix()),
+		},
+	}, nil
+}
+
+func TestMarsFundingHistory(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := &fakeMarsMarketStateClient{rate: "0.001", lastUpdated: start}
+	provider := &MarsProvider{perpsClient: client}
+	ctx := context.Background()
+
+	// A fresh provider has only sampled the current market state
+	_, err := provider.FundingHistory(ctx, "perps/ubtc", start.Add(-time.Hour))
+	assert.ErrorIs(t, err, ErrInsufficientFundingHistory)
+
+	// The state last updated before since is the rate in effect at since
+	history, err := provider.FundingHistory(ctx, "perps/ubtc", start.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []FundingRate{{Time: start, Rate: 0.365}}, history)
+
+	client.rate, client.lastUpdated = "0.002", start.Add(2*time.Hour)
+	history, err = provider.FundingHistory(ctx, "perps/ubtc", start.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, start.Add(2*time.Hour), history[1].Time)
+	assert.InDelta(t, 0.73, history[1].Rate, 1e-12)
+
+	history, err = provider.FundingHistory(ctx, "perps/ubtc", start.Add(3*time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+}
+
+func TestMarsRecordFundingSample(t *testing.T) {
+	provider := &MarsProvider{}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	provider.recordFundingSample("uatom", FundingRate{Time: start, Rate: 0.1})
+	// Unchanged market state is not sampled twice
+	provider.recordFundingSample("uatom", FundingRate{Time: start, Rate: 0.1})
+	provider.recordFundingSample("uatom", FundingRate{Time: start.Add(time.Hour), Rate: 0.2})
+	provider.recordFundingSample("utia", FundingRate{Time: start, Rate: 0.3})
+
+	assert.Len(t, provider.fundingSamples["uatom"], 2)
+	assert.Len(t, provider.fundingSamples["utia"], 1)
+
+	for i := 0; i < MarsMaxFundingSamples; i++ {
+		provider.recordFundingSample("uatom", FundingRate{Time: start.Add(time.Duration(i+2) * time.Hour)})
+	}
+
+	samples := provider.fundingSamples["uatom"]
+	assert.Len(t, samples, MarsMaxFundingSamples)
+	assert.Equal(t, start.Add(time.Duration(MarsMaxFundingSamples+1)*time.Hour), samples[len(samples)-1].Time)
+}
@@ -36,6 +36,11 @@ type Provider interface {
 	CreateMarketOrder(ctx context.Context, price, margin, size sdkmath.Int, isBuy, reduceOnly bool) ([]sdk.Msg, error)
 	CreateLimitOrder(ctx context.Context, price, margin, size sdkmath.Int, isBuy, reduceOnly bool) ([]sdk.Msg, error)
 
+	// Multi-Market Management, the single market methods act on the configured market
+	GetMarkets() []string
+	GetPositions(ctx context.Context) (map[string]*Position, error)
+	CreateMarketOrderForMarket(ctx context.Context, market string, price, margin, size sdkmath.Int, isBuy, reduceOnly bool) ([]sdk.Msg, error)
+
 	// Position Management
 	GetPosition(ctx context.Context) (*Position, error)
 	GetLiquidationPrice(equity, size, entryPrice, maintenanceMargin sdkmath.LegacyDec) sdkmath.LegacyDec
@@ -66,6 +71,27 @@ type ExecutionResult struct {
 	Events   []abcitypes.Event
 	Position *Position
 	Executed bool
-	Messages []sdk.Msg // The messages that were or would be sent
-	Notes    string    // Additional information about the execution
+	Messages []sdk.Msg    // The messages that were or would be sent
+	Notes    string       // Additional information about the execution
+	Order    *OrderReport // Fill reconciliation of the order, nil when the provider does not track orders
+}
+
+// OrderStatus is the outcome of an order once reconciled against its fills
+type OrderStatus string
+
+const (
+	OrderStatusPending         OrderStatus = "PENDING" // Not yet final, fills may still arrive
+	OrderStatusFilled          OrderStatus = "FILLED"
+	OrderStatusPartiallyFilled OrderStatus = "PARTIALLY_FILLED"
+	OrderStatusExpired         OrderStatus = "EXPIRED" // Final without any fill
+)
+
+// OrderReport summarises the fills of an order, including any reissued remainder
+type OrderReport struct {
+	OrderIDs     []string // Provider order identifiers, one per order sent
+	Status       OrderStatus
+	Requested    sdkmath.Int       // Size requested in the units orders are placed in
+	Filled       sdkmath.Int       // Size filled in the units orders are placed in
+	AveragePrice sdkmath.LegacyDec // Size weighted average fill price
+	Fees         sdkmath.LegacyDec // Fees paid in the collateral denom, negative for rebates
 }
@@ -0,0 +1,972 @@
+package perps
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/margined-protocol/locust-core/pkg/connection"
+	levanamarket "github.com/margined-protocol/locust-core/pkg/contracts/levana/market"
+	"github.com/margined-protocol/locust-core/pkg/ibc"
+	"github.com/margined-protocol/locust-core/pkg/math"
+	"go.uber.org/zap"
+
+	sdkmath "cosmossdk.io/math"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+)
+
+const (
+	LevanaDefaultLeverage = 2.0
+	LevanaDefaultSlippage = 0.01 // 1%
+	levanaNftPageLimit    = 30
+	levanaQueryTimeout    = 10 * time.Second
+)
+
+// LevanaConfig holds the configuration for the Levana provider
+type LevanaConfig struct {
+	ChainID         string
+	MarketAddress   string
+	MarketClient    levanamarket.QueryClient
+	CollateralDenom string
+	CW20Contract    *string // nil for natively collateralised markets
+	Decimals        int64
+	Leverage        float64
+
+	SignerAccount  string
+	Executor       string
+	ClientRegistry *connection.ClientRegistry
+	MsgHandler     ibc.MessageHandler
+}
+
+// LevanaPerpsProvider implements the Provider interface for Levana Perps
+//
+// Levana positions are NFTs owned directly by the signer wallet, there are no
+// subaccounts and collateral lives inside each position. Sizes and prices are
+// assumed to be quoted in the collateral asset.
+type LevanaPerpsProvider struct {
+	logger          *zap.Logger
+	chainID         string
+	marketAddress   string
+	collateralDenom string
+	cw20Contract    *string
+	decimals        int64
+	leverage        float64
+	slippage        float64
+
+	// Providers && Clients
+	clientRegistry *connection.ClientRegistry
+	msgHandler     ibc.MessageHandler
+	marketClient   levanamarket.QueryClient
+	levana         *levanamarket.LevanaProvider
+
+	// Other
+	signerAccount string
+	senderAddress string
+	executor      string
+
+	// Liquidation price reported by the market on the last successful position query
+	liquidationPrice   sdkmath.LegacyDec
+	liquidationPriceMu sync.Mutex
+}
+
+// NewLevanaPerpsProvider creates a new Levana provider
+func NewLevanaPerpsProvider(
+	logger *zap.Logger,
+	chainID string,
+	marketAddress string,
+	marketClient levanamarket.QueryClient,
+	collateralDenom string,
+	cw20Contract *string,
+	decimals int64,
+	leverage float64,
+	signerAccount string,
+	senderAddress string,
+	executor string,
+	clientRegistry *connection.ClientRegistry,
+	msgHandler ibc.MessageHandler,
+) *LevanaPerpsProvider {
+	if leverage <= 0 {
+		leverage = LevanaDefaultLeverage
+	}
+
+	return &LevanaPerpsProvider{
+		logger:           logger,
+		chainID:          chainID,
+		marketAddress:    marketAddress,
+		marketClient:     marketClient,
+		collateralDenom:  collateralDenom,
+		cw20Contract:     cw20Contract,
+		decimals:         decimals,
+		leverage:         leverage,
+		slippage:         LevanaDefaultSlippage,
+		signerAccount:    signerAccount,
+		senderAddress:    senderAddress,
+		executor:         executor,
+		clientRegistry:   clientRegistry,
+		msgHandler:       msgHandler,
+		levana:           levanamarket.NewLevanaProvider(),
+		liquidationPrice: sdkmath.LegacyZeroDec(),
+	}
+}
+
+// Initialize implements Provider
+func (m *LevanaPerpsProvider) Initialize(ctx context.Context) error {
+	status, err := m.marketClient.Status(ctx, m.marketAddress)
+	if err != nil {
+		return fmt.Errorf("failed to fetch market status: %w", err)
+	}
+
+	// Make sure the configured collateral matches the market
+	switch {
+	case status.Collateral.Native != nil:
+		if m.cw20Contract != nil {
+			return fmt.Errorf("market %s uses native collateral but cw20 contract is configured", status.MarketID)
+		}
+		if status.Collateral.Native.Denom != m.collateralDenom {
+			return fmt.Errorf("collateral denom mismatch: market %s, configured %s", status.Collateral.Native.Denom, m.collateralDenom)
+		}
+		if int64(status.Collateral.Native.DecimalPlaces) != m.decimals {
+			return fmt.Errorf("collateral decimals mismatch: market %d, configured %d", status.Collateral.Native.DecimalPlaces, m.decimals)
+		}
+	case status.Collateral.CW20 != nil:
+		if m.cw20Contract == nil || *m.cw20Contract != status.Collateral.CW20.Addr {
+			return fmt.Errorf("market %s uses cw20 collateral %s", status.MarketID, status.Collateral.CW20.Addr)
+		}
+		if int64(status.Collateral.CW20.DecimalPlaces) != m.decimals {
+			return fmt.Errorf("collateral decimals mismatch: market %d, configured %d", status.Collateral.CW20.DecimalPlaces, m.decimals)
+		}
+	default:
+		return fmt.Errorf("market %s has no collateral configured", status.MarketID)
+	}
+
+	m.logger.Info("Initialized Levana provider",
+		zap.String("market", status.MarketID),
+		zap.String("market_address", m.marketAddress),
+	)
+
+	return nil
+}
+
+// GetPosition implements Provider
+func (m *LevanaPerpsProvider) GetPosition(ctx context.Context) (*Position, error) {
+	position, _, err := m.queryPosition(ctx)
+	return position, err
+}
+
+// queryPosition returns the open position and its liquidation price, which is recorded for
+// GetLiquidationPrice
+func (m *LevanaPerpsProvider) queryPosition(ctx context.Context) (*Position, sdkmath.LegacyDec, error) {
+	positions, err := m.getOpenPositions(ctx)
+	if err != nil {
+		return nil, sdkmath.LegacyDec{}, err
+	}
+
+	position, liquidationPrice, err := ProcessLevanaPositions(positions, m.decimals)
+	if err != nil {
+		return nil, sdkmath.LegacyDec{}, fmt.Errorf("error processing levana positions: %w", err)
+	}
+
+	m.liquidationPriceMu.Lock()
+	m.liquidationPrice = liquidationPrice
+	m.liquidationPriceMu.Unlock()
+
+	return position, liquidationPrice, nil
+}
+
+// CheckSubaccount implements Provider
+func (m *LevanaPerpsProvider) CheckSubaccount(_ string) (bool, error) {
+	// Positions are owned by the wallet directly, there is nothing to check
+	return true, nil
+}
+
+// GetSubaccount implements Provider
+func (m *LevanaPerpsProvider) GetSubaccount() string {
+	return m.executor
+}
+
+// GetProviderChainID implements Provider
+func (m *LevanaPerpsProvider) GetProviderChainID() string {
+	return m.chainID
+}
+
+// GetProviderName implements Provider
+func (m *LevanaPerpsProvider) GetProviderName() string {
+	return string(ProviderLevana)
+}
+
+// GetProviderDenom implements Provider
+func (m *LevanaPerpsProvider) GetProviderDenom() string {
+	return m.collateralDenom
+}
+
+// GetProviderExecutor implements Provider
+func (m *LevanaPerpsProvider) GetProviderExecutor() string {
+	return m.executor
+}
+
+// GetAccountBalance implements Provider
+func (m *LevanaPerpsProvider) GetAccountBalance() (sdk.Coins, error) {
+	if m.cw20Contract != nil {
+		return nil, fmt.Errorf("cw20 collateral balances not supported")
+	}
+
+	balance, err := m.clientRegistry.GetBalance(context.Background(), m.chainID, m.signerAccount, m.collateralDenom)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance: %w", err)
+	}
+
+	return sdk.NewCoins(sdk.NewCoin(m.collateralDenom, *balance)), nil
+}
+
+// GetSubaccountBalance implements Provider
+func (m *LevanaPerpsProvider) GetSubaccountBalance() (sdk.Coins, error) {
+	// Active collateral across open positions is the closest thing to a subaccount balance
+	position, err := m.GetPosition(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return sdk.NewCoins(sdk.NewCoin(m.collateralDenom, position.Margin)), nil
+}
+
+// GetMarkets implements Provider, each Levana market is a separate contract so only one is tracked
+func (m *LevanaPerpsProvider) GetMarkets() []string {
+	return []string{m.marketAddress}
+}
+
+// GetPositions implements Provider
+func (m *LevanaPerpsProvider) GetPositions(ctx context.Context) (map[string]*Position, error) {
+	position, err := m.GetPosition(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]*Position{m.marketAddress: position}, nil
+}
+
+// CreateMarketOrderForMarket implements Provider, the market is the market contract address
+func (m *LevanaPerpsProvider) CreateMarketOrderForMarket(ctx context.Context, market string, price, margin, size sdkmath.Int, isBuy, reduceOnly bool) ([]sdk.Msg, error) {
+	if market != m.marketAddress {
+		return nil, fmt.Errorf("market %s is not tracked", market)
+	}
+
+	return m.CreateMarketOrder(ctx, price, margin, size, isBuy, reduceOnly)
+}
+
+// CreateMarketOrder implements Provider, price is a fixed point value with the collateral decimals
+func (m *LevanaPerpsProvider) CreateMarketOrder(ctx context.Context, price, margin, size sdkmath.Int, isBuy, reduceOnly bool) ([]sdk.Msg, error) {
+	// NOTE: we do not error on zero sizes
+	if size.IsZero() {
+		return nil, nil
+	}
+
+	account, err := m.getSender()
+	if err != nil {
+		return nil, err
+	}
+
+	positions, err := m.getOpenPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	priceDec := m.toDecimal(price)
+
+	if reduceOnly {
+		return m.buildReducePositionMsgs(account, positions, priceDec, size.Abs())
+	}
+
+	return m.buildIncreasePositionMsgs(account, positions, priceDec, margin, size.Abs(), isBuy)
+}
+
+// CreateLimitOrder implements Provider, price is a fixed point value with the collateral decimals
+func (m *LevanaPerpsProvider) CreateLimitOrder(_ context.Context, price, margin, size sdkmath.Int, isBuy, reduceOnly bool) ([]sdk.Msg, error) {
+	if reduceOnly {
+		return nil, fmt.Errorf("reduce only limit orders not supported by Levana provider")
+	}
+
+	if price.IsZero() || size.IsZero() {
+		return nil, nil
+	}
+
+	account, err := m.getSender()
+	if err != nil {
+		return nil, err
+	}
+
+	priceDec := m.toDecimal(price)
+
+	margin, leverage, err := m.orderParams(priceDec, margin, size.Abs(), m.leverage)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := m.levana.PlaceLimitOrder(
+		account,
+		m.marketAddress,
+		m.cw20Contract,
+		levanamarket.PlaceLimitOrderMsg{
+			TriggerPrice: priceDec.MustFloat64(),
+			Leverage:     leverage,
+			Direction:    levanaDirection(isBuy),
+		},
+		m.collateralFunds(margin),
+	)
+	if err != nil {
+		m.logger.Error("Error creating place limit order msg", zap.Error(err))
+		return nil, err
+	}
+
+	return []sdk.Msg{msg}, nil
+}
+
+// DepositSubaccount implements Provider, collateral is added to the open position
+func (m *LevanaPerpsProvider) DepositSubaccount(ctx context.Context, amount sdkmath.Int) ([]sdk.Msg, error) {
+	if !amount.IsPositive() {
+		return nil, nil
+	}
+
+	account, err := m.getSender()
+	if err != nil {
+		return nil, err
+	}
+
+	position, err := m.getPrimaryPosition(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if position == nil {
+		return nil, fmt.Errorf("no open position to add collateral to")
+	}
+
+	id, err := strconv.Atoi(position.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid position id %s: %w", position.ID, err)
+	}
+
+	m.logger.Debug("Adding collateral",
+		zap.String("sender", account),
+		zap.String("position", position.ID),
+		zap.String("amount", amount.String()),
+	)
+
+	msg, err := m.levana.AddCollateral(
+		account,
+		m.marketAddress,
+		m.cw20Contract,
+		levanamarket.UpdatePositionAddCollateralImpactLeverageMsg{ID: id},
+		m.collateralFunds(amount),
+	)
+	if err != nil {
+		m.logger.Error("Error creating add collateral msg", zap.Error(err))
+		return nil, err
+	}
+
+	return []sdk.Msg{msg}, nil
+}
+
+// WithdrawSubaccount implements Provider, collateral is removed from the open position
+func (m *LevanaPerpsProvider) WithdrawSubaccount(ctx context.Context, amount sdkmath.Int) ([]sdk.Msg, error) {
+	if !amount.IsPositive() {
+		return nil, nil
+	}
+
+	account, err := m.getSender()
+	if err != nil {
+		return nil, err
+	}
+
+	position, err := m.getPrimaryPosition(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if position == nil {
+		return nil, fmt.Errorf("no open position to remove collateral from")
+	}
+
+	id, err := strconv.Atoi(position.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid position id %s: %w", position.ID, err)
+	}
+
+	activeCollateral, err := parseLevanaDec(position.ActiveCollateral)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse active collateral: %w", err)
+	}
+
+	withdrawAmount := m.toDecimal(amount)
+	if withdrawAmount.GTE(activeCollateral) {
+		return nil, fmt.Errorf("cannot remove %s collateral, only %s active", withdrawAmount, activeCollateral)
+	}
+
+	m.logger.Debug("Removing collateral",
+		zap.String("sender", account),
+		zap.String("position", position.ID),
+		zap.String("amount", amount.String()),
+	)
+
+	msg, err := m.levana.RemoveCollateral(
+		account,
+		m.marketAddress,
+		m.cw20Contract,
+		levanamarket.UpdatePositionRemoveCollateralImpactLeverageMsg{
+			ID:     id,
+			Amount: withdrawAmount.MustFloat64(),
+		},
+		nil,
+	)
+	if err != nil {
+		m.logger.Error("Error creating remove collateral msg", zap.Error(err))
+		return nil, err
+	}
+
+	return []sdk.Msg{msg}, nil
+}
+
+// GetLiquidationPrice implements Provider, it queries the open positions and returns the
+// liquidation price reported by the market rather than computing one. When the market cannot
+// be queried the price of the last successful position query is returned.
+func (m *LevanaPerpsProvider) GetLiquidationPrice(_, _, _, _ sdkmath.LegacyDec) sdkmath.LegacyDec {
+	ctx, cancel := context.WithTimeout(context.Background(), levanaQueryTimeout)
+	defer cancel()
+
+	_, liquidationPrice, err := m.queryPosition(ctx)
+	if err == nil {
+		return liquidationPrice
+	}
+
+	m.liquidationPriceMu.Lock()
+	defer m.liquidationPriceMu.Unlock()
+
+	m.logger.Warn("Failed to query liquidation price, using the last known price",
+		zap.String("market", m.marketAddress),
+		zap.String("liquidation_price", m.liquidationPrice.String()),
+		zap.Error(err),
+	)
+
+	return m.liquidationPrice
+}
+
+// ProcessPerpEvent implements Provider
+func (m *LevanaPerpsProvider) ProcessPerpEvent(_ []abcitypes.Event) (currentPrice string, entryPrice string, err error) {
+	// Levana executes positions in a deferred manner so we read the prices from the position itself
+	position, err := m.GetPosition(context.Background())
+	if err != nil {
+		return "", "", fmt.Errorf("error fetching position: %w", err)
+	}
+
+	if position.Amount.IsZero() {
+		return "", "", fmt.Errorf("no open position")
+	}
+
+	return position.CurrentPrice.String(), position.EntryPrice.String(), nil
+}
+
+// CreateSubaccount implements Provider
+func (m *LevanaPerpsProvider) CreateSubaccount(_ string) (sdk.Msg, error) {
+	return nil, fmt.Errorf("subaccounts not supported by Levana provider")
+}
+
+func (m *LevanaPerpsProvider) IncreasePosition(ctx context.Context, price float64, amount, margin sdkmath.Int, isLong bool) (*ExecutionResult, error) {
+	// High level-logic:
+	// 1. Open a new position or add collateral to the existing one
+	// 2. Send the message
+	// 3. Wait for the deferred execution and return the execution result
+	if amount.IsNegative() {
+		return nil, fmt.Errorf("amount cannot be negative")
+	}
+	if margin.IsNegative() {
+		return nil, fmt.Errorf("margin cannot be negative")
+	}
+
+	initialPosition, err := m.GetPosition(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get initial position: %w", err)
+	}
+
+	if !initialPosition.Amount.IsZero() && initialPosition.Amount.IsPositive() != isLong {
+		return nil, fmt.Errorf("position direction mismatch: position size %s, isLong %t", initialPosition.Amount, isLong)
+	}
+
+	msgs, err := m.CreateMarketOrder(ctx, math.FloatToFixedInt(price, m.decimals), margin, amount, isLong, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.executeMsgs(ctx, msgs, initialPosition)
+}
+
+func (m *LevanaPerpsProvider) ReducePosition(ctx context.Context, price float64, amount, _ sdkmath.Int, isLong bool) (*ExecutionResult, error) {
+	// NOTE: collateral released by the reduction is returned to the wallet by the market
+	if amount.IsNegative() {
+		return nil, fmt.Errorf("amount cannot be negative")
+	}
+
+	initialPosition, err := m.GetPosition(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get initial position: %w", err)
+	}
+
+	if initialPosition.Amount.IsZero() {
+		return &ExecutionResult{
+			Position: initialPosition,
+			Notes:    "no open position",
+		}, nil
+	}
+
+	if initialPosition.Amount.IsPositive() != isLong {
+		return nil, fmt.Errorf("position direction mismatch: position size %s, isLong %t", initialPosition.Amount, isLong)
+	}
+
+	msgs, err := m.CreateMarketOrder(ctx, math.FloatToFixedInt(price, m.decimals), sdkmath.ZeroInt(), amount, !isLong, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.executeMsgs(ctx, msgs, initialPosition)
+}
+
+func (m *LevanaPerpsProvider) ClosePosition(ctx context.Context, isLong bool) (*ExecutionResult, error) {
+	account, err := m.getSender()
+	if err != nil {
+		return nil, err
+	}
+
+	positions, err := m.getOpenPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	initialPosition, _, err := ProcessLevanaPositions(positions, m.decimals)
+	if err != nil {
+		return nil, fmt.Errorf("error processing levana positions: %w", err)
+	}
+
+	// Nothing to close
+	if len(positions) == 0 {
+		m.logger.Info("No open position to close", zap.String("market", m.marketAddress))
+		return &ExecutionResult{
+			Position: initialPosition,
+			Notes:    "no open position",
+		}, nil
+	}
+
+	msgs := make([]sdk.Msg, 0, len(positions))
+	for _, position := range positions {
+		if (position.DirectionToBase == levanamarket.Long) != isLong {
+			return nil, fmt.Errorf("position %s direction mismatch: position is %s, isLong %t", position.ID, position.DirectionToBase, isLong)
+		}
+
+		msg, err := m.buildClosePositionMsg(account, position)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+
+	return m.executeMsgs(ctx, msgs, initialPosition)
+}
+
+func (m *LevanaPerpsProvider) AdjustMargin(ctx context.Context, margin sdkmath.Int, isAdd bool) (*ExecutionResult, error) {
+	if !margin.IsPositive() {
+		return nil, fmt.Errorf("margin must be positive")
+	}
+
+	initialPosition, err := m.GetPosition(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get initial position: %w", err)
+	}
+
+	var msgs []sdk.Msg
+	if isAdd {
+		msgs, err = m.DepositSubaccount(ctx, margin)
+	} else {
+		msgs, err = m.WithdrawSubaccount(ctx, margin)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return m.executeMsgs(ctx, msgs, initialPosition)
+}
+
+// Helper functions
+func (m *LevanaPerpsProvider) buildIncreasePositionMsgs(
+	account string,
+	positions []levanamarket.Position,
+	price sdkmath.LegacyDec,
+	margin, size sdkmath.Int,
+	isLong bool,
+) ([]sdk.Msg, error) {
+	var slippageAssert *levanamarket.SlippageAssert
+	if price.IsPositive() {
+		slippageAssert = &levanamarket.SlippageAssert{
+			Price:     price.MustFloat64(),
+			Tolerance: m.slippage,
+		}
+	}
+
+	// No open position so open a fresh one
+	if len(positions) == 0 {
+		margin, leverage, err := m.orderParams(price, margin, size, m.leverage)
+		if err != nil {
+			return nil, err
+		}
+
+		m.logger.Debug("Opening position",
+			zap.String("sender", account),
+			zap.String("margin", margin.String()),
+			zap.Float64("leverage", leverage),
+			zap.Bool("isLong", isLong),
+		)
+
+		msg, err := m.levana.OpenPosition(
+			account,
+			m.marketAddress,
+			m.cw20Contract,
+			levanamarket.OpenPositionMsg{
+				SlippageAssert: slippageAssert,
+				Leverage:       leverage,
+				Direction:      levanaDirection(isLong),
+				Amount:         margin.Int64(),
+			},
+			m.collateralFunds(margin),
+		)
+		if err != nil {
+			m.logger.Error("Error creating open position msg", zap.Error(err))
+			return nil, err
+		}
+
+		return []sdk.Msg{msg}, nil
+	}
+
+	// Otherwise grow the existing position at its current leverage
+	position := positions[0]
+	if (position.DirectionToBase == levanamarket.Long) != isLong {
+		return nil, fmt.Errorf("position %s direction mismatch: position is %s, isLong %t", position.ID, position.DirectionToBase, isLong)
+	}
+
+	id, err := strconv.Atoi(position.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid position id %s: %w", position.ID, err)
+	}
+
+	positionLeverage, err := strconv.ParseFloat(position.Leverage, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse position leverage: %w", err)
+	}
+
+	margin, _, err = m.orderParams(price, margin, size, positionLeverage)
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Debug("Increasing position",
+		zap.String("sender", account),
+		zap.String("position", position.ID),
+		zap.String("margin", margin.String()),
+	)
+
+	msg, err := m.levana.AddCollateralImpactSize(
+		account,
+		m.marketAddress,
+		m.cw20Contract,
+		levanamarket.UpdatePositionAddCollateralImpactSizeMsg{
+			ID:             id,
+			SlippageAssert: slippageAssert,
+		},
+		m.collateralFunds(margin),
+	)
+	if err != nil {
+		m.logger.Error("Error creating add collateral impact size msg", zap.Error(err))
+		return nil, err
+	}
+
+	return []sdk.Msg{msg}, nil
+}
+
+func (m *LevanaPerpsProvider) buildReducePositionMsgs(
+	account string,
+	positions []levanamarket.Position,
+	price sdkmath.LegacyDec,
+	size sdkmath.Int,
+) ([]sdk.Msg, error) {
+	if len(positions) == 0 {
+		return nil, nil
+	}
+
+	position := positions[0]
+
+	positionSize, err := parseLevanaDec(position.PositionSizeBase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse position size: %w", err)
+	}
+	positionSize = positionSize.Abs()
+
+	// Reducing by the full size or more is a close
+	reduceSize := m.toDecimal(size)
+	if reduceSize.GTE(positionSize) {
+		msg, err := m.buildClosePositionMsg(account, position)
+		if err != nil {
+			return nil, err
+		}
+		return []sdk.Msg{msg}, nil
+	}
+
+	id, err := strconv.Atoi(position.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid position id %s: %w", position.ID, err)
+	}
+
+	activeCollateral, err := parseLevanaDec(position.ActiveCollateral)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse active collateral: %w", err)
+	}
+
+	// Remove collateral pro-rata so leverage stays unchanged
+	collateral := activeCollateral.Mul(reduceSize).Quo(positionSize)
+
+	var slippageAssert *levanamarket.SlippageAssert
+	if price.IsPositive() {
+		slippageAssert = &levanamarket.SlippageAssert{
+			Price:     price.MustFloat64(),
+			Tolerance: m.slippage,
+		}
+	}
+
+	m.logger.Debug("Reducing position",
+		zap.String("sender", account),
+		zap.String("position", position.ID),
+		zap.String("collateral", collateral.String()),
+	)
+
+	msg, err := m.levana.RemoveCollateralImpactSize(
+		account,
+		m.marketAddress,
+		m.cw20Contract,
+		levanamarket.UpdatePositionRemoveCollateralImpactSizeMsg{
+			ID:             id,
+			Amount:         collateral.MustFloat64(),
+			SlippageAssert: slippageAssert,
+		},
+	)
+	if err != nil {
+		m.logger.Error("Error creating remove collateral impact size msg", zap.Error(err))
+		return nil, err
+	}
+
+	return []sdk.Msg{msg}, nil
+}
+
+func (m *LevanaPerpsProvider) buildClosePositionMsg(account string, position levanamarket.Position) (sdk.Msg, error) {
+	id, err := strconv.Atoi(position.ID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid position id %s: %w", position.ID, err)
+	}
+
+	msg, err := m.levana.ClosePosition(
+		account,
+		m.marketAddress,
+		m.cw20Contract,
+		levanamarket.ClosePositionMsg{ID: id},
+	)
+	if err != nil {
+		m.logger.Error("Error creating close position msg", zap.Error(err))
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+// orderParams returns the margin and leverage needed to open size at price, when no
+// margin is given it is derived from the fallback leverage
+func (m *LevanaPerpsProvider) orderParams(price sdkmath.LegacyDec, margin, size sdkmath.Int, fallbackLeverage float64) (sdkmath.Int, float64, error) {
+	if !price.IsPositive() {
+		return sdkmath.Int{}, 0, fmt.Errorf("price must be positive")
+	}
+
+	notional := sdkmath.LegacyNewDecFromInt(size).Mul(price)
+
+	if !margin.IsPositive() {
+		if fallbackLeverage <= 0 {
+			return sdkmath.Int{}, 0, fmt.Errorf("leverage must be positive")
+		}
+
+		margin = notional.Quo(math.FloatToLegacyDec(fallbackLeverage)).Ceil().TruncateInt()
+		return margin, fallbackLeverage, nil
+	}
+
+	leverage := notional.QuoInt(margin).MustFloat64()
+	if leverage < 1 {
+		return sdkmath.Int{}, 0, fmt.Errorf("leverage %f is below the minimum of 1", leverage)
+	}
+
+	return margin, leverage, nil
+}
+
+// executeMsgs sends the messages, if a handler is provided, and waits for the position to update
+func (m *LevanaPerpsProvider) executeMsgs(ctx context.Context, msgs []sdk.Msg, initialPosition *Position) (*ExecutionResult, error) {
+	result := &ExecutionResult{
+		Messages: msgs,
+		Position: initialPosition,
+	}
+
+	if len(msgs) == 0 {
+		result.Notes = "no actions to execute"
+		return result, nil
+	}
+
+	// Without a handler we only return the messages that would be sent
+	if m.msgHandler == nil {
+		result.Notes = "no message handler configured, messages not sent"
+		return result, nil
+	}
+
+	resp, err := m.msgHandler(m.chainID, msgs, false, true)
+	if err != nil {
+		return result, err
+	}
+
+	result.TxHash = resp.TxHash
+	result.Events = resp.Events
+	result.Executed = true
+
+	m.logger.Info("Levana market transaction", zap.String("tx", resp.TxHash))
+
+	// Levana defers execution until the next crank, so the size is not known up front
+	updatedPosition, changed, err := m.waitForPositionUpdate(ctx, initialPosition)
+	if err != nil {
+		return result, err
+	}
+	result.Position = updatedPosition
+
+	if !changed {
+		result.Notes = "position update pending deferred execution"
+	}
+
+	return result, nil
+}
+
+// waitForPositionUpdate polls the position until it differs from the initial position
+func (m *LevanaPerpsProvider) waitForPositionUpdate(ctx context.Context, initialPosition *Position) (*Position, bool, error) {
+	var position *Position
+	var err error
+
+	for attempts := 0; attempts < 3; attempts++ {
+		time.Sleep(1 * time.Second)
+
+		position, err = m.GetPosition(ctx)
+		if err != nil {
+			m.logger.Warn("Failed to get updated position",
+				zap.Error(err),
+				zap.Int("attempt", attempts+1),
+			)
+			continue
+		}
+
+		if !position.Amount.Equal(initialPosition.Amount) || !position.Margin.Equal(initialPosition.Margin) {
+			return position, true, nil
+		}
+	}
+
+	if position == nil {
+		return nil, false, fmt.Errorf("failed to get updated position: %w", err)
+	}
+
+	return position, false, nil
+}
+
+// getOpenPositions returns all open positions owned by the signer in the market
+func (m *LevanaPerpsProvider) getOpenPositions(ctx context.Context) ([]levanamarket.Position, error) {
+	owner, err := m.getSender()
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []string
+	var startAfter *string
+	limit := levanaNftPageLimit
+
+	for {
+		resp, err := m.marketClient.NftProxy(ctx, m.marketAddress, owner, startAfter, &limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch position tokens: %w", err)
+		}
+
+		tokens = append(tokens, resp.Tokens...)
+
+		if len(resp.Tokens) < limit {
+			break
+		}
+
+		last := resp.Tokens[len(resp.Tokens)-1]
+		startAfter = &last
+	}
+
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	resp, err := m.marketClient.Positions(ctx, m.marketAddress, tokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch positions: %w", err)
+	}
+
+	return resp.Positions, nil
+}
+
+// getPrimaryPosition returns the position orders and margin changes are applied to
+func (m *LevanaPerpsProvider) getPrimaryPosition(ctx context.Context) (*levanamarket.Position, error) {
+	positions, err := m.getOpenPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(positions) == 0 {
+		return nil, nil
+	}
+
+	if len(positions) > 1 {
+		m.logger.Warn("Multiple open positions, using the first",
+			zap.String("market", m.marketAddress),
+			zap.Int("positions", len(positions)),
+		)
+	}
+
+	return &positions[0], nil
+}
+
+// getSender returns the signer address on the Levana chain
+func (m *LevanaPerpsProvider) getSender() (string, error) {
+	if m.senderAddress == "" {
+		return "", fmt.Errorf("sender address not configured")
+	}
+
+	return m.senderAddress, nil
+}
+
+// collateralFunds returns the funds attached to a message
+func (m *LevanaPerpsProvider) collateralFunds(amount sdkmath.Int) *sdk.Coins {
+	funds := sdk.NewCoins(sdk.NewCoin(m.collateralDenom, amount))
+	return &funds
+}
+
+// toDecimal converts a fixed point value with the collateral decimals to a decimal
+func (m *LevanaPerpsProvider) toDecimal(value sdkmath.Int) sdkmath.LegacyDec {
+	return sdkmath.LegacyNewDecFromInt(value).Quo(sdkmath.LegacyNewDec(10).Power(uint64(m.decimals)))
+}
+
+// levanaDirection converts a side into a Levana direction
+func levanaDirection(isLong bool) string {
+	if isLong {
+		return levanamarket.Long
+	}
+	return levanamarket.Short
+}
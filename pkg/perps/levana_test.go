@@ -0,0 +1,255 @@
+package perps
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	levanamarket "github.com/margined-protocol/locust-core/pkg/contracts/levana/market"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+func TestProcessLevanaPositions(t *testing.T) {
+	tests := []struct {
+		name                 string
+		positions            []levanamarket.Position
+		wantAmount           int64
+		wantMargin           int64
+		wantPnl              int64
+		wantEntryPrice       string
+		wantCurrentPrice     string
+		wantLiquidationPrice string
+		wantErr              bool
+	}{
+		{
+			name:                 "no positions",
+			positions:            nil,
+			wantAmount:           0,
+			wantMargin:           0,
+			wantPnl:              0,
+			wantEntryPrice:       "0",
+			wantCurrentPrice:     "0",
+			wantLiquidationPrice: "0",
+		},
+		{
+			name: "single long",
+			positions: []levanamarket.Position{
+				{
+					ID:                   "1",
+					DirectionToBase:      levanamarket.Long,
+					PositionSizeBase:     "10",
+					PositionSizeUSD:      "105",
+					EntryPriceBase:       "10",
+					ActiveCollateral:     "50",
+					PNLCollateral:        "5",
+					LiquidationPriceBase: "5.5",
+				},
+			},
+			wantAmount:           10_000_000,
+			wantMargin:           50_000_000,
+			wantPnl:              5_000_000,
+			wantEntryPrice:       "10",
+			wantCurrentPrice:     "10.5",
+			wantLiquidationPrice: "5.5",
+		},
+		{
+			name: "multiple shorts",
+			positions: []levanamarket.Position{
+				{
+					ID:                   "1",
+					DirectionToBase:      levanamarket.Short,
+					PositionSizeBase:     "-10",
+					PositionSizeUSD:      "-100",
+					EntryPriceBase:       "12",
+					ActiveCollateral:     "40",
+					PNLCollateral:        "-2",
+					LiquidationPriceBase: "15",
+				},
+				{
+					ID:                   "2",
+					DirectionToBase:      levanamarket.Short,
+					PositionSizeBase:     "-30",
+					PositionSizeUSD:      "-300",
+					EntryPriceBase:       "8",
+					ActiveCollateral:     "60",
+					PNLCollateral:        "1",
+					LiquidationPriceBase: "11",
+				},
+			},
+			wantAmount:           -40_000_000,
+			wantMargin:           100_000_000,
+			wantPnl:              -1_000_000,
+			wantEntryPrice:       "9",
+			wantCurrentPrice:     "10",
+			wantLiquidationPrice: "15",
+		},
+		{
+			name: "invalid size",
+			positions: []levanamarket.Position{
+				{
+					ID:               "1",
+					DirectionToBase:  levanamarket.Long,
+					PositionSizeBase: "abc",
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			position, liquidationPrice, err := ProcessLevanaPositions(tt.positions, 6)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, sdkmath.NewInt(tt.wantAmount), position.Amount)
+			assert.Equal(t, sdkmath.NewInt(tt.wantMargin), position.Margin)
+			assert.Equal(t, sdkmath.NewInt(tt.wantPnl), position.UnrealizedPnl)
+			assert.True(t, sdkmath.LegacyMustNewDecFromStr(tt.wantEntryPrice).Equal(position.EntryPrice),
+				"entry price: got %s, want %s", position.EntryPrice, tt.wantEntryPrice)
+			assert.True(t, sdkmath.LegacyMustNewDecFromStr(tt.wantCurrentPrice).Equal(position.CurrentPrice),
+				"current price: got %s, want %s", position.CurrentPrice, tt.wantCurrentPrice)
+			assert.True(t, sdkmath.LegacyMustNewDecFromStr(tt.wantLiquidationPrice).Equal(liquidationPrice),
+				"liquidation price: got %s, want %s", liquidationPrice, tt.wantLiquidationPrice)
+		})
+	}
+}
+
+func TestLevanaOrderParams(t *testing.T) {
+	provider := &LevanaPerpsProvider{decimals: 6}
+
+	tests := []struct {
+		name         string
+		price        string
+		margin       int64
+		size         int64
+		fallback     float64
+		wantMargin   int64
+		wantLeverage float64
+		wantErr      bool
+	}{
+		{
+			name:         "leverage from margin",
+			price:        "10",
+			margin:       25_000_000,
+			size:         10_000_000,
+			fallback:     2,
+			wantMargin:   25_000_000,
+			wantLeverage: 4,
+		},
+		{
+			name:         "margin from fallback leverage",
+			price:        "10",
+			margin:       0,
+			size:         10_000_000,
+			fallback:     2,
+			wantMargin:   50_000_000,
+			wantLeverage: 2,
+		},
+		{
+			name:     "leverage below one",
+			price:    "10",
+			margin:   200_000_000,
+			size:     10_000_000,
+			fallback: 2,
+			wantErr:  true,
+		},
+		{
+			name:     "zero price",
+			price:    "0",
+			margin:   0,
+			size:     10_000_000,
+			fallback: 2,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			margin, leverage, err := provider.orderParams(
+				sdkmath.LegacyMustNewDecFromStr(tt.price),
+				sdkmath.NewInt(tt.margin),
+				sdkmath.NewInt(tt.size),
+				tt.fallback,
+			)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, sdkmath.NewInt(tt.wantMargin), margin)
+			assert.InDelta(t, tt.wantLeverage, leverage, 1e-9)
+		})
+	}
+}
+
+// fakeLevanaMarketClient returns a single long position liquidated at the configured price
+type fakeLevanaMarketClient struct {
+	levanamarket.QueryClient
+	liquidationPrice atomic.Value
+	fail             atomic.Bool
+}
+
+func (c *fakeLevanaMarketClient) NftProxy(_ context.Context, _, _ string, _ *string, _ *int, _ ...grpc.CallOption) (*levanamarket.NftProxyResponse, error) {
+	if c.fail.Load() {
+		return nil, errors.New("node unavailable")
+	}
+	return &levanamarket.NftProxyResponse{Tokens: []string{"1"}}, nil
+}
+
+func (c *fakeLevanaMarketClient) Positions(_ context.Context, _ string, _ []string, _ ...grpc.CallOption) (*levanamarket.PositionsResponse, error) {
+	return &levanamarket.PositionsResponse{Positions: []levanamarket.Position{{
+		ID:                   "1",
+		DirectionToBase:      levanamarket.Long,
+		PositionSizeBase:     "10",
+		PositionSizeUSD:      "100",
+		EntryPriceBase:       "10",
+		ActiveCollateral:     "50",
+		PNLCollateral:        "0",
+		LiquidationPriceBase: c.liquidationPrice.Load().(string),
+	}}}, nil
+}
+
+func TestLevanaGetLiquidationPrice(t *testing.T) {
+	client := &fakeLevanaMarketClient{}
+	client.liquidationPrice.Store("5.5")
+
+	provider := NewLevanaPerpsProvider(zap.NewNop(), "osmosis-1", "osmo1market", client, "uusdc", nil, 6, 0, "signer", "osmo1sender", "", nil, nil)
+
+	zero := sdkmath.LegacyZeroDec()
+
+	// The price is queried without a prior GetPosition
+	assert.Equal(t, "5.500000000000000000", provider.GetLiquidationPrice(zero, zero, zero, zero).String())
+
+	// Concurrent calls see the current price
+	client.liquidationPrice.Store("6")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, "6.000000000000000000", provider.GetLiquidationPrice(zero, zero, zero, zero).String())
+			_, err := provider.GetPosition(context.Background())
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// The last known price is used when the market cannot be queried
+	client.fail.Store(true)
+	client.liquidationPrice.Store("7")
+	assert.Equal(t, "6.000000000000000000", provider.GetLiquidationPrice(zero, zero, zero, zero).String())
+}
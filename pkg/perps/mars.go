@@ -3,6 +3,10 @@ package perps
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/margined-protocol/locust-core/pkg/contracts/mars/creditmanager"
 	marsperps "github.com/margined-protocol/locust-core/pkg/contracts/mars/perps"
@@ -19,6 +23,7 @@ import (
 
 const (
 	MarsMaintenanceMarginRatio = 0.105 // 9.5x leverage equivalent
+	MarsMaxFundingSamples      = 24 * 30
 )
 
 // MarsProvider implements the Provider interface for Mars Protocol
@@ -29,13 +34,21 @@ type MarsProvider struct {
 	collateralDenom string
 	outDecimals     int
 
+	// Tracked perp denoms, includes the configured market
+	markets map[string]struct{}
+
+	// Funding rates sampled from the market state, Mars keeps no funding history
+	fundingSamples   map[string][]FundingRate
+	fundingSamplesMu sync.Mutex
+
 	// Providers && Clients
 	msgHandler   ibc.MessageHandler
 	creditClient creditmanager.QueryClient
 	perpsClient  marsperps.QueryClient
 
 	// Other
-	executor string
+	senderAddress string
+	executor      string
 }
 
 // NewMarsProvider creates a new Mars provider
@@ -47,7 +60,9 @@ func NewMarsProvider(
 	config types.MarsConfig,
 	collateralDenom string,
 	outDecimals int,
+	senderAddress string,
 	executor string,
+	msgHandler ibc.MessageHandler,
 ) *MarsProvider {
 	return &MarsProvider{
 		logger:          logger,
@@ -57,7 +72,10 @@ func NewMarsProvider(
 		config:          config,
 		collateralDenom: collateralDenom,
 		outDecimals:     outDecimals,
+		senderAddress:   senderAddress,
 		executor:        executor,
+		msgHandler:      msgHandler,
+		markets:         map[string]struct{}{config.Market: {}},
 	}
 }
 
@@ -152,24 +170,93 @@ func (m *MarsProvider) GetSubaccountBalance() (sdk.Coins, error) {
 	return nil, fmt.Errorf("not implemented")
 }
 
+// AddMarkets tracks additional perp denoms traded from the same credit account
+func (m *MarsProvider) AddMarkets(markets ...string) {
+	for _, market := range markets {
+		m.markets[market] = struct{}{}
+	}
+}
+
+// GetMarkets implements Provider
+func (m *MarsProvider) GetMarkets() []string {
+	markets := make([]string, 0, len(m.markets))
+	for market := range m.markets {
+		markets = append(markets, market)
+	}
+	sort.Strings(markets)
+
+	return markets
+}
+
+// GetPositions implements Provider, margin is shared across markets as the credit account is cross margined
+func (m *MarsProvider) GetPositions(ctx context.Context) (map[string]*Position, error) {
+	creditPosition, err := m.creditClient.Positions(
+		ctx,
+		&creditmanager.PositionsRequest{
+			AccountID: m.creditAccountID(),
+		},
+	)
+	if err != nil {
+		m.logger.Error("Error fetching credit accounts", zap.String("executor", m.executor), zap.Error(err))
+		return nil, fmt.Errorf("failed to fetch credit accounts: %w", err)
+	}
+
+	perpPositions, err := m.perpsClient.PositionsByAccount(
+		ctx,
+		&marsperps.PositionsByAccountRequest{
+			AccountID: m.creditAccountID(),
+		},
+	)
+	if err != nil {
+		m.logger.Error("Error fetching perp positions", zap.String("executor", m.executor), zap.Error(err))
+		return nil, fmt.Errorf("failed to fetch perp positions: %w", err)
+	}
+
+	positions := make(map[string]*Position)
+	for _, market := range m.GetMarkets() {
+		var perpPosition *marsperps.PerpPosition
+		for i := range perpPositions.Positions {
+			if perpPositions.Positions[i].Denom == market {
+				perpPosition = &perpPositions.Positions[i]
+				break
+			}
+		}
+
+		position, err := GetPosition(*creditPosition, perpPosition, m.collateralDenom)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get position for %s: %w", market, err)
+		}
+		positions[market] = &position
+	}
+
+	return positions, nil
+}
+
 // CreateMarketOrder implements Provider
-func (m *MarsProvider) CreateMarketOrder(_ context.Context, _, margin, size sdkmath.Int, _, reduceOnly bool) ([]sdk.Msg, error) {
+func (m *MarsProvider) CreateMarketOrder(ctx context.Context, price, margin, size sdkmath.Int, isBuy, reduceOnly bool) ([]sdk.Msg, error) {
+	return m.CreateMarketOrderForMarket(ctx, m.config.Market, price, margin, size, isBuy, reduceOnly)
+}
+
+// CreateMarketOrderForMarket implements Provider
+func (m *MarsProvider) CreateMarketOrderForMarket(_ context.Context, market string, _, margin, size sdkmath.Int, _, reduceOnly bool) ([]sdk.Msg, error) {
+	if _, exists := m.markets[market]; !exists {
+		return nil, fmt.Errorf("market %s is not tracked", market)
+	}
+
 	// NOTE: currently isBuy is not used but that _should_ change negative size is a sell
-	// _, account, err := m.clientRegistry.GetSignerAccountAndAddress(m.signerAccount, DydxChainID)
-	// if err != nil {
-	// 	return nil, err
-	// }
 	// Price is basically unused in mars
-
-	account := "todoasabove-1"
+	account, err := m.getSender()
+	if err != nil {
+		return nil, err
+	}
 
 	// Convert the previous increasePerpPosition/decreasePerpPosition logic to handle both cases
 	if reduceOnly {
 		// Logic for reducing position
-		return m.buildReducePositionMsgs(account, margin, size)
+		return m.buildReducePositionMsgs(account, market, margin, size)
 	}
 	// Logic for increasing position
-	return m.buildIncreasePositionMsgs(account, margin, size)
+	return m.buildIncreasePositionMsgs(account, market, margin, size)
 }
 
 // CreateLimitOrder implements Provider
@@ -180,14 +267,17 @@ func (m *MarsProvider) CreateLimitOrder(_ context.Context, _, _, _ sdkmath.Int,
 
 // DepositSubaccount implements Provider
 func (m *MarsProvider) DepositSubaccount(_ context.Context, amount sdkmath.Int) ([]sdk.Msg, error) {
-	account := "todoasabove-2"
+	account, err := m.getSender()
+	if err != nil {
+		return nil, err
+	}
 
 	m.logger.Debug("Depositing Subaccount",
 		zap.String("sender", account),
 		zap.String("amount", amount.String()),
 	)
 
-	creditAccount := fmt.Sprintf("%v", account)
+	creditAccount := m.creditAccountID()
 
 	actions := []creditmanager.Action{}
 
@@ -217,14 +307,17 @@ func (m *MarsProvider) DepositSubaccount(_ context.Context, amount sdkmath.Int)
 
 // WithdrawSubaccount implements Provider
 func (m *MarsProvider) WithdrawSubaccount(_ context.Context, amount sdkmath.Int) ([]sdk.Msg, error) {
-	account := "todoasabove-3"
+	account, err := m.getSender()
+	if err != nil {
+		return nil, err
+	}
 
 	m.logger.Debug("Withdrawing Subaccount",
 		zap.String("sender", account),
 		zap.String("amount", amount.String()),
 	)
 
-	creditAccount := fmt.Sprintf("%v", account)
+	creditAccount := m.creditAccountID()
 	amountStr := amount.Abs().String()
 
 	actions := []creditmanager.Action{}
@@ -276,14 +369,15 @@ func (m *MarsProvider) CreateSubaccount(account string) (sdk.Msg, error) {
 }
 
 // Helper functions
-func (m *MarsProvider) buildIncreasePositionMsgs(account string, margin, size sdkmath.Int) ([]sdk.Msg, error) {
+func (m *MarsProvider) buildIncreasePositionMsgs(account, market string, margin, size sdkmath.Int) ([]sdk.Msg, error) {
 	m.logger.Debug("Increasing Perp Position",
 		zap.String("sender", account),
+		zap.String("market", market),
 		zap.String("additional_margin", margin.String()),
 		zap.String("additional_amount", size.String()),
 	)
 
-	creditAccount := fmt.Sprintf("%v", account)
+	creditAccount := m.creditAccountID()
 	orderSize := size.BigInt().String()
 
 	actions := []creditmanager.Action{}
@@ -300,7 +394,7 @@ func (m *MarsProvider) buildIncreasePositionMsgs(account string, margin, size sd
 	if size.GT(sdkmath.ZeroInt()) {
 		actions = append(actions, creditmanager.Action{
 			ExecutePerpOrder: &creditmanager.PerpOrder{
-				Denom:     m.collateralDenom,
+				Denom:     market,
 				OrderSize: &orderSize,
 			},
 		})
@@ -327,14 +421,15 @@ func (m *MarsProvider) buildIncreasePositionMsgs(account string, margin, size sd
 	return []sdk.Msg{updateMsg}, nil
 }
 
-func (m *MarsProvider) buildReducePositionMsgs(account string, margin, size sdkmath.Int) ([]sdk.Msg, error) {
+func (m *MarsProvider) buildReducePositionMsgs(account, market string, margin, size sdkmath.Int) ([]sdk.Msg, error) {
 	m.logger.Debug("Reducing Perp Position",
 		zap.String("sender", account),
+		zap.String("market", market),
 		zap.String("margin_delta", margin.String()),
 		zap.String("size_delta", size.String()),
 	)
 
-	creditAccount := fmt.Sprintf("%v", account)
+	creditAccount := m.creditAccountID()
 	reduceOnly := true
 	marginStr := margin.Abs().String()
 	sizeStr := size.BigInt().String()
@@ -344,7 +439,7 @@ func (m *MarsProvider) buildReducePositionMsgs(account string, margin, size sdkm
 	if !size.IsZero() {
 		actions = append(actions, creditmanager.Action{
 			ExecutePerpOrder: &creditmanager.PerpOrder{
-				Denom:      m.collateralDenom,
+				Denom:      market,
 				OrderSize:  &sizeStr,
 				ReduceOnly: &reduceOnly,
 			},
@@ -470,14 +565,278 @@ func (m *MarsProvider) IncreasePosition(ctx context.Context, _ float64, amount,
 	return result, nil
 }
 
-func (m *MarsProvider) ReducePosition(_ context.Context, _ float64, _, _ sdkmath.Int, _ bool) (*ExecutionResult, error) {
-	return nil, fmt.Errorf("not implemented")
+func (m *MarsProvider) ReducePosition(ctx context.Context, _ float64, amount, margin sdkmath.Int, isLong bool) (*ExecutionResult, error) {
+	// High level-logic:
+	// 1. Create a reduce only order and withdraw the released margin in a single update
+	// 2. Send the message
+	// 3. Verify the position change and return the execution result
+	if amount.IsNegative() {
+		return nil, fmt.Errorf("amount cannot be negative")
+	}
+	if margin.IsNegative() {
+		return nil, fmt.Errorf("margin cannot be negative")
+	}
+
+	initialPosition, err := m.GetPosition(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get initial position: %w", err)
+	}
+
+	if !initialPosition.Amount.IsZero() && initialPosition.Amount.IsPositive() != isLong {
+		return nil, fmt.Errorf("position direction mismatch: position size %s, isLong %t", initialPosition.Amount, isLong)
+	}
+
+	// Reducing a long is a sell, reducing a short is a buy
+	sizeChange := amount
+	if isLong {
+		sizeChange = amount.Neg()
+	}
+
+	// Never reduce by more than the open position
+	if amount.GT(initialPosition.Amount.Abs()) {
+		m.logger.Warn("Reduce amount exceeds open position, capping to position size",
+			zap.String("requested", amount.String()),
+			zap.String("position", initialPosition.Amount.String()),
+		)
+		sizeChange = initialPosition.Amount.Neg()
+	}
+
+	account, err := m.getSender()
+	if err != nil {
+		return nil, err
+	}
+
+	msgs, err := m.buildReducePositionMsgs(account, m.config.Market, margin, sizeChange)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.executeMsgs(ctx, msgs, initialPosition, sizeChange)
 }
 
-func (m *MarsProvider) ClosePosition(_ context.Context, _ bool) (*ExecutionResult, error) {
-	return nil, fmt.Errorf("not implemented")
+func (m *MarsProvider) ClosePosition(ctx context.Context, isLong bool) (*ExecutionResult, error) {
+	initialPosition, err := m.GetPosition(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get initial position: %w", err)
+	}
+
+	// Nothing to close
+	if initialPosition.Amount.IsZero() {
+		m.logger.Info("No open position to close", zap.String("market", m.config.Market))
+		return &ExecutionResult{
+			Position: initialPosition,
+			Notes:    "no open position",
+		}, nil
+	}
+
+	if initialPosition.Amount.IsPositive() != isLong {
+		return nil, fmt.Errorf("position direction mismatch: position size %s, isLong %t", initialPosition.Amount, isLong)
+	}
+
+	account, err := m.getSender()
+	if err != nil {
+		return nil, err
+	}
+
+	// Close the full position, margin is left in the credit account
+	sizeChange := initialPosition.Amount.Neg()
+	msgs, err := m.buildReducePositionMsgs(account, m.config.Market, sdkmath.ZeroInt(), sizeChange)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.executeMsgs(ctx, msgs, initialPosition, sizeChange)
 }
 
-func (m *MarsProvider) AdjustMargin(_ context.Context, _ sdkmath.Int, _ bool) (*ExecutionResult, error) {
-	return nil, fmt.Errorf("not implemented")
+func (m *MarsProvider) AdjustMargin(ctx context.Context, margin sdkmath.Int, isAdd bool) (*ExecutionResult, error) {
+	if !margin.IsPositive() {
+		return nil, fmt.Errorf("margin must be positive")
+	}
+
+	initialPosition, err := m.GetPosition(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get initial position: %w", err)
+	}
+
+	var msgs []sdk.Msg
+	if isAdd {
+		msgs, err = m.DepositSubaccount(ctx, margin)
+	} else {
+		if margin.GT(initialPosition.Margin) {
+			return nil, fmt.Errorf("cannot withdraw %s, only %s margin available", margin, initialPosition.Margin)
+		}
+		msgs, err = m.WithdrawSubaccount(ctx, margin)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Margin changes leave the position size untouched
+	return m.executeMsgs(ctx, msgs, initialPosition, sdkmath.ZeroInt())
+}
+
+// executeMsgs sends the messages, if a handler is provided, and verifies the resulting position
+func (m *MarsProvider) executeMsgs(ctx context.Context, msgs []sdk.Msg, initialPosition *Position, expectedSizeChange sdkmath.Int) (*ExecutionResult, error) {
+	result := &ExecutionResult{
+		Messages: msgs,
+		Position: initialPosition,
+	}
+
+	if len(msgs) == 0 {
+		result.Notes = "no actions to execute"
+		return result, nil
+	}
+
+	// Without a handler we only return the messages that would be sent
+	if m.msgHandler == nil {
+		result.Notes = "no message handler configured, messages not sent"
+		return result, nil
+	}
+
+	resp, err := m.msgHandler(m.chainID, msgs, false, true)
+	if err != nil {
+		return result, err
+	}
+
+	result.TxHash = resp.TxHash
+	result.Events = resp.Events
+	result.Executed = true
+
+	m.logger.Info("Credit account update transaction", zap.String("tx", resp.TxHash))
+
+	if !expectedSizeChange.IsZero() {
+		if err := verifyPositionChange(ctx, m.logger, m, initialPosition, expectedSizeChange); err != nil {
+			return result, err
+		}
+	}
+
+	updatedPosition, err := m.GetPosition(ctx)
+	if err != nil {
+		return result, err
+	}
+	result.Position = updatedPosition
+
+	return result, nil
+}
+
+// getSender returns the signer address on the Mars chain
+func (m *MarsProvider) getSender() (string, error) {
+	if m.senderAddress == "" {
+		return "", fmt.Errorf("sender address not configured")
+	}
+
+	return m.senderAddress, nil
+}
+
+// creditAccountID returns the credit account the provider trades from, this
+// matches the account used when querying positions
+func (m *MarsProvider) creditAccountID() string {
+	return fmt.Sprintf("%v", m.executor)
+}
+
+var _ FundingSource = (*MarsProvider)(nil)
+
+// FundingHistory implements FundingSource. Mars only exposes the current funding rate so the
+// history is built from the samples taken by this provider, every call takes a new sample.
+// The rate only changes when the market state is updated, so the history starts with the
+// sample in effect at since. Samples are kept in memory, ErrInsufficientFundingHistory is
+// returned until the provider has a sample from before since, e.g. after a restart.
+// Updates between two calls are not seen, callers poll at least as often as they need rates.
+func (m *MarsProvider) FundingHistory(ctx context.Context, market string, since time.Time) ([]FundingRate, error) {
+	if _, err := m.CurrentFundingRate(ctx, market); err != nil {
+		return nil, err
+	}
+
+	m.fundingSamplesMu.Lock()
+	defer m.fundingSamplesMu.Unlock()
+
+	samples := m.fundingSamples[market]
+
+	// Index of the last sample at or before since
+	first := -1
+	for i, sample := range samples {
+		if sample.Time.After(since) {
+			break
+		}
+		first = i
+	}
+
+	if first < 0 {
+		oldest := "none"
+		if len(samples) > 0 {
+			oldest = samples[0].Time.Format(time.RFC3339)
+		}
+		return nil, fmt.Errorf("%w: %s sampled since %s, requested %s", ErrInsufficientFundingHistory, market, oldest, since.Format(time.RFC3339))
+	}
+
+	return append([]FundingRate{}, samples[first:]...), nil
+}
+
+// CurrentFundingRate implements FundingSource using the market state, Mars funding rates are daily
+func (m *MarsProvider) CurrentFundingRate(ctx context.Context, market string) (*FundingRate, error) {
+	state, err := m.perpsClient.MarketState(ctx, &marsperps.MarketStateRequest{Denom: market})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch market state for %s: %w", market, err)
+	}
+
+	rate, err := strconv.ParseFloat(state.MarketState.Funding.Rate, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid funding rate %s: %w", state.MarketState.Funding.Rate, err)
+	}
+
+	sample := FundingRate{
+		Time: time.Unix(int64(state.MarketState.LastUpdated), 0).UTC(),
+		Rate: rate * daysPerYear,
+	}
+
+	m.recordFundingSample(market, sample)
+
+	return &sample, nil
+}
+
+// AccruedFunding implements FundingSource using the unrealized pnl of the perp position
+func (m *MarsProvider) AccruedFunding(ctx context.Context, market string) (sdkmath.Int, error) {
+	position, err := m.perpsClient.Position(
+		ctx,
+		&marsperps.PositionRequest{
+			AccountID: m.creditAccountID(),
+			Denom:     market,
+		},
+	)
+	if err != nil {
+		return sdkmath.Int{}, fmt.Errorf("failed to fetch perp position: %w", err)
+	}
+
+	if position.Position == nil || position.Position.UnrealizedPnl.AccruedFunding == nil {
+		return sdkmath.ZeroInt(), nil
+	}
+
+	accrued, err := sdkmath.LegacyNewDecFromStr(*position.Position.UnrealizedPnl.AccruedFunding)
+	if err != nil {
+		return sdkmath.Int{}, fmt.Errorf("failed to parse accrued funding %s: %w", *position.Position.UnrealizedPnl.AccruedFunding, err)
+	}
+
+	return accrued.TruncateInt(), nil
+}
+
+// recordFundingSample stores a funding sample unless the market state has not been updated
+// since the last one, at most MarsMaxFundingSamples are kept per market
+func (m *MarsProvider) recordFundingSample(market string, sample FundingRate) {
+	m.fundingSamplesMu.Lock()
+	defer m.fundingSamplesMu.Unlock()
+
+	if m.fundingSamples == nil {
+		m.fundingSamples = make(map[string][]FundingRate)
+	}
+
+	samples := m.fundingSamples[market]
+	if len(samples) > 0 && !sample.Time.After(samples[len(samples)-1].Time) {
+		return
+	}
+
+	samples = append(samples, sample)
+	if len(samples) > MarsMaxFundingSamples {
+		samples = samples[len(samples)-MarsMaxFundingSamples:]
+	}
+	m.fundingSamples[market] = samples
 }
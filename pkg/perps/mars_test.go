@@ -0,0 +1,350 @@
+package perps
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	wasmdtypes "github.com/CosmWasm/wasmd/x/wasm/types"
+	"github.com/margined-protocol/locust-core/pkg/contracts/mars/creditmanager"
+	marsperps "github.com/margined-protocol/locust-core/pkg/contracts/mars/perps"
+	"github.com/margined-protocol/locust-core/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	sdkmath "cosmossdk.io/math"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+const (
+	marsSender        = "neutron1sender"
+	marsCreditManager = "neutron1creditmanager"
+)
+
+// fakeCreditClient returns the configured deposits of the credit account
+type fakeCreditClient struct {
+	creditmanager.QueryClient
+	deposits sdk.Coins
+}
+
+func (c *fakeCreditClient) Positions(_ context.Context, req *creditmanager.PositionsRequest, _ ...grpc.CallOption) (*creditmanager.PositionsResponse, error) {
+	return &creditmanager.PositionsResponse{AccountID: req.AccountID, Deposits: c.deposits}, nil
+}
+
+// fakeMarsPerpsClient returns a position of the configured size, or none when empty
+type fakeMarsPerpsClient struct {
+	marsperps.QueryClient
+	size string
+}
+
+func (c *fakeMarsPerpsClient) Position(_ context.Context, req *marsperps.PositionRequest, _ ...grpc.CallOption) (*marsperps.PositionResponse, error) {
+	if c.size == "" {
+		return &marsperps.PositionResponse{AccountID: req.AccountID}, nil
+	}
+
+	size, pnl := c.size, "0"
+	return &marsperps.PositionResponse{
+		AccountID: req.AccountID,
+		Position: &marsperps.PerpPosition{
+			Denom:         req.Denom,
+			Size:          &size,
+			EntryPrice:    "10",
+			CurrentPrice:  "10",
+			UnrealizedPnl: marsperps.PnlAmounts{Pnl: &pnl},
+		},
+	}, nil
+}
+
+func newTestMarsProvider(size string, margin int64) *MarsProvider {
+	return NewMarsProvider(
+		zap.NewNop(),
+		"neutron-1",
+		&fakeCreditClient{deposits: sdk.NewCoins(sdk.NewInt64Coin("uusdc", margin))},
+		&fakeMarsPerpsClient{size: size},
+		types.MarsConfig{CreditManager: marsCreditManager, Market: "perps/ubtc"},
+		"uusdc",
+		6,
+		marsSender,
+		"42",
+		nil,
+	)
+}
+
+// creditAccountUpdate decodes the update_credit_account message sent to the credit manager
+func creditAccountUpdate(t *testing.T, msgs []sdk.Msg) ([]creditmanager.Action, sdk.Coins) {
+	t.Helper()
+
+	require.Len(t, msgs, 1)
+	msg, ok := msgs[0].(*wasmdtypes.MsgExecuteContract)
+	require.True(t, ok)
+	assert.Equal(t, marsSender, msg.Sender)
+	assert.Equal(t, marsCreditManager, msg.Contract)
+
+	var execute struct {
+		UpdateCreditAccount struct {
+			AccountID *string                `json:"account_id"`
+			Actions   []creditmanager.Action `json:"actions"`
+		} `json:"update_credit_account"`
+	}
+	require.NoError(t, json.Unmarshal(msg.Msg, &execute))
+	require.NotNil(t, execute.UpdateCreditAccount.AccountID)
+	assert.Equal(t, "42", *execute.UpdateCreditAccount.AccountID)
+
+	return execute.UpdateCreditAccount.Actions, msg.Funds
+}
+
+func perpOrderAction(size string) creditmanager.Action {
+	reduceOnly := true
+	return creditmanager.Action{
+		ExecutePerpOrder: &creditmanager.PerpOrder{Denom: "perps/ubtc", OrderSize: &size, ReduceOnly: &reduceOnly},
+	}
+}
+
+func withdrawAction(amount string) creditmanager.Action {
+	return creditmanager.Action{
+		WithdrawToWallet: &creditmanager.WithdrawData{
+			Coin:      creditmanager.ActionCoin{Denom: "uusdc", Amount: creditmanager.ActionAmount{Exact: &amount}},
+			Recipient: marsSender,
+		},
+	}
+}
+
+func TestMarsReducePosition(t *testing.T) {
+	tests := []struct {
+		name          string
+		position      string
+		amount        int64
+		margin        int64
+		isLong        bool
+		actions       []creditmanager.Action
+		errorContains string
+	}{
+		{
+			name:     "reduce long",
+			position: "100",
+			amount:   40,
+			margin:   5,
+			isLong:   true,
+			actions:  []creditmanager.Action{perpOrderAction("-40"), withdrawAction("5")},
+		},
+		{
+			name:     "reduce short",
+			position: "-100",
+			amount:   40,
+			isLong:   false,
+			actions:  []creditmanager.Action{perpOrderAction("40")},
+		},
+		{
+			name:     "amount capped to long position",
+			position: "100",
+			amount:   150,
+			isLong:   true,
+			actions:  []creditmanager.Action{perpOrderAction("-100")},
+		},
+		{
+			name:     "amount capped to short position",
+			position: "-100",
+			amount:   150,
+			isLong:   false,
+			actions:  []creditmanager.Action{perpOrderAction("100")},
+		},
+		{
+			name:     "margin only",
+			position: "100",
+			margin:   5,
+			isLong:   true,
+			actions:  []creditmanager.Action{withdrawAction("5")},
+		},
+		{
+			name:          "short reduced as long",
+			position:      "-100",
+			amount:        40,
+			isLong:        true,
+			errorContains: "position direction mismatch",
+		},
+		{
+			name:          "long reduced as short",
+			position:      "100",
+			amount:        150,
+			isLong:        false,
+			errorContains: "position direction mismatch",
+		},
+		{
+			name:          "negative amount",
+			position:      "100",
+			amount:        -1,
+			isLong:        true,
+			errorContains: "amount cannot be negative",
+		},
+		{
+			name:          "negative margin",
+			position:      "100",
+			margin:        -1,
+			isLong:        true,
+			errorContains: "margin cannot be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newTestMarsProvider(tt.position, 50)
+
+			result, err := provider.ReducePosition(context.Background(), 0, sdkmath.NewInt(tt.amount), sdkmath.NewInt(tt.margin), tt.isLong)
+			if tt.errorContains != "" {
+				assert.ErrorContains(t, err, tt.errorContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.False(t, result.Executed)
+
+			actions, funds := creditAccountUpdate(t, result.Messages)
+			assert.Equal(t, tt.actions, actions)
+			assert.True(t, funds.IsZero())
+		})
+	}
+}
+
+func TestMarsClosePosition(t *testing.T) {
+	tests := []struct {
+		name          string
+		position      string
+		isLong        bool
+		actions       []creditmanager.Action
+		notes         string
+		errorContains string
+	}{
+		{
+			name:     "close long",
+			position: "100",
+			isLong:   true,
+			actions:  []creditmanager.Action{perpOrderAction("-100")},
+		},
+		{
+			name:     "close short",
+			position: "-100",
+			isLong:   false,
+			actions:  []creditmanager.Action{perpOrderAction("100")},
+		},
+		{
+			name:   "no position",
+			isLong: true,
+			notes:  "no open position",
+		},
+		{
+			name:          "direction mismatch",
+			position:      "100",
+			isLong:        false,
+			errorContains: "position direction mismatch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newTestMarsProvider(tt.position, 50)
+
+			result, err := provider.ClosePosition(context.Background(), tt.isLong)
+			if tt.errorContains != "" {
+				assert.ErrorContains(t, err, tt.errorContains)
+				return
+			}
+			require.NoError(t, err)
+
+			if tt.actions == nil {
+				assert.Empty(t, result.Messages)
+				assert.Equal(t, tt.notes, result.Notes)
+				return
+			}
+
+			// Closing leaves the margin in the credit account
+			actions, funds := creditAccountUpdate(t, result.Messages)
+			assert.Equal(t, tt.actions, actions)
+			assert.True(t, funds.IsZero())
+		})
+	}
+}
+
+func TestMarsAdjustMargin(t *testing.T) {
+	tests := []struct {
+		name          string
+		margin        int64
+		isAdd         bool
+		actions       []creditmanager.Action
+		funds         sdk.Coins
+		errorContains string
+	}{
+		{
+			name:    "add margin",
+			margin:  20,
+			isAdd:   true,
+			actions: []creditmanager.Action{{Deposit: &creditmanager.Coin{Denom: "uusdc", Amount: "20"}}},
+			funds:   sdk.NewCoins(sdk.NewInt64Coin("uusdc", 20)),
+		},
+		{
+			name:    "withdraw margin",
+			margin:  20,
+			actions: []creditmanager.Action{withdrawAction("20")},
+		},
+		{
+			name:    "withdraw all margin",
+			margin:  50,
+			actions: []creditmanager.Action{withdrawAction("50")},
+		},
+		{
+			name:          "withdraw more than available",
+			margin:        51,
+			errorContains: "only 50 margin available",
+		},
+		{
+			name:          "zero margin",
+			isAdd:         true,
+			errorContains: "margin must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newTestMarsProvider("100", 50)
+
+			result, err := provider.AdjustMargin(context.Background(), sdkmath.NewInt(tt.margin), tt.isAdd)
+			if tt.errorContains != "" {
+				assert.ErrorContains(t, err, tt.errorContains)
+				return
+			}
+			require.NoError(t, err)
+
+			actions, funds := creditAccountUpdate(t, result.Messages)
+			assert.Equal(t, tt.actions, actions)
+			assert.Equal(t, tt.funds.String(), funds.String())
+		})
+	}
+}
+
+func TestParseMarsConfig(t *testing.T) {
+	rawConfig := map[string]interface{}{
+		"chain_id":         "neutron-1",
+		"credit_client":    &fakeCreditClient{},
+		"perps_client":     &fakeMarsPerpsClient{},
+		"mars_config":      types.MarsConfig{Market: "perps/ubtc"},
+		"collateral_denom": "uusdc",
+		"out_decimals":     6,
+		"executor":         "42",
+	}
+
+	// The signer and handler are optional
+	config, err := parseMarsConfig(rawConfig)
+	require.NoError(t, err)
+	assert.Nil(t, config.ClientRegistry)
+	assert.Nil(t, config.MsgHandler)
+
+	rawConfig["signer_account"] = "signer"
+	config, err = parseMarsConfig(rawConfig)
+	require.NoError(t, err)
+	assert.Equal(t, "signer", config.SignerAccount)
+
+	rawConfig["msg_handler"] = "handler"
+	_, err = parseMarsConfig(rawConfig)
+	assert.ErrorContains(t, err, "msg_handler must be ibc.MessageHandler")
+}
@@ -0,0 +1,211 @@
+package perps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/margined-protocol/locust-core/pkg/math"
+	"go.uber.org/zap"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+// OrderbookQuote is the expected execution of an order walked against the orderbook
+type OrderbookQuote struct {
+	BestPrice    float64 // Best price on the side of the book the order takes from
+	AveragePrice float64 // Size weighted average fill price
+	WorstPrice   float64 // Price of the last level the order reaches
+	MidPrice     float64
+	Size         float64
+	Slippage     float64 // Relative distance of the average fill price from the mid price
+}
+
+type orderbookLevel struct {
+	price float64
+	size  float64
+}
+
+// QuoteOrderbook walks the orderbook levels to compute the expected average and worst fill
+// prices for the given size in base units, buys take from the asks and sells from the bids
+func QuoteOrderbook(book *IndexerOrderbookResponse, size float64, isBuy bool) (*OrderbookQuote, error) {
+	if book == nil {
+		return nil, fmt.Errorf("orderbook is empty")
+	}
+
+	if size <= 0 {
+		return nil, fmt.Errorf("size must be positive, got %f", size)
+	}
+
+	bids, err := parseOrderbookLevels(book.Bids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse bids: %w", err)
+	}
+
+	asks, err := parseOrderbookLevels(book.Asks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse asks: %w", err)
+	}
+
+	// The indexer returns sorted levels but we do not rely on it
+	sort.Slice(bids, func(i, j int) bool { return bids[i].price > bids[j].price })
+	sort.Slice(asks, func(i, j int) bool { return asks[i].price < asks[j].price })
+
+	levels := bids
+	if isBuy {
+		levels = asks
+	}
+
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("orderbook has no liquidity on the %s side", bookSide(isBuy))
+	}
+
+	var filled, notional, worstPrice float64
+	for _, level := range levels {
+		fill := level.size
+		if remaining := size - filled; fill > remaining {
+			fill = remaining
+		}
+
+		filled += fill
+		notional += fill * level.price
+		worstPrice = level.price
+
+		if filled >= size {
+			break
+		}
+	}
+
+	if filled < size {
+		return nil, fmt.Errorf("insufficient orderbook depth: requested %f, available %f", size, filled)
+	}
+
+	quote := &OrderbookQuote{
+		BestPrice:    levels[0].price,
+		AveragePrice: notional / filled,
+		WorstPrice:   worstPrice,
+		MidPrice:     levels[0].price,
+		Size:         size,
+	}
+
+	// Measure slippage from the mid when both sides are quoted, otherwise from the best price
+	if len(bids) > 0 && len(asks) > 0 {
+		quote.MidPrice = (bids[0].price + asks[0].price) / 2
+	}
+
+	quote.Slippage = (quote.AveragePrice - quote.MidPrice) / quote.MidPrice
+	if !isBuy {
+		quote.Slippage = -quote.Slippage
+	}
+	if quote.Slippage < 0 {
+		quote.Slippage = 0
+	}
+
+	return quote, nil
+}
+
+// parseOrderbookLevels converts the indexer levels to floats, skipping empty levels
+func parseOrderbookLevels(levels []IndexerOrderbookLevel) ([]orderbookLevel, error) {
+	parsed := make([]orderbookLevel, 0, len(levels))
+	for _, level := range levels {
+		price, err := strconv.ParseFloat(level.Price, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price %s: %w", level.Price, err)
+		}
+
+		size, err := strconv.ParseFloat(level.Size, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size %s: %w", level.Size, err)
+		}
+
+		if price <= 0 || size <= 0 {
+			continue
+		}
+
+		parsed = append(parsed, orderbookLevel{price: price, size: size})
+	}
+
+	return parsed, nil
+}
+
+func bookSide(isBuy bool) string {
+	if isBuy {
+		return "ask"
+	}
+	return "bid"
+}
+
+// QueryOrderbook fetches the orderbook of a perpetual market from the indexer
+func (m *DydxProvider) QueryOrderbook(ctx context.Context, market string) (*IndexerOrderbookResponse, error) {
+	path := fmt.Sprintf("/orderbooks/perpetualMarket/%s", market)
+
+	url := fmt.Sprintf("%s%s", m.indexerURL, path)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("indexer request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result IndexerOrderbookResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// PriceMarketOrder prices an order of size base quantums off the orderbook, the worst
+// expected fill price plus the price buffer is returned in subticks. Orders whose expected
+// slippage exceeds the maximum are refused.
+func (m *DydxProvider) PriceMarketOrder(ctx context.Context, params *DydxMarket, size sdkmath.Int, isBuy bool) (sdkmath.Int, *OrderbookQuote, error) {
+	book, err := m.QueryOrderbook(ctx, params.Ticker)
+	if err != nil {
+		return sdkmath.Int{}, nil, fmt.Errorf("error fetching orderbook for %s: %w", params.Ticker, err)
+	}
+
+	baseSize := params.quantumsToSize(size)
+
+	quote, err := QuoteOrderbook(book, baseSize.MustFloat64(), isBuy)
+	if err != nil {
+		return sdkmath.Int{}, nil, fmt.Errorf("error quoting orderbook for %s: %w", params.Ticker, err)
+	}
+
+	if quote.Slippage > m.maxSlippage {
+		return sdkmath.Int{}, quote, fmt.Errorf("expected slippage %.4f exceeds maximum %.4f for %s", quote.Slippage, m.maxSlippage, params.Ticker)
+	}
+
+	m.logger.Debug("Quoted orderbook",
+		zap.String("market", params.Ticker),
+		zap.Bool("isBuy", isBuy),
+		zap.Float64("size", quote.Size),
+		zap.Float64("bestPrice", quote.BestPrice),
+		zap.Float64("averagePrice", quote.AveragePrice),
+		zap.Float64("worstPrice", quote.WorstPrice),
+		zap.Float64("slippage", quote.Slippage),
+	)
+
+	bufferedPrice := math.AdjustSlippageFloat64(quote.WorstPrice, m.priceBuffer, isBuy)
+	quantumPrice := math.FloatToQuantumPrice(bufferedPrice, params.QuantumConversionExponent)
+
+	price, err := params.validateAndRoundPrice(quantumPrice)
+	if err != nil {
+		return sdkmath.Int{}, nil, err
+	}
+
+	return price, quote, nil
+}
@@ -0,0 +1,134 @@
+package perps
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sdkmath "cosmossdk.io/math"
+)
+
+func TestQuoteOrderbook(t *testing.T) {
+	book := &IndexerOrderbookResponse{
+		Bids: []IndexerOrderbookLevel{
+			{Price: "9.9", Size: "10"},
+			{Price: "9.7", Size: "30"},
+			{Price: "9.8", Size: "20"},
+		},
+		Asks: []IndexerOrderbookLevel{
+			{Price: "10.1", Size: "10"},
+			{Price: "10.2", Size: "20"},
+			{Price: "10.3", Size: "0"},
+			{Price: "10.4", Size: "30"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		book         *IndexerOrderbookResponse
+		size         float64
+		isBuy        bool
+		wantAverage  float64
+		wantWorst    float64
+		wantSlippage float64
+		wantErr      bool
+	}{
+		{
+			name:         "buy within best level",
+			book:         book,
+			size:         5,
+			isBuy:        true,
+			wantAverage:  10.1,
+			wantWorst:    10.1,
+			wantSlippage: 0.01,
+		},
+		{
+			name:         "buy across levels skipping empty level",
+			book:         book,
+			size:         40,
+			isBuy:        true,
+			wantAverage:  (10*10.1 + 20*10.2 + 10*10.4) / 40,
+			wantWorst:    10.4,
+			wantSlippage: ((10*10.1+20*10.2+10*10.4)/40 - 10) / 10,
+		},
+		{
+			name:         "sell across unsorted levels",
+			book:         book,
+			size:         25,
+			isBuy:        false,
+			wantAverage:  (10*9.9 + 15*9.8) / 25,
+			wantWorst:    9.8,
+			wantSlippage: (10 - (10*9.9+15*9.8)/25) / 10,
+		},
+		{
+			name:    "insufficient depth",
+			book:    book,
+			size:    100,
+			isBuy:   false,
+			wantErr: true,
+		},
+		{
+			name: "empty side",
+			book: &IndexerOrderbookResponse{
+				Bids: []IndexerOrderbookLevel{{Price: "9.9", Size: "10"}},
+			},
+			size:    1,
+			isBuy:   true,
+			wantErr: true,
+		},
+		{
+			name: "invalid level",
+			book: &IndexerOrderbookResponse{
+				Asks: []IndexerOrderbookLevel{{Price: "abc", Size: "10"}},
+			},
+			size:    1,
+			isBuy:   true,
+			wantErr: true,
+		},
+		{
+			name:    "zero size",
+			book:    book,
+			size:    0,
+			isBuy:   true,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := QuoteOrderbook(tt.book, tt.size, tt.isBuy)
+
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.InDelta(t, tt.wantAverage, quote.AveragePrice, 1e-9)
+			assert.InDelta(t, tt.wantWorst, quote.WorstPrice, 1e-9)
+			assert.InDelta(t, tt.wantSlippage, quote.Slippage, 1e-9)
+		})
+	}
+}
+
+func TestQuantumsToSize(t *testing.T) {
+	tests := []struct {
+		name             string
+		atomicResolution int64
+		quantums         int64
+		want             string
+	}{
+		{name: "negative resolution", atomicResolution: -6, quantums: 1_500_000, want: "1.5"},
+		{name: "zero resolution", atomicResolution: 0, quantums: 42, want: "42"},
+		{name: "positive resolution", atomicResolution: 2, quantums: 3, want: "300"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			market := &DydxMarket{AtomicResolution: tt.atomicResolution}
+			got := market.quantumsToSize(sdkmath.NewInt(tt.quantums))
+			assert.True(t, sdkmath.LegacyMustNewDecFromStr(tt.want).Equal(got), "got %s, want %s", got, tt.want)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 
+	levanamarket "github.com/margined-protocol/locust-core/pkg/contracts/levana/market"
 	"github.com/margined-protocol/locust-core/pkg/math"
 
 	sdkmath "cosmossdk.io/math"
@@ -131,3 +132,96 @@ func ProcessIndexerResponse(
 
 	return position, nil
 }
+
+// ProcessLevanaPositions aggregates open Levana positions into a Position and returns
+// the liquidation price of the first position
+func ProcessLevanaPositions(
+	positions []levanamarket.Position,
+	decimals int64,
+) (*Position, sdkmath.LegacyDec, error) {
+	// Initialize position with zero values for all fields
+	position := &Position{
+		CurrentPrice:  sdkmath.LegacyZeroDec(),
+		EntryPrice:    sdkmath.LegacyZeroDec(),
+		Margin:        sdkmath.ZeroInt(),
+		Amount:        sdkmath.ZeroInt(),
+		UnrealizedPnl: sdkmath.ZeroInt(),
+		RealizedPnl:   sdkmath.ZeroInt(),
+	}
+	liquidationPrice := sdkmath.LegacyZeroDec()
+
+	if len(positions) == 0 {
+		return position, liquidationPrice, nil
+	}
+
+	multiplier := sdkmath.LegacyNewDec(10).Power(uint64(decimals))
+	totalSize := sdkmath.LegacyZeroDec()
+	weightedEntry := sdkmath.LegacyZeroDec()
+
+	for i, p := range positions {
+		size, err := parseLevanaDec(p.PositionSizeBase)
+		if err != nil {
+			return nil, liquidationPrice, fmt.Errorf("failed to parse position size: %w", err)
+		}
+		size = size.Abs()
+
+		entryPrice, err := parseLevanaDec(p.EntryPriceBase)
+		if err != nil {
+			return nil, liquidationPrice, fmt.Errorf("failed to parse entry price: %w", err)
+		}
+
+		collateral, err := parseLevanaDec(p.ActiveCollateral)
+		if err != nil {
+			return nil, liquidationPrice, fmt.Errorf("failed to parse active collateral: %w", err)
+		}
+
+		pnl, err := parseLevanaDec(p.PNLCollateral)
+		if err != nil {
+			return nil, liquidationPrice, fmt.Errorf("failed to parse pnl: %w", err)
+		}
+
+		// Shorts are reported as negative amounts
+		signedSize := size
+		if p.DirectionToBase == levanamarket.Short {
+			signedSize = size.Neg()
+		}
+
+		position.Amount = position.Amount.Add(signedSize.Mul(multiplier).TruncateInt())
+		position.Margin = position.Margin.Add(collateral.Mul(multiplier).TruncateInt())
+		position.UnrealizedPnl = position.UnrealizedPnl.Add(pnl.Mul(multiplier).TruncateInt())
+
+		totalSize = totalSize.Add(size)
+		weightedEntry = weightedEntry.Add(entryPrice.Mul(size))
+
+		if i == 0 {
+			liquidationPrice, err = parseLevanaDec(p.LiquidationPriceBase)
+			if err != nil {
+				return nil, liquidationPrice, fmt.Errorf("failed to parse liquidation price: %w", err)
+			}
+
+			// The current price is implied by the USD value of the position
+			sizeUSD, err := parseLevanaDec(p.PositionSizeUSD)
+			if err != nil {
+				return nil, liquidationPrice, fmt.Errorf("failed to parse position size usd: %w", err)
+			}
+			if size.IsPositive() {
+				position.CurrentPrice = sizeUSD.Abs().Quo(size)
+			}
+		}
+	}
+
+	if totalSize.IsPositive() {
+		position.EntryPrice = weightedEntry.Quo(totalSize)
+	}
+
+	return position, liquidationPrice, nil
+}
+
+// parseLevanaDec parses a Levana decimal string, empty values are treated as zero
+func parseLevanaDec(value string) (sdkmath.LegacyDec, error) {
+	if value == "" {
+		return sdkmath.LegacyZeroDec(), nil
+	}
+
+	return sdkmath.LegacyNewDecFromStr(value)
+}
@@ -73,6 +73,56 @@ type IndexerCandle struct {
 	OrderbookMidPriceClose string `json:"orderbookMidPriceClose"`
 }
 
+// IndexerOrderbookResponse represents the orderbook of a perpetual market from the indexer
+type IndexerOrderbookResponse struct {
+	Bids []IndexerOrderbookLevel `json:"bids"`
+	Asks []IndexerOrderbookLevel `json:"asks"`
+}
+
+// IndexerOrderbookLevel represents a single price level in the orderbook
+type IndexerOrderbookLevel struct {
+	Price string `json:"price"`
+	Size  string `json:"size"`
+}
+
+// IndexerOrder represents a single order from the dYdX indexer, the orders endpoint
+// returns a plain list of these
+type IndexerOrder struct {
+	ID               string `json:"id"`
+	SubaccountID     string `json:"subaccountId"`
+	ClientID         string `json:"clientId"`
+	ClobPairID       string `json:"clobPairId"`
+	Side             string `json:"side"`
+	Size             string `json:"size"`
+	TotalFilled      string `json:"totalFilled"`
+	Price            string `json:"price"`
+	Type             string `json:"type"`
+	Status           string `json:"status"`
+	TimeInForce      string `json:"timeInForce"`
+	ReduceOnly       bool   `json:"reduceOnly"`
+	OrderFlags       string `json:"orderFlags"`
+	GoodTilBlock     string `json:"goodTilBlock,omitempty"`
+	GoodTilBlockTime string `json:"goodTilBlockTime,omitempty"`
+	TriggerPrice     string `json:"triggerPrice,omitempty"`
+	Ticker           string `json:"ticker"`
+	SubaccountNumber uint32 `json:"subaccountNumber"`
+}
+
+// IndexerHistoricalFundingResponse represents the funding history of a market from the
+// indexer, newest first
+type IndexerHistoricalFundingResponse struct {
+	HistoricalFunding []IndexerHistoricalFunding `json:"historicalFunding"`
+}
+
+// IndexerHistoricalFunding represents a single hourly funding payment
+type IndexerHistoricalFunding struct {
+	Ticker            string `json:"ticker"`
+	Rate              string `json:"rate"`
+	Price             string `json:"price"`
+	EffectiveAt       string `json:"effectiveAt"`
+	EffectiveAtHeight string `json:"effectiveAtHeight"`
+}
+
 // IndexerFillResponse represents a response containing fills from the dYdX indexer
 type IndexerFillResponse struct {
 	Fills []IndexerFill `json:"fills"`
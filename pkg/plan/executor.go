@@ -0,0 +1,497 @@
+package plan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/margined-protocol/locust-core/pkg/connection"
+	"github.com/margined-protocol/locust-core/pkg/types"
+	"go.uber.org/zap"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+const (
+	// DefaultConfirmTimeout is how long a step waits for its IBC packets to be received
+	DefaultConfirmTimeout = 10 * time.Minute
+	// DefaultLookupTimeout is how long a step waits for its recorded tx to be included
+	DefaultLookupTimeout = time.Minute
+)
+
+var (
+	// ErrPlanFailed is returned when a step failed, the completed steps have been rolled back
+	ErrPlanFailed = errors.New("plan failed")
+	// ErrPlanMismatch is returned when resuming a plan whose steps differ from its progress
+	ErrPlanMismatch = errors.New("plan does not match its persisted progress")
+)
+
+// stepResult is the included tx of a step
+type stepResult struct {
+	TxHash string
+	Height int64
+	Events []abci.Event
+}
+
+type (
+	// sendFunc sends the messages and waits for their tx to be included
+	sendFunc func(ctx context.Context, msg connection.ChainMessage) (*stepResult, error)
+	// broadcastFunc submits the messages without waiting for inclusion, passing the tx hash to
+	// record before the tx is submitted. The hash is empty when nothing was submitted.
+	broadcastFunc func(ctx context.Context, msg connection.ChainMessage, record func(txHash string) error) (string, error)
+	// lookupFunc waits for a recorded tx to be included
+	lookupFunc func(ctx context.Context, chainID, txHash string) (*stepResult, error)
+	// confirmFunc waits for the IBC packets sent in the events to complete on their destination
+	confirmFunc func(ctx context.Context, sourceChainID, destChainID string, events []abci.Event) error
+)
+
+// Executor runs plans, persisting the progress of each step so an interrupted plan resumes
+// where it stopped
+type Executor struct {
+	logger         *zap.Logger
+	store          Store
+	send           sendFunc
+	broadcast      broadcastFunc
+	lookup         lookupFunc
+	confirm        confirmFunc
+	confirmTimeout time.Duration
+}
+
+// NewExecutor creates a plan executor sending messages through the registry's clients
+func NewExecutor(
+	logger *zap.Logger,
+	clientRegistry *connection.ClientRegistry,
+	messageSender connection.MessageSender,
+	cfg *types.Config,
+	store Store,
+) *Executor {
+	send := func(ctx context.Context, msg connection.ChainMessage) (*stepResult, error) {
+		resp, err := connection.SendMessages(ctx, logger, clientRegistry, msg, messageSender, cfg, cfg.DryRun, msg.IsFeeClient, msg.WrapAuthz)
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil || resp.TxResponse == nil {
+			return &stepResult{}, nil
+		}
+		return &stepResult{TxHash: resp.TxHash, Height: resp.Height, Events: resp.Events}, nil
+	}
+
+	broadcast := func(ctx context.Context, msg connection.ChainMessage, record func(txHash string) error) (string, error) {
+		// A dry run only simulates the messages
+		if cfg.DryRun {
+			_, err := connection.SendMessages(ctx, logger, clientRegistry, msg, messageSender, cfg, true, msg.IsFeeClient, msg.WrapAuthz)
+			return "", err
+		}
+		return connection.SubmitMessages(ctx, logger, clientRegistry, msg, cfg, record)
+	}
+
+	lookup := func(ctx context.Context, chainID, txHash string) (*stepResult, error) {
+		rpcClient, err := clientRegistry.GetRPCClient(chainID)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := rpcClient.NewTxWaiter().WaitForTx(ctx, txHash, DefaultLookupTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if !result.Succeeded() {
+			return nil, fmt.Errorf("tx %s failed with code %d: %s", txHash, result.Code, result.Log)
+		}
+		return &stepResult{TxHash: result.TxHash, Height: result.Height, Events: result.Events}, nil
+	}
+
+	return &Executor{
+		logger:         logger,
+		store:          store,
+		send:           send,
+		broadcast:      broadcast,
+		lookup:         lookup,
+		confirm:        newPacketConfirmer(logger, clientRegistry).confirm,
+		confirmTimeout: DefaultConfirmTimeout,
+	}
+}
+
+// SetConfirmTimeout sets how long a step waits for its IBC packets to be received
+func (e *Executor) SetConfirmTimeout(timeout time.Duration) {
+	e.confirmTimeout = timeout
+}
+
+// Execute runs the plan, resuming from its persisted progress. When a step fails the running
+// steps are awaited and the completed steps rolled back in reverse order, ErrPlanFailed is
+// returned with the progress. A cancelled context stops the plan without rolling back so it
+// can be resumed.
+func (e *Executor) Execute(ctx context.Context, p *Plan) (*Progress, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	progress, err := e.store.Load(ctx, p.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if progress == nil {
+		progress = newProgress(p)
+		if err := e.store.Save(ctx, progress); err != nil {
+			return nil, err
+		}
+	} else if !progress.matches(p) {
+		return progress, fmt.Errorf("%w: %s", ErrPlanMismatch, p.ID)
+	}
+
+	switch progress.Status {
+	case StatusCompleted:
+		return progress, nil
+	case StatusFailed, StatusRolledBack:
+		return progress, fmt.Errorf("%w: %s", ErrPlanFailed, progress.Error)
+	}
+
+	run := &execution{
+		executor: e,
+		plan:     p,
+		progress: progress,
+	}
+
+	return run.run(ctx)
+}
+
+// execution is a single run of a plan
+type execution struct {
+	executor *Executor
+	plan     *Plan
+
+	mu       sync.Mutex
+	progress *Progress
+}
+
+// stepOutcome is the result of running a step
+type stepOutcome struct {
+	stepID string
+	err    error
+}
+
+func (r *execution) run(ctx context.Context) (*Progress, error) {
+	failure := r.failedStep()
+
+	started := make(map[string]bool, len(r.plan.Steps))
+	outcomes := make(chan stepOutcome)
+	running := 0
+
+	for {
+		if failure == nil && ctx.Err() == nil {
+			for i := range r.plan.Steps {
+				step := &r.plan.Steps[i]
+				if started[step.ID] || !r.ready(step) {
+					continue
+				}
+
+				started[step.ID] = true
+				running++
+				go func() {
+					outcomes <- stepOutcome{stepID: step.ID, err: r.executeStep(ctx, step)}
+				}()
+			}
+		}
+
+		if running == 0 {
+			break
+		}
+
+		outcome := <-outcomes
+		running--
+
+		if outcome.err != nil && ctx.Err() == nil && failure == nil {
+			failure = fmt.Errorf("step %s failed: %w", outcome.stepID, outcome.err)
+		}
+	}
+
+	if failure != nil {
+		return r.rollback(ctx, failure)
+	}
+
+	if ctx.Err() != nil {
+		return r.snapshot(), ctx.Err()
+	}
+
+	r.mu.Lock()
+	r.progress.Status = StatusCompleted
+	r.mu.Unlock()
+	r.persist(ctx)
+
+	r.executor.logger.Info("Plan completed", zap.String("plan", r.plan.ID))
+
+	return r.snapshot(), nil
+}
+
+// failedStep returns the failure of a step that failed before a restart
+func (r *execution) failedStep() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range r.progress.StepIDs {
+		if step := r.progress.Steps[id]; step.Status == StepFailed {
+			return fmt.Errorf("step %s failed: %s", id, step.Error)
+		}
+	}
+	return nil
+}
+
+// ready reports whether a step still has to run and the steps it depends on have completed
+func (r *execution) ready(step *Step) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.progress.Steps[step.ID].Status == StepCompleted {
+		return false
+	}
+
+	for _, dependency := range step.DependsOn {
+		if r.progress.Steps[dependency].Status != StepCompleted {
+			return false
+		}
+	}
+
+	return true
+}
+
+// executeStep sends the messages of the step and waits for its IBC packets to be received. The
+// tx hash is persisted before the tx is submitted, so a step interrupted after that is looked up
+// on resume and only sent again when its tx never made it into a block.
+func (r *execution) executeStep(ctx context.Context, step *Step) error {
+	e := r.executor
+	logger := e.logger.With(zap.String("plan", r.plan.ID), zap.String("step", step.ID))
+
+	r.mu.Lock()
+	stepProgress := *r.progress.Steps[step.ID]
+	r.mu.Unlock()
+
+	var result *stepResult
+	var err error
+
+	switch {
+	case stepProgress.Status == StepIncluded && stepProgress.TxHash != "":
+		logger.Info("Resuming step", zap.String("tx_hash", stepProgress.TxHash))
+
+		result, err = e.lookup(ctx, step.Message.ChainID, stepProgress.TxHash)
+		if err != nil {
+			return r.fail(ctx, step.ID, fmt.Errorf("failed to look up tx %s: %w", stepProgress.TxHash, err))
+		}
+
+	case stepProgress.Status == StepBroadcast && stepProgress.TxHash != "":
+		logger.Info("Resuming broadcast step", zap.String("tx_hash", stepProgress.TxHash))
+
+		result, err = e.lookup(ctx, step.Message.ChainID, stepProgress.TxHash)
+		switch {
+		case errors.Is(err, connection.ErrTxDropped):
+			// The tx is neither on chain nor in the mempool, sending the step again cannot
+			// execute it twice
+			logger.Warn("Recorded tx was dropped, sending the step again", zap.String("tx_hash", stepProgress.TxHash))
+
+			result, err = r.sendStep(ctx, step, logger)
+			if err != nil {
+				return r.fail(ctx, step.ID, err)
+			}
+		case err != nil:
+			// The tx may still be included, so the step is not sent again
+			return r.fail(ctx, step.ID, fmt.Errorf("failed to look up tx %s: %w", stepProgress.TxHash, err))
+		}
+
+	default:
+		result, err = r.sendStep(ctx, step, logger)
+		if err != nil {
+			return r.fail(ctx, step.ID, err)
+		}
+	}
+
+	if result.TxHash != "" && stepProgress.Status != StepIncluded {
+		r.update(step.ID, func(p *StepProgress) {
+			p.Status = StepIncluded
+			p.Height = result.Height
+		})
+		r.persist(ctx)
+	}
+
+	// Nothing was sent in a dry run or for a step without messages
+	if step.IBCDestination != "" && result.TxHash != "" {
+		logger.Info("Waiting for IBC packets to be received", zap.String("destination", step.IBCDestination))
+
+		confirmCtx, cancel := context.WithTimeout(ctx, e.confirmTimeout)
+		err := e.confirm(confirmCtx, step.Message.ChainID, step.IBCDestination, result.Events)
+		cancel()
+		if err != nil {
+			return r.fail(ctx, step.ID, fmt.Errorf("IBC packets not received on %s: %w", step.IBCDestination, err))
+		}
+	}
+
+	r.mu.Lock()
+	r.progress.Completed = append(r.progress.Completed, step.ID)
+	r.mu.Unlock()
+	r.update(step.ID, func(p *StepProgress) { p.Status = StepCompleted })
+	r.persist(ctx)
+
+	logger.Info("Step completed", zap.String("tx_hash", result.TxHash))
+
+	return nil
+}
+
+// sendStep submits the messages of the step, persisting the tx hash before the tx is submitted,
+// and waits for the tx to be included
+func (r *execution) sendStep(ctx context.Context, step *Step, logger *zap.Logger) (*stepResult, error) {
+	e := r.executor
+
+	// Nothing is sent unless the attempt is persisted
+	r.update(step.ID, func(p *StepProgress) {
+		p.Status = StepRunning
+		p.TxHash = ""
+	})
+	if err := r.save(ctx); err != nil {
+		return nil, fmt.Errorf("failed to save plan progress: %w", err)
+	}
+
+	logger.Info("Executing step", zap.String("chain", step.Message.ChainID))
+
+	txHash, err := e.broadcast(ctx, step.Message, func(txHash string) error {
+		r.update(step.ID, func(p *StepProgress) {
+			p.Status = StepBroadcast
+			p.TxHash = txHash
+		})
+		return r.save(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing was sent in a dry run or for a step without messages
+	if txHash == "" {
+		return &stepResult{}, nil
+	}
+
+	result, err := e.lookup(ctx, step.Message.ChainID, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for tx %s: %w", txHash, err)
+	}
+
+	return result, nil
+}
+
+// fail records the failure of a step, unless the plan was cancelled so the step is resumed
+func (r *execution) fail(ctx context.Context, stepID string, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+
+	r.update(stepID, func(p *StepProgress) {
+		p.Status = StepFailed
+		p.Error = err.Error()
+	})
+	r.persist(ctx)
+
+	return err
+}
+
+// rollback runs the rollback of the completed steps in reverse completion order
+func (r *execution) rollback(ctx context.Context, failure error) (*Progress, error) {
+	e := r.executor
+	e.logger.Error("Plan failed, rolling back completed steps",
+		zap.String("plan", r.plan.ID),
+		zap.Error(failure),
+	)
+
+	r.mu.Lock()
+	r.progress.Error = failure.Error()
+	completed := slices.Clone(r.progress.Completed)
+	r.mu.Unlock()
+
+	steps := make(map[string]*Step, len(r.plan.Steps))
+	for i := range r.plan.Steps {
+		steps[r.plan.Steps[i].ID] = &r.plan.Steps[i]
+	}
+
+	status := StatusRolledBack
+	var rollbackErrs []error
+
+	for i := len(completed) - 1; i >= 0; i-- {
+		step := steps[completed[i]]
+		if step.Rollback == nil {
+			continue
+		}
+
+		r.mu.Lock()
+		stepStatus := r.progress.Steps[step.ID].Status
+		r.mu.Unlock()
+		if stepStatus == StepRolledBack {
+			continue
+		}
+
+		result, err := e.send(ctx, *step.Rollback)
+		if err != nil {
+			e.logger.Error("Failed to roll back step",
+				zap.String("plan", r.plan.ID),
+				zap.String("step", step.ID),
+				zap.Error(err),
+			)
+
+			status = StatusFailed
+			rollbackErrs = append(rollbackErrs, fmt.Errorf("rollback of step %s failed: %w", step.ID, err))
+			r.update(step.ID, func(p *StepProgress) {
+				p.Status = StepRollbackFailed
+				p.Error = err.Error()
+			})
+			r.persist(ctx)
+			continue
+		}
+
+		r.update(step.ID, func(p *StepProgress) {
+			p.Status = StepRolledBack
+			p.RollbackTxHash = result.TxHash
+		})
+		r.persist(ctx)
+	}
+
+	r.mu.Lock()
+	r.progress.Status = status
+	r.mu.Unlock()
+	r.persist(ctx)
+
+	return r.snapshot(), fmt.Errorf("%w: %w", ErrPlanFailed, errors.Join(append([]error{failure}, rollbackErrs...)...))
+}
+
+// update changes the progress of a step
+func (r *execution) update(stepID string, change func(p *StepProgress)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	step := r.progress.Steps[stepID]
+	change(step)
+	step.UpdatedAt = time.Now()
+	r.progress.UpdatedAt = step.UpdatedAt
+}
+
+// persist saves the progress, failures are logged as the plan carries on in memory
+func (r *execution) persist(ctx context.Context) {
+	if err := r.save(ctx); err != nil {
+		r.executor.logger.Error("Failed to save plan progress",
+			zap.String("plan", r.plan.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// save persists the progress
+func (r *execution) save(ctx context.Context) error {
+	progress := r.snapshot()
+
+	// The progress is persisted even when the plan is being cancelled
+	return r.executor.store.Save(context.WithoutCancel(ctx), progress)
+}
+
+// snapshot returns a copy of the progress
+func (r *execution) snapshot() *Progress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.progress.clone()
+}
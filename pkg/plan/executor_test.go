@@ -0,0 +1,481 @@
+package plan
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/margined-protocol/locust-core/pkg/connection"
+	"github.com/margined-protocol/locust-core/pkg/ibc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+// fakeChains records the messages sent by the executor, identifying each by its chain ID
+type fakeChains struct {
+	mu        sync.Mutex
+	sent      []string
+	confirmed []string
+	lookedUp  []string
+	failures  map[string]error
+	// block holds sends to a chain until the context is done
+	block map[string]bool
+	// dropped lists the txs that left the mempool without being included
+	dropped map[string]bool
+}
+
+func newFakeChains() *fakeChains {
+	return &fakeChains{
+		failures: make(map[string]error),
+		block:    make(map[string]bool),
+		dropped:  make(map[string]bool),
+	}
+}
+
+func (f *fakeChains) broadcast(ctx context.Context, msg connection.ChainMessage, record func(txHash string) error) (string, error) {
+	f.mu.Lock()
+	f.sent = append(f.sent, msg.ChainID)
+	err := f.failures[msg.ChainID]
+	block := f.block[msg.ChainID]
+	f.mu.Unlock()
+
+	if block {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+
+	// Failures are rejections of the recorded tx
+	txHash := "TX-" + msg.ChainID
+	if recordErr := record(txHash); recordErr != nil {
+		return "", recordErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return txHash, nil
+}
+
+func (f *fakeChains) send(ctx context.Context, msg connection.ChainMessage) (*stepResult, error) {
+	f.mu.Lock()
+	f.sent = append(f.sent, msg.ChainID)
+	err := f.failures[msg.ChainID]
+	block := f.block[msg.ChainID]
+	f.mu.Unlock()
+
+	if block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &stepResult{TxHash: "TX-" + msg.ChainID, Height: 10, Events: transferEvents()}, nil
+}
+
+func (f *fakeChains) lookup(_ context.Context, _, txHash string) (*stepResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lookedUp = append(f.lookedUp, txHash)
+	if f.dropped[txHash] {
+		return nil, connection.ErrTxDropped
+	}
+	if err := f.failures["lookup-"+txHash]; err != nil {
+		return nil, err
+	}
+	return &stepResult{TxHash: txHash, Height: 10, Events: transferEvents()}, nil
+}
+
+func (f *fakeChains) confirm(_ context.Context, _, destChainID string, events []abci.Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.confirmed = append(f.confirmed, destChainID)
+	if _, err := ibc.SentPackets(events); err != nil {
+		return err
+	}
+	return f.failures["confirm-"+destChainID]
+}
+
+func (f *fakeChains) sentMessages() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.sent...)
+}
+
+func (f *fakeChains) lookedUpTxs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.lookedUp...)
+}
+
+func transferEvents() []abci.Event {
+	return []abci.Event{{
+		Type: "send_packet",
+		Attributes: []abci.EventAttribute{
+			{Key: "packet_sequence", Value: "12"},
+			{Key: "packet_dst_port", Value: "transfer"},
+			{Key: "packet_dst_channel", Value: "channel-0"},
+		},
+	}}
+}
+
+func newTestExecutor(store Store, chains *fakeChains) *Executor {
+	return &Executor{
+		logger:         zap.NewNop(),
+		store:          store,
+		send:           chains.send,
+		broadcast:      chains.broadcast,
+		lookup:         chains.lookup,
+		confirm:        chains.confirm,
+		confirmTimeout: time.Second,
+	}
+}
+
+func rollbackMsg(chainID string) *connection.ChainMessage {
+	msg := connection.NewChainMsg(chainID, nil)
+	return &msg
+}
+
+// withdrawTransferDeposit withdraws from Mars, transfers to dYdX and deposits to a subaccount
+func withdrawTransferDeposit() *Plan {
+	return &Plan{
+		ID: "rebalance-1",
+		Steps: []Step{
+			{
+				ID:       "withdraw",
+				Message:  connection.NewAuthzChainMsg("neutron-1", nil),
+				Rollback: rollbackMsg("neutron-1-deposit"),
+			},
+			{
+				ID:             "transfer",
+				Message:        connection.NewAuthzChainMsg("neutron-1-transfer", nil),
+				DependsOn:      []string{"withdraw"},
+				IBCDestination: "dydx-mainnet-1",
+				Rollback:       rollbackMsg("dydx-mainnet-1-transfer"),
+			},
+			{
+				ID:        "deposit",
+				Message:   connection.NewChainMsg("dydx-mainnet-1", nil),
+				DependsOn: []string{"transfer"},
+			},
+		},
+	}
+}
+
+func TestExecuteRunsStepsInOrder(t *testing.T) {
+	chains := newFakeChains()
+	store := NewMemoryStore()
+
+	progress, err := newTestExecutor(store, chains).Execute(context.Background(), withdrawTransferDeposit())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"neutron-1", "neutron-1-transfer", "dydx-mainnet-1"}, chains.sentMessages())
+	assert.Equal(t, []string{"dydx-mainnet-1"}, chains.confirmed)
+	assert.Equal(t, StatusCompleted, progress.Status)
+	assert.Equal(t, []string{"withdraw", "transfer", "deposit"}, progress.Completed)
+	assert.Equal(t, "TX-neutron-1-transfer", progress.Steps["transfer"].TxHash)
+
+	saved, err := store.Load(context.Background(), "rebalance-1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, saved.Status)
+
+	// A completed plan is not executed again
+	_, err = newTestExecutor(store, chains).Execute(context.Background(), withdrawTransferDeposit())
+	require.NoError(t, err)
+	assert.Len(t, chains.sentMessages(), 3)
+}
+
+func TestExecuteRollsBackOnFailure(t *testing.T) {
+	chains := newFakeChains()
+	chains.failures["dydx-mainnet-1"] = errors.New("insufficient funds")
+
+	progress, err := newTestExecutor(NewMemoryStore(), chains).Execute(context.Background(), withdrawTransferDeposit())
+	require.ErrorIs(t, err, ErrPlanFailed)
+	assert.ErrorContains(t, err, "insufficient funds")
+
+	// Completed steps are compensated in reverse order
+	assert.Equal(t, []string{
+		"neutron-1", "neutron-1-transfer", "dydx-mainnet-1",
+		"dydx-mainnet-1-transfer", "neutron-1-deposit",
+	}, chains.sentMessages())
+
+	assert.Equal(t, StatusRolledBack, progress.Status)
+	assert.Equal(t, StepFailed, progress.Steps["deposit"].Status)
+	assert.Equal(t, StepRolledBack, progress.Steps["transfer"].Status)
+	assert.Equal(t, "TX-dydx-mainnet-1-transfer", progress.Steps["transfer"].RollbackTxHash)
+	assert.Equal(t, StepRolledBack, progress.Steps["withdraw"].Status)
+}
+
+func TestExecuteRollsBackOnMissingReceipt(t *testing.T) {
+	chains := newFakeChains()
+	chains.failures["confirm-dydx-mainnet-1"] = ibc.ErrPacketTimedOut
+
+	progress, err := newTestExecutor(NewMemoryStore(), chains).Execute(context.Background(), withdrawTransferDeposit())
+	require.ErrorIs(t, err, ErrPlanFailed)
+	assert.ErrorIs(t, err, ibc.ErrPacketTimedOut)
+
+	// The transfer itself is not compensated, its funds are refunded when the packet times out
+	assert.Equal(t, []string{"neutron-1", "neutron-1-transfer", "neutron-1-deposit"}, chains.sentMessages())
+	assert.Equal(t, StepFailed, progress.Steps["transfer"].Status)
+	assert.Equal(t, StepPending, progress.Steps["deposit"].Status)
+}
+
+func TestExecuteRollbackFailure(t *testing.T) {
+	chains := newFakeChains()
+	chains.failures["dydx-mainnet-1"] = errors.New("insufficient funds")
+	chains.failures["dydx-mainnet-1-transfer"] = errors.New("out of gas")
+
+	progress, err := newTestExecutor(NewMemoryStore(), chains).Execute(context.Background(), withdrawTransferDeposit())
+	require.ErrorIs(t, err, ErrPlanFailed)
+	assert.ErrorContains(t, err, "rollback of step transfer failed")
+
+	// The remaining rollbacks still run
+	assert.Equal(t, StatusFailed, progress.Status)
+	assert.Equal(t, StepRollbackFailed, progress.Steps["transfer"].Status)
+	assert.Equal(t, StepRolledBack, progress.Steps["withdraw"].Status)
+}
+
+func TestExecuteResumes(t *testing.T) {
+	p := withdrawTransferDeposit()
+	store := NewMemoryStore()
+
+	// The bot restarted while waiting for the transfer to be received
+	progress := newProgress(p)
+	progress.Steps["withdraw"].Status = StepCompleted
+	progress.Steps["transfer"].Status = StepIncluded
+	progress.Steps["transfer"].TxHash = "TRANSFER"
+	progress.Completed = []string{"withdraw"}
+	require.NoError(t, store.Save(context.Background(), progress))
+
+	chains := newFakeChains()
+	progress, err := newTestExecutor(store, chains).Execute(context.Background(), p)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"dydx-mainnet-1"}, chains.sentMessages())
+	assert.Equal(t, []string{"TRANSFER", "TX-dydx-mainnet-1"}, chains.lookedUpTxs())
+	assert.Equal(t, []string{"dydx-mainnet-1"}, chains.confirmed)
+	assert.Equal(t, []string{"withdraw", "transfer", "deposit"}, progress.Completed)
+}
+
+func TestExecuteRecordsTxHashBeforeBroadcast(t *testing.T) {
+	store := NewMemoryStore()
+	chains := newFakeChains()
+	executor := newTestExecutor(store, chains)
+
+	var recorded []StepStatus
+	executor.broadcast = func(ctx context.Context, msg connection.ChainMessage, record func(txHash string) error) (string, error) {
+		return chains.broadcast(ctx, msg, func(txHash string) error {
+			if err := record(txHash); err != nil {
+				return err
+			}
+
+			// The hash is persisted before the tx is submitted
+			saved, err := store.Load(ctx, "rebalance-1")
+			require.NoError(t, err)
+			for _, step := range saved.Steps {
+				if step.TxHash == txHash {
+					recorded = append(recorded, step.Status)
+				}
+			}
+			return nil
+		})
+	}
+
+	_, err := executor.Execute(context.Background(), withdrawTransferDeposit())
+	require.NoError(t, err)
+	assert.Equal(t, []StepStatus{StepBroadcast, StepBroadcast, StepBroadcast}, recorded)
+}
+
+func TestExecuteResumesBroadcastStep(t *testing.T) {
+	tests := []struct {
+		name      string
+		dropped   bool
+		lookupErr error
+		sent      []string
+		status    Status
+	}{
+		{
+			name:   "included",
+			sent:   []string{"dydx-mainnet-1"},
+			status: StatusCompleted,
+		},
+		{
+			name:    "dropped",
+			dropped: true,
+			sent:    []string{"neutron-1-transfer", "dydx-mainnet-1"},
+			status:  StatusCompleted,
+		},
+		{
+			// The tx may still be included so the step is not sent again
+			name:      "not found in time",
+			lookupErr: connection.ErrTxTimeout,
+			sent:      []string{"neutron-1-deposit"},
+			status:    StatusRolledBack,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := withdrawTransferDeposit()
+			store := NewMemoryStore()
+
+			// The bot restarted after recording the transfer tx, it may have been submitted
+			progress := newProgress(p)
+			progress.Steps["withdraw"].Status = StepCompleted
+			progress.Steps["transfer"].Status = StepBroadcast
+			progress.Steps["transfer"].TxHash = "TRANSFER"
+			progress.Completed = []string{"withdraw"}
+			require.NoError(t, store.Save(context.Background(), progress))
+
+			chains := newFakeChains()
+			chains.dropped["TRANSFER"] = tt.dropped
+			chains.failures["lookup-TRANSFER"] = tt.lookupErr
+
+			progress, err := newTestExecutor(store, chains).Execute(context.Background(), p)
+			if tt.status == StatusCompleted {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, ErrPlanFailed)
+			}
+
+			assert.Equal(t, tt.sent, chains.sentMessages())
+			assert.Equal(t, "TRANSFER", chains.lookedUpTxs()[0])
+			assert.Equal(t, tt.status, progress.Status)
+		})
+	}
+}
+
+func TestExecuteCancelled(t *testing.T) {
+	p := withdrawTransferDeposit()
+	store := NewMemoryStore()
+
+	chains := newFakeChains()
+	chains.block["dydx-mainnet-1"] = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		assert.Eventually(t, func() bool { return len(chains.sentMessages()) == 3 }, time.Second, time.Millisecond)
+		cancel()
+	}()
+
+	// Cancelling leaves the plan to be resumed instead of rolling it back
+	progress, err := newTestExecutor(store, chains).Execute(ctx, p)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, StatusRunning, progress.Status)
+	assert.Equal(t, StepRunning, progress.Steps["deposit"].Status)
+	assert.Len(t, chains.sentMessages(), 3)
+
+	resumed := newFakeChains()
+	progress, err = newTestExecutor(store, resumed).Execute(context.Background(), p)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dydx-mainnet-1"}, resumed.sentMessages())
+	assert.Equal(t, StatusCompleted, progress.Status)
+}
+
+func TestExecuteRunsIndependentStepsConcurrently(t *testing.T) {
+	var started sync.WaitGroup
+	started.Add(2)
+
+	chains := newFakeChains()
+	executor := newTestExecutor(NewMemoryStore(), chains)
+	broadcast := executor.broadcast
+	executor.broadcast = func(ctx context.Context, msg connection.ChainMessage, record func(txHash string) error) (string, error) {
+		if msg.ChainID != "osmosis-1" {
+			// Both independent steps must be in flight before either completes
+			started.Done()
+			started.Wait()
+		}
+		return broadcast(ctx, msg, record)
+	}
+
+	p := &Plan{
+		ID: "parallel",
+		Steps: []Step{
+			{ID: "neutron", Message: connection.NewChainMsg("neutron-1", nil)},
+			{ID: "dydx", Message: connection.NewChainMsg("dydx-mainnet-1", nil)},
+			{ID: "osmosis", Message: connection.NewChainMsg("osmosis-1", nil), DependsOn: []string{"neutron", "dydx"}},
+		},
+	}
+
+	progress, err := executor.Execute(context.Background(), p)
+	require.NoError(t, err)
+	assert.Equal(t, "osmosis", progress.Completed[2])
+}
+
+func TestExecutePlanMismatch(t *testing.T) {
+	store := NewMemoryStore()
+	chains := newFakeChains()
+
+	_, err := newTestExecutor(store, chains).Execute(context.Background(), withdrawTransferDeposit())
+	require.NoError(t, err)
+
+	changed := withdrawTransferDeposit()
+	changed.Steps = changed.Steps[:2]
+
+	_, err = newTestExecutor(store, chains).Execute(context.Background(), changed)
+	assert.ErrorIs(t, err, ErrPlanMismatch)
+}
+
+func TestPlanValidate(t *testing.T) {
+	msg := connection.NewChainMsg("neutron-1", nil)
+
+	tests := []struct {
+		name    string
+		plan    Plan
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			plan: Plan{ID: "p", Steps: []Step{{ID: "a", Message: msg}, {ID: "b", Message: msg, DependsOn: []string{"a"}}}},
+		},
+		{
+			name:    "missing plan ID",
+			plan:    Plan{Steps: []Step{{ID: "a", Message: msg}}},
+			wantErr: true,
+		},
+		{
+			name:    "no steps",
+			plan:    Plan{ID: "p"},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate step",
+			plan:    Plan{ID: "p", Steps: []Step{{ID: "a", Message: msg}, {ID: "a", Message: msg}}},
+			wantErr: true,
+		},
+		{
+			name:    "missing chain",
+			plan:    Plan{ID: "p", Steps: []Step{{ID: "a"}}},
+			wantErr: true,
+		},
+		{
+			name:    "depends on later step",
+			plan:    Plan{ID: "p", Steps: []Step{{ID: "a", Message: msg, DependsOn: []string{"b"}}, {ID: "b", Message: msg}}},
+			wantErr: true,
+		},
+		{
+			name:    "depends on itself",
+			plan:    Plan{ID: "p", Steps: []Step{{ID: "a", Message: msg, DependsOn: []string{"a"}}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.plan.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
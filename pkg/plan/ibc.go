@@ -0,0 +1,49 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/margined-protocol/locust-core/pkg/connection"
+	"github.com/margined-protocol/locust-core/pkg/ibc"
+	"go.uber.org/zap"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+// packetConfirmer waits for the packets sent by a step to complete, following each packet on
+// both chains with the IBC lifecycle tracker
+type packetConfirmer struct {
+	logger         *zap.Logger
+	clientRegistry *connection.ClientRegistry
+}
+
+func newPacketConfirmer(logger *zap.Logger, clientRegistry *connection.ClientRegistry) *packetConfirmer {
+	return &packetConfirmer{
+		logger:         logger,
+		clientRegistry: clientRegistry,
+	}
+}
+
+// confirm waits until every packet sent in the events has been received on the destination
+// chain with a successful acknowledgement. A packet that timed out or failed is returned as
+// soon as it is observed, with ibc.ErrPacketTimedOut or ibc.ErrPacketErrorAck.
+func (c *packetConfirmer) confirm(ctx context.Context, sourceChainID, destChainID string, events []abci.Event) error {
+	packets, err := ibc.SentPackets(events)
+	if err != nil {
+		return err
+	}
+
+	tracker, err := ibc.NewPacketTracker(c.logger, c.clientRegistry, sourceChainID, destChainID)
+	if err != nil {
+		return err
+	}
+
+	for _, packet := range packets {
+		if err := tracker.WaitForCompletion(ctx, packet); err != nil {
+			return fmt.Errorf("packet %d on %s: %w", packet.Sequence, packet.SourceChannel, err)
+		}
+	}
+
+	return nil
+}
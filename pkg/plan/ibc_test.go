@@ -0,0 +1,25 @@
+package plan
+
+import (
+	"context"
+	"testing"
+
+	"github.com/margined-protocol/locust-core/pkg/ibc"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+func TestConfirmWithoutPackets(t *testing.T) {
+	confirmer := newPacketConfirmer(zap.NewNop(), nil)
+
+	events := []abci.Event{{
+		Type:       "transfer",
+		Attributes: []abci.EventAttribute{{Key: "recipient", Value: "dydx1abc"}},
+	}}
+
+	// Nothing is tracked for a step that sent no packets
+	err := confirmer.confirm(context.Background(), "neutron-1", "dydx-mainnet-1", events)
+	assert.ErrorIs(t, err, ibc.ErrNoSendPacket)
+}
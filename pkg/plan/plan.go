@@ -0,0 +1,77 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/margined-protocol/locust-core/pkg/connection"
+)
+
+// Step is a batch of messages on one chain within a plan
+type Step struct {
+	// ID identifies the step within the plan and in the persisted progress
+	ID string
+	// Message holds the messages of the step and the chain they are sent to
+	Message connection.ChainMessage
+	// DependsOn lists the earlier steps that must complete before this step starts
+	DependsOn []string
+	// IBCDestination is the chain receiving the IBC packets sent by the step, when set the
+	// step only completes once the packets are received there
+	IBCDestination string
+	// Rollback compensates the step when a later step fails, e.g. depositing back what the
+	// step withdrew
+	Rollback *connection.ChainMessage
+}
+
+// Plan is an ordered list of steps executed across chains. Steps start as soon as the steps
+// they depend on have completed, and completed steps are rolled back in reverse order when a
+// step fails.
+type Plan struct {
+	// ID identifies the persisted progress of the plan, a restarted bot resumes a plan by
+	// executing it again with the same ID
+	ID    string
+	Steps []Step
+}
+
+// Validate checks the step IDs are unique and only depend on earlier steps
+func (p *Plan) Validate() error {
+	if p.ID == "" {
+		return errors.New("plan ID must be set")
+	}
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("plan %s has no steps", p.ID)
+	}
+
+	seen := make(map[string]bool, len(p.Steps))
+	for _, step := range p.Steps {
+		if step.ID == "" {
+			return fmt.Errorf("plan %s has a step without an ID", p.ID)
+		}
+		if seen[step.ID] {
+			return fmt.Errorf("plan %s has duplicate step %s", p.ID, step.ID)
+		}
+		if step.Message.ChainID == "" {
+			return fmt.Errorf("step %s has no chain ID", step.ID)
+		}
+
+		// Depending on earlier steps only keeps the plan acyclic
+		for _, dependency := range step.DependsOn {
+			if !seen[dependency] {
+				return fmt.Errorf("step %s depends on %s, which is not an earlier step", step.ID, dependency)
+			}
+		}
+
+		seen[step.ID] = true
+	}
+
+	return nil
+}
+
+// stepIDs returns the IDs of the steps in order
+func (p *Plan) stepIDs() []string {
+	ids := make([]string, len(p.Steps))
+	for i, step := range p.Steps {
+		ids[i] = step.ID
+	}
+	return ids
+}
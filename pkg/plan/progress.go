@@ -0,0 +1,232 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Status is the state of a plan
+type Status string
+
+const (
+	StatusRunning    Status = "running"
+	StatusCompleted  Status = "completed"
+	StatusFailed     Status = "failed"
+	StatusRolledBack Status = "rolled_back"
+)
+
+// StepStatus is the state of a step
+type StepStatus string
+
+const (
+	StepPending StepStatus = "pending"
+	// StepRunning steps are being signed, their tx has not been submitted so they are sent
+	// again when the plan resumes
+	StepRunning StepStatus = "running"
+	// StepBroadcast steps have their tx hash recorded and the tx may have been submitted, when
+	// the plan resumes the tx is looked up and the step only sent again if the tx was dropped
+	StepBroadcast StepStatus = "broadcast"
+	// StepIncluded steps have their tx in a block and wait for their IBC packets to be received
+	StepIncluded       StepStatus = "included"
+	StepCompleted      StepStatus = "completed"
+	StepFailed         StepStatus = "failed"
+	StepRolledBack     StepStatus = "rolled_back"
+	StepRollbackFailed StepStatus = "rollback_failed"
+)
+
+// StepProgress is the persisted state of a step
+type StepProgress struct {
+	Status         StepStatus `json:"status"`
+	ChainID        string     `json:"chain_id"`
+	TxHash         string     `json:"tx_hash,omitempty"`
+	Height         int64      `json:"height,omitempty"`
+	RollbackTxHash string     `json:"rollback_tx_hash,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Progress is the persisted state of a plan
+type Progress struct {
+	PlanID string                   `json:"plan_id"`
+	Status Status                   `json:"status"`
+	Steps  map[string]*StepProgress `json:"steps"`
+	// StepIDs are the steps of the plan in order, a resumed plan must have the same steps
+	StepIDs []string `json:"step_ids"`
+	// Completed lists the completed steps in completion order, they are rolled back in reverse
+	Completed []string  `json:"completed"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// newProgress creates the progress of a plan that has not started
+func newProgress(p *Plan) *Progress {
+	progress := &Progress{
+		PlanID:    p.ID,
+		Status:    StatusRunning,
+		Steps:     make(map[string]*StepProgress, len(p.Steps)),
+		StepIDs:   p.stepIDs(),
+		UpdatedAt: time.Now(),
+	}
+
+	for _, step := range p.Steps {
+		progress.Steps[step.ID] = &StepProgress{
+			Status:    StepPending,
+			ChainID:   step.Message.ChainID,
+			UpdatedAt: progress.UpdatedAt,
+		}
+	}
+
+	return progress
+}
+
+// matches reports whether the progress was recorded for a plan with the same steps
+func (p *Progress) matches(plan *Plan) bool {
+	return p.PlanID == plan.ID && slices.Equal(p.StepIDs, plan.stepIDs())
+}
+
+// clone returns a deep copy of the progress
+func (p *Progress) clone() *Progress {
+	clone := *p
+	clone.Steps = make(map[string]*StepProgress, len(p.Steps))
+	for id, step := range p.Steps {
+		stepCopy := *step
+		clone.Steps[id] = &stepCopy
+	}
+	clone.StepIDs = slices.Clone(p.StepIDs)
+	clone.Completed = slices.Clone(p.Completed)
+	return &clone
+}
+
+// Store persists the progress of plans
+type Store interface {
+	// Load returns the progress of a plan, nil when the plan has not been started
+	Load(ctx context.Context, planID string) (*Progress, error)
+	Save(ctx context.Context, progress *Progress) error
+	Delete(ctx context.Context, planID string) error
+}
+
+// MemoryStore keeps progress in memory, it does not survive a restart
+type MemoryStore struct {
+	mu       sync.Mutex
+	progress map[string]*Progress
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		progress: make(map[string]*Progress),
+	}
+}
+
+// Load returns a copy of the progress of a plan
+func (s *MemoryStore) Load(_ context.Context, planID string) (*Progress, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	progress, ok := s.progress[planID]
+	if !ok {
+		return nil, nil
+	}
+	return progress.clone(), nil
+}
+
+// Save stores a copy of the progress
+func (s *MemoryStore) Save(_ context.Context, progress *Progress) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.progress[progress.PlanID] = progress.clone()
+	return nil
+}
+
+// Delete removes the progress of a plan
+func (s *MemoryStore) Delete(_ context.Context, planID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.progress, planID)
+	return nil
+}
+
+// FileStore persists the progress of each plan as a JSON file in a directory
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a file store, creating the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create plan directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(planID string) string {
+	return filepath.Join(s.dir, filepath.Base(planID)+".json")
+}
+
+// Load reads the progress of a plan
+func (s *FileStore) Load(_ context.Context, planID string) (*Progress, error) {
+	data, err := os.ReadFile(s.path(planID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read progress of plan %s: %w", planID, err)
+	}
+
+	var progress Progress
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return nil, fmt.Errorf("failed to decode progress of plan %s: %w", planID, err)
+	}
+
+	return &progress, nil
+}
+
+// Save writes the progress of a plan, replacing the previous file atomically so a crash
+// cannot leave partial progress behind
+func (s *FileStore) Save(_ context.Context, progress *Progress) error {
+	data, err := json.MarshalIndent(progress, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode progress of plan %s: %w", progress.PlanID, err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, filepath.Base(progress.PlanID)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save progress of plan %s: %w", progress.PlanID, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save progress of plan %s: %w", progress.PlanID, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save progress of plan %s: %w", progress.PlanID, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save progress of plan %s: %w", progress.PlanID, err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path(progress.PlanID)); err != nil {
+		return fmt.Errorf("failed to save progress of plan %s: %w", progress.PlanID, err)
+	}
+
+	return nil
+}
+
+// Delete removes the progress of a plan
+func (s *FileStore) Delete(_ context.Context, planID string) error {
+	if err := os.Remove(s.path(planID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete progress of plan %s: %w", planID, err)
+	}
+	return nil
+}
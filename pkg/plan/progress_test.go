@@ -0,0 +1,62 @@
+package plan
+
+import (
+	"context"
+	"testing"
+
+	"github.com/margined-protocol/locust-core/pkg/connection"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	progress, err := store.Load(ctx, "rebalance-1")
+	require.NoError(t, err)
+	assert.Nil(t, progress)
+
+	progress = newProgress(&Plan{
+		ID:    "rebalance-1",
+		Steps: []Step{{ID: "withdraw", Message: connection.NewChainMsg("neutron-1", nil)}},
+	})
+	progress.Steps["withdraw"].Status = StepIncluded
+	progress.Steps["withdraw"].TxHash = "ABC"
+	require.NoError(t, store.Save(ctx, progress))
+
+	loaded, err := store.Load(ctx, "rebalance-1")
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, loaded.Status)
+	assert.Equal(t, []string{"withdraw"}, loaded.StepIDs)
+	assert.Equal(t, StepIncluded, loaded.Steps["withdraw"].Status)
+	assert.Equal(t, "ABC", loaded.Steps["withdraw"].TxHash)
+	assert.Equal(t, "neutron-1", loaded.Steps["withdraw"].ChainID)
+
+	require.NoError(t, store.Delete(ctx, "rebalance-1"))
+	loaded, err = store.Load(ctx, "rebalance-1")
+	require.NoError(t, err)
+	assert.Nil(t, loaded)
+
+	// Deleting a missing plan is not an error
+	require.NoError(t, store.Delete(ctx, "rebalance-1"))
+}
+
+func TestMemoryStoreCopies(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	progress := newProgress(&Plan{
+		ID:    "p",
+		Steps: []Step{{ID: "a", Message: connection.NewChainMsg("neutron-1", nil)}},
+	})
+	require.NoError(t, store.Save(ctx, progress))
+
+	progress.Steps["a"].Status = StepCompleted
+
+	loaded, err := store.Load(ctx, "p")
+	require.NoError(t, err)
+	assert.Equal(t, StepPending, loaded.Steps["a"].Status)
+}
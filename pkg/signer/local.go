@@ -0,0 +1,76 @@
+package signer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+)
+
+// KeyringSigner signs with a key in a local keyring
+type KeyringSigner struct {
+	keyring keyring.Keyring
+	name    string
+	pubKey  cryptotypes.PubKey
+}
+
+// NewKeyringSigner creates a signer for the key named name in the keyring
+func NewKeyringSigner(kr keyring.Keyring, name string) (*KeyringSigner, error) {
+	record, err := kr.Key(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key %s: %w", name, err)
+	}
+
+	pubKey, err := record.GetPubKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public key of %s: %w", name, err)
+	}
+
+	return &KeyringSigner{
+		keyring: kr,
+		name:    name,
+		pubKey:  pubKey,
+	}, nil
+}
+
+// PubKey returns the public key of the key
+func (s *KeyringSigner) PubKey() cryptotypes.PubKey {
+	return s.pubKey
+}
+
+// Sign signs with the key in the keyring
+func (s *KeyringSigner) Sign(_ context.Context, signBytes []byte, signMode signing.SignMode) ([]byte, error) {
+	signature, _, err := s.keyring.Sign(s.name, signBytes, signMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign with %s: %w", s.name, err)
+	}
+	return signature, nil
+}
+
+// MemorySigner signs with a private key held in memory, it is meant for tests
+type MemorySigner struct {
+	privKey cryptotypes.PrivKey
+}
+
+// NewMemorySigner creates a signer for the private key
+func NewMemorySigner(privKey cryptotypes.PrivKey) *MemorySigner {
+	return &MemorySigner{privKey: privKey}
+}
+
+// GenerateMemorySigner creates a signer for a new secp256k1 key
+func GenerateMemorySigner() *MemorySigner {
+	return NewMemorySigner(secp256k1.GenPrivKey())
+}
+
+// PubKey returns the public key of the private key
+func (s *MemorySigner) PubKey() cryptotypes.PubKey {
+	return s.privKey.PubKey()
+}
+
+// Sign signs with the private key, the sign mode only changes the bytes being signed
+func (s *MemorySigner) Sign(_ context.Context, signBytes []byte, _ signing.SignMode) ([]byte, error) {
+	return s.privKey.Sign(signBytes)
+}
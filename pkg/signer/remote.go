@@ -0,0 +1,167 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/margined-protocol/locust-core/pkg/types"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+)
+
+// DefaultRemoteTimeout bounds each request to a remote signer
+const DefaultRemoteTimeout = 10 * time.Second
+
+// maxResponseSize bounds the responses read from a remote signer
+const maxResponseSize = 1 << 20
+
+// ErrRemoteSigner is returned when a remote signer rejects a request
+var ErrRemoteSigner = errors.New("remote signer error")
+
+// The remote signer protocol is JSON over HTTP, byte fields are base64 encoded:
+//
+//	GET  /v1/keys/{key_id}       returns keyResponse
+//	POST /v1/keys/{key_id}/sign  takes signRequest and returns signResponse
+//
+// Requests are authenticated with a bearer token, errors return errorResponse.
+
+type keyResponse struct {
+	KeyID      string `json:"key_id"`
+	PubKeyType string `json:"pub_key_type"`
+	PubKey     []byte `json:"pub_key"`
+}
+
+type signRequest struct {
+	SignBytes []byte `json:"sign_bytes"`
+	SignMode  string `json:"sign_mode"`
+}
+
+type signResponse struct {
+	Signature []byte `json:"signature"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// RemoteSigner signs with a key held by a remote signing service, so the key never reaches
+// the bot host
+type RemoteSigner struct {
+	keyURL    string
+	authToken string
+	client    *http.Client
+	pubKey    cryptotypes.PubKey
+}
+
+// NewRemoteSigner connects to the remote signer and fetches the public key of the key
+func NewRemoteSigner(ctx context.Context, cfg *types.RemoteSigner) (*RemoteSigner, error) {
+	if cfg.Endpoint == "" || cfg.KeyID == "" {
+		return nil, errors.New("remote signer endpoint and key ID must be set")
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultRemoteTimeout
+	}
+
+	s := &RemoteSigner{
+		keyURL:    strings.TrimSuffix(cfg.Endpoint, "/") + "/v1/keys/" + url.PathEscape(cfg.KeyID),
+		authToken: cfg.AuthToken,
+		client:    &http.Client{Timeout: timeout},
+	}
+
+	var key keyResponse
+	if err := s.do(ctx, http.MethodGet, s.keyURL, nil, &key); err != nil {
+		return nil, fmt.Errorf("failed to get key %s: %w", cfg.KeyID, err)
+	}
+
+	pubKey, err := decodePubKey(key.PubKeyType, key.PubKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key for %s: %w", cfg.KeyID, err)
+	}
+	s.pubKey = pubKey
+
+	return s, nil
+}
+
+// PubKey returns the public key of the remote key
+func (s *RemoteSigner) PubKey() cryptotypes.PubKey {
+	return s.pubKey
+}
+
+// Sign asks the remote signer to sign the bytes, the signature is verified before it is used
+func (s *RemoteSigner) Sign(ctx context.Context, signBytes []byte, signMode signing.SignMode) ([]byte, error) {
+	var res signResponse
+	req := signRequest{SignBytes: signBytes, SignMode: signMode.String()}
+	if err := s.do(ctx, http.MethodPost, s.keyURL+"/sign", req, &res); err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	if !s.pubKey.VerifySignature(signBytes, res.Signature) {
+		return nil, fmt.Errorf("%w: signature does not match the public key", ErrRemoteSigner)
+	}
+
+	return res.Signature, nil
+}
+
+func (s *RemoteSigner) do(ctx context.Context, method, target string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.authToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.authToken)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp errorResponse
+		if json.Unmarshal(data, &errResp) == nil && errResp.Error != "" {
+			return fmt.Errorf("%w: %s: %s", ErrRemoteSigner, resp.Status, errResp.Error)
+		}
+		return fmt.Errorf("%w: %s", ErrRemoteSigner, resp.Status)
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+// decodePubKey decodes an account public key, only secp256k1 keys are supported
+func decodePubKey(keyType string, key []byte) (cryptotypes.PubKey, error) {
+	if keyType != "secp256k1" {
+		return nil, fmt.Errorf("unsupported key type %q", keyType)
+	}
+	if len(key) != secp256k1.PubKeySize {
+		return nil, fmt.Errorf("expected %d bytes, got %d", secp256k1.PubKeySize, len(key))
+	}
+	return &secp256k1.PubKey{Key: key}, nil
+}
@@ -0,0 +1,99 @@
+package signer
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/margined-protocol/locust-core/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/ed25519"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+)
+
+func TestRemoteSigner(t *testing.T) {
+	local := GenerateMemorySigner()
+	server := httptest.NewServer(NewServer("secret", map[string]Signer{"bot": local}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	remote, err := NewRemoteSigner(ctx, &types.RemoteSigner{
+		Endpoint:  server.URL + "/",
+		KeyID:     "bot",
+		AuthToken: "secret",
+	})
+	require.NoError(t, err)
+	assert.True(t, local.PubKey().Equals(remote.PubKey()))
+
+	signature, err := remote.Sign(ctx, []byte("sign bytes"), signing.SignMode_SIGN_MODE_DIRECT)
+	require.NoError(t, err)
+	assert.True(t, local.PubKey().VerifySignature([]byte("sign bytes"), signature))
+}
+
+func TestRemoteSignerErrors(t *testing.T) {
+	server := httptest.NewServer(NewServer("secret", map[string]Signer{
+		"bot":     GenerateMemorySigner(),
+		"ed25519": NewMemorySigner(ed25519.GenPrivKey()),
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		cfg     types.RemoteSigner
+		wantErr string
+	}{
+		{
+			name:    "wrong auth token",
+			cfg:     types.RemoteSigner{Endpoint: server.URL, KeyID: "bot", AuthToken: "wrong"},
+			wantErr: "invalid auth token",
+		},
+		{
+			name:    "unknown key",
+			cfg:     types.RemoteSigner{Endpoint: server.URL, KeyID: "other", AuthToken: "secret"},
+			wantErr: "unknown key other",
+		},
+		{
+			name:    "unsupported key type",
+			cfg:     types.RemoteSigner{Endpoint: server.URL, KeyID: "ed25519", AuthToken: "secret"},
+			wantErr: "unsupported key type",
+		},
+		{
+			name:    "missing key ID",
+			cfg:     types.RemoteSigner{Endpoint: server.URL},
+			wantErr: "must be set",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRemoteSigner(context.Background(), &tt.cfg)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+// wrongKeySigner signs with a different key than the one it reports
+type wrongKeySigner struct {
+	*MemorySigner
+	other *MemorySigner
+}
+
+func (s wrongKeySigner) Sign(ctx context.Context, signBytes []byte, signMode signing.SignMode) ([]byte, error) {
+	return s.other.Sign(ctx, signBytes, signMode)
+}
+
+func TestRemoteSignerVerifiesSignature(t *testing.T) {
+	server := httptest.NewServer(NewServer("", map[string]Signer{
+		"bot": wrongKeySigner{MemorySigner: GenerateMemorySigner(), other: GenerateMemorySigner()},
+	}))
+	defer server.Close()
+
+	remote, err := NewRemoteSigner(context.Background(), &types.RemoteSigner{Endpoint: server.URL, KeyID: "bot"})
+	require.NoError(t, err)
+
+	_, err = remote.Sign(context.Background(), []byte("sign bytes"), signing.SignMode_SIGN_MODE_DIRECT)
+	assert.ErrorIs(t, err, ErrRemoteSigner)
+}
@@ -0,0 +1,96 @@
+package signer
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+)
+
+// Server serves local signers over the remote signer protocol. It is a stand-in for a
+// remote signing service in tests and local development, it applies no signing policy.
+type Server struct {
+	authToken string
+	signers   map[string]Signer
+	mux       *http.ServeMux
+}
+
+// NewServer creates a server for the signers keyed by key ID, requests must carry the auth
+// token when it is set
+func NewServer(authToken string, signers map[string]Signer) *Server {
+	s := &Server{
+		authToken: authToken,
+		signers:   signers,
+		mux:       http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET /v1/keys/{key_id}", s.handleKey)
+	s.mux.HandleFunc("POST /v1/keys/{key_id}/sign", s.handleSign)
+
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.authToken != "" {
+		token := []byte("Bearer " + s.authToken)
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), token) != 1 {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "invalid auth token"})
+			return
+		}
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) handleKey(w http.ResponseWriter, r *http.Request) {
+	keyID := r.PathValue("key_id")
+	signer, ok := s.signers[keyID]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "unknown key " + keyID})
+		return
+	}
+
+	pubKey := signer.PubKey()
+	writeJSON(w, http.StatusOK, keyResponse{
+		KeyID:      keyID,
+		PubKeyType: pubKey.Type(),
+		PubKey:     pubKey.Bytes(),
+	})
+}
+
+func (s *Server) handleSign(w http.ResponseWriter, r *http.Request) {
+	keyID := r.PathValue("key_id")
+	signer, ok := s.signers[keyID]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorResponse{Error: "unknown key " + keyID})
+		return
+	}
+
+	var req signRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxResponseSize)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+
+	signMode, ok := signing.SignMode_value[req.SignMode]
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "unknown sign mode " + req.SignMode})
+		return
+	}
+
+	signature, err := signer.Sign(r.Context(), req.SignBytes, signing.SignMode(signMode))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, signResponse{Signature: signature})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,105 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/ignite/cli/v28/ignite/pkg/cosmosaccount"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+)
+
+// Signer holds the key of an account and signs the bytes of its txs
+type Signer interface {
+	// PubKey returns the public key of the account
+	PubKey() cryptotypes.PubKey
+	// Sign signs the bytes produced for the sign mode
+	Sign(ctx context.Context, signBytes []byte, signMode signing.SignMode) ([]byte, error)
+}
+
+// Account returns an account named name holding only the public key of the signer, txs of
+// the account must be signed through a keyring returned by NewKeyring
+func Account(name string, s Signer) (cosmosaccount.Account, error) {
+	record, err := keyring.NewOfflineRecord(name, s.PubKey())
+	if err != nil {
+		return cosmosaccount.Account{}, fmt.Errorf("failed to create account record: %w", err)
+	}
+
+	return cosmosaccount.Account{Name: name, Record: record}, nil
+}
+
+// signerKeyring serves the key named name from the signer and every other key from the
+// base keyring
+type signerKeyring struct {
+	keyring.Keyring
+
+	name   string
+	record *keyring.Record
+	signer Signer
+}
+
+// NewKeyring returns a keyring that signs for the account named name with the signer, so
+// txs built with the SDK tx factory, or an ignite cosmosclient, are signed by the signer.
+// Other keys are served by the base keyring.
+func NewKeyring(base keyring.Keyring, name string, s Signer) (keyring.Keyring, error) {
+	account, err := Account(name, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &signerKeyring{
+		Keyring: base,
+		name:    name,
+		record:  account.Record,
+		signer:  s,
+	}, nil
+}
+
+func (k *signerKeyring) isSigner(address sdk.Address) bool {
+	return bytes.Equal(address.Bytes(), k.signer.PubKey().Address().Bytes())
+}
+
+// Key returns the record of the signer for its name
+func (k *signerKeyring) Key(uid string) (*keyring.Record, error) {
+	if uid == k.name {
+		return k.record, nil
+	}
+	return k.Keyring.Key(uid)
+}
+
+// KeyByAddress returns the record of the signer for its address
+func (k *signerKeyring) KeyByAddress(address sdk.Address) (*keyring.Record, error) {
+	if k.isSigner(address) {
+		return k.record, nil
+	}
+	return k.Keyring.KeyByAddress(address)
+}
+
+// Sign signs with the signer for its name
+func (k *signerKeyring) Sign(uid string, msg []byte, signMode signing.SignMode) ([]byte, cryptotypes.PubKey, error) {
+	if uid == k.name {
+		return k.sign(msg, signMode)
+	}
+	return k.Keyring.Sign(uid, msg, signMode)
+}
+
+// SignByAddress signs with the signer for its address
+func (k *signerKeyring) SignByAddress(address sdk.Address, msg []byte, signMode signing.SignMode) ([]byte, cryptotypes.PubKey, error) {
+	if k.isSigner(address) {
+		return k.sign(msg, signMode)
+	}
+	return k.Keyring.SignByAddress(address, msg, signMode)
+}
+
+func (k *signerKeyring) sign(msg []byte, signMode signing.SignMode) ([]byte, cryptotypes.PubKey, error) {
+	// The keyring interface carries no context, remote signers bound requests with their timeout
+	signature, err := k.signer.Sign(context.Background(), msg, signMode)
+	if err != nil {
+		return nil, nil, err
+	}
+	return signature, k.signer.PubKey(), nil
+}
@@ -0,0 +1,111 @@
+package signer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cosmos/cosmos-sdk/client/tx"
+	"github.com/cosmos/cosmos-sdk/crypto/hd"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	moduletestutil "github.com/cosmos/cosmos-sdk/types/module/testutil"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+)
+
+func TestKeyringSigner(t *testing.T) {
+	enc := moduletestutil.MakeTestEncodingConfig()
+	kr := keyring.NewInMemory(enc.Codec)
+
+	record, _, err := kr.NewMnemonic("bot", keyring.English, sdk.FullFundraiserPath, keyring.DefaultBIP39Passphrase, hd.Secp256k1)
+	require.NoError(t, err)
+	pubKey, err := record.GetPubKey()
+	require.NoError(t, err)
+
+	s, err := NewKeyringSigner(kr, "bot")
+	require.NoError(t, err)
+	assert.True(t, pubKey.Equals(s.PubKey()))
+
+	signature, err := s.Sign(context.Background(), []byte("sign bytes"), signing.SignMode_SIGN_MODE_DIRECT)
+	require.NoError(t, err)
+	assert.True(t, pubKey.VerifySignature([]byte("sign bytes"), signature))
+
+	_, err = NewKeyringSigner(kr, "missing")
+	assert.Error(t, err)
+}
+
+func TestKeyringSignsTxWithSigner(t *testing.T) {
+	enc := moduletestutil.MakeTestEncodingConfig()
+	s := GenerateMemorySigner()
+
+	kr, err := NewKeyring(keyring.NewInMemory(enc.Codec), "bot", s)
+	require.NoError(t, err)
+
+	txf := tx.Factory{}.
+		WithTxConfig(enc.TxConfig).
+		WithKeybase(kr).
+		WithChainID("neutron-1").
+		WithAccountNumber(7).
+		WithSequence(3).
+		WithSignMode(signing.SignMode_SIGN_MODE_DIRECT)
+
+	txBuilder := enc.TxConfig.NewTxBuilder()
+	txBuilder.SetMemo("locust")
+
+	require.NoError(t, tx.Sign(context.Background(), txf, "bot", txBuilder, true))
+
+	signatures, err := txBuilder.GetTx().GetSignaturesV2()
+	require.NoError(t, err)
+	require.Len(t, signatures, 1)
+	assert.True(t, s.PubKey().Equals(signatures[0].PubKey))
+
+	signBytes, err := authsigning.GetSignBytesAdapter(context.Background(), enc.TxConfig.SignModeHandler(), signing.SignMode_SIGN_MODE_DIRECT, authsigning.SignerData{
+		Address:       sdk.AccAddress(s.PubKey().Address()).String(),
+		ChainID:       "neutron-1",
+		AccountNumber: 7,
+		Sequence:      3,
+		PubKey:        s.PubKey(),
+	}, txBuilder.GetTx())
+	require.NoError(t, err)
+
+	data, ok := signatures[0].Data.(*signing.SingleSignatureData)
+	require.True(t, ok)
+	assert.True(t, s.PubKey().VerifySignature(signBytes, data.Signature))
+}
+
+func TestKeyringServesSignerKey(t *testing.T) {
+	enc := moduletestutil.MakeTestEncodingConfig()
+	s := GenerateMemorySigner()
+
+	kr, err := NewKeyring(keyring.NewInMemory(enc.Codec), "bot", s)
+	require.NoError(t, err)
+
+	record, err := kr.Key("bot")
+	require.NoError(t, err)
+	address, err := record.GetAddress()
+	require.NoError(t, err)
+	assert.Equal(t, sdk.AccAddress(s.PubKey().Address()), address)
+
+	record, err = kr.KeyByAddress(address)
+	require.NoError(t, err)
+	assert.Equal(t, "bot", record.Name)
+
+	signature, pubKey, err := kr.SignByAddress(address, []byte("sign bytes"), signing.SignMode_SIGN_MODE_DIRECT)
+	require.NoError(t, err)
+	assert.True(t, pubKey.VerifySignature([]byte("sign bytes"), signature))
+
+	// Other keys are served by the base keyring
+	_, err = kr.Key("other")
+	assert.Error(t, err)
+	_, _, err = kr.Sign("other", []byte("sign bytes"), signing.SignMode_SIGN_MODE_DIRECT)
+	assert.Error(t, err)
+
+	account, err := Account("bot", s)
+	require.NoError(t, err)
+	bech32, err := account.Address("neutron")
+	require.NoError(t, err)
+	assert.Contains(t, bech32, "neutron1")
+}
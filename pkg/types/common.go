@@ -14,6 +14,20 @@ type SigningKey struct {
 	AppName string `toml:"app_name"`
 	Backend string `toml:"backend"`
 	RootDir string `toml:"root_dir"`
+	// Remote signs with a remote signer instead of the keyring, keeping the key off the host
+	Remote *RemoteSigner `toml:"remote"`
+}
+
+// RemoteSigner is a signing service holding the key of an account
+type RemoteSigner struct {
+	// Endpoint is the base URL of the signer
+	Endpoint string `toml:"endpoint"`
+	// KeyID identifies the key on the signer
+	KeyID string `toml:"key_id"`
+	// Account is the account name the key is used as, usually the signer account
+	Account   string        `toml:"account"`
+	AuthToken string        `toml:"auth_token"`
+	Timeout   time.Duration `toml:"timeout"`
 }
 
 type Chain struct {
@@ -24,8 +38,27 @@ type Chain struct {
 	GasAdjustment *float64             `toml:"gas_adjustment" mapstructure:"gas_adjustment"`
 	GasPrices     *string              `toml:"gas_prices" mapstructure:"gas_prices"`
 	GasDenom      string               `toml:"gas_denom" mapstructure:"gas_denom"`
+	FeeEstimation *FeeEstimation       `toml:"fee_estimation" mapstructure:"fee_estimation"`
 	GRPCEndpoints []GRPCEndpointConfig `toml:"grpc_endpoints" mapstructure:"grpc_endpoints"`
 	RPCEndpoints  []RPCEndpointConfig  `toml:"rpc_endpoints" mapstructure:"rpc_endpoints"`
+	// CoinType is the BIP-44 coin type accounts on the chain are derived with, 118 when zero
+	CoinType uint32 `toml:"coin_type" mapstructure:"coin_type"`
+	// KeyAlgo is the key type of accounts on the chain, "secp256k1" when empty or
+	// "eth_secp256k1" for EVM chains such as Injective and Evmos
+	KeyAlgo string `toml:"key_algo" mapstructure:"key_algo"`
+}
+
+// FeeEstimation replaces the static gas of a chain with simulated gas priced from the chain's
+// fee market, the configured gas prices are used when the fee market cannot be queried
+type FeeEstimation struct {
+	// FeeMarket is the module queried for the gas price: "feemarket", "osmosis" or empty to
+	// always use the configured gas prices
+	FeeMarket       string   `toml:"fee_market" mapstructure:"fee_market"`
+	GasAdjustment   *float64 `toml:"gas_adjustment" mapstructure:"gas_adjustment"`
+	PriceMultiplier *float64 `toml:"price_multiplier" mapstructure:"price_multiplier"`
+	MaxGas          uint64   `toml:"max_gas" mapstructure:"max_gas"`
+	MaxGasPrice     *string  `toml:"max_gas_price" mapstructure:"max_gas_price"`
+	MaxFee          *string  `toml:"max_fee" mapstructure:"max_fee"`
 }
 
 type Config struct {
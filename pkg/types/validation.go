@@ -6,6 +6,28 @@ import (
 
 // ValidateConfig checks if the configuration is valid.
 func ValidateConfig(cfg Config) error {
+	if remote := cfg.Key.Remote; remote != nil {
+		if remote.Endpoint == "" || remote.KeyID == "" || remote.Account == "" {
+			return errors.New("'endpoint', 'key_id', and 'account' must all be provided for a remote signer")
+		}
+	}
+
+	switch cfg.Chain.KeyAlgo {
+	case "", "secp256k1", "eth_secp256k1":
+	default:
+		return errors.New("'key_algo' must be 'secp256k1' or 'eth_secp256k1'")
+	}
+
+	if cfg.Chain.FeeEstimation != nil {
+		if cfg.Chain.Fees != nil {
+			return errors.New("if 'fee_estimation' is provided, 'fees' must not be provided")
+		}
+		if cfg.Chain.GasPrices == nil {
+			return errors.New("'gas_prices' must be provided as the fallback of 'fee_estimation'")
+		}
+		return nil
+	}
+
 	if cfg.Chain.Fees != nil && (cfg.Chain.GasAdjustment != nil || cfg.Chain.Gas != nil || cfg.Chain.GasPrices != nil) {
 		return errors.New("if 'fees' is provided, 'gas', 'gas_adjustment', and 'gas_prices' must not be provided")
 	}